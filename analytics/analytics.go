@@ -0,0 +1,262 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gehoer/profile"
+)
+
+// ItemAccuracy sums up the answers about one item
+type ItemAccuracy struct {
+	Item         string
+	Asked        int
+	Correct      int
+	Accuracy     float64
+	MeanResponse time.Duration
+}
+
+// Filter returns the answers given in one exercise, or all for ""
+func Filter(history []profile.Answer, exerciseName string) []profile.Answer {
+	if exerciseName == "" {
+		return history
+	}
+	var out []profile.Answer
+	for _, a := range history {
+		if a.Exercise == exerciseName {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Accuracy returns the accuracy of each item asked about, the least
+// accurate first
+func Accuracy(history []profile.Answer) []ItemAccuracy {
+	index := make(map[string]int)
+	var items []ItemAccuracy
+	var response []time.Duration
+	for _, a := range history {
+		i, ok := index[a.Item]
+		if !ok {
+			i = len(items)
+			index[a.Item] = i
+			items = append(items, ItemAccuracy{Item: a.Item})
+			response = append(response, 0)
+		}
+		items[i].Asked++
+		if a.Correct {
+			items[i].Correct++
+		}
+		response[i] += a.ResponseTime()
+	}
+	for i := range items {
+		items[i].Accuracy = float64(items[i].Correct) / float64(items[i].Asked)
+		items[i].MeanResponse = response[i] / time.Duration(items[i].Asked)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Accuracy != items[j].Accuracy {
+			return items[i].Accuracy < items[j].Accuracy
+		}
+		return items[i].Item < items[j].Item
+	})
+	return items
+}
+
+// Confusion counts what was answered for what was asked. Counts[i][j] is
+// how often Labels[i] was answered as Labels[j].
+type Confusion struct {
+	Labels []string
+	Counts [][]int
+}
+
+// ConfusionMatrix builds the confusion matrix of the answers given as a key
+// of the same kind as the item asked about, such as "note:treble:4" answered
+// as "note:treble:5". A prefix such as "interval:" keeps one kind of item.
+func ConfusionMatrix(history []profile.Answer, prefix string) Confusion {
+	seen := make(map[string]bool)
+	var pairs [][2]string
+	for _, a := range history {
+		if !strings.HasPrefix(a.Item, prefix) || kind(a.Item) == "" || kind(a.Given) != kind(a.Item) {
+			continue
+		}
+		pairs = append(pairs, [2]string{a.Item, a.Given})
+		seen[a.Item], seen[a.Given] = true, true
+	}
+	var c Confusion
+	for label := range seen {
+		c.Labels = append(c.Labels, label)
+	}
+	sort.Strings(c.Labels)
+	index := make(map[string]int, len(c.Labels))
+	for i, label := range c.Labels {
+		index[label] = i
+	}
+	c.Counts = make([][]int, len(c.Labels))
+	for i := range c.Counts {
+		c.Counts[i] = make([]int, len(c.Labels))
+	}
+	for _, p := range pairs {
+		c.Counts[index[p[0]]][index[p[1]]]++
+	}
+	return c
+}
+
+// kind returns the kind of an item key with its colon, as "note:", or ""
+// for an answer that is no key
+func kind(key string) string {
+	if i := strings.Index(key, ":"); i > 0 {
+		return key[:i+1]
+	}
+	return ""
+}
+
+// Confusions returns the off-diagonal cells of the matrix, the most
+// frequent first
+func (c Confusion) Confusions() []ConfusedPair {
+	var pairs []ConfusedPair
+	for i, row := range c.Counts {
+		for j, n := range row {
+			if i != j && n > 0 {
+				pairs = append(pairs, ConfusedPair{Asked: c.Labels[i], Answered: c.Labels[j], Count: n})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Count > pairs[j].Count })
+	return pairs
+}
+
+// ConfusedPair is an item answered as another
+type ConfusedPair struct {
+	Asked, Answered string
+	Count           int
+}
+
+// DefaultBuckets are the upper bounds of the response-time histogram
+var DefaultBuckets = []time.Duration{
+	time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 8 * time.Second, 13 * time.Second,
+}
+
+// ResponseTimes describes the distribution of the time taken to answer
+type ResponseTimes struct {
+	Count                 int
+	Min, Median, P90, Max time.Duration
+	Mean                  time.Duration
+	Buckets               []Bucket
+}
+
+// Bucket counts the responses up to Upper and above the previous bucket;
+// the last bucket, with Upper 0, counts the slower ones
+type Bucket struct {
+	Upper time.Duration
+	Count int
+}
+
+// ResponseDistribution sums up the response times into a histogram with
+// the bucket bounds, DefaultBuckets when nil
+func ResponseDistribution(history []profile.Answer, bounds []time.Duration) ResponseTimes {
+	if bounds == nil {
+		bounds = DefaultBuckets
+	}
+	r := ResponseTimes{Count: len(history), Buckets: make([]Bucket, len(bounds)+1)}
+	for i, b := range bounds {
+		r.Buckets[i].Upper = b
+	}
+	if len(history) == 0 {
+		return r
+	}
+	times := make([]time.Duration, len(history))
+	var total time.Duration
+	for i, a := range history {
+		t := a.ResponseTime()
+		times[i] = t
+		total += t
+		b := sort.Search(len(bounds), func(k int) bool { return t <= bounds[k] })
+		r.Buckets[b].Count++
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	r.Min, r.Max = times[0], times[len(times)-1]
+	r.Median = times[len(times)/2]
+	r.P90 = times[(len(times)*9+9)/10-1] // the nearest rank, ceil(0.9n)
+	r.Mean = total / time.Duration(len(times))
+	return r
+}
+
+// TrendPoint sums up the answers of one day
+type TrendPoint struct {
+	Day          time.Time
+	Asked        int
+	Correct      int
+	Accuracy     float64
+	MeanResponse time.Duration
+}
+
+// Trend returns the accuracy and response time per day answers were given,
+// in order, with days in the answers' own time zone
+func Trend(history []profile.Answer) []TrendPoint {
+	var points []TrendPoint
+	index := make(map[time.Time]int)
+	var response []time.Duration
+	for _, a := range history {
+		y, m, d := a.Time.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, a.Time.Location())
+		i, ok := index[day]
+		if !ok {
+			i = len(points)
+			index[day] = i
+			points = append(points, TrendPoint{Day: day})
+			response = append(response, 0)
+		}
+		points[i].Asked++
+		if a.Correct {
+			points[i].Correct++
+		}
+		response[i] += a.ResponseTime()
+	}
+	for i := range points {
+		points[i].Accuracy = float64(points[i].Correct) / float64(points[i].Asked)
+		points[i].MeanResponse = response[i] / time.Duration(points[i].Asked)
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Day.Before(points[j].Day) })
+	return points
+}
+
+// Report gathers the statistics of a profile's answers
+type Report struct {
+	Profile   string
+	Generated time.Time
+	Exercise  string // the exercise reported on, or "" for all
+	Answers   int
+	Accuracy  float64
+	Items     []ItemAccuracy
+	Confusion Confusion // asked against answered items
+	Responses ResponseTimes
+	Trend     []TrendPoint
+}
+
+// NewReport reports on the answers a profile gave in one exercise, or in
+// all for ""
+func NewReport(p *profile.Profile, exerciseName string, now time.Time) Report {
+	history := Filter(p.History, exerciseName)
+	r := Report{
+		Profile:   p.Name,
+		Generated: now,
+		Exercise:  exerciseName,
+		Answers:   len(history),
+		Items:     Accuracy(history),
+		Confusion: ConfusionMatrix(history, ""),
+		Responses: ResponseDistribution(history, nil),
+		Trend:     Trend(history),
+	}
+	correct := 0
+	for _, a := range history {
+		if a.Correct {
+			correct++
+		}
+	}
+	if len(history) > 0 {
+		r.Accuracy = float64(correct) / float64(len(history))
+	}
+	return r
+}
@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gehoer/profile"
+)
+
+var testTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// answer returns an answer given at testTime plus the days and hours
+func answer(exerciseName, item, given string, correct bool, days, hours int, response time.Duration) profile.Answer {
+	return profile.Answer{
+		Time:     testTime.AddDate(0, 0, days).Add(time.Duration(hours) * time.Hour),
+		Exercise: exerciseName,
+		Item:     item,
+		Given:    given,
+		Correct:  correct,
+		Response: response.Milliseconds(),
+	}
+}
+
+func testHistory() []profile.Answer {
+	return []profile.Answer{
+		answer("note_reading", "note:treble:4", "note:treble:4", true, 0, 0, time.Second),
+		answer("note_reading", "note:treble:4", "note:treble:5", false, 0, 1, 3*time.Second),
+		answer("note_reading", "note:bass:0", "note:bass:0", true, 0, 2, 2*time.Second),
+		answer("note_reading", "note:treble:5", "note:treble:4", false, 1, 0, 4*time.Second),
+		answer("note_reading", "note:treble:5", "x", false, 1, 1, 6*time.Second),
+		answer("interval", "interval:m3", "interval:M3", false, 2, 0, 2*time.Second),
+		answer("interval", "interval:m3", "interval:m3", true, 2, 1, 4*time.Second),
+	}
+}
+
+func TestFilter(t *testing.T) {
+	history := testHistory()
+	if got := Filter(history, ""); len(got) != len(history) {
+		t.Errorf("%d answers in all, want %d", len(got), len(history))
+	}
+	if got := Filter(history, "interval"); len(got) != 2 || got[0].Item != "interval:m3" {
+		t.Errorf("interval answers %v", got)
+	}
+	if got := Filter(history, "chord"); len(got) != 0 {
+		t.Errorf("chord answers %v", got)
+	}
+}
+
+func TestAccuracy(t *testing.T) {
+	want := []ItemAccuracy{
+		{"note:treble:5", 2, 0, 0, 5 * time.Second},
+		{"interval:m3", 2, 1, 0.5, 3 * time.Second},
+		{"note:treble:4", 2, 1, 0.5, 2 * time.Second},
+		{"note:bass:0", 1, 1, 1, 2 * time.Second},
+	}
+	if got := Accuracy(testHistory()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := Accuracy(nil); len(got) != 0 {
+		t.Errorf("got %v without answers", got)
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   Confusion
+	}{
+		{"", Confusion{
+			Labels: []string{"interval:M3", "interval:m3", "note:bass:0", "note:treble:4", "note:treble:5"},
+			Counts: [][]int{
+				{0, 0, 0, 0, 0},
+				{1, 1, 0, 0, 0},
+				{0, 0, 1, 0, 0},
+				{0, 0, 0, 1, 1},
+				{0, 0, 0, 1, 0},
+			},
+		}},
+		{"interval:", Confusion{
+			Labels: []string{"interval:M3", "interval:m3"},
+			Counts: [][]int{{0, 0}, {1, 1}},
+		}},
+		{"chord:", Confusion{Counts: [][]int{}}},
+	}
+	for _, tt := range tests {
+		if got := ConfusionMatrix(testHistory(), tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+
+	want := []ConfusedPair{
+		{"interval:m3", "interval:M3", 1},
+		{"note:treble:4", "note:treble:5", 1},
+		{"note:treble:5", "note:treble:4", 1},
+	}
+	if got := ConfusionMatrix(testHistory(), "").Confusions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("confusions %v, want %v", got, want)
+	}
+}
+
+func TestResponseDistribution(t *testing.T) {
+	var history []profile.Answer
+	for _, ms := range []int{500, 1000, 1001, 2000, 2500, 3000, 4000, 9000, 13000, 20000} {
+		history = append(history, answer("note_reading", "note:treble:4", "", true, 0, 0, time.Duration(ms)*time.Millisecond))
+	}
+	r := ResponseDistribution(history, nil)
+	if r.Count != 10 || r.Min != 500*time.Millisecond || r.Max != 20*time.Second {
+		t.Errorf("%d responses from %v to %v", r.Count, r.Min, r.Max)
+	}
+	if r.Median != 3*time.Second || r.P90 != 13*time.Second || r.Mean != 5600100*time.Microsecond {
+		t.Errorf("median %v, P90 %v and mean %v, want 3s, 13s and 5.6001s", r.Median, r.P90, r.Mean)
+	}
+	// Responses on a bound fall in the bucket it closes
+	want := []Bucket{
+		{time.Second, 2}, {2 * time.Second, 2}, {3 * time.Second, 2}, {5 * time.Second, 1},
+		{8 * time.Second, 0}, {13 * time.Second, 2}, {0, 1},
+	}
+	if !reflect.DeepEqual(r.Buckets, want) {
+		t.Errorf("buckets %v, want %v", r.Buckets, want)
+	}
+
+	r = ResponseDistribution(history[:1], []time.Duration{time.Second})
+	if r.P90 != 500*time.Millisecond || r.Median != r.P90 || !reflect.DeepEqual(r.Buckets, []Bucket{{time.Second, 1}, {0, 0}}) {
+		t.Errorf("one response: %+v", r)
+	}
+
+	r = ResponseDistribution(nil, nil)
+	if r.Count != 0 || r.Max != 0 || len(r.Buckets) != len(DefaultBuckets)+1 {
+		t.Errorf("no responses: %+v", r)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	oslo := time.FixedZone("CET", 60*60)
+	history := append(testHistory(),
+		// Late on the first day in UTC, but the next day in Oslo
+		profile.Answer{Time: testTime.Add(11*time.Hour + 30*time.Minute).In(oslo), Correct: true, Response: 1000},
+	)
+	want := []TrendPoint{
+		{testTime.Truncate(24 * time.Hour), 3, 2, 2.0 / 3, 2 * time.Second},
+		{time.Date(2024, 3, 2, 0, 0, 0, 0, oslo), 1, 1, 1, time.Second},
+		{testTime.AddDate(0, 0, 1).Truncate(24 * time.Hour), 2, 0, 0, 5 * time.Second},
+		{testTime.AddDate(0, 0, 2).Truncate(24 * time.Hour), 2, 1, 0.5, 3 * time.Second},
+	}
+	if got := Trend(history); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	p := profile.New("Kari", testTime)
+	p.History = testHistory()
+	r := NewReport(p, "note_reading", testTime.AddDate(0, 0, 3))
+	if r.Profile != "Kari" || r.Exercise != "note_reading" || r.Answers != 5 || r.Accuracy != 0.4 {
+		t.Errorf("report %+v", r)
+	}
+	if len(r.Items) != 3 || len(r.Trend) != 2 || r.Responses.Count != 5 {
+		t.Errorf("%d items, %d days and %d responses", len(r.Items), len(r.Trend), r.Responses.Count)
+	}
+	if len(r.Confusion.Labels) != 3 || len(r.Confusion.Confusions()) != 2 {
+		t.Errorf("confusion %v", r.Confusion)
+	}
+
+	if r := NewReport(profile.New("Tom", testTime), "", testTime); r.Answers != 0 || r.Accuracy != 0 {
+		t.Errorf("empty report %+v", r)
+	}
+}
@@ -0,0 +1,207 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gehoer/profile"
+)
+
+// jsonReport is a Report as written to JSON
+type jsonReport struct {
+	Profile   string         `json:"profile"`
+	Generated time.Time      `json:"generated"`
+	Exercise  string         `json:"exercise,omitempty"`
+	Answers   int            `json:"answers"`
+	Accuracy  float64        `json:"accuracy"`
+	Items     []jsonItem     `json:"items"`
+	Confusion jsonConfusion  `json:"confusion"`
+	Responses jsonResponses  `json:"response_times"`
+	Trend     []jsonTrendDay `json:"trend"`
+}
+
+type jsonItem struct {
+	Item         string  `json:"item"`
+	Asked        int     `json:"asked"`
+	Correct      int     `json:"correct"`
+	Accuracy     float64 `json:"accuracy"`
+	MeanResponse int64   `json:"mean_response_ms"`
+}
+
+type jsonConfusion struct {
+	Labels []string `json:"labels"`
+	Counts [][]int  `json:"counts"`
+}
+
+type jsonResponses struct {
+	Count   int          `json:"count"`
+	Min     int64        `json:"min_ms"`
+	Median  int64        `json:"median_ms"`
+	P90     int64        `json:"p90_ms"`
+	Max     int64        `json:"max_ms"`
+	Mean    int64        `json:"mean_ms"`
+	Buckets []jsonBucket `json:"buckets"`
+}
+
+type jsonBucket struct {
+	Upper int64 `json:"upper_ms,omitempty"` // left out for the last, open bucket
+	Count int   `json:"count"`
+}
+
+type jsonTrendDay struct {
+	Day          string  `json:"day"`
+	Asked        int     `json:"asked"`
+	Correct      int     `json:"correct"`
+	Accuracy     float64 `json:"accuracy"`
+	MeanResponse int64   `json:"mean_response_ms"`
+}
+
+// WriteJSON writes the report as indented JSON, with times in
+// milliseconds
+func WriteJSON(w io.Writer, r Report) error {
+	out := jsonReport{
+		Profile:   r.Profile,
+		Generated: r.Generated,
+		Exercise:  r.Exercise,
+		Answers:   r.Answers,
+		Accuracy:  r.Accuracy,
+		Items:     []jsonItem{},
+		Confusion: jsonConfusion{Labels: []string{}, Counts: [][]int{}},
+		Responses: jsonResponses{
+			Count:  r.Responses.Count,
+			Min:    r.Responses.Min.Milliseconds(),
+			Median: r.Responses.Median.Milliseconds(),
+			P90:    r.Responses.P90.Milliseconds(),
+			Max:    r.Responses.Max.Milliseconds(),
+			Mean:   r.Responses.Mean.Milliseconds(),
+		},
+		Trend: []jsonTrendDay{},
+	}
+	if len(r.Confusion.Labels) > 0 {
+		out.Confusion = jsonConfusion{Labels: r.Confusion.Labels, Counts: r.Confusion.Counts}
+	}
+	for _, it := range r.Items {
+		out.Items = append(out.Items, jsonItem{it.Item, it.Asked, it.Correct, it.Accuracy, it.MeanResponse.Milliseconds()})
+	}
+	for _, b := range r.Responses.Buckets {
+		out.Responses.Buckets = append(out.Responses.Buckets, jsonBucket{b.Upper.Milliseconds(), b.Count})
+	}
+	for _, p := range r.Trend {
+		out.Trend = append(out.Trend, jsonTrendDay{p.Day.Format(time.DateOnly), p.Asked, p.Correct, p.Accuracy, p.MeanResponse.Milliseconds()})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// WriteHistoryCSV writes every answer as a CSV row, for spreadsheets
+func WriteHistoryCSV(w io.Writer, history []profile.Answer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "exercise", "item", "given", "correct", "response_ms"})
+	for _, a := range history {
+		cw.Write([]string{
+			a.Time.Format(time.RFC3339),
+			a.Exercise,
+			a.Item,
+			a.Given,
+			strconv.FormatBool(a.Correct),
+			strconv.FormatInt(a.Response, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteAccuracyCSV writes the accuracy of each item as CSV
+func WriteAccuracyCSV(w io.Writer, items []ItemAccuracy) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"item", "asked", "correct", "accuracy", "mean_response_ms"})
+	for _, it := range items {
+		cw.Write([]string{
+			it.Item,
+			strconv.Itoa(it.Asked),
+			strconv.Itoa(it.Correct),
+			strconv.FormatFloat(it.Accuracy, 'f', 3, 64),
+			strconv.FormatInt(it.MeanResponse.Milliseconds(), 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteConfusionCSV writes the confusion matrix as CSV, with the asked
+// items down the first column and the answers across the first row
+func WriteConfusionCSV(w io.Writer, c Confusion) error {
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"asked \\ answered"}, c.Labels...))
+	for i, row := range c.Counts {
+		record := []string{c.Labels[i]}
+		for _, n := range row {
+			record = append(record, strconv.Itoa(n))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportFiles writes the report as JSON and the history, item accuracy and
+// confusion matrix as CSV into dir, named after the profile and the
+// day. It returns the paths written.
+func ExportFiles(dir string, p *profile.Profile, r Report) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", fileName(p.Name), r.Generated.Format(time.DateOnly)))
+	files := []struct {
+		suffix string
+		write  func(io.Writer) error
+	}{
+		{".json", func(w io.Writer) error { return WriteJSON(w, r) }},
+		{"-history.csv", func(w io.Writer) error { return WriteHistoryCSV(w, Filter(p.History, r.Exercise)) }},
+		{"-items.csv", func(w io.Writer) error { return WriteAccuracyCSV(w, r.Items) }},
+		{"-confusion.csv", func(w io.Writer) error { return WriteConfusionCSV(w, r.Confusion) }},
+	}
+	var paths []string
+	for _, f := range files {
+		path := base + f.suffix
+		out, err := os.Create(path)
+		if err != nil {
+			return paths, fmt.Errorf("failed to write report: %w", err)
+		}
+		err = f.write(out)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write report: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// fileName keeps the letters and digits of a name, for file names
+func fileName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "rapport"
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gehoer/profile"
+)
+
+func TestWriteJSON(t *testing.T) {
+	p := profile.New("Kari", testTime)
+	p.History = testHistory()
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, NewReport(p, "interval", testTime)); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Answers   int
+		Items     []map[string]any
+		Confusion struct {
+			Labels []string
+			Counts [][]int
+		}
+		Responses struct {
+			Median  int64 `json:"median_ms"`
+			P90     int64 `json:"p90_ms"`
+			Buckets []map[string]int64
+		} `json:"response_times"`
+		Trend []struct {
+			Day    string
+			Asked  int
+			MeanMS int64 `json:"mean_response_ms"`
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Answers != 2 || len(got.Items) != 1 || got.Items[0]["item"] != "interval:m3" || got.Items[0]["mean_response_ms"] != 3000.0 {
+		t.Errorf("answers and items %d %v", got.Answers, got.Items)
+	}
+	if strings.Join(got.Confusion.Labels, " ") != "interval:M3 interval:m3" || len(got.Confusion.Counts) != 2 {
+		t.Errorf("confusion %v", got.Confusion)
+	}
+	if got.Responses.Median != 4000 || got.Responses.P90 != 4000 || len(got.Responses.Buckets) != len(DefaultBuckets)+1 {
+		t.Errorf("responses %+v", got.Responses)
+	}
+	if last := got.Responses.Buckets[len(DefaultBuckets)]; len(last) != 1 || last["count"] != 0 {
+		t.Errorf("open bucket %v, want only a count", last)
+	}
+	if len(got.Trend) != 1 || got.Trend[0].Day != "2024-03-03" || got.Trend[0].Asked != 2 || got.Trend[0].MeanMS != 3000 {
+		t.Errorf("trend %+v", got.Trend)
+	}
+
+	// An empty report writes empty lists rather than nulls
+	buf.Reset()
+	if err := WriteJSON(&buf, NewReport(profile.New("Tom", testTime), "", testTime)); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); strings.Contains(s, "null") {
+		t.Errorf("empty report has nulls:\n%s", s)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	history := testHistory()
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		want  string
+	}{
+		{"history", func(b *bytes.Buffer) error { return WriteHistoryCSV(b, history[4:6]) }, `time,exercise,item,given,correct,response_ms
+2024-03-02T13:00:00Z,note_reading,note:treble:5,x,false,6000
+2024-03-03T12:00:00Z,interval,interval:m3,interval:M3,false,2000
+`},
+		{"accuracy", func(b *bytes.Buffer) error { return WriteAccuracyCSV(b, Accuracy(history[5:])) }, `item,asked,correct,accuracy,mean_response_ms
+interval:m3,2,1,0.500,3000
+`},
+		{"confusion", func(b *bytes.Buffer) error { return WriteConfusionCSV(b, ConfusionMatrix(history, "interval:")) }, `asked \ answered,interval:M3,interval:m3
+interval:M3,0,0
+interval:m3,1,1
+`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.name, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestExportFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rapportar")
+	p := profile.New("Kari Nordmann/2b", testTime)
+	p.History = testHistory()
+	paths, err := ExportFiles(dir, p, NewReport(p, "", testTime.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+		names = append(names, filepath.Base(path))
+	}
+	want := "Kari_Nordmann2b-2024-03-01.json Kari_Nordmann2b-2024-03-01-history.csv Kari_Nordmann2b-2024-03-01-items.csv Kari_Nordmann2b-2024-03-01-confusion.csv"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("wrote %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,243 @@
+package audio
+
+import (
+	"math"
+
+	"gehoer/music"
+)
+
+// TicksPerQuarter is the MIDI resolution of rendered events
+const TicksPerQuarter = 480
+
+// DefaultTempo is used when a score has no tempo (quarter notes per minute)
+const DefaultTempo = 120
+
+// NoteEvent is a sounding note in MIDI terms
+type NoteEvent struct {
+	Tick     int // onset in ticks from the start of the score
+	Duration int // sounding length in ticks
+	Pitch    int // MIDI note number
+	Velocity int // 1-127
+}
+
+// slot is an element placed on the score's timeline
+type slot struct {
+	ref    music.ElementRef
+	elem   music.MusicElement
+	tick   int
+	length int // written length in ticks
+}
+
+// timeline lays out every element of the score in ticks, in playback order
+// with repeats expanded. Onsets are rounded from the exact running time so
+// tuplets such as 7:4 do not drift. Chord notes share the onset and length
+// of the first note of the chord.
+func timeline(score *music.Score) []slot {
+	var slots []slot
+	quarters := 0.0
+	for _, mi := range score.PlaybackOrder() {
+		m := score.Measures[mi]
+		chordTick, chordLength := 0, 0
+		for ei, e := range m.Elements {
+			tick := int(math.Round(quarters * TicksPerQuarter))
+			quarters += m.ElementQuarters(ei)
+			length := int(math.Round(quarters*TicksPerQuarter)) - tick
+			if n, ok := e.(*music.Note); ok && n.Chord {
+				tick, length = chordTick, chordLength
+			} else {
+				chordTick, chordLength = tick, length
+			}
+			slots = append(slots, slot{
+				ref:    music.ElementRef{Measure: mi, Element: ei},
+				elem:   e,
+				tick:   tick,
+				length: length,
+			})
+		}
+	}
+	return slots
+}
+
+// hairpinSpan is a hairpin resolved to ticks and velocities
+type hairpinSpan struct {
+	startTick, endTick int
+	from, to           int
+}
+
+// TicksToSeconds converts a tick count to seconds at the given tempo
+func TicksToSeconds(ticks, tempo int) float64 {
+	if tempo <= 0 {
+		tempo = DefaultTempo
+	}
+	return float64(ticks) / TicksPerQuarter * 60.0 / float64(tempo)
+}
+
+// Render converts a score to note events using DefaultOptions
+func Render(score *music.Score) []NoteEvent {
+	return RenderWithOptions(score, DefaultOptions())
+}
+
+// RenderWithOptions converts a score to note events. Dynamics set the
+// velocity until the next marking, hairpins ramp it towards the dynamic that
+// follows them (or one dynamic step when none does), accents raise single
+// attacks, and staccato/tenuto marks change how much of the written length
+// is sounded. Tied notes sound as one. Grace notes and ornaments are
+// realized as configured in opts.
+func RenderWithOptions(score *music.Score, opts Options) []NoteEvent {
+	slots := timeline(score)
+
+	var events []NoteEvent
+	// Pitch to index in events of the notes the previous onset ties into
+	// this one, and of those this onset ties onward. A tie that the next
+	// onset does not continue is dropped.
+	tied, tying := make(map[int]int), make(map[int]int)
+	var graces []NoteEvent // grace notes waiting for their principal note
+	level := music.DefaultVelocity
+	var active *hairpinSpan
+	bar := NeighborContext{Key: score.KeySignature}
+	last := music.ElementRef{Measure: -1}
+
+	for i, s := range slots {
+		note, ok := s.elem.(*music.Note)
+
+		// Accidentals last to the end of the measure, and a repeat starts it again
+		if s.ref.Measure != last.Measure || s.ref.Element < last.Element {
+			bar.Clef, bar.Bar = score.ClefAt(s.ref.Measure), make(map[int]int)
+		}
+		last = s.ref
+		if !ok || !note.Chord && !note.IsGrace() {
+			tied, tying = tying, make(map[int]int)
+		}
+
+		if ok && note.Dynamic != music.DynamicNone && !note.Dynamic.IsAccent() {
+			level = note.Dynamic.SustainVelocity()
+			active = nil
+		}
+
+		// Start a hairpin that begins on this element
+		for _, hp := range score.Hairpins {
+			if hp.Start != s.ref {
+				continue
+			}
+			end, found := findSlot(slots, i, hp.End)
+			if !found {
+				continue
+			}
+			span := &hairpinSpan{
+				startTick: s.tick,
+				endTick:   slots[end].tick + slots[end].length,
+				from:      level,
+				to:        hairpinTarget(slots, i, end, level, hp.Type),
+			}
+			active = span
+		}
+
+		velocity := level
+		if active != nil {
+			if s.tick >= active.endTick {
+				level = active.to
+				velocity = level
+				active = nil
+			} else if active.endTick > active.startTick {
+				frac := float64(s.tick-active.startTick) / float64(active.endTick-active.startTick)
+				velocity = active.from + int(frac*float64(active.to-active.from))
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		if note.Dynamic != music.DynamicNone {
+			// sf, sfz and fp mark the attack; the level afterwards is handled above
+			velocity = note.Dynamic.Velocity()
+		}
+
+		lengthFactor := float32(music.DefaultLengthFactor)
+		for _, art := range note.Articulations {
+			velocity += art.VelocityBoost()
+			if f := art.LengthFactor(); f != music.DefaultLengthFactor {
+				lengthFactor = f
+			}
+		}
+
+		if note.IsGrace() {
+			graces = append(graces, NoteEvent{Tick: s.tick, Pitch: note.Pitch, Velocity: clampVelocity(velocity)})
+			bar.hear(note)
+			continue
+		}
+
+		duration := int(float32(s.length)*lengthFactor + 0.5)
+		if duration < 1 {
+			duration = 1
+		}
+		if ei, ok := tied[note.Pitch]; ok {
+			// Continue the note tied to this one
+			delete(tied, note.Pitch)
+			events[ei].Duration = s.tick + duration - events[ei].Tick
+			if note.Tie {
+				tying[note.Pitch] = ei
+			}
+			continue
+		}
+
+		principal := NoteEvent{
+			Tick:     s.tick,
+			Duration: duration,
+			Pitch:    note.Pitch,
+			Velocity: clampVelocity(velocity),
+		}
+		if len(graces) > 0 {
+			events = opts.realizeGraces(events, graces, &principal, graceKind(slots, i, len(graces)))
+			graces = nil
+		}
+		events = append(events, opts.realizeOrnaments(bar, note, principal)...)
+		bar.hear(note)
+		if note.Tie {
+			tying[note.Pitch] = len(events) - 1
+		}
+	}
+
+	// Grace notes at the very end have no principal; play them as written
+	for _, g := range graces {
+		g.Duration = opts.AcciaccaturaTicks
+		events = append(events, g)
+	}
+
+	return events
+}
+
+// findSlot returns the first slot at or after from holding the element
+func findSlot(slots []slot, from int, ref music.ElementRef) (int, bool) {
+	for j := from; j < len(slots); j++ {
+		if slots[j].ref == ref {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// hairpinTarget finds the velocity a hairpin leads to: the first dynamic
+// after its start up to the element following its end, or one dynamic step
+// away from the starting level
+func hairpinTarget(slots []slot, start, end, level int, kind music.HairpinType) int {
+	for j := start + 1; j <= end+1 && j < len(slots); j++ {
+		if n, ok := slots[j].elem.(*music.Note); ok && n.Dynamic != music.DynamicNone {
+			return n.Dynamic.Velocity()
+		}
+	}
+	if kind == music.Crescendo {
+		return clampVelocity(level + 16)
+	}
+	return clampVelocity(level - 16)
+}
+
+func clampVelocity(v int) int {
+	if v < 1 {
+		return 1
+	}
+	if v > 127 {
+		return 127
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"testing"
+
+	"gehoer/music"
+)
+
+// tieScore returns a score of 2/4 measures in C major
+func tieScore(measures ...[]music.MusicElement) *music.Score {
+	score := &music.Score{
+		KeySignature:  music.KeySignature{Tonic: "C", Mode: "dur"},
+		TimeSignature: music.TimeSignature{Numerator: 2, Denominator: 4},
+	}
+	for _, elems := range measures {
+		score.Measures = append(score.Measures, &music.Measure{
+			Number:        len(score.Measures) + 1,
+			Clef:          music.TrebleClef,
+			TimeSignature: score.TimeSignature,
+			Elements:      elems,
+		})
+	}
+	return score
+}
+
+func note(pitch, line int, value music.NoteValue, tie, chord bool) *music.Note {
+	return &music.Note{Pitch: pitch, StaffLine: line, Duration: value, Tie: tie, Chord: chord}
+}
+
+func TestRenderTies(t *testing.T) {
+	half, quarter := music.HalfNote, music.QuarterNote
+	rest := &music.Rest{Duration: quarter}
+	tests := []struct {
+		name  string
+		score *music.Score
+		want  []int // onset and pitch of each event, in ticks
+	}{
+		{
+			"over the bar line",
+			tieScore([]music.MusicElement{note(60, -2, half, true, false)}, []music.MusicElement{note(60, -2, half, false, false)}),
+			[]int{0, 60},
+		},
+		{
+			"on through three notes",
+			tieScore([]music.MusicElement{note(60, -2, quarter, true, false), note(60, -2, quarter, true, false)}, []music.MusicElement{note(60, -2, half, false, false)}),
+			[]int{0, 60},
+		},
+		{
+			"to another pitch is dropped",
+			tieScore([]music.MusicElement{note(60, -2, quarter, true, false), note(62, -1, quarter, false, false)}, []music.MusicElement{note(60, -2, half, false, false)}),
+			[]int{0, 60, 480, 62, 960, 60},
+		},
+		{
+			"into a rest is dropped",
+			tieScore([]music.MusicElement{note(60, -2, quarter, true, false), rest}, []music.MusicElement{note(60, -2, half, false, false)}),
+			[]int{0, 60, 960, 60},
+		},
+		{
+			"one note of a chord",
+			tieScore(
+				[]music.MusicElement{note(60, -2, half, true, false), note(64, 0, half, false, true)},
+				[]music.MusicElement{note(60, -2, half, false, false), note(67, 2, half, false, true)},
+			),
+			[]int{0, 60, 0, 64, 960, 67},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := Render(tt.score)
+			var got []int
+			for _, ev := range events {
+				got = append(got, ev.Tick, ev.Pitch)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("events %+v, want onsets and pitches %v", events, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("events %+v, want onsets and pitches %v", events, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTiedNoteSoundsOverTheBarLine(t *testing.T) {
+	half := music.HalfNote
+	score := tieScore([]music.MusicElement{note(60, -2, half, true, false)}, []music.MusicElement{note(60, -2, half, false, false)})
+	alone := Render(tieScore([]music.MusicElement{note(60, -2, half, false, false)}))
+	if events := Render(score); len(events) != 1 || events[0].Duration != 2*TicksPerQuarter+alone[0].Duration {
+		t.Errorf("tied halves %+v, want the first held and the second sounded as %+v", events, alone)
+	}
+}
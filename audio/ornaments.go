@@ -0,0 +1,204 @@
+package audio
+
+import (
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// Options configures how grace notes and ornaments are performed
+type Options struct {
+	// AcciaccaturaTicks is the length of each acciaccatura
+	AcciaccaturaTicks int
+	// AcciaccaturaBeforeBeat plays acciaccaturas ahead of the beat, taking
+	// time from the previous note, instead of on the beat
+	AcciaccaturaBeforeBeat bool
+	// AppoggiaturaFraction is the share of the principal note taken by appoggiaturas
+	AppoggiaturaFraction float64
+	// OrnamentNoteTicks is the length of the quick notes in trills and mordents
+	OrnamentNoteTicks int
+	// TrillStartsOnUpper begins trills on the upper neighbour, as in baroque practice
+	TrillStartsOnUpper bool
+	// Neighbor returns the auxiliary pitch above or below a note, used by ornaments
+	Neighbor func(ctx NeighborContext, note *music.Note, upper bool) int
+}
+
+// NeighborContext is what a Neighbor function knows of a note's
+// surroundings
+type NeighborContext struct {
+	Key  music.KeySignature
+	Clef string
+	Bar  map[int]int // alterations heard earlier in the measure, by diatonic number
+}
+
+// DefaultOptions returns a light, folk-style realization: acciaccaturas
+// before the beat, appoggiaturas taking half the note, trills from the note
+func DefaultOptions() Options {
+	return Options{
+		AcciaccaturaTicks:      TicksPerQuarter / 8,
+		AcciaccaturaBeforeBeat: true,
+		AppoggiaturaFraction:   0.5,
+		OrnamentNoteTicks:      TicksPerQuarter / 8,
+		TrillStartsOnUpper:     false,
+		Neighbor:               DiatonicNeighbor,
+	}
+}
+
+// hear records the alteration a note gives its line for the rest of the
+// measure
+func (ctx *NeighborContext) hear(note *music.Note) {
+	if p, ok := theory.NotePitch(note, ctx.Clef); ok {
+		ctx.Bar[p.Diatonic()] = p.Alter
+	}
+}
+
+// DiatonicNeighbor returns the next note of the scale above or below the
+// note: the neighbouring step, altered as earlier in the measure or else as
+// the key signature says. A trill on E in D major thus goes to F sharp. Keys
+// that cannot be read count as C major.
+func DiatonicNeighbor(ctx NeighborContext, note *music.Note, upper bool) int {
+	key, err := localization.ParseKey(ctx.Key.Tonic, ctx.Key.Mode)
+	if err != nil {
+		key = theory.KeyOfFifths(0, "dur")
+	}
+	p, ok := theory.NotePitch(note, ctx.Clef)
+	if !ok {
+		p = key.Spell(note.Pitch)
+	}
+	diatonic := p.Diatonic() - 1
+	if upper {
+		diatonic = p.Diatonic() + 1
+	}
+	alter, ok := ctx.Bar[diatonic]
+	if !ok {
+		alter = key.Alterations()[((diatonic%7)+7)%7]
+	}
+	return theory.NewPitch(diatonic, alter).MIDI()
+}
+
+// graceKind decides how the count grace notes before slot i are performed:
+// any appoggiatura in the group makes it an appoggiatura group
+func graceKind(slots []slot, i, count int) music.GraceType {
+	for j := i - count; j < i; j++ {
+		if n, ok := slots[j].elem.(*music.Note); ok && n.Grace == music.Appoggiatura {
+			return music.Appoggiatura
+		}
+	}
+	return music.Acciaccatura
+}
+
+// realizeGraces places the grace notes around their principal note,
+// adjusting the principal (and the previous notes for acciaccaturas played
+// before the beat), and returns the extended event list
+func (o Options) realizeGraces(events, graces []NoteEvent, principal *NoteEvent, kind music.GraceType) []NoteEvent {
+	n := len(graces)
+
+	if kind == music.Appoggiatura {
+		total := int(float64(principal.Duration) * o.AppoggiaturaFraction)
+		each := total / n
+		if each < 1 {
+			return append(events, graces...)
+		}
+		for k := range graces {
+			graces[k].Tick = principal.Tick + k*each
+			graces[k].Duration = each
+		}
+		principal.Tick += n * each
+		principal.Duration -= n * each
+		return append(events, graces...)
+	}
+
+	each := o.AcciaccaturaTicks
+	if o.AcciaccaturaBeforeBeat {
+		start := principal.Tick - n*each
+		if start < 0 {
+			start = 0
+		}
+		// Earlier notes stop where the grace notes begin
+		for j := range events {
+			if events[j].Tick < start && events[j].Tick+events[j].Duration > start {
+				events[j].Duration = start - events[j].Tick
+			}
+		}
+		for k := range graces {
+			graces[k].Tick = start + k*each
+			graces[k].Duration = each
+		}
+		return append(events, graces...)
+	}
+
+	for k := range graces {
+		graces[k].Tick = principal.Tick + k*each
+		graces[k].Duration = each
+	}
+	if principal.Duration > n*each {
+		principal.Tick += n * each
+		principal.Duration -= n * each
+	}
+	return append(events, graces...)
+}
+
+// realizeOrnaments expands the note's first ornament into the notes that
+// are actually played; notes without ornaments are returned unchanged
+func (o Options) realizeOrnaments(ctx NeighborContext, note *music.Note, ev NoteEvent) []NoteEvent {
+	if len(note.Ornaments) == 0 || o.Neighbor == nil {
+		return []NoteEvent{ev}
+	}
+
+	upper := o.Neighbor(ctx, note, true)
+	lower := o.Neighbor(ctx, note, false)
+	t := o.OrnamentNoteTicks
+	if t < 1 {
+		t = 1
+	}
+
+	// sequence plays quick notes then holds the last pitch to the end
+	sequence := func(pitches ...int) []NoteEvent {
+		if len(pitches)*t > ev.Duration {
+			t = ev.Duration / len(pitches)
+			if t < 1 {
+				return []NoteEvent{ev}
+			}
+		}
+		out := make([]NoteEvent, len(pitches))
+		for k, p := range pitches {
+			out[k] = NoteEvent{Tick: ev.Tick + k*t, Duration: t, Pitch: p, Velocity: ev.Velocity}
+		}
+		out[len(out)-1].Duration = ev.Duration - (len(pitches)-1)*t
+		return out
+	}
+
+	switch note.Ornaments[0] {
+	case music.Trill:
+		count := ev.Duration / t
+		if count < 2 {
+			return []NoteEvent{ev}
+		}
+		pitches := make([]int, count)
+		for k := range pitches {
+			onUpper := k%2 == 1
+			if o.TrillStartsOnUpper {
+				onUpper = !onUpper
+			}
+			pitches[k] = ev.Pitch
+			if onUpper {
+				pitches[k] = upper
+			}
+		}
+		return sequence(pitches...)
+	case music.Mordent:
+		return sequence(ev.Pitch, lower, ev.Pitch)
+	case music.InvertedMordent:
+		return sequence(ev.Pitch, upper, ev.Pitch)
+	case music.Turn:
+		// The turn fills the note in four equal parts
+		quarter := ev.Duration / 4
+		if quarter < 1 {
+			return []NoteEvent{ev}
+		}
+		t = quarter
+		return sequence(upper, ev.Pitch, lower, ev.Pitch)
+	default:
+		return []NoteEvent{ev}
+	}
+}
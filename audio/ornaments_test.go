@@ -0,0 +1,70 @@
+package audio
+
+import (
+	"testing"
+
+	"gehoer/music"
+)
+
+// ornamentPitches renders a measure in the key and returns the pitches of
+// the events that sound the last note
+func ornamentPitches(t *testing.T, tonic, mode string, notes ...*music.Note) []int {
+	t.Helper()
+	elements := make([]music.MusicElement, len(notes))
+	for i, n := range notes {
+		elements[i] = n
+	}
+	score := &music.Score{
+		KeySignature:  music.KeySignature{Tonic: tonic, Mode: mode},
+		TimeSignature: music.TimeSignature{Numerator: 4, Denominator: 4},
+		Measures:      []*music.Measure{{Clef: music.TrebleClef, Elements: elements}},
+	}
+	events := Render(score)
+	start := (len(notes) - 1) * TicksPerQuarter
+	var pitches []int
+	for _, ev := range events {
+		if ev.Tick >= start {
+			pitches = append(pitches, ev.Pitch)
+		}
+	}
+	return pitches
+}
+
+func quarter(pitch, line int, ornaments ...music.Ornament) *music.Note {
+	return &music.Note{Pitch: pitch, Duration: music.QuarterNote, StaffLine: line, Ornaments: ornaments}
+}
+
+func TestOrnamentNeighborsFollowKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		tonic, mode string
+		notes       []*music.Note
+		want        []int // the first pitches of the ornament
+	}{
+		{"trill on E in C major", "C", "dur", []*music.Note{quarter(64, 0, music.Trill)}, []int{64, 65, 64, 65}},
+		{"trill on E in D major", "D", "dur", []*music.Note{quarter(64, 0, music.Trill)}, []int{64, 66, 64, 66}},
+		{"inverted mordent on G in A flat major", "Ass", "dur", []*music.Note{quarter(67, 2, music.InvertedMordent)}, []int{67, 68, 67}},
+		{"mordent on C in D minor", "D", "moll", []*music.Note{quarter(72, 5, music.Mordent)}, []int{72, 70, 72}},
+		{"inverted mordent on A in F major", "F", "dur", []*music.Note{quarter(69, 3, music.InvertedMordent)}, []int{69, 70, 69}},
+		{"turn on D in D major", "D", "dur", []*music.Note{quarter(62, -1, music.Turn)}, []int{64, 62, 61, 62}},
+		{
+			"natural earlier in the bar",
+			"D", "dur",
+			[]*music.Note{quarter(65, 1), quarter(64, 0, music.Trill)},
+			[]int{64, 65, 64, 65},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ornamentPitches(t, tt.tonic, tt.mode, tt.notes...)
+			if len(got) < len(tt.want) {
+				t.Fatalf("got pitches %v, want them to start with %v", got, tt.want)
+			}
+			for i, p := range tt.want {
+				if got[i] != p {
+					t.Fatalf("got pitches %v, want them to start with %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
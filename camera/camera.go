@@ -1,19 +1,49 @@
 package camera
 
 import (
+	"gehoer/input"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+// Actions the camera responds to
+const (
+	PanLeft  input.Action = "pan_left"
+	PanRight input.Action = "pan_right"
+	PanUp    input.Action = "pan_up"
+	PanDown  input.Action = "pan_down"
+	ZoomIn   input.Action = "zoom_in"
+	ZoomOut  input.Action = "zoom_out"
+	Drag     input.Action = "drag"
+)
+
+// DefaultBindings pans with the arrow keys and the left mouse button, and
+// zooms with I and O as well as the wheel
+func DefaultBindings() *input.Bindings {
+	b := input.NewBindings()
+	b.Bind(PanLeft, input.KeyLeft)
+	b.Bind(PanRight, input.KeyRight)
+	b.Bind(PanUp, input.KeyUp)
+	b.Bind(PanDown, input.KeyDown)
+	b.Bind(ZoomIn, input.KeyI)
+	b.Bind(ZoomOut, input.KeyO)
+	b.BindButtons(Drag, input.MouseLeft)
+	return b
+}
+
 type Controller struct {
 	Camera    rl.Camera2D
+	Input     input.Source
+	Bindings  *input.Bindings
 	Dragging  bool
-	LastPos   rl.Vector2
+	LastPos   input.Point
 	MinZoom   float32
 	MaxZoom   float32
 	MoveSpeed float32
+	ZoomSpeed float32 // per wheel step or frame a zoom key is held
 }
 
-func NewController(screenWidth, screenHeight int) *Controller {
+func NewController(screenWidth, screenHeight int, in input.Source) *Controller {
 	return &Controller{
 		Camera: rl.Camera2D{
 			Target:   rl.NewVector2(0, 0),
@@ -21,33 +51,38 @@ func NewController(screenWidth, screenHeight int) *Controller {
 			Zoom:     1.0,
 			Rotation: 0,
 		},
+		Input:     in,
+		Bindings:  DefaultBindings(),
 		MinZoom:   0.1,
 		MaxZoom:   10.0,
 		MoveSpeed: 10,
+		ZoomSpeed: 0.1,
 	}
 }
 
 // Update processes input and updates the camera accordingly.
-// Should be called once per frame.
+// Should be called once per frame, after the input is polled.
 func (cc *Controller) Update() {
+	in, b := cc.Input, cc.Bindings
+
 	// Keyboard pan
 	speed := cc.MoveSpeed / cc.Camera.Zoom
-	if rl.IsKeyDown(rl.KeyRight) {
+	if b.Down(in, PanRight) {
 		cc.Camera.Target.X += speed
 	}
-	if rl.IsKeyDown(rl.KeyLeft) {
+	if b.Down(in, PanLeft) {
 		cc.Camera.Target.X -= speed
 	}
-	if rl.IsKeyDown(rl.KeyUp) {
+	if b.Down(in, PanUp) {
 		cc.Camera.Target.Y -= speed
 	}
-	if rl.IsKeyDown(rl.KeyDown) {
+	if b.Down(in, PanDown) {
 		cc.Camera.Target.Y += speed
 	}
 
 	// Mouse drag pan
-	if rl.IsMouseButtonDown(rl.MouseButtonLeft) {
-		mp := rl.GetMousePosition()
+	if b.Down(in, Drag) {
+		mp := in.MousePosition()
 		if !cc.Dragging {
 			cc.Dragging = true
 			cc.LastPos = mp
@@ -63,18 +98,40 @@ func (cc *Controller) Update() {
 	}
 
 	// Mouse wheel zoom (zoom towards pointer)
-	wheel := rl.GetMouseWheelMove()
-	if wheel != 0 {
-		mouse := rl.GetMousePosition()
-		worldBefore := rl.GetScreenToWorld2D(mouse, cc.Camera)
-		cc.Camera.Zoom += wheel * 0.1
-		if cc.Camera.Zoom < cc.MinZoom {
-			cc.Camera.Zoom = cc.MinZoom
-		} else if cc.Camera.Zoom > cc.MaxZoom {
-			cc.Camera.Zoom = cc.MaxZoom
-		}
-		worldAfter := rl.GetScreenToWorld2D(mouse, cc.Camera)
-		cc.Camera.Target.X += worldBefore.X - worldAfter.X
-		cc.Camera.Target.Y += worldBefore.Y - worldAfter.Y
+	if wheel := in.Wheel(); wheel != 0 {
+		cc.zoom(wheel*cc.ZoomSpeed, in.MousePosition())
+	}
+
+	// Keyboard zoom (zoom towards the middle of the screen)
+	center := input.Point{X: cc.Camera.Offset.X, Y: cc.Camera.Offset.Y}
+	if b.Down(in, ZoomIn) {
+		cc.zoom(cc.ZoomSpeed, center)
+	}
+	if b.Down(in, ZoomOut) {
+		cc.zoom(-cc.ZoomSpeed, center)
+	}
+}
+
+// zoom changes the zoom by delta, keeping the world point under the screen
+// point p in place
+func (cc *Controller) zoom(delta float32, p input.Point) {
+	worldBefore := ScreenToWorld(p, cc.Camera)
+	cc.Camera.Zoom += delta
+	if cc.Camera.Zoom < cc.MinZoom {
+		cc.Camera.Zoom = cc.MinZoom
+	} else if cc.Camera.Zoom > cc.MaxZoom {
+		cc.Camera.Zoom = cc.MaxZoom
+	}
+	worldAfter := ScreenToWorld(p, cc.Camera)
+	cc.Camera.Target.X += worldBefore.X - worldAfter.X
+	cc.Camera.Target.Y += worldBefore.Y - worldAfter.Y
+}
+
+// ScreenToWorld maps a point on the screen into the camera's world, as
+// rl.GetScreenToWorld2D does for an unrotated camera but without a window
+func ScreenToWorld(p input.Point, camera rl.Camera2D) rl.Vector2 {
+	return rl.Vector2{
+		X: (p.X-camera.Offset.X)/camera.Zoom + camera.Target.X,
+		Y: (p.Y-camera.Offset.Y)/camera.Zoom + camera.Target.Y,
 	}
 }
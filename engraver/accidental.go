@@ -0,0 +1,98 @@
+package engraver
+
+import (
+	"gehoer/music"
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+// accidentalGap is the space in staff spaces between an accidental and the
+// notehead, and between columns of accidentals
+const accidentalGap = 0.25
+
+// accidentalClearance is how many staff positions apart two accidentals
+// must be to share a column: a seventh
+const accidentalClearance = 6
+
+// GenerateAccidentalCommands draws the accidentals of a chord's notes,
+// sorted from the lowest, to the left of right. Accidentals closer than a
+// seventh go in separate columns; each note takes the column nearest the
+// noteheads that has room, from the highest note down.
+func (e *Engraver) GenerateAccidentalCommands(sorted []*music.Note, right, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	var columns [][]*music.Note
+	for i := len(sorted) - 1; i >= 0; i-- {
+		note := sorted[i]
+		if accidentalToGlyphName(note.Accidental) == "" {
+			continue
+		}
+		c := 0
+		for c < len(columns) && !fitsAccidentalColumn(columns[c], note) {
+			c++
+		}
+		if c == len(columns) {
+			columns = append(columns, nil)
+		}
+		columns[c] = append(columns[c], note)
+	}
+
+	gap := units.StaffSpacesToPixels(accidentalGap)
+	right -= gap
+	for _, column := range columns {
+		width := float32(0)
+		for _, note := range column {
+			w := e.accidentalWidth(note)
+			width = max(width, w)
+			// Right-aligned in the column
+			e.generateAccidentalGlyphs(note, right-w, y-staffPositionToPixels(note.StaffLine), color, buffer)
+		}
+		right -= width + gap
+	}
+}
+
+// fitsAccidentalColumn reports whether the note's accidental clears those
+// already in the column
+func fitsAccidentalColumn(column []*music.Note, note *music.Note) bool {
+	for _, other := range column {
+		d := other.StaffLine - note.StaffLine
+		if d < accidentalClearance && d > -accidentalClearance {
+			return false
+		}
+	}
+	return true
+}
+
+// accidentalWidth returns the width of the note's accidental, with the
+// parentheses of a courtesy accidental
+func (e *Engraver) accidentalWidth(note *music.Note) float32 {
+	names := []string{accidentalToGlyphName(note.Accidental)}
+	if note.Courtesy {
+		names = append(names, "accidentalParensLeft", "accidentalParensRight")
+	}
+	width := float32(0)
+	for _, name := range names {
+		if glyph, ok := e.MusicFont.GetGlyph(name); ok {
+			width += e.bboxWidthInPixels(glyph.BBox)
+		} else {
+			width += units.StaffSpacesToPixels(1) // fallback for missing glyphs
+		}
+	}
+	return width
+}
+
+// generateAccidentalGlyphs draws one accidental with its left edge at x,
+// in parentheses when it is a courtesy accidental
+func (e *Engraver) generateAccidentalGlyphs(note *music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	names := []string{accidentalToGlyphName(note.Accidental)}
+	if note.Courtesy {
+		names = []string{"accidentalParensLeft", names[0], "accidentalParensRight"}
+	}
+	for _, name := range names {
+		glyph, ok := e.MusicFont.GetGlyph(name)
+		if !ok {
+			x += units.StaffSpacesToPixels(1)
+			continue
+		}
+		buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, x, y, 0, color))
+		x += e.bboxWidthInPixels(glyph.BBox)
+	}
+}
@@ -0,0 +1,55 @@
+package engraver
+
+import (
+	"gehoer/musicfont"
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+// GenerateCustomGlyphCommands creates commands for a registered custom glyph
+// with its origin at (x, y). Shapes with a fill are filled, contours of the
+// same shape together and open ones closed as in SVG. Open contours, such as
+// lines, and unfilled shapes are also stroked. It returns false if no such
+// glyph exists.
+func (e *Engraver) GenerateCustomGlyphCommands(glyphName string, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) bool {
+	glyph, ok := e.MusicFont.GetCustomGlyph(glyphName)
+	if !ok {
+		return false
+	}
+
+	thickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.StemThickness))
+
+	var fill [][]renderer.Vector2
+	for i, contour := range glyph.Contours {
+		points := make([]renderer.Vector2, len(contour.Points))
+		for j, p := range contour.Points {
+			// Staff spaces are y-up, screen pixels are y-down
+			points[j] = renderer.Vector2{
+				X: x + units.StaffSpacesToPixels(float32(p[0])),
+				Y: y - units.StaffSpacesToPixels(float32(p[1])),
+			}
+		}
+		if contour.Fill == musicfont.FillNone || !contour.Closed {
+			buffer.AddCommand(renderer.NewPathCommand(points, contour.Closed, thickness, color))
+		}
+		if contour.Fill != musicfont.FillNone {
+			fill = append(fill, points)
+		}
+		// The shape is complete when the next contour belongs to another
+		if next := i + 1; len(fill) > 0 && (next == len(glyph.Contours) || glyph.Contours[next].Shape != contour.Shape) {
+			buffer.AddCommand(renderer.NewFillPathCommand(fill, contour.Fill == musicfont.FillEvenOdd, color))
+			fill = nil
+		}
+	}
+	return true
+}
+
+// GenerateGlyphCommands draws a glyph by name, preferring the SMuFL font and
+// falling back to registered custom glyphs
+func (e *Engraver) GenerateGlyphCommands(glyphName string, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) bool {
+	if cmd := e.CreateGlyphCommand(glyphName, x, y, color); cmd != nil {
+		buffer.AddCommand(*cmd)
+		return true
+	}
+	return e.GenerateCustomGlyphCommands(glyphName, x, y, color, buffer)
+}
@@ -1,6 +1,7 @@
 package engraver
 
 import (
+	"gehoer/localization"
 	"gehoer/music"
 	"gehoer/musicfont"
 	"gehoer/renderer"
@@ -11,6 +12,10 @@ type Engraver struct {
 	Score     *music.Score
 	MusicFont *musicfont.MusicFont
 	fontSize  float32
+
+	// NoteColors colours single notes and rests, such as answers marked
+	// right or wrong; the rest of the score is black
+	NoteColors map[music.ElementRef]renderer.Color
 }
 
 func NewEngraver(score *music.Score, musicFont *musicfont.MusicFont) *Engraver {
@@ -37,6 +42,9 @@ func (e *Engraver) MeasureLengthPx(measure *music.Measure) float32 {
 	spacing := units.StaffSpacesToPixels(2) // pixels between glyphs, tweak as needed
 
 	for _, elem := range measure.Elements {
+		if note, ok := elem.(*music.Note); ok && note.Chord {
+			continue
+		}
 		glyphName := elem.GlyphName()
 		glyph, ok := e.MusicFont.GetGlyph(glyphName)
 		if !ok {
@@ -62,33 +70,113 @@ func (e *Engraver) GenerateDrawCommands(originX, originY float32, buffer *render
 	x := originX
 	y := originY
 
-	for _, measure := range e.Score.Measures {
+	// Element x positions, used afterwards to place spanners such as hairpins
+	positions := make(map[music.ElementRef]float32)
+
+	// Scores without a known key are drawn without a key signature
+	key, keyErr := localization.ParseKey(e.Score.KeySignature.Tonic, e.Score.KeySignature.Mode)
+
+	for mi, measure := range e.Score.Measures {
 		staffLength := e.MeasureLengthPx(measure)
 		e.GenerateStaffCommands(x, y, staffLength, renderer.Black, buffer)
 
 		// Draw clef if present
 		if measure.Clef != "" {
-			if cmd := e.CreateGlyphCommand(measure.Clef, x, y, renderer.Black); cmd != nil {
+			clefY := y - staffPositionToPixels(music.ClefStaffPosition(measure.Clef))
+			if cmd := e.CreateGlyphCommand(music.ClefGlyphName(measure.Clef), x, clefY, renderer.Black); cmd != nil {
 				buffer.AddCommand(*cmd)
 			}
 			x += 40 // arbitrary advance, use glyph bbox width ideally
 		}
+		if mi == 0 && keyErr == nil {
+			x += e.GenerateKeySignatureCommands(key, e.Score.ClefAt(mi), x, y, renderer.Black, buffer)
+		}
+
+		if measure.RepeatStart {
+			x += e.GenerateRepeatCommands(true, x, y, renderer.Black, buffer)
+		}
+		measureStartX := x
 
 		// Draw measure elements (notes/rests/etc)
-		for _, elem := range measure.Elements {
+		elementX := make([]float32, len(measure.Elements))
+		for ei, elem := range measure.Elements {
+			if note, ok := elem.(*music.Note); ok && note.Chord && !note.IsGrace() && ei > 0 {
+				// Drawn with the chord's first note
+				elementX[ei] = elementX[ei-1]
+				positions[music.ElementRef{Measure: mi, Element: ei}] = elementX[ei]
+				continue
+			}
+			ref := music.ElementRef{Measure: mi, Element: ei}
+			positions[ref] = x
+			elementX[ei] = x
+			color := e.elementColor(ref)
 			switch el := elem.(type) {
 			case *music.Note:
-				e.GenerateNoteCommands(el, x, y, renderer.Black, buffer)
+				if el.IsGrace() {
+					e.GenerateNoteCommands(el, x, y, color, buffer)
+					x += graceNoteAdvancePx
+					continue
+				}
+				e.GenerateChordCommands(measure.ChordNotes(ei), x, y, color, buffer)
 				x += 20 // advance x by some spacing (replace with glyph bbox width)
 			default:
-				if cmd := e.CreateGlyphCommand(el.GlyphName(), x, y, renderer.Black); cmd != nil {
-					buffer.AddCommand(*cmd)
+				e.GenerateGlyphCommands(el.GlyphName(), x, y, color, buffer)
+				if bbox, ok := e.MusicFont.BoundingBoxes[el.GlyphName()]; ok {
+					// Rest dots go in the third space
+					right := x + units.StaffSpacesToPixels(float32(bbox.NE[0]))
+					e.GenerateDotCommands(el.GetDots(), 5, right, y, color, buffer)
 				}
 				x += 20
 			}
 		}
 
+		e.GenerateTupletCommands(measure, elementX, y, renderer.Black, buffer)
+
+		if measure.RepeatEnd {
+			x += e.GenerateRepeatCommands(false, x, y, renderer.Black, buffer)
+		}
+		if measure.Ending != 0 {
+			e.GenerateEndingCommands(measure.Ending, measureStartX, x, y, renderer.Black, buffer)
+		}
+
 		// Draw barline (not shown)
 		x += units.StaffSpacesToPixels(5) // some margin after each measure
 	}
+
+	e.GenerateTieCommands(positions, y, renderer.Black, buffer)
+
+	for _, hp := range e.Score.Hairpins {
+		startX, okStart := positions[hp.Start]
+		endX, okEnd := positions[hp.End]
+		if !okStart || !okEnd {
+			continue
+		}
+		// Leave room for a dynamic at the start and end on the far side of the last note
+		startX += units.StaffSpacesToPixels(1.5)
+		endX += units.StaffSpacesToPixels(1)
+		e.GenerateHairpinCommands(hp, startX, endX, y, renderer.Black, buffer)
+	}
+}
+
+// elementColor returns the colour of a note or rest: its entry in
+// NoteColors, or black
+func (e *Engraver) elementColor(ref music.ElementRef) renderer.Color {
+	if c, ok := e.NoteColors[ref]; ok {
+		return c
+	}
+	return renderer.Black
+}
+
+// FeedbackColors colours the elements marked right green and those marked
+// wrong red, for NoteColors
+func FeedbackColors(marks map[music.ElementRef]bool) map[music.ElementRef]renderer.Color {
+	colors := make(map[music.ElementRef]renderer.Color, len(marks))
+	for ref, right := range marks {
+		if right {
+			colors[ref] = renderer.Green
+		} else {
+			colors[ref] = renderer.Red
+		}
+	}
+	return colors
 }
@@ -33,6 +33,14 @@ func CreateGlyphCommand(font rl.Font, glyph rune, originX, originY, verticalOffs
 	return renderer.NewGlyphCommand(font, glyph, position, units.FontRenderSizePx, color)
 }
 
+// CreateScaledGlyphCommand creates a glyph draw command at a fraction of the
+// normal size, e.g. for grace notes. The origin stays on the staff position.
+func CreateScaledGlyphCommand(font rl.Font, glyph rune, originX, originY, scale float32, color renderer.Color) renderer.GlyphCommand {
+	size := units.FontRenderSizePx * scale
+	position := renderer.Vector2{X: originX, Y: originY - size/2}
+	return renderer.NewGlyphCommand(font, glyph, position, size, color)
+}
+
 // CalculateBBoxPosition calculates the bounding box rectangle for debugging
 func CalculateBBoxPosition(bbox musicfont.GlyphBBox, originX, originY, verticalOffsetStaffSpaces float32) (x, y, width, height float32) {
 	widthPx := units.StaffSpacesToPixels(float32(bbox.NE[0]) - float32(bbox.SW[0]))
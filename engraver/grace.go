@@ -0,0 +1,115 @@
+package engraver
+
+import (
+	"gehoer/music"
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+// GraceNoteScale is the size of grace notes relative to normal notes, the
+// customary two-thirds cue size used with SMuFL fonts
+const GraceNoteScale = 0.66
+
+// graceNoteAdvancePx is the horizontal space taken by a grace note
+var graceNoteAdvancePx = units.StaffSpacesToPixels(1.6)
+
+// scaled converts staff spaces to pixels at grace note size
+func scaled(staffSpaces float32) float32 {
+	return units.StaffSpacesToPixels(staffSpaces) * GraceNoteScale
+}
+
+// GenerateGraceNoteCommands draws a small grace note with its stem up.
+// Acciaccaturas get a slash through the stem.
+func (e *Engraver) GenerateGraceNoteCommands(note *music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	noteheadName := note.NoteheadGlyphName()
+	glyph, ok := e.MusicFont.GetGlyph(noteheadName)
+	if !ok {
+		return
+	}
+
+	noteheadY := y - staffPositionToPixels(note.StaffLine)
+	buffer.AddCommand(CreateScaledGlyphCommand(e.MusicFont.Font, glyph.Codepoint, x, noteheadY, GraceNoteScale, color))
+
+	stemThickness := scaled(float32(e.MusicFont.EngravingDefaults.StemThickness))
+	stemX, stemStartY := x+scaled(1.18), noteheadY
+	if a, ok := e.MusicFont.Anchors[noteheadName]["stemUpSE"]; ok {
+		stemX = x + scaled(float32(a[0]))
+		stemStartY = noteheadY - scaled(float32(a[1]))
+	}
+	stemX -= stemThickness / 2
+
+	if note.HasStem() {
+		stemEndY := stemStartY - scaled(3.5)
+		buffer.AddCommand(renderer.NewLineCommand(
+			renderer.Vector2{X: stemX, Y: stemStartY},
+			renderer.Vector2{X: stemX, Y: stemEndY},
+			stemThickness, color))
+
+		if note.HasFlag() {
+			flagName := e.flagGlyphName(note.Duration, true)
+			if flag, ok := e.MusicFont.GetGlyph(flagName); ok {
+				buffer.AddCommand(CreateScaledGlyphCommand(e.MusicFont.Font, flag.Codepoint, stemX, stemEndY, GraceNoteScale, color))
+			}
+		}
+
+		if note.Grace == music.Acciaccatura {
+			// Slash from lower left to upper right across the stem, below the flag
+			slashY := stemEndY + scaled(1.2)
+			start := renderer.Vector2{X: stemX - scaled(0.6), Y: slashY + scaled(0.5)}
+			end := renderer.Vector2{X: stemX + scaled(0.9), Y: slashY - scaled(0.5)}
+			buffer.AddCommand(renderer.NewLineCommand(start, end, stemThickness, color))
+		}
+	}
+
+	if accidentalName := accidentalToGlyphName(note.Accidental); accidentalName != "" {
+		if acc, ok := e.MusicFont.GetGlyph(accidentalName); ok {
+			buffer.AddCommand(CreateScaledGlyphCommand(e.MusicFont.Font, acc.Codepoint, x-scaled(1.5), noteheadY, GraceNoteScale, color))
+		}
+	}
+
+	// Ledger lines, shortened to the smaller notehead
+	thickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.LegerLineThickness))
+	centerX := x + scaled(float32(glyph.BBox.SW[0]+glyph.BBox.NE[0])/2)
+	half := scaled(0.75)
+	for line := bottomStaffPosition - 2; line >= note.StaffLine; line -= 2 {
+		lineY := y - staffPositionToPixels(line)
+		buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: centerX - half, Y: lineY}, renderer.Vector2{X: centerX + half, Y: lineY}, thickness, color))
+	}
+	for line := topStaffPosition + 2; line <= note.StaffLine; line += 2 {
+		lineY := y - staffPositionToPixels(line)
+		buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: centerX - half, Y: lineY}, renderer.Vector2{X: centerX + half, Y: lineY}, thickness, color))
+	}
+}
+
+// GenerateOrnamentCommands stacks the note's ornaments above the staff,
+// clear of the notehead, its stem and any articulations
+func (e *Engraver) GenerateOrnamentCommands(note *music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	if len(note.Ornaments) == 0 {
+		return
+	}
+
+	position := note.StaffLine + 3
+	if note.HasStem() && isStemUp(note) {
+		position = note.StaffLine + 9
+	}
+	if len(note.Articulations) > 0 && note.ArticulationPlacement != music.PlacementBelow {
+		position += 3
+	}
+	if position < 11 {
+		position = 11 // at least one and a half spaces above the top line
+	}
+
+	centerX := e.noteheadCenterX(note, x)
+	for _, ornament := range note.Ornaments {
+		glyph, ok := e.MusicFont.GetGlyph(ornament.GlyphName())
+		if !ok {
+			continue
+		}
+		// Ornament glyphs sit on their baseline
+		glyphY := y - staffPositionToPixels(position) + units.StaffSpacesToPixels(float32(glyph.BBox.SW[1]))
+		buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, centerX-glyphCenterOffset(glyph), glyphY, 0, color))
+
+		heightSteps := int(float32(glyph.BBox.NE[1]-glyph.BBox.SW[1])*2+0.5) + 1
+		position += heightSteps
+	}
+}
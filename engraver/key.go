@@ -0,0 +1,53 @@
+package engraver
+
+import (
+	"gehoer/music"
+	"gehoer/renderer"
+	"gehoer/theory"
+	"gehoer/units"
+)
+
+// keySharpPositions and keyFlatPositions are the staff positions of a key
+// signature's sharps and flats in each clef, in the order they are written
+var (
+	keySharpPositions = map[string][7]int{
+		music.TrebleClef: {8, 5, 9, 6, 3, 7, 4},
+		music.BassClef:   {6, 3, 7, 4, 1, 5, 2},
+		music.AltoClef:   {7, 4, 8, 5, 2, 6, 3},
+		music.TenorClef:  {2, 6, 3, 7, 4, 8, 5},
+	}
+	keyFlatPositions = map[string][7]int{
+		music.TrebleClef: {4, 7, 3, 6, 2, 5, 1},
+		music.BassClef:   {2, 5, 1, 4, 0, 3, -1},
+		music.AltoClef:   {3, 6, 2, 5, 1, 4, 0},
+		music.TenorClef:  {5, 8, 4, 7, 3, 6, 2},
+	}
+)
+
+// GenerateKeySignatureCommands draws the sharps or flats of the key's
+// signature in the clef and returns the width they take
+func (e *Engraver) GenerateKeySignatureCommands(key theory.Key, clef string, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) float32 {
+	steps := key.AlteredSteps()
+	if len(steps) == 0 {
+		return 0
+	}
+	if _, ok := keySharpPositions[clef]; !ok {
+		clef = music.TrebleClef // as the score reads unknown clefs
+	}
+	glyphName, positions := "accidentalSharp", keySharpPositions[clef]
+	if key.Fifths() < 0 {
+		glyphName, positions = "accidentalFlat", keyFlatPositions[clef]
+	}
+
+	advance := units.StaffSpacesToPixels(1)
+	if glyph, ok := e.MusicFont.GetGlyph(glyphName); ok {
+		advance = e.bboxWidthInPixels(glyph.BBox) + units.StaffSpacesToPixels(0.2)
+	}
+	for i := range steps {
+		accY := y - staffPositionToPixels(positions[i])
+		if cmd := e.CreateGlyphCommand(glyphName, x+float32(i)*advance, accY, color); cmd != nil {
+			buffer.AddCommand(*cmd)
+		}
+	}
+	return float32(len(steps))*advance + units.StaffSpacesToPixels(1)
+}
@@ -0,0 +1,185 @@
+package engraver
+
+import (
+	"sort"
+
+	"gehoer/music"
+	"gehoer/musicfont"
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+// Staff positions (half staff spaces above the bottom line) used for marks
+const (
+	dynamicsPositionBelow = -5 // dynamics and hairpins, 2.5 spaces under the staff
+	dynamicsPositionAbove = 13
+	hairpinOpeningSpaces  = 0.5 // half the opening of a hairpin's wide end
+)
+
+// noteheadCenterX returns the horizontal centre of the notehead drawn at x,
+// preferring the font's optical centre anchor over the bounding box
+func (e *Engraver) noteheadCenterX(note *music.Note, x float32) float32 {
+	name := note.NoteheadGlyphName()
+	if a, ok := e.MusicFont.Anchors[name]["opticalCenter"]; ok {
+		return x + units.StaffSpacesToPixels(float32(a[0]))
+	}
+	if bbox, ok := e.MusicFont.BoundingBoxes[name]; ok {
+		return x + units.StaffSpacesToPixels(float32(bbox.SW[0]+bbox.NE[0])/2)
+	}
+	return x + units.StaffSpacesToPixels(0.6)
+}
+
+// glyphCenterOffset returns the horizontal offset from a glyph's origin to its
+// optical centre (or bounding-box centre) in pixels
+func glyphCenterOffset(glyph *musicfont.Glyph) float32 {
+	if a, ok := glyph.Anchors["opticalCenter"]; ok {
+		return units.StaffSpacesToPixels(float32(a[0]))
+	}
+	return units.StaffSpacesToPixels(float32(glyph.BBox.SW[0]+glyph.BBox.NE[0]) / 2)
+}
+
+// articulationOrder puts marks that sit closest to the notehead first
+func articulationOrder(a music.Articulation) int {
+	switch a {
+	case music.Staccato, music.Staccatissimo:
+		return 0
+	case music.Tenuto:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// GenerateArticulationCommands draws the note's articulations on the notehead
+// side away from the stem, unless a placement is forced. Staccato and tenuto
+// marks inside the staff sit in spaces; accents and marcatos go outside it.
+func (e *Engraver) GenerateArticulationCommands(note *music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	if len(note.Articulations) == 0 {
+		return
+	}
+
+	stemUp := isStemUp(note)
+	hasStem := note.HasStem()
+	above := !stemUp
+	switch note.ArticulationPlacement {
+	case music.PlacementAbove:
+		above = true
+	case music.PlacementBelow:
+		above = false
+	}
+
+	arts := append([]music.Articulation(nil), note.Articulations...)
+	sort.SliceStable(arts, func(i, j int) bool {
+		return articulationOrder(arts[i]) < articulationOrder(arts[j])
+	})
+
+	// Start one space from the notehead, or past the stem end if on the stem side
+	position := note.StaffLine - 2
+	if above {
+		position = note.StaffLine + 2
+	}
+	if hasStem && above == stemUp {
+		if above {
+			position = note.StaffLine + 8
+		} else {
+			position = note.StaffLine - 8
+		}
+	}
+
+	step := -1
+	if above {
+		step = 1
+	}
+	centerX := e.noteheadCenterX(note, x)
+
+	for _, art := range arts {
+		glyphName := art.GlyphName(above)
+		glyph, ok := e.MusicFont.GetGlyph(glyphName)
+		if !ok {
+			continue
+		}
+
+		switch art {
+		case music.Accent, music.Marcato:
+			if above && position < 10 {
+				position = 10
+			} else if !above && position > -2 {
+				position = -2
+			}
+		default:
+			// Avoid staff lines: even positions inside the staff are lines
+			if position >= bottomStaffPosition && position <= topStaffPosition && position%2 == 0 {
+				position += step
+			}
+		}
+
+		// Centre the glyph on the staff position
+		midY := float32(glyph.BBox.SW[1]+glyph.BBox.NE[1]) / 2
+		glyphY := y - staffPositionToPixels(position) + units.StaffSpacesToPixels(midY)
+		glyphX := centerX - glyphCenterOffset(glyph)
+		buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, glyphX, glyphY, 0, color))
+
+		// Advance past this glyph plus a small gap
+		heightSteps := int(float32(glyph.BBox.NE[1]-glyph.BBox.SW[1])*2+0.5) + 1
+		position += step * heightSteps
+	}
+}
+
+// dynamicsPosition returns the staff position of the dynamics line for a note,
+// pushed further out when the note itself lies beyond it
+func dynamicsPosition(note *music.Note, placement music.Placement) int {
+	if placement == music.PlacementAbove {
+		if note.StaffLine+6 > dynamicsPositionAbove {
+			return note.StaffLine + 6
+		}
+		return dynamicsPositionAbove
+	}
+	if note.StaffLine-6 < dynamicsPositionBelow {
+		return note.StaffLine - 6
+	}
+	return dynamicsPositionBelow
+}
+
+// GenerateDynamicCommands draws the note's dynamic marking centred under (or
+// over) the notehead, aligning the glyph's optical centre where available
+func (e *Engraver) GenerateDynamicCommands(note *music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	glyphName := note.Dynamic.GlyphName()
+	if glyphName == "" {
+		return
+	}
+	glyph, ok := e.MusicFont.GetGlyph(glyphName)
+	if !ok {
+		return
+	}
+
+	// Dynamics glyphs sit on their baseline; put it on the dynamics line
+	glyphY := y - staffPositionToPixels(dynamicsPosition(note, note.DynamicPlacement))
+	glyphX := e.noteheadCenterX(note, x) - glyphCenterOffset(glyph)
+	buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, glyphX, glyphY, 0, color))
+}
+
+// GenerateHairpinCommands draws a crescendo or diminuendo wedge between two x
+// positions on the dynamics line of the staff whose bottom line is at y
+func (e *Engraver) GenerateHairpinCommands(hairpin music.Hairpin, startX, endX, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	if endX <= startX {
+		return
+	}
+	position := dynamicsPositionBelow
+	if hairpin.Placement == music.PlacementAbove {
+		position = dynamicsPositionAbove
+	}
+
+	// Dynamics glyphs sit on the line; centre the wedge on the letters' x-height
+	centerY := y - staffPositionToPixels(position) - units.StaffSpacesToPixels(0.5)
+	opening := units.StaffSpacesToPixels(hairpinOpeningSpaces)
+	thickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.HairpinThickness))
+
+	pointX, openX := startX, endX
+	if hairpin.Type == music.Diminuendo {
+		pointX, openX = endX, startX
+	}
+
+	point := renderer.Vector2{X: pointX, Y: centerY}
+	buffer.AddCommand(renderer.NewLineCommand(point, renderer.Vector2{X: openX, Y: centerY - opening}, thickness, color))
+	buffer.AddCommand(renderer.NewLineCommand(point, renderer.Vector2{X: openX, Y: centerY + opening}, thickness, color))
+}
@@ -1,6 +1,8 @@
 package engraver
 
 import (
+	"sort"
+
 	"gehoer/music"
 	"gehoer/renderer"
 	"gehoer/units"
@@ -8,47 +10,110 @@ import (
 
 // GenerateNoteCommands generates the drawing commands for a note including notehead, stem, flags, and ledger lines
 func (e *Engraver) GenerateNoteCommands(note *music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
-	noteheadName := note.NoteheadGlyphName()
+	if note.IsGrace() {
+		e.GenerateGraceNoteCommands(note, x, y, color, buffer)
+		return
+	}
+	e.GenerateChordCommands([]*music.Note{note}, x, y, color, buffer)
+}
+
+// GenerateChordCommands draws notes sharing one stem: the noteheads (with
+// notes a second apart on opposite sides of the stem), their accidentals,
+// dots and ledger lines, one stem and flag, and the first note's marks
+func (e *Engraver) GenerateChordCommands(notes []*music.Note, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	head := notes[0]
+	noteheadName := head.NoteheadGlyphName()
 	glyph, ok := e.MusicFont.GetGlyph(noteheadName)
 	if !ok {
 		return
 	}
 
-	// Vertical offset for the notehead position on staff lines (staffLine = 0 is bottom line)
-	verticalOffsetPx := units.StaffSpacesToPixels(float32(note.StaffLine))
-	noteheadX := x
-	noteheadY := y - verticalOffsetPx
+	sorted := append([]*music.Note(nil), notes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StaffLine < sorted[j].StaffLine })
+	low, high := sorted[0].StaffLine, sorted[len(sorted)-1].StaffLine
+	stemUp := low+high < 2*middleStaffPosition
 
-	// Draw notehead
-	cmd := CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, noteheadX, noteheadY, 0, color)
-	buffer.AddCommand(cmd)
+	// A notehead a second from a neighbour on the normal side goes on the
+	// other side of the stem
+	noteheadWidth := e.bboxWidthInPixels(glyph.BBox)
+	offsets := make([]float32, len(sorted))
+	if stemUp {
+		for i := 1; i < len(sorted); i++ {
+			if sorted[i].StaffLine-sorted[i-1].StaffLine == 1 && offsets[i-1] == 0 {
+				offsets[i] = noteheadWidth
+			}
+		}
+	} else {
+		for i := len(sorted) - 2; i >= 0; i-- {
+			if sorted[i+1].StaffLine-sorted[i].StaffLine == 1 && offsets[i+1] == 0 {
+				offsets[i] = -noteheadWidth
+			}
+		}
+	}
+
+	dotsRight := x + units.StaffSpacesToPixels(float32(glyph.BBox.NE[0]))
+	for _, off := range offsets {
+		if off > 0 {
+			dotsRight += off
+			break
+		}
+	}
+
+	for i, note := range sorted {
+		// Vertical offset for the notehead position on staff lines (staffLine = 0 is bottom line)
+		noteheadX := x + offsets[i]
+		noteheadY := y - staffPositionToPixels(note.StaffLine)
 
-	if note.Duration != music.WholeNote {
-		stemUp := note.StaffLine < 3 // example rule for stem direction
+		// Draw notehead
+		buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, noteheadX, noteheadY, 0, color))
+
+		e.GenerateDotCommands(note.Dots, note.StaffLine, dotsRight, y, color, buffer)
+	}
+
+	// Accidentals go left of the leftmost notehead
+	accidentalsRight := x
+	for _, off := range offsets {
+		if off < 0 {
+			accidentalsRight += off
+			break
+		}
+	}
+	e.GenerateAccidentalCommands(sorted, accidentalsRight, y, color, buffer)
+
+	if head.Duration != music.WholeNote {
 		stemLength := units.StaffSpacesToPixels(3.5)
 		stemThickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.StemThickness))
 
+		// The stem starts at the notehead furthest from its end and reaches
+		// a normal stem length past the nearest one
 		var stemX, stemStartY, stemEndY float32
-
 		if stemUp {
+			lowY := y - staffPositionToPixels(low)
+			highY := y - staffPositionToPixels(high)
 			if a, ok := e.MusicFont.Anchors[noteheadName]["stemUpSE"]; ok {
-				stemX = noteheadX + units.StaffSpacesToPixels(float32(a[0]))
-				stemStartY = noteheadY - units.StaffSpacesToPixels(float32(a[1]))
+				stemX = x + units.StaffSpacesToPixels(float32(a[0]))
+				dy := units.StaffSpacesToPixels(float32(a[1]))
+				stemStartY = lowY - dy
+				stemEndY = highY - dy - stemLength
 			} else {
 				// fallback if no anchor
-				stemX = noteheadX + units.StaffSpacesToPixels(0.5)
-				stemStartY = noteheadY
+				stemX = x + units.StaffSpacesToPixels(0.5)
+				stemStartY = lowY
+				stemEndY = highY - stemLength
 			}
-			stemEndY = stemStartY - stemLength
 		} else {
+			lowY := y - staffPositionToPixels(low)
+			highY := y - staffPositionToPixels(high)
 			if a, ok := e.MusicFont.Anchors[noteheadName]["stemDownNW"]; ok {
-				stemX = noteheadX + units.StaffSpacesToPixels(float32(a[0]))
-				stemStartY = noteheadY - units.StaffSpacesToPixels(float32(a[1]))
+				stemX = x + units.StaffSpacesToPixels(float32(a[0]))
+				dy := units.StaffSpacesToPixels(float32(a[1]))
+				stemStartY = highY - dy
+				stemEndY = lowY - dy + stemLength
 			} else {
-				stemX = noteheadX - units.StaffSpacesToPixels(0.5)
-				stemStartY = noteheadY
+				stemX = x - units.StaffSpacesToPixels(0.5)
+				stemStartY = highY
+				stemEndY = lowY + stemLength
 			}
-			stemEndY = stemStartY + stemLength
 		}
 
 		stemDrawX := stemX
@@ -63,8 +128,8 @@ func (e *Engraver) GenerateNoteCommands(note *music.Note, x, y float32, color re
 		end := renderer.Vector2{X: stemDrawX, Y: stemEndY}
 		buffer.AddCommand(renderer.NewLineCommand(start, end, stemThickness, color))
 
-		if note.HasFlag() {
-			flagName := e.flagGlyphName(note.Duration, stemUp)
+		if head.HasFlag() {
+			flagName := e.flagGlyphName(head.Duration, stemUp)
 			flagGlyph, ok := e.MusicFont.GetGlyph(flagName)
 			if ok {
 				dx, dy := float32(0), float32(0)
@@ -87,43 +152,65 @@ func (e *Engraver) GenerateNoteCommands(note *music.Note, x, y float32, color re
 		}
 	}
 
-	// Draw accidental if present
-	if note.Accidental != "" {
-		accidentalX := x - units.StaffSpacesToPixels(1.5)
-		accidentalName := accidentalToGlyphName(note.Accidental)
-		accGlyph, ok := e.MusicFont.GetGlyph(accidentalName)
-		if ok {
-			accCmd := CreateGlyphCommand(e.MusicFont.Font, accGlyph.Codepoint, accidentalX, y, 0, color)
-			buffer.AddCommand(accCmd)
-		}
-	}
-
-	// Draw ledger lines if note is outside staff range
-	const bottomStaffLine = 0
-	const topStaffLine = 8
+	// Draw ledger lines if notes are outside staff range
 
 	thickness := float32(e.MusicFont.EngravingDefaults.StaffLineThickness)
 	thickness = units.StaffSpacesToPixels(thickness)
 
 	centerX := x + units.StaffSpacesToPixels(float32((glyph.BBox.SW[0]+glyph.BBox.NE[0])/2))
+	left := centerX - units.StaffSpacesToPixels(0.75)
+	right := centerX + units.StaffSpacesToPixels(0.75)
+	for i, off := range offsets {
+		// Widen the lines under noteheads beside the stem
+		if (sorted[i].StaffLine < bottomStaffPosition || sorted[i].StaffLine > topStaffPosition) && off != 0 {
+			if off < 0 {
+				left += off
+			} else {
+				right += off
+			}
+		}
+	}
 
-	if note.StaffLine < bottomStaffLine {
+	if low < bottomStaffPosition {
 		// ledger lines below staff
-		for line := bottomStaffLine - 2; line >= note.StaffLine; line -= 2 {
-			yLine := y + units.StaffSpacesToPixels(float32(bottomStaffLine-line)*0.5)
-			start := renderer.Vector2{X: centerX - units.StaffSpacesToPixels(0.75), Y: yLine}
-			end := renderer.Vector2{X: centerX + units.StaffSpacesToPixels(0.75), Y: yLine}
-			buffer.AddCommand(renderer.NewLineCommand(start, end, thickness, color))
+		for line := bottomStaffPosition - 2; line >= low; line -= 2 {
+			yLine := y - staffPositionToPixels(line)
+			buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: left, Y: yLine}, renderer.Vector2{X: right, Y: yLine}, thickness, color))
 		}
-	} else if note.StaffLine > topStaffLine {
+	}
+	if high > topStaffPosition {
 		// ledger lines above staff
-		for line := topStaffLine + 2; line <= note.StaffLine; line += 2 {
-			yLine := y - units.StaffSpacesToPixels(float32(line-topStaffLine)*0.5)
-			start := renderer.Vector2{X: centerX - units.StaffSpacesToPixels(0.75), Y: yLine}
-			end := renderer.Vector2{X: centerX + units.StaffSpacesToPixels(0.75), Y: yLine}
-			buffer.AddCommand(renderer.NewLineCommand(start, end, thickness, color))
+		for line := topStaffPosition + 2; line <= high; line += 2 {
+			yLine := y - staffPositionToPixels(line)
+			buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: left, Y: yLine}, renderer.Vector2{X: right, Y: yLine}, thickness, color))
 		}
 	}
+
+	e.GenerateArticulationCommands(head, x, y, color, buffer)
+	e.GenerateOrnamentCommands(head, x, y, color, buffer)
+	e.GenerateDynamicCommands(head, x, y, color, buffer)
+}
+
+// GenerateDotCommands draws augmentation dots to the right of a glyph ending
+// at x. Dots for a glyph on a staff line are moved up into the space above.
+func (e *Engraver) GenerateDotCommands(dots, position int, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	if dots <= 0 {
+		return
+	}
+	glyph, ok := e.MusicFont.GetGlyph("augmentationDot")
+	if !ok {
+		return
+	}
+	if position%2 == 0 {
+		position++
+	}
+	dotY := y - staffPositionToPixels(position)
+	dotX := x + units.StaffSpacesToPixels(0.4)
+	advance := units.StaffSpacesToPixels(float32(glyph.BBox.NE[0]-glyph.BBox.SW[0]) + 0.3)
+	for i := 0; i < dots; i++ {
+		buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, dotX, dotY, 0, color))
+		dotX += advance
+	}
 }
 
 // Helper function to map accidentals to SMuFL glyph names
@@ -135,6 +222,10 @@ func accidentalToGlyphName(acc string) string {
 		return "accidentalFlat"
 	case "natural":
 		return "accidentalNatural"
+	case "double_sharp":
+		return "accidentalDoubleSharp"
+	case "double_flat":
+		return "accidentalDoubleFlat"
 	default:
 		return ""
 	}
@@ -0,0 +1,47 @@
+package engraver
+
+import (
+	"strconv"
+
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+// Volta bracket layout
+const (
+	endingPosition   = 13  // staff position of the bracket line
+	endingHookSpaces = 1.5 // length of the hook at the bracket start
+)
+
+// GenerateRepeatCommands draws a start (left) or end repeat barline at x
+// and returns the horizontal space it takes
+func (e *Engraver) GenerateRepeatCommands(left bool, x, y float32, color renderer.Color, buffer *renderer.CommandBuffer) float32 {
+	name := "repeatRight"
+	if left {
+		name = "repeatLeft"
+	}
+	glyph, ok := e.MusicFont.GetGlyph(name)
+	if !ok {
+		return 0
+	}
+	buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, glyph.Codepoint, x, y, 0, color))
+	return e.bboxWidthInPixels(glyph.BBox) + units.StaffSpacesToPixels(0.5)
+}
+
+// GenerateEndingCommands draws a first/second ending bracket with its
+// number over the measure from startX to endX
+func (e *Engraver) GenerateEndingCommands(number int, startX, endX, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	if endX <= startX {
+		return
+	}
+	lineY := y - staffPositionToPixels(endingPosition)
+	thickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.RepeatEndingLineThickness))
+	hook := units.StaffSpacesToPixels(endingHookSpaces)
+
+	buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: startX, Y: lineY}, renderer.Vector2{X: endX, Y: lineY}, thickness, color))
+	buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: startX, Y: lineY}, renderer.Vector2{X: startX, Y: lineY + hook}, thickness, color))
+
+	fontSize := units.StaffSpacesToPixels(1.5)
+	textPos := renderer.Vector2{X: startX + units.StaffSpacesToPixels(0.4), Y: lineY + units.StaffSpacesToPixels(0.2)}
+	buffer.AddCommand(renderer.NewTextCommand(strconv.Itoa(number)+".", textPos, int32(fontSize), color))
+}
@@ -1,10 +1,34 @@
 package engraver
 
 import (
+	"gehoer/music"
 	"gehoer/renderer"
 	"gehoer/units"
 )
 
+// Staff positions count diatonic steps, half a staff space each, from the
+// bottom line: 0 is the bottom line, 1 the first space and 8 the top line.
+// Ledger lines were always placed this way; noteheads once moved a whole
+// space per step, which put every other note between lines and a step
+// apart twice too far. Everything on the staff is placed by position now.
+const (
+	bottomStaffPosition = 0
+	middleStaffPosition = 4
+	topStaffPosition    = 8
+)
+
+// staffPositionToPixels converts a staff position to pixels above the
+// bottom line
+func staffPositionToPixels(position int) float32 {
+	return units.StaffSpacesToPixels(float32(position) * 0.5)
+}
+
+// isStemUp applies the usual rule: stems go up for notes below the middle
+// line and down from it
+func isStemUp(note *music.Note) bool {
+	return note.StaffLine < middleStaffPosition
+}
+
 type Staff struct {
 	LengthInStaffSpaces float32
 	// You can add thickness or other rendering options here if needed
@@ -0,0 +1,82 @@
+package engraver
+
+import (
+	"gehoer/music"
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+// Tie shape in staff spaces
+const (
+	tieGapSpaces    = 0.15 // space between a tie end and the notehead
+	tieOffsetSpaces = 0.5  // vertical distance from the notehead centre
+	tieHeightSpaces = 0.6  // rise of the arc at its middle
+	tieSegments     = 12
+)
+
+// tieAbove decides the side of a tie: opposite the stem for single notes,
+// and outwards from the middle for notes in a chord
+func tieAbove(m *music.Measure, ei int) bool {
+	chord := m.ChordNotes(m.ChordHead(ei))
+	note := m.Elements[ei].(*music.Note)
+	low, high := chord[0].StaffLine, chord[0].StaffLine
+	for _, n := range chord {
+		low = min(low, n.StaffLine)
+		high = max(high, n.StaffLine)
+	}
+	if len(chord) > 1 && note.StaffLine*2 != low+high {
+		return note.StaffLine*2 > low+high
+	}
+	return low+high >= 2*middleStaffPosition
+}
+
+// GenerateTieCommands draws an arc from every tied note to the note it
+// continues into, using the element x positions recorded while engraving
+func (e *Engraver) GenerateTieCommands(positions map[music.ElementRef]float32, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	for mi, m := range e.Score.Measures {
+		for ei, el := range m.Elements {
+			note, ok := el.(*music.Note)
+			if !ok || !note.Tie {
+				continue
+			}
+			ref := music.ElementRef{Measure: mi, Element: ei}
+			target, ok := e.Score.TieTarget(ref)
+			if !ok {
+				continue
+			}
+			startX, okStart := positions[ref]
+			endX, okEnd := positions[target]
+			if !okStart || !okEnd {
+				continue
+			}
+
+			width := units.StaffSpacesToPixels(1.18)
+			if glyph, ok := e.MusicFont.GetGlyph(note.NoteheadGlyphName()); ok {
+				width = e.bboxWidthInPixels(glyph.BBox)
+			}
+			startX += width + units.StaffSpacesToPixels(tieGapSpaces)
+			endX -= units.StaffSpacesToPixels(tieGapSpaces)
+			if endX <= startX {
+				continue
+			}
+
+			direction := float32(1) // pixels grow downwards
+			if tieAbove(m, ei) {
+				direction = -1
+			}
+			baseY := y - staffPositionToPixels(note.StaffLine) + direction*units.StaffSpacesToPixels(tieOffsetSpaces)
+			controlY := baseY + direction*units.StaffSpacesToPixels(tieHeightSpaces*2)
+
+			// Quadratic Bézier whose middle rises tieHeightSpaces
+			points := make([]renderer.Vector2, tieSegments+1)
+			for i := range points {
+				t := float32(i) / tieSegments
+				px := startX + (endX-startX)*t
+				py := (1-t)*(1-t)*baseY + 2*(1-t)*t*controlY + t*t*baseY
+				points[i] = renderer.Vector2{X: px, Y: py}
+			}
+			thickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.TieMidpointThickness))
+			buffer.AddCommand(renderer.NewPathCommand(points, false, thickness, color))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package engraver
+
+import (
+	"strconv"
+
+	"gehoer/music"
+	"gehoer/renderer"
+	"gehoer/units"
+)
+
+const (
+	tupletHookSpaces     = 0.6 // length of the bracket's end hooks
+	tupletNumberGap      = 0.4 // gap between the bracket and the number
+	tupletNestingSpacing = 3   // staff positions between nested tuplets
+)
+
+// drawsBeams tells whether flagged notes are joined by beams. Until the
+// engraver draws beams, every note keeps its own flag.
+const drawsBeams = false
+
+// tupletIsBeamed reports whether a beam is drawn across the tuplet: beams are
+// drawn and the group holds only flagged notes, no rests. The beam then shows
+// the group and the bracket is left out.
+func tupletIsBeamed(measure *music.Measure, t music.Tuplet) bool {
+	if !drawsBeams {
+		return false
+	}
+	for i := t.Start; i <= t.End; i++ {
+		note, ok := measure.Elements[i].(*music.Note)
+		if !ok || !note.HasFlag() {
+			return false
+		}
+	}
+	return true
+}
+
+// tupletNumberGlyphs returns the SMuFL glyph names for "3" or "3:2"
+func tupletNumberGlyphs(t music.Tuplet) []string {
+	var names []string
+	for _, d := range strconv.Itoa(t.Actual) {
+		names = append(names, "tuplet"+string(d))
+	}
+	if t.ShowRatio {
+		names = append(names, "tupletColon")
+		for _, d := range strconv.Itoa(t.Normal) {
+			names = append(names, "tuplet"+string(d))
+		}
+	}
+	return names
+}
+
+// tupletAbove decides the side of the tuplet mark. Brackets default to above;
+// beamed groups follow their stems so the number sits by the beam.
+func tupletAbove(measure *music.Measure, t music.Tuplet, beamed bool) bool {
+	switch t.Placement {
+	case music.PlacementAbove:
+		return true
+	case music.PlacementBelow:
+		return false
+	}
+	if !beamed {
+		return true
+	}
+	for i := t.Start; i <= t.End; i++ {
+		if note, ok := measure.Elements[i].(*music.Note); ok && isStemUp(note) {
+			return true
+		}
+	}
+	return false
+}
+
+// tupletPosition returns the staff position just clear of the group's noteheads and stems
+func tupletPosition(measure *music.Measure, t music.Tuplet, above bool) int {
+	position := 10 // one space above the top line
+	if !above {
+		position = -2
+	}
+	for i := t.Start; i <= t.End; i++ {
+		note, ok := measure.Elements[i].(*music.Note)
+		if !ok {
+			continue
+		}
+		extent := note.StaffLine
+		if note.HasStem() && isStemUp(note) == above {
+			if above {
+				extent += 7
+			} else {
+				extent -= 7
+			}
+		}
+		if above && extent+2 > position {
+			position = extent + 2
+		} else if !above && extent-2 < position {
+			position = extent - 2
+		}
+	}
+	return position
+}
+
+// GenerateTupletCommands draws the number and, for unbeamed groups, the
+// bracket of every tuplet in the measure. positions holds the x of each
+// element; nested tuplets are stacked outside the ones they contain.
+func (e *Engraver) GenerateTupletCommands(measure *music.Measure, positions []float32, y float32, color renderer.Color, buffer *renderer.CommandBuffer) {
+	noteheadWidth := units.StaffSpacesToPixels(1.2)
+	if bbox, ok := e.MusicFont.BoundingBoxes["noteheadBlack"]; ok {
+		noteheadWidth = units.StaffSpacesToPixels(float32(bbox.NE[0] - bbox.SW[0]))
+	}
+
+	for ti, t := range measure.Tuplets {
+		if t.End >= len(positions) {
+			continue
+		}
+		beamed := tupletIsBeamed(measure, t)
+		above := tupletAbove(measure, t, beamed)
+
+		// Outer tuplets sit beyond the inner ones they enclose
+		depth := measure.TupletDepth(ti)
+		nested := 0
+		for tj, other := range measure.Tuplets {
+			if tj != ti && t.Encloses(other) {
+				if d := measure.TupletDepth(tj) - depth; d > nested {
+					nested = d
+				}
+			}
+		}
+		position := tupletPosition(measure, t, above)
+		if above {
+			position += nested * tupletNestingSpacing
+		} else {
+			position -= nested * tupletNestingSpacing
+		}
+		lineY := y - staffPositionToPixels(position)
+
+		startX := positions[t.Start]
+		endX := positions[t.End] + noteheadWidth
+		centerX := (startX + endX) / 2
+
+		// Number glyphs, laid out left to right and centred on the group
+		names := tupletNumberGlyphs(t)
+		totalWidth := float32(0)
+		widths := make([]float32, len(names))
+		for i, name := range names {
+			if g, ok := e.MusicFont.GetGlyph(name); ok {
+				widths[i] = e.bboxWidthInPixels(g.BBox)
+			}
+			totalWidth += widths[i]
+		}
+		// Centre the digits vertically on the bracket line
+		numberY := lineY + units.StaffSpacesToPixels(0.5)
+		numberX := centerX - totalWidth/2
+		for i, name := range names {
+			if g, ok := e.MusicFont.GetGlyph(name); ok {
+				buffer.AddCommand(CreateGlyphCommand(e.MusicFont.Font, g.Codepoint, numberX, numberY, 0, color))
+			}
+			numberX += widths[i]
+		}
+
+		if beamed {
+			continue
+		}
+
+		thickness := units.StaffSpacesToPixels(float32(e.MusicFont.EngravingDefaults.TupletBracketThickness))
+		gap := totalWidth/2 + units.StaffSpacesToPixels(tupletNumberGap)
+		hook := units.StaffSpacesToPixels(tupletHookSpaces)
+		if !above {
+			hook = -hook
+		}
+
+		left := renderer.Vector2{X: startX, Y: lineY}
+		right := renderer.Vector2{X: endX, Y: lineY}
+		buffer.AddCommand(renderer.NewLineCommand(left, renderer.Vector2{X: centerX - gap, Y: lineY}, thickness, color))
+		buffer.AddCommand(renderer.NewLineCommand(renderer.Vector2{X: centerX + gap, Y: lineY}, right, thickness, color))
+		buffer.AddCommand(renderer.NewLineCommand(left, renderer.Vector2{X: startX, Y: lineY + hook}, thickness, color))
+		buffer.AddCommand(renderer.NewLineCommand(right, renderer.Vector2{X: endX, Y: lineY + hook}, thickness, color))
+	}
+}
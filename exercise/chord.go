@@ -0,0 +1,286 @@
+package exercise
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"gehoer/audio"
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// ChordOptions chooses the chords a ChordExercise asks about and how they
+// are played
+type ChordOptions struct {
+	Qualities  []theory.ChordQuality // nil for all triads and seventh chords
+	Inversions bool                  // ask for inversions as well as root position
+	Low, High  int                   // MIDI range the chords' notes stay within
+	Clef       string
+	Tempo      int  // quarter notes per minute of the audio clip
+	Arpeggiate bool // play the notes one at a time before the chord
+}
+
+// DefaultChordOptions returns options for every quality in root position,
+// played as a block chord around the treble staff
+func DefaultChordOptions() ChordOptions {
+	return ChordOptions{
+		Low:   53, // F3
+		High:  81, // A5
+		Clef:  music.TrebleClef,
+		Tempo: audio.DefaultTempo,
+	}
+}
+
+// minChordRange is the narrowest range every chord fits in on every root:
+// the widest chord (a major seventh) plus the eleven semitones its root
+// can lie above the bottom of the range
+const minChordRange = 22
+
+// chordRoots are the roots chords are built on, one or two spellings of
+// each pitch class
+var chordRoots = []theory.Pitch{
+	{Step: 0}, {Step: 0, Alter: 1}, {Step: 1, Alter: -1}, {Step: 1}, {Step: 2, Alter: -1},
+	{Step: 2}, {Step: 3}, {Step: 3, Alter: 1}, {Step: 4, Alter: -1}, {Step: 4},
+	{Step: 5, Alter: -1}, {Step: 5}, {Step: 6, Alter: -1}, {Step: 6},
+}
+
+// ChordExercise asks for the quality, and optionally the inversion, of
+// chords on random roots
+type ChordExercise struct {
+	opts ChordOptions
+	rng  *rand.Rand
+}
+
+// NewChordExercise returns an exercise drawing its chords from rng, which
+// a caller can seed for a repeatable sequence. It fails for unknown
+// qualities and ranges too narrow for every chord.
+func NewChordExercise(opts ChordOptions, rng *rand.Rand) (*ChordExercise, error) {
+	if opts.Qualities == nil {
+		opts.Qualities = append(append([]theory.ChordQuality(nil), theory.Triads...), theory.Sevenths...)
+	}
+	if len(opts.Qualities) == 0 {
+		return nil, fmt.Errorf("no chord qualities to ask about")
+	}
+	for _, q := range opts.Qualities {
+		if q.String() == "" {
+			return nil, fmt.Errorf("unknown chord quality %d", q)
+		}
+	}
+	if opts.High-opts.Low < minChordRange {
+		return nil, fmt.Errorf("the range %d-%d is too narrow for every chord; it needs %d semitones", opts.Low, opts.High, minChordRange)
+	}
+	if opts.Clef == "" {
+		opts.Clef = music.TrebleClef
+	}
+	return &ChordExercise{opts: opts, rng: rng}, nil
+}
+
+// ChordQuestion is one chord to recognise
+type ChordQuestion struct {
+	Chord        theory.Chord
+	AskInversion bool
+	Score        *music.Score      // the chord engraved as a whole-note chord
+	Clip         []audio.NoteEvent // the chord as played
+	Tempo        int               // to time the clip with audio.TicksToSeconds
+}
+
+// Next returns a new question
+func (e *ChordExercise) Next() ChordQuestion {
+	q := e.opts.Qualities[e.rng.IntN(len(e.opts.Qualities))]
+	inversion := 0
+	if e.opts.Inversions {
+		inversion = e.rng.IntN(q.Size())
+	}
+	return e.ask(q, inversion)
+}
+
+// Items returns the keys of the qualities and inversions the exercise asks
+// about, for a Scheduler
+func (e *ChordExercise) Items() []string {
+	var items []string
+	for _, q := range e.opts.Qualities {
+		inversions := 1
+		if e.opts.Inversions {
+			inversions = q.Size()
+		}
+		for inversion := 0; inversion < inversions; inversion++ {
+			items = append(items, ChordItem(q, inversion))
+		}
+	}
+	return items
+}
+
+// Ask returns a question about the item, a key from Items, on a random root
+func (e *ChordExercise) Ask(item string) (ChordQuestion, error) {
+	for _, q := range e.opts.Qualities {
+		inversions := 1
+		if e.opts.Inversions {
+			inversions = q.Size()
+		}
+		for inversion := 0; inversion < inversions; inversion++ {
+			if ChordItem(q, inversion) == item {
+				return e.ask(q, inversion), nil
+			}
+		}
+	}
+	return ChordQuestion{}, fmt.Errorf("the exercise does not ask about %q", item)
+}
+
+// ask returns a question about a quality in an inversion
+func (e *ChordExercise) ask(q theory.ChordQuality, inversion int) ChordQuestion {
+	for {
+		root := chordRoots[e.rng.IntN(len(chordRoots))]
+		chord, err := theory.NewChord(root, q, inversion)
+		if err != nil || !readable(chord.Pitches()) {
+			continue
+		}
+		octaves, ok := e.placement(chord.Pitches())
+		if !ok {
+			continue
+		}
+		chord.Root.Octave += octaves
+		pitches := chord.Pitches()
+		return ChordQuestion{
+			Chord:        chord,
+			AskInversion: e.opts.Inversions,
+			Score:        chordScore(pitches, e.opts.Clef),
+			Clip:         audio.Render(chordClip(pitches, e.opts.Tempo, e.opts.Arpeggiate)),
+			Tempo:        e.opts.Tempo,
+		}
+	}
+}
+
+// readable reports whether a chord needs at most one double sharp or flat
+func readable(pitches []theory.Pitch) bool {
+	doubles := 0
+	for _, p := range pitches {
+		if p.Alter < -1 || p.Alter > 1 {
+			doubles++
+		}
+	}
+	return doubles <= 1
+}
+
+// placement picks a random number of octaves to move the chord by that
+// keeps it in range. ok is false when no octave does.
+func (e *ChordExercise) placement(pitches []theory.Pitch) (octaves int, ok bool) {
+	low, high := pitches[0].MIDI(), pitches[len(pitches)-1].MIDI()
+	var shifts []int
+	for k := -10; k <= 10; k++ {
+		if low+12*k >= e.opts.Low && high+12*k <= e.opts.High {
+			shifts = append(shifts, k)
+		}
+	}
+	if len(shifts) == 0 {
+		return 0, false
+	}
+	return shifts[e.rng.IntN(len(shifts))], true
+}
+
+// chordScore writes the pitches as a whole-note chord in C major, with the
+// accidentals they need
+func chordScore(pitches []theory.Pitch, clef string) *music.Score {
+	score := music.NewScore("", "", "C", "dur", 4, 4, 0)
+	m := score.AddMeasure(nil)
+	m.Clef = clef
+	barAlters := make(map[int]int)
+	for i, p := range pitches {
+		m.AddNote(&music.Note{
+			Pitch:      p.MIDI(),
+			Duration:   music.WholeNote,
+			StaffLine:  p.Diatonic() - music.ClefBottomLine(clef),
+			Accidental: theory.AccidentalFor(p.Diatonic(), p.Alter, [7]int{}, barAlters),
+			Chord:      i > 0,
+		})
+	}
+	return score
+}
+
+// chordClip writes the chord as heard: a whole-note block chord, after the
+// notes one at a time in quarter notes when arpeggiated
+func chordClip(pitches []theory.Pitch, tempo int, arpeggiate bool) *music.Score {
+	score := music.NewScore("", "", "C", "dur", 4, 4, tempo)
+	if arpeggiate {
+		m := score.AddMeasure(nil)
+		for _, p := range pitches {
+			m.AddNote(&music.Note{Pitch: p.MIDI(), Duration: music.QuarterNote})
+		}
+		for i := len(pitches); i < 4; i++ {
+			m.AddRest(&music.Rest{Duration: music.QuarterNote})
+		}
+	}
+	m := score.AddMeasure(nil)
+	for i, p := range pitches {
+		m.AddNote(&music.Note{Pitch: p.MIDI(), Duration: music.WholeNote, Chord: i > 0})
+	}
+	return score
+}
+
+// ChordAnswer is a student's answer to a chord question
+type ChordAnswer struct {
+	Quality   theory.ChordQuality
+	Inversion int // ignored when the question does not ask for it
+}
+
+// ChordGrade is a graded chord answer, with the chords named in Norwegian
+type ChordGrade struct {
+	Correct          bool
+	QualityCorrect   bool
+	InversionCorrect bool   // true when the inversion was not asked for
+	Expected         string // the chord asked about, such as "molltreklang, første omvending"
+	Given            string // the answer, named the same way
+	Feedback         string // "rett", or "feil" and the right answer
+}
+
+// Grade checks an answer against the question. An inversion that sounds
+// the same as the one asked about counts as right: every inversion of an
+// augmented triad or a diminished seventh chord sounds like root position
+// on another root.
+func (q ChordQuestion) Grade(a ChordAnswer, loc *localization.Localization) ChordGrade {
+	g := ChordGrade{
+		QualityCorrect:   a.Quality == q.Chord.Quality,
+		InversionCorrect: !q.AskInversion || a.Inversion == q.Chord.Inversion,
+	}
+	if g.QualityCorrect && !g.InversionCorrect {
+		asked := chordShape(q.Chord.Quality, q.Chord.Inversion)
+		g.InversionCorrect = asked != nil && slices.Equal(chordShape(a.Quality, a.Inversion), asked)
+	}
+	g.Correct = g.QualityCorrect && g.InversionCorrect
+	g.Expected = q.answerName(q.Chord.Quality, q.Chord.Inversion, loc)
+	g.Given = q.answerName(a.Quality, a.Inversion, loc)
+	if g.Correct {
+		g.Feedback = loc.GetTerm("correct")
+	} else {
+		g.Feedback = loc.GetTerm("incorrect") + ": " + g.Expected
+	}
+	return g
+}
+
+// chordShape returns the semitones of a chord's notes above its bass, or
+// nil for an inversion the quality does not have
+func chordShape(q theory.ChordQuality, inversion int) []int {
+	chord, err := theory.NewChord(theory.Pitch{}, q, inversion)
+	if err != nil {
+		return nil
+	}
+	pitches := chord.Pitches()
+	shape := make([]int, len(pitches))
+	for i, p := range pitches {
+		shape[i] = p.MIDI() - pitches[0].MIDI()
+	}
+	return shape
+}
+
+// answerName names a chord as the question asks for it: with its
+// inversion, root position included, when the inversion is asked for
+func (q ChordQuestion) answerName(quality theory.ChordQuality, inversion int, loc *localization.Localization) string {
+	if !q.AskInversion {
+		return loc.ChordName(quality, 0)
+	}
+	if inversion == 0 {
+		return loc.ChordName(quality, 0) + ", " + loc.InversionName(0)
+	}
+	return loc.ChordName(quality, inversion)
+}
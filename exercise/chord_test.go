@@ -0,0 +1,158 @@
+package exercise
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+func newTestChordExercise(t *testing.T, opts ChordOptions) *ChordExercise {
+	t.Helper()
+	e, err := NewChordExercise(opts, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestNewChordExerciseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts func(*ChordOptions)
+	}{
+		{"no qualities", func(o *ChordOptions) { o.Qualities = []theory.ChordQuality{} }},
+		{"unknown quality", func(o *ChordOptions) { o.Qualities = []theory.ChordQuality{theory.MajorTriad, 42} }},
+		{"range too narrow", func(o *ChordOptions) { o.Low, o.High = 60, 60+minChordRange-1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultChordOptions()
+			tt.opts(&opts)
+			if _, err := NewChordExercise(opts, rand.New(rand.NewPCG(1, 2))); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestChordExerciseQuestions(t *testing.T) {
+	for _, arpeggiate := range []bool{false, true} {
+		opts := DefaultChordOptions()
+		opts.Inversions = true
+		opts.Low, opts.High = 48, 48+minChordRange
+		opts.Arpeggiate = arpeggiate
+		e := newTestChordExercise(t, opts)
+		for i := 0; i < 200; i++ {
+			q := e.Next()
+			pitches := q.Chord.Pitches()
+			if !q.AskInversion {
+				t.Fatal("inversion not asked for")
+			}
+			if low, high := pitches[0].MIDI(), pitches[len(pitches)-1].MIDI(); low < opts.Low || high > opts.High {
+				t.Fatalf("%+v spans %d-%d, outside %d-%d", q.Chord, low, high, opts.Low, opts.High)
+			}
+			if !readable(pitches) {
+				t.Fatalf("%+v has %v", q.Chord, pitches)
+			}
+
+			m := q.Score.Measures[0]
+			if len(m.Elements) != len(pitches) {
+				t.Fatalf("%+v engraved as %d notes", q.Chord, len(m.Elements))
+			}
+			barAlters := map[int]int{}
+			for j, e := range m.Elements {
+				n := e.(*music.Note)
+				p := pitches[j]
+				want := theory.AccidentalFor(p.Diatonic(), p.Alter, [7]int{}, barAlters)
+				if n.Pitch != p.MIDI() || n.Chord != (j > 0) || n.Accidental != want {
+					t.Fatalf("%+v note %d is %+v, want %v with %q", q.Chord, j, n, p, want)
+				}
+				if got, ok := theory.NotePitch(n, q.Score.ClefAt(0)); !ok || got != p {
+					t.Fatalf("%+v note %d reads as %v, want %v", q.Chord, j, got, p)
+				}
+			}
+
+			notes := len(pitches)
+			if arpeggiate {
+				notes *= 2
+			}
+			if len(q.Clip) != notes {
+				t.Fatalf("%+v plays %d notes, want %d", q.Chord, len(q.Clip), notes)
+			}
+		}
+	}
+}
+
+func TestChordExerciseItems(t *testing.T) {
+	opts := DefaultChordOptions()
+	if got := len(newTestChordExercise(t, opts).Items()); got != len(theory.Triads)+len(theory.Sevenths) {
+		t.Errorf("%d items in root position", got)
+	}
+
+	opts.Inversions = true
+	e := newTestChordExercise(t, opts)
+	items := e.Items()
+	if want := 3*len(theory.Triads) + 4*len(theory.Sevenths); len(items) != want {
+		t.Errorf("%d items with inversions, want %d", len(items), want)
+	}
+	for _, item := range items {
+		q, err := e.Ask(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ChordItem(q.Chord.Quality, q.Chord.Inversion); got != item {
+			t.Errorf("asked about %s, got %s", item, got)
+		}
+	}
+	if _, err := e.Ask(ChordItem(theory.MajorTriad, 3)); err == nil {
+		t.Error("asked about a third inversion of a triad")
+	}
+}
+
+func TestChordGrade(t *testing.T) {
+	loc := localization.NewNynorskLocalization("C", "dur")
+	chord := func(q theory.ChordQuality, inversion int) theory.Chord {
+		return theory.Chord{Root: theory.Pitch{Step: 1, Octave: 4}, Quality: q, Inversion: inversion}
+	}
+	tests := []struct {
+		name              string
+		chord             theory.Chord
+		askInversion      bool
+		answer            ChordAnswer
+		quality, inverted bool
+		expected          string
+	}{
+		{"right quality", chord(theory.MinorTriad, 0), false, ChordAnswer{theory.MinorTriad, 0}, true, true, "molltreklang"},
+		{"inversion not asked", chord(theory.MinorTriad, 2), false, ChordAnswer{theory.MinorTriad, 0}, true, true, "molltreklang"},
+		{"wrong quality", chord(theory.MinorTriad, 0), false, ChordAnswer{theory.MajorTriad, 0}, false, true, "molltreklang"},
+		{"right inversion", chord(theory.DominantSeventh, 3), true, ChordAnswer{theory.DominantSeventh, 3}, true, true, "dominantseptimakkord, tredje omvending"},
+		{"wrong inversion", chord(theory.MajorTriad, 1), true, ChordAnswer{theory.MajorTriad, 0}, true, false, "durtreklang, første omvending"},
+		{"root position named", chord(theory.MajorTriad, 0), true, ChordAnswer{theory.MajorTriad, 2}, true, false, "durtreklang, grunnstilling"},
+		{"augmented inversions sound alike", chord(theory.AugmentedTriad, 1), true, ChordAnswer{theory.AugmentedTriad, 0}, true, true, "forstørra treklang, første omvending"},
+		{"diminished seventh inversions sound alike", chord(theory.DiminishedSeventh, 0), true, ChordAnswer{theory.DiminishedSeventh, 2}, true, true, "forminska septimakkord, grunnstilling"},
+		{"inversion the chord does not have", chord(theory.AugmentedTriad, 0), true, ChordAnswer{theory.AugmentedTriad, 3}, true, false, "forstørra treklang, grunnstilling"},
+		{"other quality in the same inversion", chord(theory.AugmentedTriad, 1), true, ChordAnswer{theory.MajorTriad, 1}, false, true, "forstørra treklang, første omvending"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := ChordQuestion{Chord: tt.chord, AskInversion: tt.askInversion}
+			g := q.Grade(tt.answer, loc)
+			if g.QualityCorrect != tt.quality || g.InversionCorrect != tt.inverted || g.Correct != (tt.quality && tt.inverted) {
+				t.Errorf("quality %v inversion %v correct %v, want %v %v", g.QualityCorrect, g.InversionCorrect, g.Correct, tt.quality, tt.inverted)
+			}
+			if g.Expected != tt.expected {
+				t.Errorf("expected %q, want %q", g.Expected, tt.expected)
+			}
+			want := "rett"
+			if !g.Correct {
+				want = "feil: " + tt.expected
+			}
+			if g.Feedback != want {
+				t.Errorf("feedback %q, want %q", g.Feedback, want)
+			}
+		})
+	}
+}
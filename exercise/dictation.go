@@ -0,0 +1,240 @@
+package exercise
+
+import (
+	"math"
+
+	"gehoer/audio"
+	"gehoer/music"
+)
+
+// DictationQuestion is a melody to hear and write down
+type DictationQuestion struct {
+	Reference *music.Score
+	Clip      []audio.NoteEvent // the melody as played
+	Tempo     int               // to time the clip with audio.TicksToSeconds
+}
+
+// NewDictation returns a question for a melody, generated by
+// GenerateMelody or taken from a corpus
+func NewDictation(reference *music.Score) DictationQuestion {
+	tempo := reference.Tempo
+	if tempo <= 0 {
+		tempo = audio.DefaultTempo
+	}
+	return DictationQuestion{
+		Reference: reference,
+		Clip:      audio.Render(reference),
+		Tempo:     tempo,
+	}
+}
+
+// AnswerSheet returns an empty score for the answer, with the reference's
+// key, time signature, tempo and first clef
+func (q DictationQuestion) AnswerSheet() *music.Score {
+	ref := q.Reference
+	sheet := music.NewScore("", "", ref.KeySignature.Tonic, ref.KeySignature.Mode,
+		ref.TimeSignature.Numerator, ref.TimeSignature.Denominator, ref.Tempo)
+	m := sheet.AddMeasure(nil)
+	m.Clef = ref.ClefAt(0)
+	return sheet
+}
+
+// DictationResult is a graded dictation. Pitches and rhythm are aligned
+// with the reference separately, so a wrong note in the right rhythm counts
+// for the rhythm.
+type DictationResult struct {
+	PitchDistance  int     // edits between the answer's pitches and the reference's
+	RhythmDistance int     // edits between the durations of notes and rests
+	PitchScore     float64 // 1 - PitchDistance / the longer of the pitch sequences
+	RhythmScore    float64 // 1 - RhythmDistance / the longer of the rhythms
+	Notes          []NoteFeedback
+}
+
+// NoteFeedback tells whether a note of the answer matches the note of the
+// reference it is aligned with
+type NoteFeedback struct {
+	Refs          []music.ElementRef // the note, the notes tied on from it and any chord notes
+	PitchCorrect  bool
+	RhythmCorrect bool
+}
+
+// Correct reports whether both the pitch and the rhythm are right
+func (f NoteFeedback) Correct() bool {
+	return f.PitchCorrect && f.RhythmCorrect
+}
+
+// Marks returns for each element of the answer's notes whether it is
+// right, for the engraver to colour green or red
+func (r DictationResult) Marks() map[music.ElementRef]bool {
+	marks := make(map[music.ElementRef]bool)
+	for _, f := range r.Notes {
+		for _, ref := range f.Refs {
+			marks[ref] = f.Correct()
+		}
+	}
+	return marks
+}
+
+// Grade compares an answer with the reference
+func (q DictationQuestion) Grade(answer *music.Score) DictationResult {
+	ref, ans := melodyEvents(q.Reference), melodyEvents(answer)
+	var r DictationResult
+
+	refNotes, ansNotes := notesOnly(ref), notesOnly(ans)
+	samePitch := func(i, j int) bool {
+		return ref[refNotes[i]].pitch == ans[ansNotes[j]].pitch
+	}
+	sameRhythm := func(i, j int) bool {
+		return ref[i].rest == ans[j].rest && math.Abs(ref[i].quarters-ans[j].quarters) < durationTolerance
+	}
+	pitchPairs, pitchDistance := align(len(refNotes), len(ansNotes), samePitch)
+	rhythmPairs, rhythmDistance := align(len(ref), len(ans), sameRhythm)
+	r.PitchDistance, r.RhythmDistance = pitchDistance, rhythmDistance
+	r.PitchScore = editScore(pitchDistance, len(refNotes), len(ansNotes))
+	r.RhythmScore = editScore(rhythmDistance, len(ref), len(ans))
+
+	// Right notes by index in ans
+	pitchOK := make(map[int]bool)
+	for _, p := range pitchPairs {
+		if p.ref >= 0 && p.ans >= 0 && samePitch(p.ref, p.ans) {
+			pitchOK[ansNotes[p.ans]] = true
+		}
+	}
+	rhythmOK := make(map[int]bool)
+	for _, p := range rhythmPairs {
+		if p.ref >= 0 && p.ans >= 0 && sameRhythm(p.ref, p.ans) {
+			rhythmOK[p.ans] = true
+		}
+	}
+	for i, ev := range ans {
+		if ev.rest {
+			continue
+		}
+		r.Notes = append(r.Notes, NoteFeedback{Refs: ev.refs, PitchCorrect: pitchOK[i], RhythmCorrect: rhythmOK[i]})
+	}
+	return r
+}
+
+// durationTolerance absorbs float rounding when comparing tuplet lengths
+const durationTolerance = 1e-6
+
+// editScore turns an edit distance into a score from 0 to 1
+func editScore(distance, a, b int) float64 {
+	longest := max(a, b)
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(distance)/float64(longest)
+}
+
+// melodyEvent is a note or rest of a melody as heard: tied notes are one
+// event, and a chord counts as its first note
+type melodyEvent struct {
+	rest     bool
+	pitch    int
+	quarters float64
+	refs     []music.ElementRef
+}
+
+// melodyEvents lists the notes and rests of a score in written order,
+// leaving out grace notes
+func melodyEvents(score *music.Score) []melodyEvent {
+	var events []melodyEvent
+	continues := make(map[music.ElementRef]int) // tie targets, to the event they continue
+	for mi, m := range score.Measures {
+		for ei, e := range m.Elements {
+			ref := music.ElementRef{Measure: mi, Element: ei}
+			quarters := m.ElementQuarters(ei)
+			switch el := e.(type) {
+			case *music.Rest:
+				events = append(events, melodyEvent{rest: true, quarters: quarters, refs: []music.ElementRef{ref}})
+			case *music.Note:
+				if el.IsGrace() {
+					continue
+				}
+				i, tied := continues[ref]
+				switch {
+				case tied:
+					if !el.Chord {
+						events[i].quarters += quarters
+					}
+					events[i].refs = append(events[i].refs, ref)
+				case el.Chord && len(events) > 0:
+					i = len(events) - 1
+					events[i].refs = append(events[i].refs, ref)
+				default:
+					events = append(events, melodyEvent{pitch: el.Pitch, quarters: quarters, refs: []music.ElementRef{ref}})
+					i = len(events) - 1
+				}
+				if el.Tie {
+					if target, ok := score.TieTarget(ref); ok {
+						continues[target] = i
+					}
+				}
+			}
+		}
+	}
+	return events
+}
+
+// notesOnly returns the indices of the events that are notes
+func notesOnly(events []melodyEvent) []int {
+	var idx []int
+	for i, ev := range events {
+		if !ev.rest {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// alignedPair pairs an index of the reference with one of the answer; -1
+// on either side is a missing or an extra item
+type alignedPair struct {
+	ref, ans int
+}
+
+// align finds the edit distance between two sequences, counting a
+// substitution, insertion or deletion as one edit, and the pairs of a
+// cheapest alignment in order
+func align(n, m int, same func(i, j int) bool) ([]alignedPair, int) {
+	cost := make([][]int, n+1)
+	for i := range cost {
+		cost[i] = make([]int, m+1)
+		cost[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		cost[0][j] = j
+	}
+	substitution := func(i, j int) int {
+		if same(i-1, j-1) {
+			return cost[i-1][j-1]
+		}
+		return cost[i-1][j-1] + 1
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost[i][j] = min(substitution(i, j), cost[i-1][j]+1, cost[i][j-1]+1)
+		}
+	}
+
+	var pairs []alignedPair
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && cost[i][j] == substitution(i, j):
+			pairs = append(pairs, alignedPair{i - 1, j - 1})
+			i, j = i-1, j-1
+		case i > 0 && cost[i][j] == cost[i-1][j]+1:
+			pairs = append(pairs, alignedPair{i - 1, -1})
+			i--
+		default:
+			pairs = append(pairs, alignedPair{-1, j - 1})
+			j--
+		}
+	}
+	for l, r := 0, len(pairs)-1; l < r; l, r = l+1, r-1 {
+		pairs[l], pairs[r] = pairs[r], pairs[l]
+	}
+	return pairs, cost[n][m]
+}
@@ -0,0 +1,136 @@
+package exercise
+
+import (
+	"reflect"
+	"testing"
+
+	"gehoer/music"
+	"gehoer/notation"
+)
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		ref, ans string
+		distance int
+		pairs    []alignedPair
+	}{
+		{"abc", "abc", 0, []alignedPair{{0, 0}, {1, 1}, {2, 2}}},
+		{"abc", "abd", 1, []alignedPair{{0, 0}, {1, 1}, {2, 2}}},
+		{"abc", "ac", 1, []alignedPair{{0, 0}, {1, -1}, {2, 1}}},
+		{"ac", "abc", 1, []alignedPair{{0, 0}, {-1, 1}, {1, 2}}},
+		{"abcd", "bcda", 2, []alignedPair{{0, -1}, {1, 0}, {2, 1}, {3, 2}, {-1, 3}}},
+		{"", "ab", 2, []alignedPair{{-1, 0}, {-1, 1}}},
+		{"ab", "", 2, []alignedPair{{0, -1}, {1, -1}}},
+		{"", "", 0, nil},
+	}
+	for _, tt := range tests {
+		same := func(i, j int) bool { return tt.ref[i] == tt.ans[j] }
+		pairs, distance := align(len(tt.ref), len(tt.ans), same)
+		if distance != tt.distance || !reflect.DeepEqual(pairs, tt.pairs) {
+			t.Errorf("%q to %q: distance %d with %v, want %d with %v", tt.ref, tt.ans, distance, pairs, tt.distance, tt.pairs)
+		}
+	}
+}
+
+func parseMelody(t *testing.T, src string) *music.Score {
+	t.Helper()
+	score, err := notation.ParseText(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return score
+}
+
+// noteFeedback is a NoteFeedback without its element references
+type noteFeedback struct {
+	refs          int
+	pitch, rhythm bool
+}
+
+func TestDictationGrade(t *testing.T) {
+	const reference = `c'4 d' e' f' | g'2 g'`
+	tests := []struct {
+		name                    string
+		answer                  string
+		pitchDistance           int
+		rhythmDistance          int
+		pitchScore, rhythmScore float64
+		notes                   []noteFeedback
+	}{
+		{
+			"right", reference, 0, 0, 1, 1,
+			[]noteFeedback{{1, true, true}, {1, true, true}, {1, true, true}, {1, true, true}, {1, true, true}, {1, true, true}},
+		},
+		{
+			"wrong pitch in the right rhythm", `c'4 d' fiss' f' | g'2 g'`, 1, 0, 5.0 / 6, 1,
+			[]noteFeedback{{1, true, true}, {1, true, true}, {1, false, true}, {1, true, true}, {1, true, true}, {1, true, true}},
+		},
+		{
+			"wrong rhythm on the right pitch", `c'4 d'8 d' e'4 f' | g'2 g'`, 1, 2, 6.0 / 7, 5.0 / 7,
+			[]noteFeedback{{1, true, true}, {1, false, false}, {1, true, false}, {1, true, true}, {1, true, true}, {1, true, true}, {1, true, true}},
+		},
+		{
+			"tied notes are one note", `c'4 d'8~ d' e'4 f' | g'4~ g' g'2`, 0, 0, 1, 1,
+			[]noteFeedback{{1, true, true}, {2, true, true}, {1, true, true}, {1, true, true}, {2, true, true}, {1, true, true}},
+		},
+		{
+			"rest for a note", `c'4 r e' f' | g'2 g'`, 1, 1, 5.0 / 6, 5.0 / 6,
+			[]noteFeedback{{1, true, true}, {1, true, true}, {1, true, true}, {1, true, true}, {1, true, true}},
+		},
+		{
+			"empty answer", ``, 6, 6, 0, 0, nil,
+		},
+	}
+	q := NewDictation(parseMelody(t, reference))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := q.Grade(parseMelody(t, tt.answer))
+			if r.PitchDistance != tt.pitchDistance || r.RhythmDistance != tt.rhythmDistance {
+				t.Errorf("distances %d and %d, want %d and %d", r.PitchDistance, r.RhythmDistance, tt.pitchDistance, tt.rhythmDistance)
+			}
+			if !near(r.PitchScore, tt.pitchScore) || !near(r.RhythmScore, tt.rhythmScore) {
+				t.Errorf("scores %g and %g, want %g and %g", r.PitchScore, r.RhythmScore, tt.pitchScore, tt.rhythmScore)
+			}
+			var notes []noteFeedback
+			for _, f := range r.Notes {
+				notes = append(notes, noteFeedback{len(f.Refs), f.PitchCorrect, f.RhythmCorrect})
+			}
+			if !reflect.DeepEqual(notes, tt.notes) {
+				t.Errorf("notes %v, want %v", notes, tt.notes)
+			}
+		})
+	}
+}
+
+func TestDictationMarks(t *testing.T) {
+	q := NewDictation(parseMelody(t, `\time 2/4 c'4 d' | e'2`))
+	r := q.Grade(parseMelody(t, `\time 2/4 c'4 f'~ | f'4 e'`))
+	want := map[music.ElementRef]bool{
+		{Measure: 0, Element: 0}: true,
+		{Measure: 0, Element: 1}: false,
+		{Measure: 1, Element: 0}: false,
+		{Measure: 1, Element: 1}: false,
+	}
+	if got := r.Marks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDictationAnswerSheet(t *testing.T) {
+	ref := parseMelody(t, `\clef bass \key e \minor \time 3/4 \tempo 4 = 72 e4 fiss g`)
+	q := NewDictation(ref)
+	if q.Tempo != 72 || len(q.Clip) != 3 {
+		t.Errorf("tempo %d and %d notes in the clip", q.Tempo, len(q.Clip))
+	}
+	sheet := q.AnswerSheet()
+	if sheet.KeySignature != ref.KeySignature || sheet.TimeSignature != ref.TimeSignature || sheet.Tempo != 72 {
+		t.Errorf("sheet in %v %v at %d, want %v %v at 72", sheet.KeySignature, sheet.TimeSignature, sheet.Tempo, ref.KeySignature, ref.TimeSignature)
+	}
+	if len(sheet.Measures) != 1 || len(sheet.Measures[0].Elements) != 0 || sheet.ClefAt(0) != music.BassClef {
+		t.Errorf("sheet has %d measures in the %s clef, want one empty measure in the bass clef", len(sheet.Measures), sheet.ClefAt(0))
+	}
+
+	if q := NewDictation(parseMelody(t, `c'4`)); q.Tempo <= 0 {
+		t.Errorf("tempo %d without one in the reference", q.Tempo)
+	}
+}
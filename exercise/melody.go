@@ -0,0 +1,226 @@
+package exercise
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/notation"
+	"gehoer/theory"
+)
+
+// MelodyOptions shapes the melodies GenerateMelody writes
+type MelodyOptions struct {
+	Key           music.KeySignature
+	TimeSignature music.TimeSignature
+	Measures      int
+	Low, High     int // MIDI range of the notes
+	MaxLeap       int // widest leap in scale steps
+	Clef          string
+	Tempo         int
+}
+
+// DefaultMelodyOptions returns options for two measures of 4/4 in C major
+// around the treble staff, moving mostly by step
+func DefaultMelodyOptions() MelodyOptions {
+	return MelodyOptions{
+		Key:           music.KeySignature{Tonic: "C", Mode: "dur"},
+		TimeSignature: music.TimeSignature{Numerator: 4, Denominator: 4},
+		Measures:      2,
+		Low:           60, // C4
+		High:          79, // G5
+		MaxLeap:       4,  // a fifth
+		Clef:          music.TrebleClef,
+		Tempo:         90,
+	}
+}
+
+// melodyValues are the lengths melodies are written in, longest first
+var melodyValues = []struct {
+	value    music.NoteValue
+	dots     int
+	quarters float64
+}{
+	{music.WholeNote, 0, 4},
+	{music.HalfNote, 1, 3},
+	{music.HalfNote, 0, 2},
+	{music.QuarterNote, 1, 1.5},
+	{music.QuarterNote, 0, 1},
+	{music.EighthNote, 0, 0.5},
+}
+
+// GenerateMelody writes a random melody in the options' key from the notes
+// of its mode's scale. It starts and ends on the tonic, moves mostly by step,
+// and ends with the tonic held to the end of the last measure. Melodies
+// move in eighth notes and longer, so it fails for meters such as 3/16 that
+// eighth notes cannot fill.
+func GenerateMelody(opts MelodyOptions, rng *rand.Rand) (*music.Score, error) {
+	key, err := localization.ParseKey(opts.Key.Tonic, opts.Key.Mode)
+	if err != nil {
+		return nil, err
+	}
+	scale, ok := theory.ModeScale(key.Mode)
+	if !ok {
+		return nil, fmt.Errorf("no scale for the mode %q", key.Mode)
+	}
+	if opts.Measures < 1 {
+		return nil, fmt.Errorf("a melody needs at least one measure")
+	}
+	if opts.MaxLeap < 1 {
+		opts.MaxLeap = 1
+	}
+	if opts.Clef == "" {
+		opts.Clef = music.TrebleClef
+	}
+	ts := opts.TimeSignature
+	if ts.Numerator <= 0 || ts.Denominator <= 0 {
+		return nil, fmt.Errorf("invalid time signature %d/%d", ts.Numerator, ts.Denominator)
+	}
+	if 8*ts.Numerator%ts.Denominator != 0 {
+		return nil, fmt.Errorf("eighth notes cannot fill a measure of %d/%d", ts.Numerator, ts.Denominator)
+	}
+	capacity := 4 * float64(ts.Numerator) / float64(ts.Denominator)
+
+	// The scale's notes in range from the lowest, and which of them are
+	// tonics
+	var notes []theory.Pitch
+	var tonics []int
+	for octave := 0; octave <= 9; octave++ {
+		for i, p := range scale.Pitches(theory.Pitch{Step: key.Step, Alter: key.Alter, Octave: octave}) {
+			if p.MIDI() >= opts.Low && p.MIDI() <= opts.High {
+				if i == 0 {
+					tonics = append(tonics, len(notes))
+				}
+				notes = append(notes, p)
+			}
+		}
+	}
+	if len(tonics) == 0 {
+		return nil, fmt.Errorf("no tonic between MIDI %d and %d", opts.Low, opts.High)
+	}
+
+	score := music.NewScore("", "", opts.Key.Tonic, opts.Key.Mode, ts.Numerator, ts.Denominator, opts.Tempo)
+	current := tonics[len(tonics)/2]
+	for mi := 0; mi < opts.Measures; mi++ {
+		m := score.AddMeasure(nil)
+		if mi == 0 {
+			m.Clef = opts.Clef
+		}
+		last := mi == opts.Measures-1
+		filled := 0.0
+		for filled < capacity-durationTolerance {
+			remaining := capacity - filled
+			if last && (remaining <= 2 || filled > 0 && rng.IntN(2) == 0) {
+				// End on the nearest tonic, held to the barline
+				current = nearest(tonics, current)
+				addMelodyNote(m, notes[current], remaining, opts.Clef)
+				break
+			}
+			limit := remaining
+			if last {
+				// Leave room for the closing tonic
+				limit -= 0.5
+			}
+			length := randomLength(limit, rng)
+			if mi > 0 || filled > 0 {
+				current = step(current, len(notes), opts.MaxLeap, rng)
+			}
+			addMelodyNote(m, notes[current], length, opts.Clef)
+			filled += length
+		}
+	}
+	if err := notation.ResolveAccidentals(score, notation.AccidentalOptions{}); err != nil {
+		return nil, err
+	}
+	return score, nil
+}
+
+// randomLength picks a note length that fits in the rest of the measure,
+// favouring quarter and eighth notes
+func randomLength(remaining float64, rng *rand.Rand) float64 {
+	weights := map[float64]int{4: 1, 3: 1, 2: 3, 1.5: 1, 1: 6, 0.5: 3}
+	total := 0
+	for _, v := range melodyValues {
+		if v.quarters <= remaining+durationTolerance {
+			total += weights[v.quarters]
+		}
+	}
+	pick := rng.IntN(total)
+	for _, v := range melodyValues {
+		if v.quarters <= remaining+durationTolerance {
+			if pick -= weights[v.quarters]; pick < 0 {
+				return v.quarters
+			}
+		}
+	}
+	return remaining
+}
+
+// step moves a random number of scale steps, mostly one, at most maxLeap,
+// staying within the n notes
+func step(current, n, maxLeap int, rng *rand.Rand) int {
+	for {
+		size := 1
+		if r := rng.IntN(10); r >= 8 {
+			size = 2 + rng.IntN(max(maxLeap-1, 1))
+		} else if r >= 6 {
+			size = 0
+		}
+		size = min(size, maxLeap)
+		if rng.IntN(2) == 0 {
+			size = -size
+		}
+		if next := current + size; next >= 0 && next < n {
+			return next
+		}
+	}
+}
+
+// nearest returns the index in tonics closest to current
+func nearest(tonics []int, current int) int {
+	best := tonics[0]
+	for _, t := range tonics {
+		if abs(t-current) < abs(best-current) {
+			best = t
+		}
+	}
+	return best
+}
+
+// addMelodyNote adds a note of the given length in quarters, as tied notes
+// when no single value has that length. Lengths are rounded down to eighth
+// notes.
+func addMelodyNote(m *music.Measure, p theory.Pitch, quarters float64, clef string) {
+	var prev *music.Note
+	for quarters > durationTolerance {
+		fits := false
+		for _, v := range melodyValues {
+			if v.quarters <= quarters+durationTolerance {
+				fits = true
+				if prev != nil {
+					prev.Tie = true
+				}
+				prev = &music.Note{
+					Pitch:     p.MIDI(),
+					Duration:  v.value,
+					Dots:      v.dots,
+					StaffLine: p.Diatonic() - music.ClefBottomLine(clef),
+				}
+				m.AddNote(prev)
+				quarters -= v.quarters
+				break
+			}
+		}
+		if !fits {
+			return // shorter than an eighth note
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,109 @@
+package exercise
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+func TestGenerateMelody(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   music.KeySignature
+		meter music.TimeSignature
+	}{
+		{"C major in 4/4", music.KeySignature{Tonic: "C", Mode: "dur"}, music.TimeSignature{Numerator: 4, Denominator: 4}},
+		{"g minor in 3/4", music.KeySignature{Tonic: "g", Mode: "moll"}, music.TimeSignature{Numerator: 3, Denominator: 4}},
+		{"D dorian in 6/8", music.KeySignature{Tonic: "D", Mode: "dorisk"}, music.TimeSignature{Numerator: 6, Denominator: 8}},
+		{"B flat major in 5/8", music.KeySignature{Tonic: "b", Mode: "dur"}, music.TimeSignature{Numerator: 5, Denominator: 8}},
+		{"fis minor in 6/16", music.KeySignature{Tonic: "fiss", Mode: "moll"}, music.TimeSignature{Numerator: 6, Denominator: 16}},
+		{"E major in 2/2", music.KeySignature{Tonic: "E", Mode: "dur"}, music.TimeSignature{Numerator: 2, Denominator: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := localization.ParseKey(tt.key.Tonic, tt.key.Mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := DefaultMelodyOptions()
+			opts.Key, opts.TimeSignature, opts.Measures = tt.key, tt.meter, 3
+			opts.Low, opts.High = 55, 81
+			rng := rand.New(rand.NewPCG(5, 6))
+			for i := 0; i < 50; i++ {
+				score, err := GenerateMelody(opts, rng)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ds := score.Validate(); len(ds) > 0 {
+					t.Fatalf("melody does not fill its measures:\n%v", ds)
+				}
+				if len(score.Measures) != opts.Measures {
+					t.Fatalf("%d measures, want %d", len(score.Measures), opts.Measures)
+				}
+				checkMelody(t, score, key, opts)
+			}
+		})
+	}
+}
+
+// checkMelody checks that a melody stays in range, writes no accidentals
+// the key does not give, leaps no further than allowed and starts and ends
+// on the tonic
+func checkMelody(t *testing.T, score *music.Score, key theory.Key, opts MelodyOptions) {
+	t.Helper()
+	var pitches []theory.Pitch // the notes a tie does not continue
+	tied := false
+	for _, m := range score.Measures {
+		for _, e := range m.Elements {
+			n := e.(*music.Note)
+			p, ok := theory.NotePitch(n, opts.Clef)
+			if !ok || n.Pitch < opts.Low || n.Pitch > opts.High {
+				t.Fatalf("note %+v out of range or off its line", n)
+			}
+			if n.Accidental != music.AccidentalNone {
+				t.Fatalf("%v has an accidental in a melody from the key's scale", p)
+			}
+			if !tied {
+				pitches = append(pitches, p)
+			}
+			tied = n.Tie
+		}
+	}
+	isTonic := func(p theory.Pitch) bool { return p.Step == key.Step && p.Alter == key.Alter }
+	if !isTonic(pitches[0]) || !isTonic(pitches[len(pitches)-1]) {
+		t.Fatalf("melody runs from %v to %v, want the tonic", pitches[0], pitches[len(pitches)-1])
+	}
+	// The last note may leap further, to the nearest tonic
+	for i := 1; i < len(pitches)-1; i++ {
+		if d := pitches[i].Diatonic() - pitches[i-1].Diatonic(); d > opts.MaxLeap || d < -opts.MaxLeap {
+			t.Fatalf("leap from %v to %v", pitches[i-1], pitches[i])
+		}
+	}
+}
+
+func TestGenerateMelodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts func(*MelodyOptions)
+	}{
+		{"unknown key", func(o *MelodyOptions) { o.Key = music.KeySignature{Tonic: "X", Mode: "dur"} }},
+		{"no measures", func(o *MelodyOptions) { o.Measures = 0 }},
+		{"no tonic in range", func(o *MelodyOptions) { o.Low, o.High = 61, 70 }},
+		{"zero numerator", func(o *MelodyOptions) { o.TimeSignature = music.TimeSignature{Numerator: 0, Denominator: 4} }},
+		{"zero denominator", func(o *MelodyOptions) { o.TimeSignature = music.TimeSignature{Numerator: 4, Denominator: 0} }},
+		{"3/16", func(o *MelodyOptions) { o.TimeSignature = music.TimeSignature{Numerator: 3, Denominator: 16} }},
+		{"7/16", func(o *MelodyOptions) { o.TimeSignature = music.TimeSignature{Numerator: 7, Denominator: 16} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultMelodyOptions()
+			tt.opts(&opts)
+			if score, err := GenerateMelody(opts, rand.New(rand.NewPCG(1, 1))); err == nil {
+				t.Errorf("got a melody of %d measures, want an error", len(score.Measures))
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package exercise
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"gehoer/localization"
+	"gehoer/music"
+)
+
+// NoteReadingOptions chooses the notes a note-reading drill shows
+type NoteReadingOptions struct {
+	Clefs           []string // nil for treble, bass, alto and tenor
+	Lowest, Highest int      // staff positions shown, 0 being the bottom line and 8 the top
+	RequireOctave   bool     // answers must give the octave, as in "c¹", "c1", "c", "C" or "C₁"
+}
+
+// DefaultNoteReadingOptions returns options for all four clefs with up to
+// two ledger lines above and below the staff
+func DefaultNoteReadingOptions() NoteReadingOptions {
+	return NoteReadingOptions{
+		Clefs:   []string{music.TrebleClef, music.BassClef, music.AltoClef, music.TenorClef},
+		Lowest:  -4,
+		Highest: 12,
+	}
+}
+
+// NoteCard is one note to name: a staff position in a clef
+type NoteCard struct {
+	Clef      string
+	StaffLine int
+}
+
+// Pitch returns the MIDI number of the natural note on the card
+func (c NoteCard) Pitch() int {
+	return music.NaturalPitchAt(c.Clef, c.StaffLine)
+}
+
+// Score returns the card as a whole note on an otherwise empty staff
+func (c NoteCard) Score() *music.Score {
+	score := music.NewScore("", "", "C", "dur", 4, 4, 0)
+	m := score.AddMeasure(nil)
+	m.Clef = c.Clef
+	m.AddNote(&music.Note{Pitch: c.Pitch(), Duration: music.WholeNote, StaffLine: c.StaffLine})
+	return score
+}
+
+// NoteStats counts the answers for one card
+type NoteStats struct {
+	Asked int
+	Wrong int
+	Time  time.Duration // spent answering, in total
+}
+
+// ErrorRate returns the share of wrong answers
+func (s NoteStats) ErrorRate() float64 {
+	if s.Asked == 0 {
+		return 0
+	}
+	return float64(s.Wrong) / float64(s.Asked)
+}
+
+// NoteReadingStats sums up a drill
+type NoteReadingStats struct {
+	Asked, Correct     int
+	Streak, BestStreak int // right answers in a row
+	Time               time.Duration
+	Notes              map[NoteCard]*NoteStats
+}
+
+// Accuracy returns the share of right answers
+func (s *NoteReadingStats) Accuracy() float64 {
+	if s.Asked == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Asked)
+}
+
+// AverageTime returns the mean time taken to answer
+func (s *NoteReadingStats) AverageTime() time.Duration {
+	if s.Asked == 0 {
+		return 0
+	}
+	return s.Time / time.Duration(s.Asked)
+}
+
+// Hardest returns up to n cards answered wrongly, the highest error rate
+// first
+func (s *NoteReadingStats) Hardest(n int) []NoteCard {
+	var cards []NoteCard
+	for card, ns := range s.Notes {
+		if ns.Wrong > 0 {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		a, b := s.Notes[cards[i]], s.Notes[cards[j]]
+		if a.ErrorRate() != b.ErrorRate() {
+			return a.ErrorRate() > b.ErrorRate()
+		}
+		if a.Wrong != b.Wrong {
+			return a.Wrong > b.Wrong
+		}
+		if cards[i].Clef != cards[j].Clef {
+			return cards[i].Clef < cards[j].Clef
+		}
+		return cards[i].StaffLine < cards[j].StaffLine
+	})
+	if len(cards) > n {
+		cards = cards[:n]
+	}
+	return cards
+}
+
+// NoteReadingExercise is a flashcard drill: it shows single notes and
+// checks their Norwegian names, timing each answer
+type NoteReadingExercise struct {
+	Stats NoteReadingStats
+	Now   func() time.Time // time.Now unless replaced, as in tests
+
+	opts    NoteReadingOptions
+	loc     *localization.Localization
+	rng     *rand.Rand
+	card    NoteCard
+	shownAt time.Time
+	started bool
+}
+
+// NewNoteReadingExercise returns a drill naming notes in loc, drawing its
+// cards from rng. It fails for unknown clefs and empty ranges.
+func NewNoteReadingExercise(opts NoteReadingOptions, loc *localization.Localization, rng *rand.Rand) (*NoteReadingExercise, error) {
+	if opts.Clefs == nil {
+		opts.Clefs = DefaultNoteReadingOptions().Clefs
+	}
+	if len(opts.Clefs) == 0 {
+		return nil, fmt.Errorf("no clefs to read")
+	}
+	for _, clef := range opts.Clefs {
+		if !music.IsValidClef(clef) {
+			return nil, fmt.Errorf("unknown clef %q", clef)
+		}
+	}
+	if opts.Lowest > opts.Highest {
+		return nil, fmt.Errorf("the range %d-%d is empty", opts.Lowest, opts.Highest)
+	}
+	return &NoteReadingExercise{
+		Stats: NoteReadingStats{Notes: make(map[NoteCard]*NoteStats)},
+		Now:   time.Now,
+		opts:  opts,
+		loc:   loc,
+		rng:   rng,
+	}, nil
+}
+
+// Next shows a new card, never the one just shown when there is another,
+// and starts timing it
+func (e *NoteReadingExercise) Next() NoteCard {
+	cards := len(e.opts.Clefs) * (e.opts.Highest - e.opts.Lowest + 1)
+	for {
+		card := NoteCard{
+			Clef:      e.opts.Clefs[e.rng.IntN(len(e.opts.Clefs))],
+			StaffLine: e.opts.Lowest + e.rng.IntN(e.opts.Highest-e.opts.Lowest+1),
+		}
+		if card != e.card || !e.started || cards == 1 {
+			e.show(card)
+			return card
+		}
+	}
+}
+
+// Items returns the keys of every card the drill shows, for a Scheduler
+func (e *NoteReadingExercise) Items() []string {
+	var items []string
+	for _, clef := range e.opts.Clefs {
+		for line := e.opts.Lowest; line <= e.opts.Highest; line++ {
+			items = append(items, NoteItem(NoteCard{Clef: clef, StaffLine: line}))
+		}
+	}
+	return items
+}
+
+// Ask shows the card of an item, a key from Items, and starts timing it
+func (e *NoteReadingExercise) Ask(item string) (NoteCard, error) {
+	for _, clef := range e.opts.Clefs {
+		for line := e.opts.Lowest; line <= e.opts.Highest; line++ {
+			if card := (NoteCard{Clef: clef, StaffLine: line}); NoteItem(card) == item {
+				e.show(card)
+				return card, nil
+			}
+		}
+	}
+	return NoteCard{}, fmt.Errorf("the drill does not show %q", item)
+}
+
+// show makes the card the one being shown
+func (e *NoteReadingExercise) show(card NoteCard) {
+	e.card, e.shownAt, e.started = card, e.Now(), true
+}
+
+// Card returns the card being shown
+func (e *NoteReadingExercise) Card() NoteCard {
+	return e.card
+}
+
+// NoteReadingResult is a checked answer
+type NoteReadingResult struct {
+	Correct  bool
+	Expected string // the note's name, as "c¹"
+	Given    string
+	Elapsed  time.Duration
+	Streak   int
+}
+
+// Answer checks a typed or clicked name for the card being shown and adds
+// it to the statistics. Unless the options require the octave, it is
+// ignored along with case; otherwise the case of the first letter tells the
+// great octave from the small.
+func (e *NoteReadingExercise) Answer(name string) NoteReadingResult {
+	expected := e.loc.GetNoteName(e.card.Pitch())
+	r := NoteReadingResult{
+		Correct:  e.matches(name, expected),
+		Expected: expected,
+		Given:    name,
+		Elapsed:  e.Now().Sub(e.shownAt),
+	}
+
+	s := &e.Stats
+	ns := s.Notes[e.card]
+	if ns == nil {
+		ns = &NoteStats{}
+		s.Notes[e.card] = ns
+	}
+	s.Asked++
+	ns.Asked++
+	s.Time += r.Elapsed
+	ns.Time += r.Elapsed
+	if r.Correct {
+		s.Correct++
+		s.Streak++
+		s.BestStreak = max(s.BestStreak, s.Streak)
+	} else {
+		ns.Wrong++
+		s.Streak = 0
+	}
+	r.Streak = s.Streak
+	return r
+}
+
+// CardNamed returns the card in the shown card's clef and the drill's range
+// that an answer names, the one nearest the shown card when the octave is
+// not required. It reports false for answers that name no such card.
+func (e *NoteReadingExercise) CardNamed(name string) (NoteCard, bool) {
+	for d := 0; d <= e.opts.Highest-e.opts.Lowest; d++ {
+		for _, line := range []int{e.card.StaffLine - d, e.card.StaffLine + d} {
+			if line < e.opts.Lowest || line > e.opts.Highest {
+				continue
+			}
+			card := NoteCard{Clef: e.card.Clef, StaffLine: line}
+			if e.matches(name, e.loc.GetNoteName(card.Pitch())) {
+				return card, true
+			}
+		}
+	}
+	return NoteCard{}, false
+}
+
+// matches compares an answer with a note name
+func (e *NoteReadingExercise) matches(answer, expected string) bool {
+	a, b := normalizeNoteName(answer), normalizeNoteName(expected)
+	if !e.opts.RequireOctave {
+		a, b = withoutOctave(a), withoutOctave(b)
+	}
+	return a != "" && a == b
+}
+
+// octaveMarks are the octave marks of Norwegian note names as digits, and
+// the subscripts below the great octave as commas
+var octaveMarks = strings.NewReplacer("¹", "1", "²", "2", "³", "3", "⁴", "4", "⁵", "5", "⁺", "+", "₁", ",", "₂", ",,")
+
+// normalizeNoteName writes the octave mark of a name in digits and commas
+// and lowercases all but its first letter, whose case tells the great octave
+// (C) from the small (c)
+func normalizeNoteName(name string) string {
+	name = octaveMarks.Replace(strings.TrimSpace(name))
+	if name == "" {
+		return ""
+	}
+	return name[:1] + strings.ToLower(name[1:])
+}
+
+// withoutOctave drops the octave from a normalized name: its mark and the
+// case of its letter
+func withoutOctave(name string) string {
+	return strings.ToLower(strings.TrimRight(name, "0123456789+,"))
+}
+
+// NoteNameChoices returns the names of the seven natural notes from c, for
+// answer buttons
+func NoteNameChoices(loc *localization.Localization) []string {
+	var names []string
+	for _, midi := range []int{60, 62, 64, 65, 67, 69, 71} {
+		names = append(names, withoutOctave(normalizeNoteName(loc.GetNoteName(midi))))
+	}
+	return names
+}
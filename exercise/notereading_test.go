@@ -0,0 +1,147 @@
+package exercise
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"gehoer/localization"
+	"gehoer/music"
+)
+
+func TestNoteReadingMatches(t *testing.T) {
+	tests := []struct {
+		answer, expected string
+		requireOctave    bool
+		want             bool
+	}{
+		{"c", "c¹", false, true},
+		{"C", "c¹", false, true},
+		{" fiss ", "Fiss", false, true},
+		{"c1", "c²", false, true},
+		{"d", "c¹", false, false},
+		{"", "c¹", false, false},
+		{"fis", "fiss", false, false},
+
+		{"c¹", "c¹", true, true},
+		{"c1", "c¹", true, true},
+		{"C1", "c¹", true, false},
+		{"c", "c¹", true, false},
+		{"c2", "c¹", true, false},
+		{"c", "c", true, true},
+		{"C", "c", true, false},
+		{"G", "G", true, true},
+		{"g", "G", true, false},
+		{"Fiss", "Fiss", true, true},
+		{"FISS", "Fiss", true, true},
+		{"fiss", "Fiss", true, false},
+		{"C₁", "C₁", true, true},
+		{"C,", "C₁", true, true},
+		{"C", "C₁", true, false},
+		{"C₂", "C₁", true, false},
+		{"A,,", "A₂", true, true},
+		{"c⁺1", "c⁺1", true, true},
+	}
+	for _, tt := range tests {
+		e := &NoteReadingExercise{opts: NoteReadingOptions{RequireOctave: tt.requireOctave}}
+		if got := e.matches(tt.answer, tt.expected); got != tt.want {
+			t.Errorf("%q for %q with the octave required %v: got %v", tt.answer, tt.expected, tt.requireOctave, got)
+		}
+	}
+}
+
+// Every note in reach of the drill has a name no other note shares, so
+// requiring the octave checks it in every clef
+func TestNoteReadingRequireOctave(t *testing.T) {
+	loc := localization.NewNynorskLocalization("C", "dur")
+	opts := DefaultNoteReadingOptions()
+	opts.Lowest, opts.Highest, opts.RequireOctave = -12, 20, true
+	e, err := NewNoteReadingExercise(opts, loc, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, clef := range opts.Clefs {
+		for line := opts.Lowest; line <= opts.Highest; line++ {
+			card := NoteCard{Clef: clef, StaffLine: line}
+			for _, other := range []int{card.Pitch() - 12, card.Pitch() + 12} {
+				e.card = card
+				name := loc.GetNoteName(other)
+				if r := e.Answer(name); r.Correct {
+					t.Errorf("%s in the %s clef accepted %q, the name of %d", r.Expected, clef, name, other)
+				}
+			}
+			e.card = card
+			if r := e.Answer(loc.GetNoteName(card.Pitch())); !r.Correct {
+				t.Errorf("%s in the %s clef refused its own name", r.Expected, clef)
+			}
+		}
+	}
+}
+
+func TestNoteNameChoices(t *testing.T) {
+	got := NoteNameChoices(localization.NewNynorskLocalization("C", "dur"))
+	want := []string{"c", "d", "e", "f", "g", "a", "h"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestNoteReadingStats(t *testing.T) {
+	loc := localization.NewNynorskLocalization("C", "dur")
+	e, err := NewNoteReadingExercise(NoteReadingOptions{Clefs: []string{music.BassClef}, Lowest: 0, Highest: 8}, loc, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := e.Ask(NoteItem(NoteCard{Clef: music.BassClef, StaffLine: 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := e.Answer("G"); !r.Correct || r.Expected != "G" {
+		t.Errorf("G2 in the bass clef: %+v", r)
+	}
+	e.Answer("a")
+	if s := e.Stats; s.Asked != 2 || s.Correct != 1 || s.Streak != 0 || s.BestStreak != 1 || s.Notes[card].Wrong != 1 {
+		t.Errorf("stats %+v", s)
+	}
+	if hardest := e.Stats.Hardest(3); len(hardest) != 1 || hardest[0] != card {
+		t.Errorf("hardest %v", hardest)
+	}
+}
+
+func TestCardNamed(t *testing.T) {
+	loc := localization.NewNynorskLocalization("C", "dur")
+	tests := []struct {
+		shown         int
+		requireOctave bool
+		answer        string
+		want          int
+		ok            bool
+	}{
+		{2, false, "g", 2, true},   // g¹, the card shown
+		{2, false, "a", 3, true},   // a¹ rather than a
+		{1, false, "c", -2, true},  // c¹ rather than c²
+		{-3, false, "c", -2, true}, // c¹, a line above h
+		{8, false, "e", 7, true},   // e², a line below f²
+		{2, true, "g", -5, true},   // g in the small octave
+		{2, true, "g²", 9, true},
+		{2, true, "g³", 0, false}, // above the drill's range
+		{2, false, "x", 0, false},
+	}
+	for _, tt := range tests {
+		opts := NoteReadingOptions{Clefs: []string{music.TrebleClef}, Lowest: -6, Highest: 10, RequireOctave: tt.requireOctave}
+		e, err := NewNoteReadingExercise(opts, loc, rand.New(rand.NewPCG(1, 2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.show(NoteCard{Clef: music.TrebleClef, StaffLine: tt.shown})
+		card, ok := e.CardNamed(tt.answer)
+		if ok != tt.ok || (ok && card != NoteCard{Clef: music.TrebleClef, StaffLine: tt.want}) {
+			t.Errorf("%q with line %d shown: got %v %v, want line %d %v", tt.answer, tt.shown, card, ok, tt.want, tt.ok)
+		}
+	}
+}
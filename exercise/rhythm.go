@@ -0,0 +1,355 @@
+package exercise
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"gehoer/audio"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// RhythmValue is a note or rest length rhythms are written in
+type RhythmValue struct {
+	Value music.NoteValue
+	Dots  int
+	Rest  bool
+}
+
+// ticks returns the value's length in audio ticks
+func (v RhythmValue) ticks() int {
+	return int(math.Round(v.Value.DottedQuarters(v.Dots) * audio.TicksPerQuarter))
+}
+
+// RhythmOptions shapes the rhythms a RhythmExercise writes and how they
+// are played and graded
+type RhythmOptions struct {
+	TimeSignature music.TimeSignature
+	Measures      int
+	Values        []RhythmValue // the pool of lengths; nil for DefaultRhythmValues
+	Pitch         int           // MIDI note the rhythm is played and written on
+	Tempo         int
+	CountIn       int        // measures of clicks before the rhythm
+	Windows       TapWindows // how close a tap must be to its onset
+}
+
+// DefaultRhythmValues are half, quarter and eighth notes and quarter rests
+var DefaultRhythmValues = []RhythmValue{
+	{Value: music.HalfNote},
+	{Value: music.QuarterNote},
+	{Value: music.EighthNote},
+	{Value: music.QuarterNote, Rest: true},
+}
+
+// DefaultRhythmOptions returns options for two measures of 4/4 at a
+// moderate tempo after a measure of count-in
+func DefaultRhythmOptions() RhythmOptions {
+	return RhythmOptions{
+		TimeSignature: music.TimeSignature{Numerator: 4, Denominator: 4},
+		Measures:      2,
+		Pitch:         71, // B4, the middle line of the treble staff
+		Tempo:         90,
+		CountIn:       1,
+		Windows:       DefaultTapWindows(),
+	}
+}
+
+// TapWindows are the largest distances in seconds, either side of an
+// onset, for a tap to count as exact or as good enough
+type TapWindows struct {
+	Exact float64
+	Hit   float64
+}
+
+// DefaultTapWindows returns windows of 50 and 120 milliseconds
+func DefaultTapWindows() TapWindows {
+	return TapWindows{Exact: 0.05, Hit: 0.12}
+}
+
+// Click track pitches and velocities; the downbeat is higher and louder
+const (
+	clickPitch          = 76
+	clickDownbeatPitch  = 81
+	clickVelocity       = 70
+	clickDownbeatAccent = 100
+)
+
+// RhythmExercise writes random rhythms for a time signature to tap or
+// write down
+type RhythmExercise struct {
+	opts RhythmOptions
+	rng  *rand.Rand
+}
+
+// NewRhythmExercise returns an exercise drawing its rhythms from rng. It
+// fails when the values cannot fill a measure of the time signature.
+func NewRhythmExercise(opts RhythmOptions, rng *rand.Rand) (*RhythmExercise, error) {
+	if opts.Values == nil {
+		opts.Values = DefaultRhythmValues
+	}
+	ts := opts.TimeSignature
+	if ts.Numerator <= 0 || ts.Denominator <= 0 {
+		return nil, fmt.Errorf("invalid time signature %d/%d", ts.Numerator, ts.Denominator)
+	}
+	if opts.Measures < 1 {
+		return nil, fmt.Errorf("a rhythm needs at least one measure")
+	}
+	if opts.Tempo <= 0 {
+		opts.Tempo = audio.DefaultTempo
+	}
+	if opts.Windows == (TapWindows{}) {
+		opts.Windows = DefaultTapWindows()
+	}
+	e := &RhythmExercise{opts: opts, rng: rng}
+	if !e.fillable()[e.measureTicks()] {
+		return nil, fmt.Errorf("the note values cannot fill a measure of %d/%d", ts.Numerator, ts.Denominator)
+	}
+	return e, nil
+}
+
+// measureTicks returns the length of a measure in ticks
+func (e *RhythmExercise) measureTicks() int {
+	ts := e.opts.TimeSignature
+	return ts.Numerator * 4 * audio.TicksPerQuarter / ts.Denominator
+}
+
+// fillable reports for each length up to a measure whether the values add
+// up to it exactly
+func (e *RhythmExercise) fillable() []bool {
+	total := e.measureTicks()
+	ok := make([]bool, total+1)
+	ok[0] = true
+	for t := 1; t <= total; t++ {
+		for _, v := range e.opts.Values {
+			if d := v.ticks(); d > 0 && d <= t && ok[t-d] {
+				ok[t] = true
+				break
+			}
+		}
+	}
+	return ok
+}
+
+// RhythmQuestion is one rhythm to tap or write down
+type RhythmQuestion struct {
+	Score  *music.Score
+	Clip   []audio.NoteEvent // the rhythm as played, after the count-in
+	Click  []audio.NoteEvent // clicks on every beat from the count-in to the end
+	Tempo  int               // to time the clips with audio.TicksToSeconds
+	Onsets []float64         // when each note starts, in seconds from the start of the clips
+	Window TapWindows
+}
+
+// Next returns a new question. Every measure is filled exactly, and the
+// rhythm starts with a note.
+func (e *RhythmExercise) Next() RhythmQuestion {
+	ts := e.opts.TimeSignature
+	clef := music.TrebleClef
+	pitch := theory.Key{Mode: "dur"}.Spell(e.opts.Pitch)
+	staffLine := pitch.Diatonic() - music.ClefBottomLine(clef)
+	score := music.NewScore("", "", "C", "dur", ts.Numerator, ts.Denominator, e.opts.Tempo)
+	fillable := e.fillable()
+	for mi := 0; mi < e.opts.Measures; mi++ {
+		m := score.AddMeasure(nil)
+		if mi == 0 {
+			m.Clef = clef
+		}
+		barAlters := make(map[int]int)
+		for remaining := e.measureTicks(); remaining > 0; {
+			var choices []RhythmValue
+			for _, v := range e.opts.Values {
+				if v.Rest && mi == 0 && remaining == e.measureTicks() {
+					continue
+				}
+				if d := v.ticks(); d > 0 && d <= remaining && fillable[remaining-d] {
+					choices = append(choices, v)
+				}
+			}
+			if len(choices) == 0 {
+				// Only rests fill the opening measure; start with one anyway
+				for _, v := range e.opts.Values {
+					if d := v.ticks(); d > 0 && d <= remaining && fillable[remaining-d] {
+						choices = append(choices, v)
+					}
+				}
+			}
+			v := choices[e.rng.IntN(len(choices))]
+			if v.Rest {
+				m.AddRest(&music.Rest{Duration: v.Value, Dots: v.Dots})
+			} else {
+				m.AddNote(&music.Note{
+					Pitch:      e.opts.Pitch,
+					Duration:   v.Value,
+					Dots:       v.Dots,
+					StaffLine:  staffLine,
+					Accidental: theory.AccidentalFor(pitch.Diatonic(), pitch.Alter, [7]int{}, barAlters),
+				})
+			}
+			remaining -= v.ticks()
+		}
+	}
+
+	offset := e.opts.CountIn * e.measureTicks()
+	clip := audio.Render(score)
+	onsets := make([]float64, len(clip))
+	for i := range clip {
+		clip[i].Tick += offset
+		onsets[i] = audio.TicksToSeconds(clip[i].Tick, e.opts.Tempo)
+	}
+	return RhythmQuestion{
+		Score:  score,
+		Clip:   clip,
+		Click:  ClickTrack(ts, e.opts.CountIn+e.opts.Measures),
+		Tempo:  e.opts.Tempo,
+		Onsets: onsets,
+		Window: e.opts.Windows,
+	}
+}
+
+// ClickTrack returns a click on every beat of the measures, louder and
+// higher on the downbeats. Compound meters such as 6/8 click on the dotted
+// beat.
+func ClickTrack(ts music.TimeSignature, measures int) []audio.NoteEvent {
+	beat := 4 * audio.TicksPerQuarter / ts.Denominator
+	beats := ts.Numerator
+	if ts.Numerator > 3 && ts.Numerator%3 == 0 && ts.Denominator >= 8 {
+		beat *= 3
+		beats /= 3
+	}
+	var clicks []audio.NoteEvent
+	for m := 0; m < measures; m++ {
+		for b := 0; b < beats; b++ {
+			ev := audio.NoteEvent{
+				Tick:     (m*beats + b) * beat,
+				Duration: beat / 4,
+				Pitch:    clickPitch,
+				Velocity: clickVelocity,
+			}
+			if b == 0 {
+				ev.Pitch, ev.Velocity = clickDownbeatPitch, clickDownbeatAccent
+			}
+			clicks = append(clicks, ev)
+		}
+	}
+	return clicks
+}
+
+// TapRating is how close a tap came to its onset
+type TapRating int
+
+const (
+	TapMissed TapRating = iota // no tap within the hit window
+	TapHit                     // within the hit window
+	TapExact                   // within the exact window
+)
+
+// OnsetGrade is the tap matched with one expected onset
+type OnsetGrade struct {
+	Onset  float64
+	Tap    float64 // the matched tap; zero when missed
+	Offset float64 // Tap - Onset: negative is early, positive late
+	Rating TapRating
+}
+
+// TapResult is a graded tapping attempt
+type TapResult struct {
+	Onsets []OnsetGrade
+	Extra  []float64 // taps not matched with any onset
+	Exact  int
+	Hits   int // taps within the hit window, exact ones included
+	Missed int
+	Score  float64 // 0 to 1: exact taps count fully, other hits half, and extra taps against
+}
+
+// GradeTaps matches tap times, in seconds on the clips' clock, with the
+// expected onsets. Taps and onsets are paired in order, each tap with at
+// most one onset, choosing the pairing with the fewest misses and extra
+// taps and then the smallest offsets.
+func (q RhythmQuestion) GradeTaps(taps []float64) TapResult {
+	taps = append([]float64(nil), taps...)
+	sort.Float64s(taps)
+	return gradeTaps(q.Onsets, taps, q.Window)
+}
+
+// gradeTaps grades sorted taps against sorted onsets
+func gradeTaps(onsets, taps []float64, w TapWindows) TapResult {
+	n, m := len(onsets), len(taps)
+	// cost[i][j] grades the first i onsets with the first j taps; a miss or
+	// extra tap costs 1 and a pairing its offset as a fraction of the window
+	cost := make([][]float64, n+1)
+	for i := range cost {
+		cost[i] = make([]float64, m+1)
+		cost[i][0] = float64(i)
+	}
+	for j := 0; j <= m; j++ {
+		cost[0][j] = float64(j)
+	}
+	pair := func(i, j int) (float64, bool) {
+		d := math.Abs(taps[j-1] - onsets[i-1])
+		if d > w.Hit {
+			return 0, false
+		}
+		return cost[i-1][j-1] + d/w.Hit/2, true
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			c := min(cost[i-1][j], cost[i][j-1]) + 1
+			if p, ok := pair(i, j); ok {
+				c = min(c, p)
+			}
+			cost[i][j] = c
+		}
+	}
+
+	var r TapResult
+	r.Onsets = make([]OnsetGrade, n)
+	for i := range onsets {
+		r.Onsets[i].Onset = onsets[i]
+	}
+	for i, j := n, m; i > 0 || j > 0; {
+		if i > 0 && j > 0 {
+			if p, ok := pair(i, j); ok && p == cost[i][j] {
+				g := &r.Onsets[i-1]
+				g.Tap, g.Offset = taps[j-1], taps[j-1]-onsets[i-1]
+				g.Rating = TapHit
+				if math.Abs(g.Offset) <= w.Exact {
+					g.Rating = TapExact
+				}
+				i, j = i-1, j-1
+				continue
+			}
+		}
+		if i > 0 && cost[i][j] == cost[i-1][j]+1 {
+			i--
+		} else {
+			r.Extra = append(r.Extra, taps[j-1])
+			j--
+		}
+	}
+	for l, h := 0, len(r.Extra)-1; l < h; l, h = l+1, h-1 {
+		r.Extra[l], r.Extra[h] = r.Extra[h], r.Extra[l]
+	}
+
+	points := 0.0
+	for _, g := range r.Onsets {
+		switch g.Rating {
+		case TapExact:
+			r.Exact++
+			r.Hits++
+			points++
+		case TapHit:
+			r.Hits++
+			points += 0.5
+		default:
+			r.Missed++
+		}
+	}
+	if total := n + len(r.Extra); total > 0 {
+		r.Score = points / float64(total)
+	} else {
+		r.Score = 1
+	}
+	return r
+}
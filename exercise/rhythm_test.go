@@ -0,0 +1,132 @@
+package exercise
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"gehoer/music"
+)
+
+func TestGradeTaps(t *testing.T) {
+	onsets := []float64{0, 0.5, 1}
+	tests := []struct {
+		name    string
+		taps    []float64
+		ratings []TapRating
+		offsets []float64
+		extra   []float64
+		score   float64
+	}{
+		{
+			"exact", []float64{0.01, 0.49, 1},
+			[]TapRating{TapExact, TapExact, TapExact}, []float64{0.01, -0.01, 0}, nil, 1,
+		},
+		{
+			"early", []float64{-0.1, 0.5, 1},
+			[]TapRating{TapHit, TapExact, TapExact}, []float64{-0.1, 0, 0}, nil, 2.5 / 3,
+		},
+		{
+			"late", []float64{0, 0.6, 1},
+			[]TapRating{TapExact, TapHit, TapExact}, []float64{0, 0.1, 0}, nil, 2.5 / 3,
+		},
+		{
+			"missed onset", []float64{0, 1},
+			[]TapRating{TapExact, TapMissed, TapExact}, []float64{0, 0, 0}, nil, 2.0 / 3,
+		},
+		{
+			"too late to count", []float64{0, 0.7, 1},
+			[]TapRating{TapExact, TapMissed, TapExact}, []float64{0, 0, 0}, []float64{0.7}, 2.0 / 4,
+		},
+		{
+			"extra tap", []float64{0, 0.25, 0.5, 1},
+			[]TapRating{TapExact, TapExact, TapExact}, []float64{0, 0, 0}, []float64{0.25}, 3.0 / 4,
+		},
+		{
+			"two taps on one onset", []float64{0, 0.42, 0.5, 1},
+			[]TapRating{TapExact, TapExact, TapExact}, []float64{0, 0, 0}, []float64{0.42}, 3.0 / 4,
+		},
+		{
+			"no taps", nil,
+			[]TapRating{TapMissed, TapMissed, TapMissed}, []float64{0, 0, 0}, nil, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gradeTaps(onsets, tt.taps, DefaultTapWindows())
+			for i, g := range r.Onsets {
+				if g.Rating != tt.ratings[i] || !near(g.Offset, tt.offsets[i]) {
+					t.Errorf("onset %g: rating %d offset %g, want %d and %g", g.Onset, g.Rating, g.Offset, tt.ratings[i], tt.offsets[i])
+				}
+			}
+			if len(r.Extra) != len(tt.extra) {
+				t.Fatalf("extra taps %v, want %v", r.Extra, tt.extra)
+			}
+			for i := range tt.extra {
+				if !near(r.Extra[i], tt.extra[i]) {
+					t.Errorf("extra taps %v, want %v", r.Extra, tt.extra)
+				}
+			}
+			if !near(r.Score, tt.score) {
+				t.Errorf("score %g, want %g", r.Score, tt.score)
+			}
+			if r.Hits+r.Missed != len(onsets) {
+				t.Errorf("%d hits and %d misses for %d onsets", r.Hits, r.Missed, len(onsets))
+			}
+		})
+	}
+}
+
+func TestGradeTapsWithoutOnsets(t *testing.T) {
+	if r := gradeTaps(nil, nil, DefaultTapWindows()); r.Score != 1 {
+		t.Errorf("score %g for nothing to tap, want 1", r.Score)
+	}
+	if r := gradeTaps(nil, []float64{0.3}, DefaultTapWindows()); r.Score != 0 || len(r.Extra) != 1 {
+		t.Errorf("got score %g and extra taps %v, want 0 and the one tap", r.Score, r.Extra)
+	}
+}
+
+func TestRhythmQuestionGradeTapsSortsTaps(t *testing.T) {
+	q := RhythmQuestion{Onsets: []float64{0, 0.5, 1}, Window: DefaultTapWindows()}
+	taps := []float64{1, 0, 0.5}
+	if r := q.GradeTaps(taps); r.Exact != 3 {
+		t.Errorf("%d exact taps out of order, want 3", r.Exact)
+	}
+	if taps[0] != 1 {
+		t.Error("GradeTaps sorted the caller's taps")
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRhythmExerciseAccidentals(t *testing.T) {
+	opts := DefaultRhythmOptions()
+	opts.Pitch = 70 // B flat
+	opts.Measures = 3
+	e, err := NewRhythmExercise(opts, rand.New(rand.NewPCG(3, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		q := e.Next()
+		for mi, m := range q.Score.Measures {
+			first := true
+			for _, el := range m.Elements {
+				n, ok := el.(*music.Note)
+				if !ok {
+					continue
+				}
+				want := music.AccidentalNone
+				if first {
+					want = music.AccidentalFlat
+				}
+				first = false
+				if n.Accidental != want || n.Pitch != 70 || n.StaffLine != 4 {
+					t.Fatalf("measure %d has %+v, want B flat with %q", mi, n, want)
+				}
+			}
+		}
+	}
+}
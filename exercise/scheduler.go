@@ -0,0 +1,222 @@
+package exercise
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gehoer/theory"
+)
+
+// Rating is how well an item was answered, as in SM-2 and FSRS
+type Rating int
+
+const (
+	Again Rating = iota // wrong: show it again soon and start over
+	Hard                // right but slow or unsure
+	Good                // right
+	Easy                // right at once
+)
+
+// RatingFor rates an answer from whether it was right and how long it took
+// against the time a confident answer should take
+func RatingFor(correct bool, elapsed, target time.Duration) Rating {
+	switch {
+	case !correct:
+		return Again
+	case elapsed > 2*target:
+		return Hard
+	case elapsed < target/2:
+		return Easy
+	default:
+		return Good
+	}
+}
+
+// Scheduling constants, after SM-2
+const (
+	initialEase    = 2.5
+	minimumEase    = 1.3
+	firstInterval  = 24 * time.Hour
+	secondInterval = 6 * 24 * time.Hour
+	relearnDelay   = 10 * time.Minute // when an item answered wrongly comes back
+)
+
+// ScheduledItem is the review state of one item
+type ScheduledItem struct {
+	Key        string        `json:"key"`
+	Ease       float64       `json:"ease"`     // how fast the interval grows
+	Interval   time.Duration `json:"interval"` // until the next review after the last
+	Due        time.Time     `json:"due"`
+	Reviews    int           `json:"reviews"` // right answers in a row
+	Lapses     int           `json:"lapses"`  // times answered wrongly after being learnt
+	LastReview time.Time     `json:"last_review"`
+}
+
+// New reports whether the item has never been reviewed
+func (it *ScheduledItem) New() bool {
+	return it.LastReview.IsZero()
+}
+
+// Scheduler picks the item to ask about next by spaced repetition: items
+// come back after intervals that grow each time they are answered right
+// and start over when they are answered wrongly. Items are keys such as
+// those of IntervalItem, NoteItem, ChordItem and KeyItem, so any exercise
+// that can ask about a given item works with it.
+type Scheduler struct {
+	Items map[string]*ScheduledItem
+	Now   func() time.Time // time.Now unless replaced, as in tests
+
+	order []string // keys in the order they were added
+	last  string
+}
+
+// NewScheduler returns a scheduler for the items, all new
+func NewScheduler(keys ...string) *Scheduler {
+	s := &Scheduler{Items: make(map[string]*ScheduledItem), Now: time.Now}
+	s.Add(keys...)
+	return s
+}
+
+// Add adds items not yet scheduled; new items are introduced in the order
+// they were added
+func (s *Scheduler) Add(keys ...string) {
+	for _, key := range keys {
+		if _, ok := s.Items[key]; ok {
+			continue
+		}
+		s.Items[key] = &ScheduledItem{Key: key, Ease: initialEase}
+		s.order = append(s.order, key)
+	}
+}
+
+// Restore adds items with their saved state, such as from a profile
+func (s *Scheduler) Restore(items []ScheduledItem) {
+	for _, it := range items {
+		if _, ok := s.Items[it.Key]; !ok {
+			s.order = append(s.order, it.Key)
+		}
+		item := it
+		s.Items[it.Key] = &item
+	}
+}
+
+// Snapshot returns the state of every item, in the order they were added
+func (s *Scheduler) Snapshot() []ScheduledItem {
+	items := make([]ScheduledItem, 0, len(s.order))
+	for _, key := range s.order {
+		items = append(items, *s.Items[key])
+	}
+	return items
+}
+
+// Next returns the item to ask about: the most overdue one that is due,
+// else the first new one, else the one due soonest. It avoids the item
+// just asked about when there is another. ok is false when there are no
+// items.
+func (s *Scheduler) Next() (key string, ok bool) {
+	now := s.Now()
+	var due, learnt []*ScheduledItem
+	var fresh *ScheduledItem
+	for _, k := range s.order {
+		it := s.Items[k]
+		if k == s.last && len(s.order) > 1 {
+			continue
+		}
+		switch {
+		case it.New():
+			if fresh == nil {
+				fresh = it
+			}
+		case !it.Due.After(now):
+			due = append(due, it)
+		default:
+			learnt = append(learnt, it)
+		}
+	}
+	byDue := func(items []*ScheduledItem) {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Due.Before(items[j].Due) })
+	}
+	var next *ScheduledItem
+	switch {
+	case len(due) > 0:
+		byDue(due)
+		next = due[0]
+	case fresh != nil:
+		next = fresh
+	case len(learnt) > 0:
+		byDue(learnt)
+		next = learnt[0]
+	default:
+		if len(s.order) == 0 {
+			return "", false
+		}
+		next = s.Items[s.order[0]]
+	}
+	s.last = next.Key
+	return next.Key, true
+}
+
+// Review records an answer about an item and schedules its next review
+func (s *Scheduler) Review(key string, r Rating) error {
+	it, ok := s.Items[key]
+	if !ok {
+		return fmt.Errorf("unknown item %q", key)
+	}
+	now := s.Now()
+
+	// SM-2's ease update, with the ratings as qualities 2 to 5
+	q := float64(r) + 2
+	it.Ease = math.Max(minimumEase, it.Ease+0.1-(5-q)*(0.08+(5-q)*0.02))
+
+	if r == Again {
+		if it.Reviews > 0 {
+			it.Lapses++
+		}
+		it.Reviews = 0
+		it.Interval = relearnDelay
+	} else {
+		it.Reviews++
+		switch it.Reviews {
+		case 1:
+			it.Interval = firstInterval
+		case 2:
+			it.Interval = secondInterval
+		default:
+			it.Interval = time.Duration(float64(it.Interval) * it.Ease)
+		}
+		switch r {
+		case Hard:
+			it.Interval = max(firstInterval, it.Interval*2/3)
+		case Easy:
+			it.Interval = it.Interval * 13 / 10
+		}
+	}
+	it.LastReview = now
+	it.Due = now.Add(it.Interval)
+	return nil
+}
+
+// Item keys for the kinds of things students drill
+
+// IntervalItem returns the key of an interval, such as "interval:m3"
+func IntervalItem(iv theory.Interval) string {
+	return "interval:" + iv.String()
+}
+
+// NoteItem returns the key of a note-reading card, such as "note:treble:4"
+func NoteItem(card NoteCard) string {
+	return fmt.Sprintf("note:%s:%d", card.Clef, card.StaffLine)
+}
+
+// ChordItem returns the key of a chord quality in an inversion, such as
+// "chord:minor_triad:1"
+func ChordItem(q theory.ChordQuality, inversion int) string {
+	return fmt.Sprintf("chord:%s:%d", q, inversion)
+}
+
+// KeyItem returns the key of a key, such as "key:6:-1:dur" for B flat major
+func KeyItem(k theory.Key) string {
+	return fmt.Sprintf("key:%d:%d:%s", k.Step, k.Alter, k.Mode)
+}
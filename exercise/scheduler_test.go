@@ -0,0 +1,188 @@
+package exercise
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// clock is a fixed time that tests move forward by hand
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestScheduler(keys ...string) (*Scheduler, *clock) {
+	c := &clock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewScheduler(keys...)
+	s.Now = c.now
+	return s, c
+}
+
+const day = 24 * time.Hour
+
+func review(t *testing.T, s *Scheduler, key string, r Rating) *ScheduledItem {
+	t.Helper()
+	if err := s.Review(key, r); err != nil {
+		t.Fatal(err)
+	}
+	return s.Items[key]
+}
+
+func next(t *testing.T, s *Scheduler) string {
+	t.Helper()
+	key, ok := s.Next()
+	if !ok {
+		t.Fatal("no next item")
+	}
+	return key
+}
+
+func TestSchedulerAsksMostOverdueFirst(t *testing.T) {
+	s, c := newTestScheduler("a", "b", "c")
+	review(t, s, "c", Good) // due in a day
+	c.advance(time.Hour)
+	review(t, s, "a", Good)
+	c.advance(time.Hour)
+	review(t, s, "b", Good)
+
+	// New items come before learnt ones that are not due
+	s.Add("d")
+	if got := next(t, s); got != "d" {
+		t.Fatalf("got %q, want the new item d", got)
+	}
+	review(t, s, "d", Good)
+
+	c.advance(2 * day)
+	for _, want := range []string{"c", "a", "b"} {
+		got := next(t, s)
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+		review(t, s, got, Good)
+	}
+}
+
+func TestSchedulerIntroducesNewItemsInOrder(t *testing.T) {
+	s, _ := newTestScheduler("a", "b", "c")
+	for _, want := range []string{"a", "b", "c"} {
+		got := next(t, s)
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+		review(t, s, got, Good)
+	}
+}
+
+func TestSchedulerAvoidsImmediateRepeats(t *testing.T) {
+	s, c := newTestScheduler("a", "b")
+	review(t, s, "a", Again)
+	review(t, s, "b", Good)
+	c.advance(relearnDelay)
+
+	// a is the only item due, but it was not just asked about
+	if got := next(t, s); got != "a" {
+		t.Fatalf("got %q, want the due item a", got)
+	}
+	review(t, s, "a", Again)
+	c.advance(relearnDelay)
+	if got := next(t, s); got != "b" {
+		t.Errorf("got %q again straight after it, want b", got)
+	}
+
+	// With one item there is nothing else to ask
+	single, _ := newTestScheduler("x")
+	next(t, single)
+	if got := next(t, single); got != "x" {
+		t.Errorf("got %q, want x", got)
+	}
+}
+
+func TestSchedulerEaseAndIntervals(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratings  []Rating
+		ease     float64
+		interval time.Duration
+		reviews  int
+		lapses   int
+	}{
+		{"good once", []Rating{Good}, 2.5, day, 1, 0},
+		{"good twice", []Rating{Good, Good}, 2.5, 6 * day, 2, 0},
+		{"good three times", []Rating{Good, Good, Good}, 2.5, 15 * day, 3, 0},
+		{"easy", []Rating{Easy}, 2.6, day * 13 / 10, 1, 0},
+		{"hard keeps at least a day", []Rating{Hard}, 2.36, day, 1, 0},
+		{"hard shortens", []Rating{Good, Hard}, 2.36, 4 * day, 2, 0},
+		{"again", []Rating{Again}, 2.18, relearnDelay, 0, 0},
+		{"ease floor", []Rating{Again, Again, Again, Again, Again, Again}, minimumEase, relearnDelay, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := newTestScheduler("a")
+			var it *ScheduledItem
+			for _, r := range tt.ratings {
+				it = review(t, s, "a", r)
+				c.advance(it.Interval)
+			}
+			if math.Abs(it.Ease-tt.ease) > 1e-9 {
+				t.Errorf("ease %g, want %g", it.Ease, tt.ease)
+			}
+			if it.Interval != tt.interval {
+				t.Errorf("interval %v, want %v", it.Interval, tt.interval)
+			}
+			if it.Reviews != tt.reviews || it.Lapses != tt.lapses {
+				t.Errorf("%d reviews and %d lapses, want %d and %d", it.Reviews, it.Lapses, tt.reviews, tt.lapses)
+			}
+			if !it.Due.Equal(it.LastReview.Add(it.Interval)) {
+				t.Errorf("due %v, want %v after the review at %v", it.Due, it.Interval, it.LastReview)
+			}
+		})
+	}
+}
+
+func TestSchedulerRelearnsAfterLapse(t *testing.T) {
+	s, c := newTestScheduler("a", "b")
+	review(t, s, "a", Good)
+	c.advance(day)
+	review(t, s, "a", Good)
+	c.advance(6 * day)
+	review(t, s, "b", Good)
+
+	it := review(t, s, "a", Again)
+	if it.Lapses != 1 || it.Reviews != 0 {
+		t.Fatalf("%d lapses and %d reviews, want 1 and 0", it.Lapses, it.Reviews)
+	}
+	if want := c.now().Add(10 * time.Minute); !it.Due.Equal(want) {
+		t.Fatalf("due %v, want ten minutes later at %v", it.Due, want)
+	}
+
+	c.advance(9 * time.Minute)
+	if !it.Due.After(c.now()) {
+		t.Error("due before ten minutes passed")
+	}
+	c.advance(time.Minute)
+	if got := next(t, s); got != "a" {
+		t.Errorf("got %q ten minutes after the lapse, want a", got)
+	}
+
+	// Relearning starts the intervals over
+	if it := review(t, s, "a", Good); it.Interval != day {
+		t.Errorf("interval %v after relearning, want a day", it.Interval)
+	}
+
+	// A wrong answer before any right one is not a lapse
+	s.Add("c")
+	if it := review(t, s, "c", Again); it.Lapses != 0 {
+		t.Errorf("a new item has %d lapses after a wrong answer, want 0", it.Lapses)
+	}
+}
+
+func TestSchedulerUnknownItem(t *testing.T) {
+	s, _ := newTestScheduler("a")
+	if err := s.Review("z", Good); err == nil {
+		t.Error("reviewing an unknown item succeeded")
+	}
+	if _, ok := NewScheduler().Next(); ok {
+		t.Error("an empty scheduler returned an item")
+	}
+}
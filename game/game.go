@@ -1,65 +1,149 @@
 package game
 
 import (
+	"os"
+	"time"
+
 	"gehoer/camera"
 	"gehoer/engraver"
 	"gehoer/grid"
+	"gehoer/input"
 	"gehoer/music"
 	"gehoer/musicfont"
+	"gehoer/notation"
 	"gehoer/renderer"
+	"gehoer/scene"
+	"gehoer/screens"
 	"gehoer/settings"
+	"gehoer/svg"
 	"gehoer/units"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+// Game draws the screens of a screens.App in a Raylib window and moves the
+// camera over the score viewer and the statistics
 type Game struct {
+	app           *screens.App
 	grid          *grid.Grid
 	camera        *camera.Controller
-	engraver      *engraver.Engraver
+	engraver      *engraver.Engraver // nil when headless
+	font          *musicfont.MusicFont
 	renderer      renderer.Renderer
 	commandBuffer *renderer.CommandBuffer
+	overlay       *renderer.CommandBuffer // drawn on the screen, not in the world
+	headless      bool
+	card          cardEngraving // the note-reading card last engraved
+}
+
+// Options replace the window, clock and profile directory, so the game can
+// run in tests
+type Options struct {
+	Input      input.Source     // the Raylib window's unless set
+	Now        func() time.Time // time.Now unless set
+	Headless   bool             // no window, font or score; nothing is drawn
+	ProfileDir string           // the user's configuration directory unless set
 }
 
+// Size of the window, for the camera and the warnings
+const (
+	screenWidth  = 1200
+	screenHeight = 800
+)
+
 func New() *Game {
-	g := &Game{}
-	g.init()
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions returns a game at the main menu
+func NewWithOptions(opts Options) *Game {
+	g := &Game{headless: opts.Headless}
+	if opts.Input == nil {
+		opts.Input = renderer.NewRaylibInput()
+	}
+	g.init(opts)
 	return g
 }
 
-func (g *Game) init() {
+func (g *Game) init(opts Options) {
 	g.grid = grid.New(units.GridSpacingPx, 4000, 4000, units.GridFontSizePx)
-	g.camera = camera.NewController(1200, 800)
-	g.renderer = renderer.NewRaylibRenderer()
+	g.camera = camera.NewController(screenWidth, screenHeight, opts.Input)
 	g.commandBuffer = renderer.NewCommandBuffer()
+	g.overlay = renderer.NewCommandBuffer()
+	g.app = screens.New(opts.Input, screens.Options{Now: opts.Now, ProfileDir: opts.ProfileDir})
+	g.app.ToWorld = func(p input.Point) input.Point {
+		w := camera.ScreenToWorld(p, g.camera.Camera)
+		return input.Point{X: w.X, Y: w.Y}
+	}
+	g.wrapScreens()
+	if !g.headless {
+		g.renderer = renderer.NewRaylibRenderer()
+		g.loadScore()
+	}
+}
 
-	// Load sample score from JSON file
-	score, err := music.LoadScoreFromJSON("assets/scores/lisa_gikk_til_skolen.json")
+// loadScore loads the sample score and the music font for the score viewer
+func (g *Game) loadScore() {
+	score, diagnostics, err := music.LoadAndValidateScore("assets/scores/lisa_gikk_til_skolen.json")
 	if err != nil {
 		panic("Failed to load score JSON: " + err.Error())
 	}
+	for _, d := range diagnostics {
+		g.app.Warn("%v", d)
+	}
+	// Show the accidentals the key and measures call for, not those written
+	if err := notation.ResolveAccidentals(score, notation.AccidentalOptions{Courtesy: true}); err != nil {
+		g.app.Warn("%v", err)
+	}
 
 	font, err := musicfont.LoadMusicFont("external/smufl", "assets/fonts/Leland/leland_metadata.json", "assets/fonts/Leland/Leland.otf", settings.MusicFontSizePx)
 	if err != nil {
 		panic("Failed to load music font: " + err.Error())
 	}
 
+	// Custom symbols (Figurenotes shapes, pictograms) are optional
+	if _, err := os.Stat("assets/glyphs"); err == nil {
+		skipped, err := svg.RegisterDir(font, "assets/glyphs", svg.DefaultImportOptions())
+		if err != nil {
+			g.app.Warn("%v", err)
+		}
+		for _, err := range skipped {
+			g.app.Warn("%v", err)
+		}
+	}
+
+	g.font = font
 	g.engraver = engraver.NewEngraver(score, font)
 }
 
 func (g *Game) Run() {
-	for !rl.WindowShouldClose() {
+	rl.SetExitKey(0) // Escape goes back a scene
+	for !rl.WindowShouldClose() && !g.app.Done() {
 		g.Update()
 		g.Draw()
 	}
 }
 
+// Update runs a frame of the current scene
 func (g *Game) Update() {
-	g.camera.Update()
-	//g.engraver.Update()
+	g.app.Update()
+}
+
+// Scene returns the current scene's ID
+func (g *Game) Scene() scene.ID {
+	id, _ := g.app.Screen()
+	return id
+}
+
+// Done reports whether the player has quit
+func (g *Game) Done() bool {
+	return g.app.Done()
 }
 
 func (g *Game) Draw() {
+	if g.headless {
+		return
+	}
 	rl.BeginDrawing()
 	rl.ClearBackground(rl.RayWhite)
 
@@ -69,12 +153,22 @@ func (g *Game) Draw() {
 	g.commandBuffer.Clear()
 
 	// Generate all drawing commands
-	g.grid.GenerateDrawCommands(g.camera.Camera, g.commandBuffer)
-	g.engraver.GenerateDrawCommands(0, 0, g.commandBuffer)
+	g.GenerateDrawCommands(g.commandBuffer)
 
 	// Execute all commands
 	g.commandBuffer.Execute(g.renderer)
 
 	rl.EndMode2D()
+
+	g.overlay.Clear()
+	g.generateWarnings(g.overlay)
+	g.overlay.Execute(g.renderer)
+
 	rl.EndDrawing()
 }
+
+// resetCamera puts the camera back where the menus expect it
+func (g *Game) resetCamera() {
+	g.camera.Camera.Target = rl.Vector2{}
+	g.camera.Camera.Zoom = 1
+}
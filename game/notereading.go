@@ -0,0 +1,75 @@
+package game
+
+import (
+	"fmt"
+
+	"gehoer/engraver"
+	"gehoer/exercise"
+	"gehoer/renderer"
+	"gehoer/screens"
+)
+
+// Layout of the note-reading drill in world coordinates
+const (
+	readingTextX    = 0
+	readingPromptY  = -120
+	readingStatsY   = 240
+	readingFontSize = 20
+)
+
+// cardEngraving is the engraving of one note-reading card
+type cardEngraving struct {
+	card     exercise.NoteCard
+	engraver *engraver.Engraver
+}
+
+// cardEngraver returns the engraving of the card, made again only when the
+// card changes
+func (g *Game) cardEngraver(card exercise.NoteCard) *engraver.Engraver {
+	if g.card.engraver == nil || g.card.card != card {
+		g.card = cardEngraving{card: card, engraver: engraver.NewEngraver(card.Score(), g.font)}
+	}
+	return g.card.engraver
+}
+
+// drawDrill draws the card, the answer buttons, the feedback on the last
+// answer and the statistics so far
+func (g *Game) drawDrill(d *screens.Drill, buffer *renderer.CommandBuffer) {
+	loc := g.app.Loc
+	text := func(s string, y float32, color renderer.Color) {
+		buffer.AddCommand(renderer.NewTextCommand(s, renderer.Vector2{X: readingTextX, Y: y}, readingFontSize, color))
+	}
+
+	text(loc.GetTerm("name_the_note")+" "+d.Typed, readingPromptY, renderer.Black)
+	if g.font != nil {
+		g.cardEngraver(d.Card()).GenerateDrawCommands(0, 0, buffer)
+	}
+
+	for i, name := range d.Choices {
+		r := screens.ButtonRect(i)
+		buffer.AddCommand(renderer.RectangleLinesCommand{
+			X: r.X, Y: r.Y, Width: r.Width, Height: r.Height,
+			LineThickness: 2, Color: renderer.DarkGray,
+		})
+		buffer.AddCommand(renderer.NewTextCommand(name, renderer.Vector2{X: r.X + 18, Y: r.Y + 14}, readingFontSize, renderer.Black))
+	}
+
+	if d.ShowsFeedback() {
+		if d.Last.Correct {
+			text(loc.GetTerm("correct"), readingPromptY+30, renderer.Green)
+		} else {
+			text(fmt.Sprintf("%s: %s", loc.GetTerm("incorrect"), d.Last.Expected), readingPromptY+30, renderer.Red)
+		}
+	}
+
+	s := d.Stats()
+	text(fmt.Sprintf("%s: %d  %s: %d", loc.GetTerm("streak"), s.Streak, loc.GetTerm("best_streak"), s.BestStreak), readingStatsY, renderer.DarkGray)
+	text(fmt.Sprintf("%s: %.0f %%  %s: %.1f s", loc.GetTerm("accuracy"), 100*s.Accuracy(), loc.GetTerm("average_time"), s.AverageTime().Seconds()), readingStatsY+25, renderer.DarkGray)
+	if hardest := s.Hardest(3); len(hardest) > 0 {
+		line := loc.GetTerm("hardest_notes") + ":"
+		for _, card := range hardest {
+			line += fmt.Sprintf(" %s (%s)", loc.GetNoteName(card.Pitch()), loc.GetTerm(card.Clef))
+		}
+		text(line, readingStatsY+50, renderer.DarkGray)
+	}
+}
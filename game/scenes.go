@@ -0,0 +1,165 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"gehoer/renderer"
+	"gehoer/scene"
+	"gehoer/screens"
+)
+
+// Text sizes of the menus
+const (
+	menuFontSize    = 24
+	menuTitleSize   = 32
+	warningFontSize = 20
+)
+
+// drawer is a scene that draws itself
+type drawer interface {
+	GenerateDrawCommands(buffer *renderer.CommandBuffer)
+}
+
+// wrapScreens replaces the score viewer and statistics screens with ones
+// that also move the camera
+func (g *Game) wrapScreens() {
+	m := g.app.Machine
+	if s, ok := m.Scene(screens.Viewer); ok {
+		m.Add(screens.Viewer, &viewerScene{Scene: s, g: g})
+	}
+	if s, ok := m.Scene(screens.Stats); ok {
+		m.Add(screens.Stats, &statsScene{StatsScreen: s.(*screens.StatsScreen), g: g})
+	}
+}
+
+// GenerateDrawCommands draws the current scene
+func (g *Game) GenerateDrawCommands(buffer *renderer.CommandBuffer) {
+	_, current := g.app.Screen()
+	switch s := current.(type) {
+	case drawer:
+		s.GenerateDrawCommands(buffer)
+	case *screens.MainMenu:
+		drawMenu(&s.Menu, buffer)
+	case *screens.SelectionMenu:
+		drawMenu(&s.Menu, buffer)
+	case *screens.ExerciseScreen:
+		if s.Drill != nil {
+			g.drawDrill(s.Drill, buffer)
+		}
+	case *screens.ResultsScreen:
+		g.drawResults(s, buffer)
+	case *screens.SettingsScreen:
+		drawSettings(s, buffer)
+	}
+}
+
+// drawMenu draws the title and the items, the selected one marked
+func drawMenu(m *screens.Menu, buffer *renderer.CommandBuffer) {
+	buffer.AddCommand(renderer.NewTextCommand(m.Title, renderer.Vector2{X: screens.MenuX, Y: screens.MenuY}, menuTitleSize, renderer.Black))
+	for i, item := range m.Items {
+		r := m.ItemRect(i)
+		color := renderer.DarkGray
+		if i == m.Selected {
+			color = renderer.Black
+			buffer.AddCommand(renderer.NewRectangleLinesCommand(r.X-10, r.Y-6, r.Width, r.Height-4, 2, renderer.DarkGray))
+		}
+		buffer.AddCommand(renderer.NewTextCommand(item, renderer.Vector2{X: r.X, Y: r.Y}, menuFontSize, color))
+	}
+}
+
+// drawResults draws the results menu and the last round's statistics
+func (g *Game) drawResults(s *screens.ResultsScreen, buffer *renderer.CommandBuffer) {
+	drawMenu(&s.Menu, buffer)
+	r := g.app.LastRound
+	if r == nil {
+		return
+	}
+	loc := g.app.Loc
+	t := loc.GetTerm
+	lines := []string{
+		fmt.Sprintf("%d/%d %s", r.Correct, r.Asked, t("correct")),
+		fmt.Sprintf("%s: %.0f %%", t("accuracy"), 100*r.Accuracy()),
+		fmt.Sprintf("%s: %d", t("best_streak"), r.BestStreak),
+		fmt.Sprintf("%s: %.1f s", t("average_time"), r.AverageTime().Seconds()),
+	}
+	if hardest := r.Hardest(3); len(hardest) > 0 {
+		line := t("hardest_notes") + ":"
+		for _, card := range hardest {
+			line += fmt.Sprintf(" %s (%s)", loc.GetNoteName(card.Pitch()), t(card.Clef))
+		}
+		lines = append(lines, line)
+	}
+	y := screens.ItemTop(3)
+	for _, line := range lines {
+		buffer.AddCommand(renderer.NewTextCommand(line, renderer.Vector2{X: screens.MenuX, Y: y}, menuFontSize, renderer.DarkGray))
+		y += screens.MenuRowHeight
+	}
+}
+
+// drawSettings draws the settings menu, the cursor while a profile is
+// named and the last error
+func drawSettings(s *screens.SettingsScreen, buffer *renderer.CommandBuffer) {
+	drawMenu(&s.Menu, buffer)
+	if s.Naming {
+		buffer.AddCommand(renderer.NewTextCommand("_", renderer.Vector2{X: screens.MenuX + screens.MenuItemWidth - 40, Y: screens.ItemTop(3)}, menuFontSize, renderer.Black))
+	}
+	if s.Message != "" {
+		buffer.AddCommand(renderer.NewTextCommand(s.Message, renderer.Vector2{X: screens.MenuX, Y: screens.ItemTop(len(s.Items) + 1)}, menuFontSize, renderer.Red))
+	}
+}
+
+// generateWarnings draws the warnings still shown along the bottom of the
+// screen, the newest lowest
+func (g *Game) generateWarnings(buffer *renderer.CommandBuffer) {
+	warnings := g.app.Warnings()
+	y := float32(screenHeight - 10 - len(warnings)*(warningFontSize+6))
+	for _, w := range warnings {
+		buffer.AddCommand(renderer.NewTextCommand(w.Message, renderer.Vector2{X: 10, Y: y}, warningFontSize, renderer.Red))
+		y += warningFontSize + 6
+	}
+}
+
+// viewerScene pans and zooms the score viewer with the camera
+type viewerScene struct {
+	scene.Scene
+	g *Game
+}
+
+func (s *viewerScene) Update(ctx *scene.Context, dt time.Duration) {
+	s.g.camera.Update()
+	s.Scene.Update(ctx, dt)
+}
+
+func (s *viewerScene) Exit(ctx *scene.Context) {
+	s.Scene.Exit(ctx)
+	s.g.resetCamera()
+}
+
+func (s *viewerScene) GenerateDrawCommands(buffer *renderer.CommandBuffer) {
+	s.g.grid.GenerateDrawCommands(s.g.camera.Camera, buffer)
+	if s.g.font != nil {
+		s.g.engraver.GenerateDrawCommands(0, 0, buffer)
+	}
+}
+
+// statsScene starts the statistics near the top left corner of the screen
+type statsScene struct {
+	*screens.StatsScreen
+	g *Game
+}
+
+func (s *statsScene) Enter(ctx *scene.Context) {
+	s.StatsScreen.Enter(ctx)
+	s.g.camera.Camera.Target.X = s.g.camera.Camera.Offset.X - 40
+	s.g.camera.Camera.Target.Y = s.g.camera.Camera.Offset.Y - 40
+}
+
+func (s *statsScene) Exit(ctx *scene.Context) {
+	s.StatsScreen.Exit(ctx)
+	s.g.resetCamera()
+}
+
+func (s *statsScene) GenerateDrawCommands(buffer *renderer.CommandBuffer) {
+	s.g.drawStats(s.StatsScreen, buffer)
+}
@@ -0,0 +1,156 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gehoer/exercise"
+	"gehoer/localization"
+	"gehoer/renderer"
+	"gehoer/screens"
+	"gehoer/theory"
+)
+
+// Layout of the statistics view in world coordinates
+const (
+	statsFontSize   = 20
+	statsLineHeight = 26
+	statsBarWidth   = 300
+	statsChartX     = 620
+	statsChartW     = 360
+	statsChartH     = 140
+	statsItemRows   = 10
+)
+
+// statsView draws the report of a statistics screen
+type statsView struct {
+	*screens.StatsScreen
+	loc *localization.Localization
+}
+
+// drawStats draws the profile's accuracy per item, response times, daily
+// trend and most frequent confusions, and the result of the last export
+func (g *Game) drawStats(s *screens.StatsScreen, buffer *renderer.CommandBuffer) {
+	v := statsView{StatsScreen: s, loc: g.app.Loc}
+	r := &v.Report
+	y := float32(0)
+	text := func(s string, x, y float32, color renderer.Color) {
+		buffer.AddCommand(renderer.NewTextCommand(s, renderer.Vector2{X: x, Y: y}, statsFontSize, color))
+	}
+	line := func(x1, y1, x2, y2, thickness float32, color renderer.Color) {
+		buffer.AddCommand(renderer.LineCommand{Start: renderer.Vector2{X: x1, Y: y1}, End: renderer.Vector2{X: x2, Y: y2}, Thickness: thickness, Color: color})
+	}
+
+	text(fmt.Sprintf("%s: %s", v.loc.GetTerm("statistics"), r.Profile), 0, y, renderer.Black)
+	y += statsLineHeight
+	text(fmt.Sprintf("%d %s, %s %.0f %%", r.Answers, v.loc.GetTerm("answers"), v.loc.GetTerm("accuracy"), 100*r.Accuracy), 0, y, renderer.DarkGray)
+	if v.Message != "" {
+		text(v.Message, 0, y+statsLineHeight, renderer.DarkGray)
+	}
+	y += 2 * statsLineHeight
+
+	// Accuracy per item, the weakest first, as green and red bars
+	top := y
+	for i, it := range r.Items {
+		if i == statsItemRows {
+			break
+		}
+		text(v.itemLabel(it.Item), 0, y, renderer.Black)
+		right := float32(160) + statsBarWidth*float32(it.Accuracy)
+		mid := y + statsFontSize/2
+		line(160, mid, right, mid, statsFontSize-4, renderer.Green)
+		line(right, mid, 160+statsBarWidth, mid, statsFontSize-4, renderer.Red)
+		text(fmt.Sprintf("%d/%d", it.Correct, it.Asked), 170+statsBarWidth, y, renderer.DarkGray)
+		y += statsLineHeight
+	}
+
+	// Response-time histogram
+	v.drawHistogram(buffer, text, line, statsChartX, top)
+
+	// Accuracy per day
+	v.drawTrend(buffer, text, statsChartX, top+statsChartH+2*statsLineHeight)
+
+	// Most frequent confusions
+	y = max(y, top+2*statsChartH+4*statsLineHeight) + statsLineHeight
+	text(v.loc.GetTerm("confusions")+":", 0, y, renderer.Black)
+	for i, c := range r.Confusion.Confusions() {
+		if i == 5 {
+			break
+		}
+		y += statsLineHeight
+		text(fmt.Sprintf("%s -> %s: %d", v.itemLabel(c.Asked), v.itemLabel(c.Answered), c.Count), 0, y, renderer.DarkGray)
+	}
+}
+
+// drawHistogram draws the response times as vertical bars
+func (v statsView) drawHistogram(buffer *renderer.CommandBuffer, text func(string, float32, float32, renderer.Color), line func(float32, float32, float32, float32, float32, renderer.Color), x, y float32) {
+	rt := v.Report.Responses
+	text(fmt.Sprintf("%s: %.1f s (%.1f-%.1f)", v.loc.GetTerm("response_time"), rt.Median.Seconds(), rt.Min.Seconds(), rt.Max.Seconds()), x, y, renderer.Black)
+	y += statsLineHeight
+	buffer.AddCommand(renderer.RectangleLinesCommand{X: x, Y: y, Width: statsChartW, Height: statsChartH, LineThickness: 1, Color: renderer.LightGray})
+	most := 1
+	for _, b := range rt.Buckets {
+		most = max(most, b.Count)
+	}
+	width := float32(statsChartW) / float32(len(rt.Buckets))
+	for i, b := range rt.Buckets {
+		bx := x + width*(float32(i)+0.5)
+		height := statsChartH * float32(b.Count) / float32(most)
+		if b.Count > 0 {
+			line(bx, y+statsChartH, bx, y+statsChartH-height, width*0.7, renderer.DarkGray)
+		}
+		label := "+"
+		if b.Upper > 0 {
+			label = strconv.Itoa(int(b.Upper.Seconds()))
+		}
+		text(label, bx-5, y+statsChartH+4, renderer.DarkGray)
+	}
+}
+
+// drawTrend draws the accuracy of each day as a line
+func (v statsView) drawTrend(buffer *renderer.CommandBuffer, text func(string, float32, float32, renderer.Color), x, y float32) {
+	trend := v.Report.Trend
+	text(v.loc.GetTerm("trend"), x, y, renderer.Black)
+	y += statsLineHeight
+	buffer.AddCommand(renderer.RectangleLinesCommand{X: x, Y: y, Width: statsChartW, Height: statsChartH, LineThickness: 1, Color: renderer.LightGray})
+	if len(trend) == 0 {
+		return
+	}
+	var points []renderer.Vector2
+	for i, p := range trend {
+		px := x + statsChartW/2
+		if len(trend) > 1 {
+			px = x + statsChartW*float32(i)/float32(len(trend)-1)
+		}
+		points = append(points, renderer.Vector2{X: px, Y: y + statsChartH*(1-float32(p.Accuracy))})
+	}
+	buffer.AddCommand(renderer.PathCommand{Points: points, Thickness: 2, Color: renderer.Green})
+	text(trend[0].Day.Format("02.01"), x, y+statsChartH+4, renderer.DarkGray)
+	if len(trend) > 1 {
+		text(trend[len(trend)-1].Day.Format("02.01"), x+statsChartW-50, y+statsChartH+4, renderer.DarkGray)
+	}
+}
+
+// itemLabel names an item key for the view, such as "D¹ (G-nøkkel)" for a
+// note card
+func (v statsView) itemLabel(key string) string {
+	parts := strings.Split(key, ":")
+	switch {
+	case parts[0] == "note" && len(parts) == 3:
+		if line, err := strconv.Atoi(parts[2]); err == nil {
+			card := exercise.NoteCard{Clef: parts[1], StaffLine: line}
+			return fmt.Sprintf("%s (%s)", v.loc.GetNoteName(card.Pitch()), v.loc.GetTerm(card.Clef))
+		}
+	case parts[0] == "chord" && len(parts) == 3:
+		for _, q := range append(append([]theory.ChordQuality(nil), theory.Triads...), theory.Sevenths...) {
+			if q.String() == parts[1] {
+				inversion, _ := strconv.Atoi(parts[2])
+				return v.loc.ChordName(q, inversion)
+			}
+		}
+	case len(parts) == 2:
+		return parts[1]
+	}
+	return key
+}
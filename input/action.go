@@ -0,0 +1,57 @@
+package input
+
+// Action is something the player does, such as "pan_left", bound to keys
+// and mouse buttons that can be changed
+type Action string
+
+// Bindings maps actions to the keys and mouse buttons that perform them
+type Bindings struct {
+	Keys    map[Action][]Key
+	Buttons map[Action][]MouseButton
+}
+
+// NewBindings returns bindings without any actions
+func NewBindings() *Bindings {
+	return &Bindings{Keys: make(map[Action][]Key), Buttons: make(map[Action][]MouseButton)}
+}
+
+// Bind sets the keys of an action, replacing those it had
+func (b *Bindings) Bind(a Action, keys ...Key) {
+	b.Keys[a] = keys
+}
+
+// BindButtons sets the mouse buttons of an action, replacing those it had
+func (b *Bindings) BindButtons(a Action, buttons ...MouseButton) {
+	b.Buttons[a] = buttons
+}
+
+// Down reports whether any key or button of the action is held
+func (b *Bindings) Down(in Source, a Action) bool {
+	for _, k := range b.Keys[a] {
+		if in.KeyDown(k) {
+			return true
+		}
+	}
+	for _, button := range b.Buttons[a] {
+		if in.MouseDown(button) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pressed reports whether any key or button of the action went down this
+// frame
+func (b *Bindings) Pressed(in Source, a Action) bool {
+	for _, k := range b.Keys[a] {
+		if in.KeyPressed(k) {
+			return true
+		}
+	}
+	for _, button := range b.Buttons[a] {
+		if in.MousePressed(button) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package input
+
+// Frame is what a Fake source reports for one frame
+type Frame struct {
+	Down         []Key         `json:"down,omitempty"` // held, including those pressed this frame
+	Pressed      []Key         `json:"pressed,omitempty"`
+	MouseDown    []MouseButton `json:"mouse_down,omitempty"`
+	MousePressed []MouseButton `json:"mouse_pressed,omitempty"`
+	Mouse        Point         `json:"mouse"`
+	Wheel        float32       `json:"wheel,omitempty"`
+	Text         string        `json:"text,omitempty"`
+}
+
+// Fake is an input source for running the game without a window, from a
+// script or a Recorder's frames: each Poll takes the next queued frame,
+// and an empty frame once they run out
+type Fake struct {
+	frames  []Frame
+	current Frame
+}
+
+// NewFake returns a source that will report the frames in order
+func NewFake(frames ...Frame) *Fake {
+	return &Fake{frames: frames}
+}
+
+// Queue adds frames after those already queued
+func (f *Fake) Queue(frames ...Frame) {
+	f.frames = append(f.frames, frames...)
+}
+
+// Press queues a frame with the keys pressed
+func (f *Fake) Press(keys ...Key) {
+	f.Queue(Frame{Down: keys, Pressed: keys})
+}
+
+// Type queues a frame with the text typed
+func (f *Fake) Type(text string) {
+	f.Queue(Frame{Text: text})
+}
+
+// Click queues a frame with the left button pressed at p
+func (f *Fake) Click(p Point) {
+	f.Queue(Frame{MouseDown: []MouseButton{MouseLeft}, MousePressed: []MouseButton{MouseLeft}, Mouse: p})
+}
+
+// Pending returns the number of frames not yet polled
+func (f *Fake) Pending() int {
+	return len(f.frames)
+}
+
+func (f *Fake) Poll() {
+	mouse := f.current.Mouse
+	f.current = Frame{Mouse: mouse} // the pointer stays where it was
+	if len(f.frames) > 0 {
+		f.current, f.frames = f.frames[0], f.frames[1:]
+	}
+}
+
+func (f *Fake) KeyDown(k Key) bool {
+	return containsKey(f.current.Down, k) || containsKey(f.current.Pressed, k)
+}
+
+func (f *Fake) KeyPressed(k Key) bool {
+	return containsKey(f.current.Pressed, k)
+}
+
+func (f *Fake) MouseDown(b MouseButton) bool {
+	return containsButton(f.current.MouseDown, b) || containsButton(f.current.MousePressed, b)
+}
+
+func (f *Fake) MousePressed(b MouseButton) bool {
+	return containsButton(f.current.MousePressed, b)
+}
+
+func (f *Fake) MousePosition() Point {
+	return f.current.Mouse
+}
+
+func (f *Fake) Wheel() float32 {
+	return f.current.Wheel
+}
+
+func (f *Fake) Chars() []rune {
+	return []rune(f.current.Text)
+}
+
+func containsKey(keys []Key, k Key) bool {
+	for _, key := range keys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func containsButton(buttons []MouseButton, b MouseButton) bool {
+	for _, button := range buttons {
+		if button == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package input
+
+// Key is a keyboard key, independent of the window library
+type Key int
+
+const (
+	KeyNone Key = iota
+	KeyEnter
+	KeyEscape
+	KeyTab
+	KeyBackspace
+	KeySpace
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyA
+	KeyB
+	KeyC
+	KeyD
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+	keyCount
+)
+
+// Keys lists every key, for sources that poll each one
+func Keys() []Key {
+	keys := make([]Key, 0, keyCount-1)
+	for k := KeyNone + 1; k < keyCount; k++ {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MouseButton is a mouse button
+type MouseButton int
+
+const (
+	MouseLeft MouseButton = iota
+	MouseRight
+	MouseMiddle
+)
+
+// Point is a position on the screen in pixels
+type Point struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+// Source is the keyboard and mouse as seen by one frame. Poll moves on to
+// the next frame; the rest describe the frame polled last.
+type Source interface {
+	Poll()
+	KeyDown(k Key) bool    // held
+	KeyPressed(k Key) bool // went down this frame
+	MouseDown(b MouseButton) bool
+	MousePressed(b MouseButton) bool
+	MousePosition() Point
+	Wheel() float32 // wheel movement this frame, positive away from the user
+	Chars() []rune  // characters typed this frame
+}
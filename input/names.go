@@ -0,0 +1,89 @@
+package input
+
+import "fmt"
+
+// keyNames are the names keys have in bindings and recordings
+var keyNames = map[Key]string{
+	KeyNone:      "none",
+	KeyEnter:     "enter",
+	KeyEscape:    "escape",
+	KeyTab:       "tab",
+	KeyBackspace: "backspace",
+	KeySpace:     "space",
+	KeyUp:        "up",
+	KeyDown:      "down",
+	KeyLeft:      "left",
+	KeyRight:     "right",
+}
+
+func init() {
+	for k := KeyA; k <= KeyZ; k++ {
+		keyNames[k] = string(rune('a' + k - KeyA))
+	}
+}
+
+var buttonNames = map[MouseButton]string{
+	MouseLeft:   "left",
+	MouseRight:  "right",
+	MouseMiddle: "middle",
+}
+
+func (k Key) String() string {
+	if name, ok := keyNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key(%d)", int(k))
+}
+
+// ParseKey returns the key of a name such as "left" or "e"
+func ParseKey(name string) (Key, error) {
+	for k, n := range keyNames {
+		if n == name {
+			return k, nil
+		}
+	}
+	return KeyNone, fmt.Errorf("unknown key %q", name)
+}
+
+func (k Key) MarshalText() ([]byte, error) {
+	if _, ok := keyNames[k]; !ok {
+		return nil, fmt.Errorf("unknown key %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *Key) UnmarshalText(text []byte) error {
+	key, err := ParseKey(string(text))
+	*k = key
+	return err
+}
+
+func (b MouseButton) String() string {
+	if name, ok := buttonNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("MouseButton(%d)", int(b))
+}
+
+// ParseMouseButton returns the button of a name such as "left"
+func ParseMouseButton(name string) (MouseButton, error) {
+	for b, n := range buttonNames {
+		if n == name {
+			return b, nil
+		}
+	}
+	return MouseLeft, fmt.Errorf("unknown mouse button %q", name)
+}
+
+func (b MouseButton) MarshalText() ([]byte, error) {
+	if _, ok := buttonNames[b]; !ok {
+		return nil, fmt.Errorf("unknown mouse button %d", int(b))
+	}
+	return []byte(b.String()), nil
+}
+
+func (b *MouseButton) UnmarshalText(text []byte) error {
+	button, err := ParseMouseButton(string(text))
+	*b = button
+	return err
+}
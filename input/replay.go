@@ -0,0 +1,79 @@
+package input
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Recorder passes another source through and keeps each frame it polls,
+// to be replayed by a Fake
+type Recorder struct {
+	Source
+	frames []Frame
+}
+
+// NewRecorder returns a recorder of in
+func NewRecorder(in Source) *Recorder {
+	return &Recorder{Source: in}
+}
+
+// Poll polls the source and records the frame
+func (r *Recorder) Poll() {
+	r.Source.Poll()
+	f := Frame{
+		Mouse: r.MousePosition(),
+		Wheel: r.Wheel(),
+		Text:  string(r.Chars()),
+	}
+	for _, k := range Keys() {
+		if r.KeyDown(k) {
+			f.Down = append(f.Down, k)
+		}
+		if r.KeyPressed(k) {
+			f.Pressed = append(f.Pressed, k)
+		}
+	}
+	for b := MouseLeft; b <= MouseMiddle; b++ {
+		if r.MouseDown(b) {
+			f.MouseDown = append(f.MouseDown, b)
+		}
+		if r.MousePressed(b) {
+			f.MousePressed = append(f.MousePressed, b)
+		}
+	}
+	r.frames = append(r.frames, f)
+}
+
+// Frames returns the frames recorded so far
+func (r *Recorder) Frames() []Frame {
+	return r.frames
+}
+
+// WriteFrames writes frames as JSON, one frame per line
+func WriteFrames(w io.Writer, frames []Frame) error {
+	enc := json.NewEncoder(w)
+	for _, f := range frames {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFrames reads frames written by WriteFrames, or written by hand as a
+// script
+func ReadFrames(r io.Reader) ([]Frame, error) {
+	dec := json.NewDecoder(r)
+	var frames []Frame
+	for {
+		var f Frame
+		err := dec.Decode(&f)
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, f)
+	}
+}
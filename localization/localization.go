@@ -1,6 +1,12 @@
 package localization
 
-import "strings"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gehoer/theory"
+)
 
 // NoteSystem represents different note naming systems
 type NoteSystem string
@@ -68,6 +74,12 @@ func NewNynorskLocalization(keyTonic, keyMode string) *Localization {
 			"time_signature": "taktart",
 			"key_signature":  "forteikn",
 
+			// Clefs, keyed by music's clef names
+			"treble": "G-nøkkel",
+			"bass":   "F-nøkkel",
+			"alto":   "altnøkkel",
+			"tenor":  "tenornøkkel",
+
 			// Modes (toneartar)
 			"ionian":     "ionisk", // same as major/dur
 			"dorian":     "dorisk",
@@ -77,6 +89,16 @@ func NewNynorskLocalization(keyTonic, keyMode string) *Localization {
 			"aeolian":    "æolisk",  // same as minor/moll
 			"locrian":    "lokrisk",
 
+			// Scales
+			"natural_minor":    "naturleg moll",
+			"harmonic_minor":   "harmonisk moll",
+			"melodic_minor":    "melodisk moll",
+			"major_pentatonic": "pentaton dur",
+			"minor_pentatonic": "pentaton moll",
+			"blues":            "bluesskala",
+			"neutral_third":    "nøytral ters",
+			"norwegian_folk":   "folkemusikkskala",
+
 			// Note values
 			"whole_note":         "heilnote",
 			"half_note":          "halvnote",
@@ -116,6 +138,8 @@ func NewNynorskLocalization(keyTonic, keyMode string) *Localization {
 			"seventh": "septim",
 			"octave":  "oktav",
 
+			"unknown_interval": "ukjent intervall",
+
 			// Interval qualities (Norwegian)
 			"diminished":        "forminska",
 			"interval_minor":    "vesle",
@@ -142,7 +166,9 @@ func NewNynorskLocalization(keyTonic, keyMode string) *Localization {
 
 			// Extended intervals
 			"ninth":            "none",
+			"tenth":            "decim",
 			"eleventh":         "undecim",
+			"twelfth":          "duodecim",
 			"thirteenth":       "terdecim",
 			"minor_ninth":      "vesle none",
 			"major_ninth":      "store none",
@@ -150,6 +176,60 @@ func NewNynorskLocalization(keyTonic, keyMode string) *Localization {
 			"sharp_eleventh":   "forstørra undecim",
 			"minor_thirteenth": "vesle terdecim",
 			"major_thirteenth": "store terdecim",
+
+			// Chords
+			"major_triad":                   "durtreklang",
+			"minor_triad":                   "molltreklang",
+			"diminished_triad":              "forminska treklang",
+			"augmented_triad":               "forstørra treklang",
+			"dominant_seventh_chord":        "dominantseptimakkord",
+			"major_seventh_chord":           "stor septimakkord",
+			"minor_seventh_chord":           "mollseptimakkord",
+			"half_diminished_seventh_chord": "halvforminska septimakkord",
+			"diminished_seventh_chord":      "forminska septimakkord",
+			"root_position":                 "grunnstilling",
+			"first_inversion":               "første omvending",
+			"second_inversion":              "andre omvending",
+			"third_inversion":               "tredje omvending",
+
+			// Exercise feedback
+			"correct":   "rett",
+			"incorrect": "feil",
+
+			// Note reading drill
+			"note_reading":  "notelesing",
+			"name_the_note": "Kva heiter tonen?",
+			"streak":        "på rad",
+			"best_streak":   "beste rekkje",
+			"accuracy":      "treffsikkerheit",
+			"average_time":  "snittid",
+			"hardest_notes": "vanskelegaste tonar",
+
+			// Statistics
+			"statistics":    "statistikk",
+			"answers":       "svar",
+			"response_time": "svartid",
+			"trend":         "utvikling",
+			"confusions":    "forvekslingar",
+			"exported_to":   "eksportert til",
+
+			// Menus
+			"main_menu":           "Hovudmeny",
+			"practice":            "Øving",
+			"score_viewer":        "Notevisar",
+			"settings":            "Innstillingar",
+			"quit":                "Avslutt",
+			"choose_exercise":     "Vel øving",
+			"all_clefs":           "Alle nøklar",
+			"back":                "Tilbake",
+			"results":             "Resultat",
+			"again":               "Ein gong til",
+			"questions_per_round": "Spørsmål per runde",
+			"require_octave":      "Krev oktav",
+			"profile":             "Profil",
+			"new_profile":         "Ny profil",
+			"yes":                 "ja",
+			"no":                  "nei",
 		},
 		NoteNames: make(map[int]string),
 	}
@@ -218,32 +298,21 @@ func (l *Localization) initializeKeySignatures() {
 
 // generateNynorskNoteNames creates a map from MIDI note numbers to Norwegian note names
 func (l *Localization) generateNynorskNoteNames() {
-	// Chromatic notes with correct Norwegian naming
-	chromaticNotes := []struct {
-		name     string
-		semitone int
-	}{
-		{"C", 0},
-		{"ciss", 1}, // C#
-		{"D", 2},
-		{"diss", 3}, // D#
-		{"E", 4},
-		{"F", 5},
-		{"fiss", 6}, // F#
-		{"G", 7},
-		{"giss", 8}, // G#
-		{"A", 9},
-		{"aiss", 10}, // A#
-		{"H", 11},    // B in international
+	key, err := l.KeySignature.Key()
+	if err != nil {
+		key = theory.Key{Mode: "dur"} // spell as in C major
 	}
 
 	// Generate note names for MIDI range 0-127
 	for midi := 0; midi <= 127; midi++ {
-		octave := (midi / 12) - 1
-		semitone := midi % 12
-
 		// Get base note name, considering key signature
-		noteName := l.getNoteNameInKey(chromaticNotes[semitone].name, semitone)
+		noteName, octave := l.getNoteNameInKey(key, midi)
+
+		// The great octave and those below it are written with a capital
+		// letter, the small octave and those above with a small one
+		if octave <= 2 {
+			noteName = strings.ToUpper(noteName[:1]) + noteName[1:]
+		}
 
 		// Add octave designation using Norwegian system
 		octaveDesignation := l.getOctaveDesignation(octave, noteName)
@@ -252,46 +321,26 @@ func (l *Localization) generateNynorskNoteNames() {
 	}
 }
 
-// getNoteNameInKey returns the appropriate note name considering the key signature
-func (l *Localization) getNoteNameInKey(defaultName string, semitone int) string {
-	// For semitone 10 (A#/Bb), always prefer "b" in Norwegian
-	if semitone == 10 {
-		return "b"
-	}
-
-	// Consider key signature for enharmonic choices
-	switch l.KeySignature.Tonic {
-	case "C", "G", "D", "A", "E", "H", "fiss", "ciss": // Sharp keys
-		// Prefer sharp names where applicable
-		return defaultName
-	case "F", "b", "ess", "ass", "dess", "gess", "cess": // Flat keys
-		// Convert to flat equivalents where applicable
-		switch semitone {
-		case 1:
-			return "dess" // Db instead of ciss
-		case 3:
-			return "ess" // Eb instead of diss
-		case 6:
-			return "gess" // Gb instead of fiss
-		case 8:
-			return "ass" // Ab instead of giss
-		case 10:
-			return "b" // Always "b" in Norwegian
-		}
-	}
-
-	return defaultName
+// getNoteNameInKey returns the note name of a MIDI pitch spelled in the key,
+// with the octave of the spelling (H sharp 3 is MIDI 60). Notes of the key
+// signature keep its accidentals, as fiss in D major and ess in B flat
+// major; other notes take sharps in sharp keys and flats in flat keys unless
+// the other spelling lies closer to the key.
+func (l *Localization) getNoteNameInKey(key theory.Key, midi int) (string, int) {
+	p := key.Spell(midi)
+	return FormatNoteName(p.Step, p.Alter), p.Octave
 }
 
 // getOctaveDesignation returns the Norwegian octave designation
 func (l *Localization) getOctaveDesignation(octave int, noteName string) string {
 	// Norwegian octave system based on C
-	// C4 = einstroken C (c¹), etc.
+	// C4 = einstroken C (c¹), etc. The store and vesle octaves differ only
+	// in the case of the letter (C and c).
 	switch octave {
 	case 0:
-		return "" // subkontra (very rare)
+		return "₂" // subkontra (very rare)
 	case 1:
-		return "" // kontra
+		return "₁" // kontra
 	case 2:
 		return "" // store
 	case 3:
@@ -340,69 +389,110 @@ func (l *Localization) GetTerm(key string) string {
 	return key // Return the key if no translation exists
 }
 
-// ConvertNoteToMIDI converts a Norwegian note name string to MIDI number
+// ConvertNoteToMIDI converts a Norwegian note name string to MIDI number.
+// The octave is that of the letter, so "hiss" in octave 4 is C5 and "cess"
+// in octave 4 is B3. Returns -1 for unknown names.
 func (l *Localization) ConvertNoteToMIDI(noteName string, octave int) int {
-	note := strings.ToLower(strings.TrimSpace(noteName))
+	step, alter, ok := ParseNoteName(noteName)
+	if !ok {
+		return -1 // Invalid note
+	}
+	return (octave+1)*12 + stepSemitones[step] + alter
+}
+
+// noteLetters are the Norwegian letters of the diatonic steps from C
+var noteLetters = [7]string{"c", "d", "e", "f", "g", "a", "h"}
 
-	var semitone int
+// stepSemitones are the semitones above C of the natural steps
+var stepSemitones = [7]int{0, 2, 4, 5, 7, 9, 11}
+
+// ParseNoteName splits a Norwegian note name such as "fiss", "ess", "b" or
+// "cississ" into its diatonic step (0 = C ... 6 = H) and its alteration in
+// semitones. Each "iss" raises and each "ess" lowers by a semitone; e and a
+// drop the first e ("ess", "ass") and H flat is "b" (double flat "bess").
+func ParseNoteName(name string) (step, alter int, ok bool) {
+	note := strings.ToLower(strings.TrimSpace(name))
 	switch note {
-	// Natural notes
-	case "c":
-		semitone = 0
-	case "d":
-		semitone = 2
-	case "e":
-		semitone = 4
-	case "f":
-		semitone = 5
-	case "g":
-		semitone = 7
-	case "a":
-		semitone = 9
-	case "h":
-		semitone = 11
-	case "b": // This is Hb (H flat) in Norwegian!
-		semitone = 10
-
-	// Sharp variants (iss)
-	case "ciss":
-		semitone = 1
-	case "diss":
-		semitone = 3
-	case "eiss":
-		semitone = 5 // E# = F
-	case "fiss":
-		semitone = 6
-	case "giss":
-		semitone = 8
-	case "aiss":
-		semitone = 10
-	case "hiss":
-		semitone = 0 // H# = C
-
-	// Flat variants (ess)
-	case "cess":
-		semitone = 11 // Cb = B/H
-	case "dess":
-		semitone = 1 // Db = C#
-	case "eess":
-		semitone = 3 // Eb = D#
-	case "fess":
-		semitone = 4 // Fb = E
-	case "gess":
-		semitone = 6 // Gb = F#
-	case "ass":
-		semitone = 8 // Ab = G#
-	// Note: "b" is used instead of "hess" for Hb in Norwegian
+	case "b":
+		return 6, -1, true
+	case "bess":
+		return 6, -2, true
+	case "":
+		return 0, 0, false
+	}
+
+	step = -1
+	for i, letter := range noteLetters {
+		if note[:1] == letter {
+			step = i
+		}
+	}
+	if step < 0 {
+		return 0, 0, false
+	}
 
+	rest := note[1:]
+	if (step == 2 || step == 5) && strings.HasPrefix(rest, "ss") {
+		// "ess" and "ass" rather than "eess" and "aess"
+		rest = "e" + rest
+	}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "iss") && alter >= 0:
+			alter++
+		case strings.HasPrefix(rest, "ess") && alter <= 0:
+			alter--
+		default:
+			return 0, 0, false
+		}
+		rest = rest[3:]
+	}
+	if alter < -2 || alter > 2 || (step == 6 && alter < 0) {
+		// H flat is spelt "b"
+		return 0, 0, false
+	}
+	return step, alter, true
+}
+
+// FormatNoteName returns the Norwegian name of a diatonic step (0 = C) with
+// an alteration of at most two semitones, or "" if there is none
+func FormatNoteName(step, alter int) string {
+	if step < 0 || step > 6 || alter < -2 || alter > 2 {
+		return ""
+	}
+	letter := noteLetters[step]
+	switch {
+	case alter > 0:
+		return letter + strings.Repeat("iss", alter)
+	case alter == 0:
+		return letter
+	case step == 6:
+		return "b" + strings.Repeat("ess", -alter-1)
+	case step == 2 || step == 5:
+		return letter + strings.Repeat("ess", -alter)[1:]
 	default:
-		return -1 // Invalid note
+		return letter + strings.Repeat("ess", -alter)
+	}
+}
+
+// ParseKey reads a key from its Norwegian tonic and mode names, such as
+// "fiss" and "moll"
+func ParseKey(tonic, mode string) (theory.Key, error) {
+	step, alter, ok := ParseNoteName(tonic)
+	if !ok {
+		return theory.Key{}, fmt.Errorf("unknown key tonic %q", tonic)
 	}
+	return theory.NewKey(step, alter, mode)
+}
 
-	return (octave+1)*12 + semitone
+// Key returns the tonic and mode of the key signature
+func (k KeySignature) Key() (theory.Key, error) {
+	return ParseKey(k.Tonic, k.Mode)
 }
 
-// GetIntervalName returns the Norwegian name for an interval in semitones
+// GetIntervalName returns the Norwegian name for an interval in semitones.
+// It cannot tell enharmonic intervals apart; IntervalName names spelled
+// intervals.
 func (l *Localization) GetIntervalName(semitones int) string {
 	switch semitones {
 	case 0:
@@ -442,8 +532,71 @@ func (l *Localization) GetIntervalName(semitones int) string {
 			}
 			return baseInterval + " + " + string(rune(octaves+'0')) + " " + l.GetTerm("octave")
 		}
-		return "ukjent intervall"
+		return l.GetTerm("unknown_interval")
+	}
+}
+
+// intervalNumberTerms are the terms for interval numbers, from the unison
+var intervalNumberTerms = []string{
+	"unison", "second", "third", "fourth", "fifth", "sixth", "seventh", "octave",
+	"ninth", "tenth", "eleventh", "twelfth", "thirteenth",
+}
+
+// qualityTerms are the terms for interval qualities
+var qualityTerms = map[theory.Quality]string{
+	theory.DoublyDiminished: "doubly_diminished",
+	theory.Diminished:       "diminished",
+	theory.Minor:            "interval_minor",
+	theory.Major:            "interval_major",
+	theory.Perfect:          "perfect",
+	theory.Augmented:        "augmented",
+	theory.DoublyAugmented:  "doubly_augmented",
+}
+
+// IntervalName returns the Norwegian name of a spelled interval with its
+// quality, such as "forstørra kvart" or "forminska kvint". Intervals wider
+// than a thirteenth are named as a simple interval plus octaves. The
+// direction is not named.
+func (l *Localization) IntervalName(iv theory.Interval) string {
+	q, ok := iv.Quality()
+	if !ok {
+		return l.GetTerm("unknown_interval")
+	}
+	number := iv.Number()
+	if number > len(intervalNumberTerms) {
+		octaves := iv.Octaves()
+		name := l.IntervalName(iv.Simple())
+		if octaves == 1 {
+			return name + " + " + l.GetTerm("octave")
+		}
+		return name + " + " + strconv.Itoa(octaves) + " " + l.GetTerm("octave")
+	}
+	if number == 1 && q == theory.Perfect {
+		return l.GetTerm("unison")
+	}
+	return l.GetTerm(qualityTerms[q]) + " " + l.GetTerm(intervalNumberTerms[number-1])
+}
+
+// inversionTerms are the terms for chord inversions, from root position
+var inversionTerms = []string{"root_position", "first_inversion", "second_inversion", "third_inversion"}
+
+// ChordName returns the Norwegian name of a chord quality in an inversion,
+// such as "molltreklang, første omvending". Root position is not named.
+func (l *Localization) ChordName(q theory.ChordQuality, inversion int) string {
+	name := l.GetTerm(q.String())
+	if inversion > 0 && inversion < len(inversionTerms) {
+		name += ", " + l.GetTerm(inversionTerms[inversion])
+	}
+	return name
+}
+
+// InversionName returns the Norwegian name of a chord inversion, such as
+// "grunnstilling" or "andre omvending"
+func (l *Localization) InversionName(inversion int) string {
+	if inversion < 0 || inversion >= len(inversionTerms) {
+		return ""
 	}
+	return l.GetTerm(inversionTerms[inversion])
 }
 
 // GetKeySignatures returns all available key signatures
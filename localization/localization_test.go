@@ -0,0 +1,66 @@
+package localization
+
+import (
+	"testing"
+
+	"gehoer/theory"
+)
+
+func TestIntervalName(t *testing.T) {
+	l := NewNynorskLocalization("C", "dur")
+	tests := []struct {
+		iv   theory.Interval
+		want string
+	}{
+		{theory.Interval{Steps: 0, Semitones: 0}, "prim"},
+		{theory.Interval{Steps: 0, Semitones: 1}, "forstørra prim"},
+		{theory.Interval{Steps: 2, Semitones: 4}, "store ters"},
+		{theory.Interval{Steps: -2, Semitones: -3}, "vesle ters"},
+		{theory.Interval{Steps: 3, Semitones: 6}, "forstørra kvart"},
+		{theory.Interval{Steps: 4, Semitones: 6}, "forminska kvint"},
+		{theory.Interval{Steps: 4, Semitones: 5}, "dobbelforminska kvint"},
+		{theory.Interval{Steps: 7, Semitones: 12}, "rein oktav"},
+		{theory.Interval{Steps: 9, Semitones: 16}, "store decim"},
+		{theory.Interval{Steps: 13, Semitones: 23}, "store septim + oktav"},
+		{theory.Interval{Steps: 16, Semitones: 28}, "store ters + 2 oktav"},
+		{theory.Interval{Steps: 2, Semitones: 7}, "ukjent intervall"},
+	}
+	for _, tt := range tests {
+		if got := l.IntervalName(tt.iv); got != tt.want {
+			t.Errorf("%v named %q, want %q", tt.iv, got, tt.want)
+		}
+	}
+	if got := l.GetIntervalName(-1); got != "ukjent intervall" {
+		t.Errorf("-1 semitones named %q", got)
+	}
+}
+
+func TestGetNoteName(t *testing.T) {
+	tests := []struct {
+		tonic, mode string
+		midi        int
+		want        string
+	}{
+		{"C", "dur", 12, "C₂"},
+		{"C", "dur", 24, "C₁"},
+		{"C", "dur", 36, "C"},
+		{"C", "dur", 48, "c"},
+		{"C", "dur", 60, "c¹"},
+		{"C", "dur", 72, "c²"},
+		{"C", "dur", 108, "c⁵"},
+		{"C", "dur", 43, "G"},
+		{"C", "dur", 55, "g"},
+		{"D", "dur", 42, "Fiss"},
+		{"D", "dur", 54, "fiss"},
+		{"F", "dur", 46, "B"},
+		{"F", "dur", 70, "b¹"},
+		{"ciss", "dur", 60, "hiss"},
+		{"C", "dur", 128, "ukjent"},
+	}
+	for _, tt := range tests {
+		l := NewNynorskLocalization(tt.tonic, tt.mode)
+		if got := l.GetNoteName(tt.midi); got != tt.want {
+			t.Errorf("%d in %s %s named %q, want %q", tt.midi, tt.tonic, tt.mode, got, tt.want)
+		}
+	}
+}
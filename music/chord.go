@@ -0,0 +1,65 @@
+package music
+
+// ChordNotes returns the note at index i followed by the chord notes
+// sounding with it, or nil if element i is not a note
+func (m *Measure) ChordNotes(i int) []*Note {
+	head, ok := m.Elements[i].(*Note)
+	if !ok {
+		return nil
+	}
+	notes := []*Note{head}
+	for j := i + 1; j < len(m.Elements); j++ {
+		n, ok := m.Elements[j].(*Note)
+		if !ok || !n.Chord {
+			break
+		}
+		notes = append(notes, n)
+	}
+	return notes
+}
+
+// TieTarget returns the note a tied note at ref continues into: the note of
+// the same pitch in the next chord or single note, skipping grace notes
+func (s *Score) TieTarget(ref ElementRef) (ElementRef, bool) {
+	note, ok := s.Measures[ref.Measure].Elements[ref.Element].(*Note)
+	if !ok {
+		return ElementRef{}, false
+	}
+	for mi := ref.Measure; mi < len(s.Measures); mi++ {
+		m := s.Measures[mi]
+		start := 0
+		if mi == ref.Measure {
+			start = ref.Element + 1
+		}
+		for ei := start; ei < len(m.Elements); ei++ {
+			n, ok := m.Elements[ei].(*Note)
+			if !ok {
+				return ElementRef{}, false
+			}
+			if n.IsGrace() || n.Chord {
+				// Grace notes, or the rest of the tied note's chord
+				continue
+			}
+			for j, c := range m.ChordNotes(ei) {
+				if c.Pitch == note.Pitch {
+					return ElementRef{Measure: mi, Element: ei + j}, true
+				}
+			}
+			return ElementRef{}, false
+		}
+	}
+	return ElementRef{}, false
+}
+
+// ChordHead returns the index of the first note of the chord element i
+// belongs to, which is i itself for notes outside chords
+func (m *Measure) ChordHead(i int) int {
+	for i > 0 {
+		n, ok := m.Elements[i].(*Note)
+		if !ok || !n.Chord {
+			break
+		}
+		i--
+	}
+	return i
+}
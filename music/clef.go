@@ -0,0 +1,75 @@
+package music
+
+// Clef names used in Measure.Clef
+const (
+	TrebleClef = "treble"
+	BassClef   = "bass"
+	AltoClef   = "alto"
+	TenorClef  = "tenor"
+)
+
+// clefInfo describes where a clef sits and which note its bottom line is
+type clefInfo struct {
+	glyph      string // SMuFL glyph name
+	position   int    // staff position of the line the clef marks
+	bottomLine int    // diatonic number of the note on the bottom line
+}
+
+var clefs = map[string]clefInfo{
+	TrebleClef: {glyph: "gClef", position: 2, bottomLine: 4*7 + 2}, // E4
+	BassClef:   {glyph: "fClef", position: 6, bottomLine: 2*7 + 4}, // G2
+	AltoClef:   {glyph: "cClef", position: 4, bottomLine: 3*7 + 3}, // F3
+	TenorClef:  {glyph: "cClef", position: 6, bottomLine: 3*7 + 1}, // D3
+}
+
+// lookupClef returns the clef info, treating "" as treble
+func lookupClef(clef string) (clefInfo, bool) {
+	if clef == "" {
+		return clefs[TrebleClef], true
+	}
+	info, ok := clefs[clef]
+	return info, ok
+}
+
+// IsValidClef reports whether the clef name is known
+func IsValidClef(clef string) bool {
+	_, ok := lookupClef(clef)
+	return ok
+}
+
+// ClefGlyphName returns the SMuFL glyph name of the clef
+func ClefGlyphName(clef string) string {
+	info, _ := lookupClef(clef)
+	return info.glyph
+}
+
+// ClefStaffPosition returns the staff position (half spaces above the
+// bottom line) the clef glyph is drawn on
+func ClefStaffPosition(clef string) int {
+	info, _ := lookupClef(clef)
+	return info.position
+}
+
+// ClefBottomLine returns the diatonic number (octave*7 + step) of the note
+// on the bottom staff line in the clef
+func ClefBottomLine(clef string) int {
+	info, _ := lookupClef(clef)
+	return info.bottomLine
+}
+
+// NaturalPitchAt returns the MIDI number of the natural note at a staff
+// position (0 = bottom line) in the clef
+func NaturalPitchAt(clef string, staffLine int) int {
+	return NaturalMIDI(ClefBottomLine(clef) + staffLine)
+}
+
+// ClefAt returns the clef in effect in measure index mi, i.e. the last clef
+// set at or before it. Scores without any clef are read in treble clef.
+func (s *Score) ClefAt(mi int) string {
+	for i := mi; i >= 0; i-- {
+		if i < len(s.Measures) && s.Measures[i].Clef != "" {
+			return s.Measures[i].Clef
+		}
+	}
+	return TrebleClef
+}
@@ -1,7 +1,7 @@
 package music
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 )
 
@@ -9,63 +9,239 @@ type JSONElement struct {
 	Type       string `json:"type"`
 	Pitch      int    `json:"pitch,omitempty"`
 	Duration   string `json:"duration"`
+	Dots       int    `json:"dots,omitempty"`
 	StaffLine  int    `json:"staff_line"`
 	Accidental string `json:"accidental,omitempty"`
+	Courtesy   bool   `json:"courtesy,omitempty"` // the accidental is a reminder
+
+	Dynamic               string   `json:"dynamic,omitempty"`
+	DynamicPlacement      string   `json:"dynamic_placement,omitempty"`
+	Articulations         []string `json:"articulations,omitempty"`
+	ArticulationPlacement string   `json:"articulation_placement,omitempty"`
+	Hairpin               string   `json:"hairpin,omitempty"` // "crescendo" or "diminuendo" starts a hairpin here
+	HairpinStop           bool     `json:"hairpin_stop,omitempty"`
+	HairpinPlacement      string   `json:"hairpin_placement,omitempty"` // defaults to dynamic_placement
+	Ornaments             []string `json:"ornaments,omitempty"`
+	Grace                 string   `json:"grace,omitempty"` // "appoggiatura" or "acciaccatura"
+	Tie                   bool     `json:"tie,omitempty"`   // tied to the next note of the same pitch
+	Chord                 bool     `json:"chord,omitempty"` // sounds with the previous note
+}
+
+type JSONTuplet struct {
+	Actual    int    `json:"actual"`
+	Normal    int    `json:"normal"`
+	Start     int    `json:"start"` // element index
+	End       int    `json:"end"`   // element index, inclusive
+	Placement string `json:"placement,omitempty"`
+	ShowRatio bool   `json:"show_ratio,omitempty"`
+}
+
+type JSONTimeSignature struct {
+	Numerator   int `json:"numerator"`
+	Denominator int `json:"denominator"`
+}
+
+type JSONKeySignature struct {
+	Tonic string `json:"tonic"`
+	Mode  string `json:"mode"`
 }
 
 type JSONMeasure struct {
-	Number   int           `json:"number"`
-	Elements []JSONElement `json:"elements"`
+	Number        int                `json:"number"`
+	Clef          string             `json:"clef,omitempty"`           // clef change at this measure
+	Pickup        bool               `json:"pickup,omitempty"`         // anacrusis, may be shorter than the time signature
+	TimeSignature *JSONTimeSignature `json:"time_signature,omitempty"` // defaults to the score's
+	Elements      []JSONElement      `json:"elements"`
+	Tuplets       []JSONTuplet       `json:"tuplets,omitempty"`
+	RepeatStart   bool               `json:"repeat_start,omitempty"`
+	RepeatEnd     bool               `json:"repeat_end,omitempty"`
+	Ending        int                `json:"ending,omitempty"` // first/second ending number
 }
 
 type JSONScore struct {
-	Title        string `json:"title"`
-	Composer     string `json:"composer"`
-	KeySignature struct {
-		Tonic string `json:"tonic"`
-		Mode  string `json:"mode"`
-	} `json:"key_signature"`
-	TimeSignature struct {
-		Numerator   int `json:"numerator"`
-		Denominator int `json:"denominator"`
-	} `json:"time_signature"`
-	Tempo    int           `json:"tempo"`
-	Measures []JSONMeasure `json:"measures"`
+	SchemaVersion int               `json:"schema_version"`
+	Title         string            `json:"title"`
+	Composer      string            `json:"composer"`
+	Clef          string            `json:"clef,omitempty"` // defaults to treble
+	KeySignature  JSONKeySignature  `json:"key_signature"`
+	TimeSignature JSONTimeSignature `json:"time_signature"`
+	Tempo         int               `json:"tempo"`
+	Measures      []JSONMeasure     `json:"measures"`
 }
 
+// LoadScoreFromJSON loads a score file of any supported schema version,
+// ignoring unknown fields
 func LoadScoreFromJSON(path string) (*Score, error) {
+	return LoadScoreFromJSONWithOptions(path, DecodeOptions{})
+}
+
+// LoadScoreFromJSONWithOptions loads a score file, migrating older schema
+// versions to the current one
+func LoadScoreFromJSONWithOptions(path string, opts DecodeOptions) (*Score, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	score, err := DecodeScoreJSON(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return score, nil
+}
 
-	var js JSONScore
-	if err := json.Unmarshal(data, &js); err != nil {
+// DecodeScoreJSON decodes a score document, migrating older schema versions
+// to the current one
+func DecodeScoreJSON(data []byte, opts DecodeOptions) (*Score, error) {
+	js, err := DecodeJSONScore(data, opts)
+	if err != nil {
 		return nil, err
 	}
+	score, _, err := js.buildScore()
+	return score, err
+}
 
+// buildScore converts the JSON score to the model. It also returns, per
+// measure, the JSON element index of each model element, since unknown
+// element types are dropped.
+func (js *JSONScore) buildScore() (*Score, [][]int, error) {
 	score := NewScore(js.Title, js.Composer, js.KeySignature.Tonic, js.KeySignature.Mode, js.TimeSignature.Numerator, js.TimeSignature.Denominator, js.Tempo)
+	jsonIndex := make([][]int, len(js.Measures))
+
+	var openHairpin *Hairpin
+	var last ElementRef
+
+	for mi, jm := range js.Measures {
+		var ts *TimeSignature
+		if jm.TimeSignature != nil {
+			ts = &TimeSignature{Numerator: jm.TimeSignature.Numerator, Denominator: jm.TimeSignature.Denominator}
+		}
+		measure := score.AddMeasure(ts)
+		if jm.Number != 0 {
+			measure.Number = jm.Number
+		}
+		measure.Pickup = jm.Pickup
+		measure.RepeatStart = jm.RepeatStart
+		measure.RepeatEnd = jm.RepeatEnd
+		measure.Ending = jm.Ending
+		measure.Clef = jm.Clef
+		if mi == 0 && measure.Clef == "" {
+			measure.Clef = js.Clef
+			if measure.Clef == "" {
+				measure.Clef = TrebleClef
+			}
+		}
 
-	for _, jm := range js.Measures {
-		measure := score.AddMeasure(nil) // Use default time signature or extend for per-measure
-		for _, elem := range jm.Elements {
+		// Model index of each JSON element, since unknown element types are dropped
+		elementIndex := make([]int, 0, len(jm.Elements)+1)
+		for ji, elem := range jm.Elements {
+			elementIndex = append(elementIndex, len(measure.Elements))
 			switch elem.Type {
 			case "note":
 				dur := parseDuration(elem.Duration)
-				measure.AddNote(&Note{
-					Pitch:      elem.Pitch,
-					Duration:   dur,
-					StaffLine:  elem.StaffLine,
-					Accidental: elem.Accidental,
-				})
+				note := &Note{
+					Pitch:                 elem.Pitch,
+					Duration:              dur,
+					Dots:                  elem.Dots,
+					StaffLine:             elem.StaffLine,
+					Accidental:            elem.Accidental,
+					Courtesy:              elem.Courtesy,
+					Dynamic:               Dynamic(elem.Dynamic),
+					DynamicPlacement:      parsePlacement(elem.DynamicPlacement),
+					ArticulationPlacement: parsePlacement(elem.ArticulationPlacement),
+					Grace:                 parseGraceType(elem.Grace),
+					Tie:                   elem.Tie,
+					Chord:                 elem.Chord,
+				}
+				for _, a := range elem.Articulations {
+					note.Articulations = append(note.Articulations, Articulation(a))
+				}
+				for _, o := range elem.Ornaments {
+					note.Ornaments = append(note.Ornaments, Ornament(o))
+				}
+				measure.AddNote(note)
 			case "rest":
 				dur := parseDuration(elem.Duration)
 				measure.AddRest(&Rest{
 					Duration: dur,
+					Dots:     elem.Dots,
 				})
+			default:
+				continue
+			}
+
+			jsonIndex[mi] = append(jsonIndex[mi], ji)
+			ref := ElementRef{Measure: mi, Element: len(measure.Elements) - 1}
+
+			// A stop ends the hairpin already running; a hairpin that starts
+			// here as well begins after it. With none running, the stop
+			// ends a hairpin that starts and ends on this element.
+			stopped := false
+			if elem.HairpinStop && openHairpin != nil {
+				openHairpin.End = ref
+				score.Hairpins = append(score.Hairpins, *openHairpin)
+				openHairpin = nil
+				stopped = true
+			}
+			if elem.Hairpin == "crescendo" || elem.Hairpin == "diminuendo" {
+				if openHairpin != nil {
+					openHairpin.End = last
+					score.Hairpins = append(score.Hairpins, *openHairpin)
+				}
+				placement := elem.HairpinPlacement
+				if placement == "" {
+					placement = elem.DynamicPlacement
+				}
+				openHairpin = &Hairpin{Type: parseHairpinType(elem.Hairpin), Start: ref, Placement: parsePlacement(placement)}
+			}
+			if elem.HairpinStop && !stopped && openHairpin != nil {
+				openHairpin.End = ref
+				score.Hairpins = append(score.Hairpins, *openHairpin)
+				openHairpin = nil
+			}
+			last = ref
+		}
+
+		elementIndex = append(elementIndex, len(measure.Elements))
+
+		for _, jt := range jm.Tuplets {
+			if jt.Start < 0 || jt.End < jt.Start || jt.End >= len(jm.Elements) {
+				return nil, nil, fmt.Errorf("measure %d: tuplet span %d-%d outside measure", measure.Number, jt.Start, jt.End)
+			}
+			err := measure.AddTuplet(Tuplet{
+				Actual:    jt.Actual,
+				Normal:    jt.Normal,
+				Start:     elementIndex[jt.Start],
+				End:       elementIndex[jt.End+1] - 1,
+				Placement: parsePlacement(jt.Placement),
+				ShowRatio: jt.ShowRatio,
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("measure %d: %w", measure.Number, err)
 			}
 		}
 	}
 
-	return score, nil
+	// A hairpin left open runs to the end of the score
+	if openHairpin != nil {
+		openHairpin.End = last
+		score.Hairpins = append(score.Hairpins, *openHairpin)
+	}
+
+	return score, jsonIndex, nil
+}
+
+// parseHairpinType converts a JSON hairpin string to HairpinType
+func parseHairpinType(s string) HairpinType {
+	if s == "diminuendo" {
+		return Diminuendo
+	}
+	return Crescendo
+}
+
+// hairpinTypeName converts HairpinType to its JSON hairpin string
+func hairpinTypeName(t HairpinType) string {
+	if t == Diminuendo {
+		return "diminuendo"
+	}
+	return "crescendo"
 }
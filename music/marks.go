@@ -0,0 +1,281 @@
+package music
+
+// Placement says on which side of the staff a mark is drawn
+type Placement int
+
+const (
+	PlacementAuto Placement = iota
+	PlacementAbove
+	PlacementBelow
+)
+
+// parsePlacement converts a JSON placement string to Placement
+func parsePlacement(s string) Placement {
+	switch s {
+	case "above":
+		return PlacementAbove
+	case "below":
+		return PlacementBelow
+	default:
+		return PlacementAuto
+	}
+}
+
+// placementName converts Placement to its JSON string
+func placementName(p Placement) string {
+	switch p {
+	case PlacementAbove:
+		return "above"
+	case PlacementBelow:
+		return "below"
+	default:
+		return ""
+	}
+}
+
+// Dynamic is a dynamic marking such as "p" or "mf"
+type Dynamic string
+
+const (
+	DynamicNone Dynamic = ""
+	DynamicPPP  Dynamic = "ppp"
+	DynamicPP   Dynamic = "pp"
+	DynamicP    Dynamic = "p"
+	DynamicMP   Dynamic = "mp"
+	DynamicMF   Dynamic = "mf"
+	DynamicF    Dynamic = "f"
+	DynamicFF   Dynamic = "ff"
+	DynamicFFF  Dynamic = "fff"
+	DynamicSF   Dynamic = "sf"  // sforzando, accents a single note
+	DynamicSFZ  Dynamic = "sfz" // sforzato, accents a single note
+	DynamicFP   Dynamic = "fp"  // forte-piano
+)
+
+// DefaultVelocity is used for notes before the first dynamic marking (mf)
+const DefaultVelocity = 80
+
+// GlyphName returns the SMuFL glyph name for the dynamic
+func (d Dynamic) GlyphName() string {
+	switch d {
+	case DynamicPPP:
+		return "dynamicPPP"
+	case DynamicPP:
+		return "dynamicPP"
+	case DynamicP:
+		return "dynamicPiano"
+	case DynamicMP:
+		return "dynamicMP"
+	case DynamicMF:
+		return "dynamicMF"
+	case DynamicF:
+		return "dynamicForte"
+	case DynamicFF:
+		return "dynamicFF"
+	case DynamicFFF:
+		return "dynamicFFF"
+	case DynamicSF:
+		return "dynamicSforzando1"
+	case DynamicSFZ:
+		return "dynamicSforzato"
+	case DynamicFP:
+		return "dynamicFortePiano"
+	default:
+		return ""
+	}
+}
+
+// Velocity returns the MIDI velocity the dynamic is played at
+func (d Dynamic) Velocity() int {
+	switch d {
+	case DynamicPPP:
+		return 16
+	case DynamicPP:
+		return 33
+	case DynamicP:
+		return 49
+	case DynamicMP:
+		return 64
+	case DynamicMF:
+		return 80
+	case DynamicF, DynamicFP:
+		return 96
+	case DynamicFF, DynamicSF, DynamicSFZ:
+		return 112
+	case DynamicFFF:
+		return 127
+	default:
+		return DefaultVelocity
+	}
+}
+
+// IsAccent reports whether the dynamic only affects the note it is attached
+// to, after which the previous level resumes
+func (d Dynamic) IsAccent() bool {
+	return d == DynamicSF || d == DynamicSFZ
+}
+
+// SustainVelocity returns the level that continues after the attack, which
+// differs from Velocity only for fp
+func (d Dynamic) SustainVelocity() int {
+	if d == DynamicFP {
+		return DynamicP.Velocity()
+	}
+	return d.Velocity()
+}
+
+// Articulation is a mark that changes the attack or length of a single note
+type Articulation string
+
+const (
+	Staccato      Articulation = "staccato"
+	Staccatissimo Articulation = "staccatissimo"
+	Accent        Articulation = "accent"
+	Marcato       Articulation = "marcato"
+	Tenuto        Articulation = "tenuto"
+)
+
+// GlyphName returns the SMuFL glyph name for the articulation drawn above or below the note
+func (a Articulation) GlyphName(above bool) string {
+	var base string
+	switch a {
+	case Staccato:
+		base = "articStaccato"
+	case Staccatissimo:
+		base = "articStaccatissimo"
+	case Accent:
+		base = "articAccent"
+	case Marcato:
+		base = "articMarcato"
+	case Tenuto:
+		base = "articTenuto"
+	default:
+		return ""
+	}
+	if above {
+		return base + "Above"
+	}
+	return base + "Below"
+}
+
+// LengthFactor returns the fraction of the written duration that is sounded
+func (a Articulation) LengthFactor() float32 {
+	switch a {
+	case Staccato:
+		return 0.5
+	case Staccatissimo:
+		return 0.25
+	case Tenuto:
+		return 1.0
+	default:
+		return DefaultLengthFactor
+	}
+}
+
+// VelocityBoost returns how much louder the articulation makes the attack
+func (a Articulation) VelocityBoost() int {
+	switch a {
+	case Accent:
+		return 20
+	case Marcato:
+		return 30
+	default:
+		return 0
+	}
+}
+
+// DefaultLengthFactor is the sounded fraction of notes without a length articulation
+const DefaultLengthFactor = 0.9
+
+// HairpinType distinguishes crescendo and diminuendo hairpins
+type HairpinType int
+
+const (
+	Crescendo HairpinType = iota
+	Diminuendo
+)
+
+// ElementRef addresses an element by measure and element index in a score
+type ElementRef struct {
+	Measure int
+	Element int
+}
+
+// Before reports whether r comes before other in the score
+func (r ElementRef) Before(other ElementRef) bool {
+	if r.Measure != other.Measure {
+		return r.Measure < other.Measure
+	}
+	return r.Element < other.Element
+}
+
+// Hairpin is a crescendo or diminuendo wedge spanning Start to End inclusive
+type Hairpin struct {
+	Type      HairpinType
+	Start     ElementRef
+	End       ElementRef
+	Placement Placement
+}
+
+// Contains reports whether the element lies under the hairpin
+func (h Hairpin) Contains(r ElementRef) bool {
+	return !r.Before(h.Start) && !h.End.Before(r)
+}
+
+// GraceType marks a note as a grace note, which takes no written time
+type GraceType int
+
+const (
+	GraceNone GraceType = iota
+	Appoggiatura
+	Acciaccatura // slashed, played as short as possible
+)
+
+// parseGraceType converts a JSON grace string to GraceType
+func parseGraceType(s string) GraceType {
+	switch s {
+	case "appoggiatura":
+		return Appoggiatura
+	case "acciaccatura":
+		return Acciaccatura
+	default:
+		return GraceNone
+	}
+}
+
+// graceTypeName converts GraceType to its JSON string
+func graceTypeName(g GraceType) string {
+	switch g {
+	case Appoggiatura:
+		return "appoggiatura"
+	case Acciaccatura:
+		return "acciaccatura"
+	default:
+		return ""
+	}
+}
+
+// Ornament is a mark asking the player to embellish a note
+type Ornament string
+
+const (
+	Trill           Ornament = "trill"
+	Mordent         Ornament = "mordent"          // principal, lower neighbour, principal
+	InvertedMordent Ornament = "inverted_mordent" // principal, upper neighbour, principal
+	Turn            Ornament = "turn"             // upper, principal, lower, principal
+)
+
+// GlyphName returns the SMuFL glyph name for the ornament
+func (o Ornament) GlyphName() string {
+	switch o {
+	case Trill:
+		return "ornamentTrill"
+	case Mordent:
+		return "ornamentMordent"
+	case InvertedMordent:
+		return "ornamentShortTrill"
+	case Turn:
+		return "ornamentTurn"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,73 @@
+package music
+
+// Accidental names used in Note.Accidental
+const (
+	AccidentalNone        = ""
+	AccidentalSharp       = "sharp"
+	AccidentalFlat        = "flat"
+	AccidentalNatural     = "natural"
+	AccidentalDoubleSharp = "double_sharp"
+	AccidentalDoubleFlat  = "double_flat"
+)
+
+// AccidentalAlter returns the chromatic alteration in semitones an
+// accidental stands for. ok is false for unknown accidental names and for
+// the empty accidental, which leaves the alteration to the key signature.
+func AccidentalAlter(accidental string) (alter int, ok bool) {
+	switch accidental {
+	case AccidentalSharp:
+		return 1, true
+	case AccidentalFlat:
+		return -1, true
+	case AccidentalNatural:
+		return 0, true
+	case AccidentalDoubleSharp:
+		return 2, true
+	case AccidentalDoubleFlat:
+		return -2, true
+	default:
+		return 0, false
+	}
+}
+
+// IsValidAccidental reports whether the accidental name is known
+func IsValidAccidental(accidental string) bool {
+	_, ok := AccidentalAlter(accidental)
+	return ok || accidental == AccidentalNone
+}
+
+// AlterAccidental returns the accidental name for an alteration
+func AlterAccidental(alter int) string {
+	switch alter {
+	case 1:
+		return AccidentalSharp
+	case -1:
+		return AccidentalFlat
+	case 2:
+		return AccidentalDoubleSharp
+	case -2:
+		return AccidentalDoubleFlat
+	default:
+		return AccidentalNatural
+	}
+}
+
+// naturalSemitones are the semitones above C of the steps C D E F G A H
+var naturalSemitones = [7]int{0, 2, 4, 5, 7, 9, 11}
+
+// floorDiv divides rounding towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// NaturalMIDI returns the MIDI number of the natural note with the given
+// diatonic number (octave*7 + step, where C4 is 28)
+func NaturalMIDI(diatonic int) int {
+	octave := floorDiv(diatonic, 7)
+	step := diatonic - octave*7
+	return (octave+1)*12 + naturalSemitones[step]
+}
@@ -0,0 +1,43 @@
+package music
+
+// PlaybackOrder returns the measure indices in the order they are played,
+// expanding repeats. A :| without a matching |: goes back to the start of
+// the score or to the measure after the previous repeat. Measures in a
+// first/second ending are only played on the pass with their number.
+func (s *Score) PlaybackOrder() []int {
+	var order []int
+	start := 0
+	pass := 1
+	sectionEnd := -1
+	taken := make(map[int]bool) // repeat ends already jumped back from
+
+	for i := 0; i < len(s.Measures); i++ {
+		m := s.Measures[i]
+		if i > sectionEnd && m.Ending == 0 && pass > 1 {
+			// Past the endings of the previous repeat
+			start, pass = i, 1
+		}
+		if m.RepeatStart && i != start {
+			start, pass = i, 1
+		}
+		if m.Ending != 0 && m.Ending != pass {
+			continue
+		}
+
+		order = append(order, i)
+
+		if m.RepeatEnd {
+			if !taken[i] {
+				taken[i] = true
+				sectionEnd = i
+				pass++
+				i = start - 1
+				continue
+			}
+			if m.Ending == 0 {
+				start, pass = i+1, 1
+			}
+		}
+	}
+	return order
+}
@@ -0,0 +1,135 @@
+package music
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentSchemaVersion is the JSON score schema version written by
+// EncodeScoreJSON. Files without a schema_version are version 1.
+//
+// Version history:
+//
+//	1  unversioned files; a hairpin ends at an element with "hairpin": "stop"
+//	2  schema_version field; hairpins end with "hairpin_stop": true so one
+//	   can end on the element where the next starts; hairpin_placement and
+//	   per-measure time_signature
+//	3  dots, tie and chord on elements, courtesy accidentals, and
+//	   repeat_start, repeat_end and ending on measures. Version 2 files
+//	   load unchanged; the bump keeps older readers from dropping them.
+const CurrentSchemaVersion = 3
+
+// jsonSchema is the published JSON Schema for the current version
+//
+//go:embed schema/score.schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema document describing score files of
+// CurrentSchemaVersion
+func JSONSchema() []byte {
+	return append([]byte(nil), jsonSchema...)
+}
+
+// DecodeOptions controls how score documents are decoded
+type DecodeOptions struct {
+	// Strict rejects fields that are not part of the current schema. Older
+	// versions are checked after migration.
+	Strict bool
+}
+
+// migrations[v] upgrades a raw document from schema version v to v+1
+var migrations = map[int]func(doc map[string]any) error{
+	1: migrateV1,
+	2: migrateV2,
+}
+
+// DecodeJSONScore decodes a score document into its JSON form, migrating
+// older schema versions to CurrentSchemaVersion. Documents from a newer
+// version are rejected.
+func DecodeJSONScore(data []byte, opts DecodeOptions) (*JSONScore, error) {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("score document must be a JSON object")
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than the supported version %d", version, CurrentSchemaVersion)
+	}
+	if version < CurrentSchemaVersion {
+		for v := version; v < CurrentSchemaVersion; v++ {
+			if err := migrations[v](doc); err != nil {
+				return nil, fmt.Errorf("migrating schema version %d: %w", v, err)
+			}
+		}
+		doc["schema_version"] = CurrentSchemaVersion
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	var js JSONScore
+	dec = json.NewDecoder(bytes.NewReader(data))
+	if opts.Strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&js); err != nil {
+		return nil, err
+	}
+	return &js, nil
+}
+
+// documentVersion reads schema_version from a raw document, defaulting to 1
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 1, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("schema_version must be a number, got %v", raw)
+	}
+	v, err := n.Int64()
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid schema_version %s", n)
+	}
+	return int(v), nil
+}
+
+// migrateV1 replaces "hairpin": "stop" with "hairpin_stop": true
+func migrateV1(doc map[string]any) error {
+	measures, _ := doc["measures"].([]any)
+	for mi, m := range measures {
+		measure, ok := m.(map[string]any)
+		if !ok {
+			return fmt.Errorf("measure %d is not an object", mi+1)
+		}
+		elements, _ := measure["elements"].([]any)
+		for _, e := range elements {
+			elem, ok := e.(map[string]any)
+			if !ok {
+				continue
+			}
+			if elem["hairpin"] == "stop" {
+				delete(elem, "hairpin")
+				elem["hairpin_stop"] = true
+			}
+		}
+	}
+	return nil
+}
+
+// migrateV2 has nothing to change: version 3 only adds optional fields
+func migrateV2(doc map[string]any) error {
+	return nil
+}
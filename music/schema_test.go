@@ -0,0 +1,60 @@
+package music
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONScoreMigratesOlderVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"version 1", `{"measures": [{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "quarter", "hairpin": "crescendo"},
+			{"type": "note", "pitch": 62, "duration": "quarter", "hairpin": "stop"}]}]}`},
+		{"version 2", `{"schema_version": 2, "measures": [{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "quarter", "hairpin": "crescendo"},
+			{"type": "note", "pitch": 62, "duration": "quarter", "hairpin_stop": true}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js, err := DecodeJSONScore([]byte(tt.doc), DecodeOptions{Strict: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if js.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("schema version %d, want %d", js.SchemaVersion, CurrentSchemaVersion)
+			}
+			elems := js.Measures[0].Elements
+			if elems[1].Hairpin != "" || !elems[1].HairpinStop {
+				t.Errorf("second element %+v, want it to stop the hairpin", elems[1])
+			}
+		})
+	}
+}
+
+func TestDecodeJSONScoreVersion3Fields(t *testing.T) {
+	doc := `{"schema_version": 3, "measures": [{"number": 1, "repeat_start": true, "repeat_end": true, "ending": 1, "elements": [
+		{"type": "note", "pitch": 61, "duration": "half", "dots": 1, "accidental": "sharp", "courtesy": true, "tie": true},
+		{"type": "note", "pitch": 65, "duration": "half", "dots": 1, "chord": true}]}]}`
+	js, err := DecodeJSONScore([]byte(doc), DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := js.Measures[0]
+	if !m.RepeatStart || !m.RepeatEnd || m.Ending != 1 {
+		t.Errorf("measure %+v, want repeats and the first ending", m)
+	}
+	first, second := m.Elements[0], m.Elements[1]
+	if first.Dots != 1 || !first.Courtesy || !first.Tie || !second.Chord {
+		t.Errorf("elements %+v and %+v lost version 3 fields", first, second)
+	}
+}
+
+func TestDecodeJSONScoreRejectsNewerVersion(t *testing.T) {
+	_, err := DecodeJSONScore([]byte(`{"schema_version": 4, "measures": []}`), DecodeOptions{})
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("got error %v, want a newer-version error", err)
+	}
+}
@@ -21,6 +21,7 @@ type Score struct {
 	TimeSignature TimeSignature
 	Tempo         int
 	Measures      []*Measure
+	Hairpins      []Hairpin
 }
 
 // KeySignature stores tonic and mode info
@@ -37,15 +38,21 @@ type TimeSignature struct {
 
 // Measure is one bar of music containing notes and rests
 type Measure struct {
-	Clef          string
+	Clef          string // clef change at the start of this measure, "" to keep the current clef
 	Number        int
+	Pickup        bool // anacrusis, allowed to be shorter than the time signature
 	Elements      []MusicElement
 	TimeSignature TimeSignature
+	Tuplets       []Tuplet
+	RepeatStart   bool // |: before this measure
+	RepeatEnd     bool // :| after this measure
+	Ending        int  // volta number of a first/second ending, 0 if none
 }
 
 // MusicElement interface implemented by Note and Rest
 type MusicElement interface {
 	GetDuration() NoteValue
+	GetDots() int
 	GlyphName() string
 }
 
@@ -53,14 +60,44 @@ type MusicElement interface {
 type Note struct {
 	Pitch      int // MIDI note number, e.g., 60 = middle C
 	Duration   NoteValue
-	StaffLine  int    // Position on staff, 0 = bottom line, etc.
+	Dots       int    // augmentation dots, each adding half the previous value
+	StaffLine  int    // Position on staff in diatonic steps: 0 = bottom line, 1 = first space, 8 = top line
 	Accidental string // "", "sharp", "flat", "natural"
+	Courtesy   bool   // the accidental is only a reminder, drawn in parentheses
+
+	Dynamic               Dynamic // dynamic marking starting at this note
+	DynamicPlacement      Placement
+	Articulations         []Articulation
+	ArticulationPlacement Placement
+	Ornaments             []Ornament
+	Grace                 GraceType // grace notes take no time in the measure
+	Tie                   bool      // tied to the next note of the same pitch
+	Chord                 bool      // sounds with the previous note and shares its stem
+}
+
+// IsGrace reports whether the note is a grace note
+func (n *Note) IsGrace() bool {
+	return n.Grace != GraceNone
+}
+
+// HasArticulation reports whether the note carries the given articulation
+func (n *Note) HasArticulation(a Articulation) bool {
+	for _, art := range n.Articulations {
+		if art == a {
+			return true
+		}
+	}
+	return false
 }
 
 func (n *Note) GetDuration() NoteValue {
 	return n.Duration
 }
 
+func (n *Note) GetDots() int {
+	return n.Dots
+}
+
 func (n *Note) HasStem() bool {
 	switch n.Duration {
 	case WholeNote:
@@ -97,12 +134,17 @@ func (n *Note) NoteheadGlyphName() string {
 // Rest represents a musical rest
 type Rest struct {
 	Duration NoteValue
+	Dots     int
 }
 
 func (r *Rest) GetDuration() NoteValue {
 	return r.Duration
 }
 
+func (r *Rest) GetDots() int {
+	return r.Dots
+}
+
 // NewScore creates a new score with initial values and empty measure slice
 func NewScore(title, composer, tonic, mode string, timeNum, timeDen, tempo int) *Score {
 	return &Score{
@@ -128,6 +170,7 @@ func (s *Score) AddMeasure(ts *TimeSignature) *Measure {
 		t = *ts
 	}
 	measure := &Measure{
+		Number:        len(s.Measures) + 1,
 		Elements:      make([]MusicElement, 0),
 		TimeSignature: t,
 	}
@@ -135,6 +178,34 @@ func (s *Score) AddMeasure(ts *TimeSignature) *Measure {
 	return measure
 }
 
+// Clone returns a deep copy of the score
+func (s *Score) Clone() *Score {
+	c := *s
+	c.Hairpins = append([]Hairpin(nil), s.Hairpins...)
+	c.Measures = make([]*Measure, len(s.Measures))
+	for i, m := range s.Measures {
+		cm := *m
+		cm.Tuplets = append([]Tuplet(nil), m.Tuplets...)
+		cm.Elements = make([]MusicElement, len(m.Elements))
+		for j, e := range m.Elements {
+			switch el := e.(type) {
+			case *Note:
+				n := *el
+				n.Articulations = append([]Articulation(nil), el.Articulations...)
+				n.Ornaments = append([]Ornament(nil), el.Ornaments...)
+				cm.Elements[j] = &n
+			case *Rest:
+				r := *el
+				cm.Elements[j] = &r
+			default:
+				cm.Elements[j] = e
+			}
+		}
+		c.Measures[i] = &cm
+	}
+	return &c
+}
+
 func (m *Measure) AddNote(note *Note) {
 	m.Elements = append(m.Elements, note)
 }
@@ -163,14 +234,31 @@ func (m *Measure) ElementPositions(width float32, leftMargin float32, rightMargi
 // ElementBeats returns beat lengths for each element in the measure
 func (m *Measure) ElementBeats() []float32 {
 	beats := make([]float32, 0, len(m.Elements))
-	for _, e := range m.Elements {
-		q := durationQuarters(e.GetDuration())
+	for i := range m.Elements {
+		q := float32(m.ElementQuarters(i))
 		b := q * float32(m.TimeSignature.Denominator) / 4.0
 		beats = append(beats, b)
 	}
 	return beats
 }
 
+// Quarters returns the length of the note value in quarter notes
+func (nv NoteValue) Quarters() float32 {
+	return durationQuarters(nv)
+}
+
+// DottedQuarters returns the length in quarter notes of the note value with
+// the given number of augmentation dots
+func (nv NoteValue) DottedQuarters(dots int) float64 {
+	q := float64(durationQuarters(nv))
+	total := q
+	for i := 0; i < dots; i++ {
+		q /= 2
+		total += q
+	}
+	return total
+}
+
 // durationQuarters converts NoteValue to quarter note units
 func durationQuarters(nv NoteValue) float32 {
 	switch nv {
@@ -201,23 +289,51 @@ func noteValueToBeats(nv NoteValue, denominator int) float32 {
 
 // parseDuration converts string duration to NoteValue enum
 func parseDuration(s string) NoteValue {
+	if nv, ok := lookupDuration(s); ok {
+		return nv
+	}
+	return QuarterNote
+}
+
+// lookupDuration converts string duration to NoteValue, reporting unknown names
+func lookupDuration(s string) (NoteValue, bool) {
 	switch s {
 	case "whole":
-		return WholeNote
+		return WholeNote, true
 	case "half":
-		return HalfNote
+		return HalfNote, true
 	case "quarter":
-		return QuarterNote
+		return QuarterNote, true
 	case "eighth":
-		return EighthNote
+		return EighthNote, true
 	case "sixteenth":
-		return SixteenthNote
+		return SixteenthNote, true
 	case "thirtysecond":
-		return ThirtySecondNote
+		return ThirtySecondNote, true
 	case "sixtyfourth":
-		return SixtyFourthNote
+		return SixtyFourthNote, true
+	default:
+		return QuarterNote, false
+	}
+}
+
+// durationName converts NoteValue to its JSON duration string
+func durationName(nv NoteValue) string {
+	switch nv {
+	case WholeNote:
+		return "whole"
+	case HalfNote:
+		return "half"
+	case EighthNote:
+		return "eighth"
+	case SixteenthNote:
+		return "sixteenth"
+	case ThirtySecondNote:
+		return "thirtysecond"
+	case SixtyFourthNote:
+		return "sixtyfourth"
 	default:
-		return QuarterNote
+		return "quarter"
 	}
 }
 
@@ -0,0 +1,79 @@
+package music
+
+import "fmt"
+
+// Tuplet plays Actual notes in the time of Normal ones (3:2 for a triplet)
+// over a run of elements in a measure. Tuplets may nest; an element's
+// duration is scaled by every tuplet that contains it.
+type Tuplet struct {
+	Actual    int
+	Normal    int
+	Start     int // first element index
+	End       int // last element index, inclusive
+	Placement Placement
+	ShowRatio bool // draw "3:2" instead of "3"
+}
+
+// Contains reports whether the element index lies inside the tuplet
+func (t Tuplet) Contains(i int) bool {
+	return i >= t.Start && i <= t.End
+}
+
+// Encloses reports whether t fully contains other (and is not the same span)
+func (t Tuplet) Encloses(other Tuplet) bool {
+	return t.Start <= other.Start && other.End <= t.End && (t.Start != other.Start || t.End != other.End)
+}
+
+// AddTuplet adds a tuplet to the measure. Tuplets must cover existing
+// elements and may nest but not partially overlap.
+func (m *Measure) AddTuplet(t Tuplet) error {
+	if t.Actual <= 0 || t.Normal <= 0 {
+		return fmt.Errorf("invalid tuplet ratio %d:%d", t.Actual, t.Normal)
+	}
+	if t.Start < 0 || t.End < t.Start || t.End >= len(m.Elements) {
+		return fmt.Errorf("tuplet span %d-%d outside measure with %d elements", t.Start, t.End, len(m.Elements))
+	}
+	for _, other := range m.Tuplets {
+		disjoint := t.End < other.Start || other.End < t.Start
+		if !disjoint && !t.Encloses(other) && !other.Encloses(t) {
+			return fmt.Errorf("tuplet span %d-%d overlaps tuplet %d-%d", t.Start, t.End, other.Start, other.End)
+		}
+	}
+	m.Tuplets = append(m.Tuplets, t)
+	return nil
+}
+
+// TupletRatio returns the factor normal/actual applied to the element's
+// written duration, combining nested tuplets
+func (m *Measure) TupletRatio(i int) (normal, actual int) {
+	normal, actual = 1, 1
+	for _, t := range m.Tuplets {
+		if t.Contains(i) {
+			normal *= t.Normal
+			actual *= t.Actual
+		}
+	}
+	return normal, actual
+}
+
+// TupletDepth returns how many tuplets enclose the tuplet at index ti
+func (m *Measure) TupletDepth(ti int) int {
+	depth := 0
+	for j, other := range m.Tuplets {
+		if j != ti && other.Encloses(m.Tuplets[ti]) {
+			depth++
+		}
+	}
+	return depth
+}
+
+// ElementQuarters returns the time element i advances the measure by, in
+// quarter notes. Grace notes and chord notes after the first take no time.
+func (m *Measure) ElementQuarters(i int) float64 {
+	if n, ok := m.Elements[i].(*Note); ok && (n.IsGrace() || n.Chord) {
+		return 0
+	}
+	normal, actual := m.TupletRatio(i)
+	e := m.Elements[i]
+	return e.GetDuration().DottedQuarters(e.GetDots()) * float64(normal) / float64(actual)
+}
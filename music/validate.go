@@ -0,0 +1,229 @@
+package music
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DiagnosticKind classifies a problem found by validation
+type DiagnosticKind int
+
+const (
+	OverfullMeasure DiagnosticKind = iota
+	UnderfullMeasure
+	UnknownDuration
+	UnknownElementType
+	StaffLinePitchMismatch
+	InvalidAccidental
+	UnknownClef
+	DanglingChordNote
+	UnmatchedTie
+)
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case OverfullMeasure:
+		return "overfull measure"
+	case UnderfullMeasure:
+		return "underfull measure"
+	case UnknownDuration:
+		return "unknown duration"
+	case UnknownElementType:
+		return "unknown element type"
+	case StaffLinePitchMismatch:
+		return "staff line/pitch mismatch"
+	case InvalidAccidental:
+		return "invalid accidental"
+	case UnknownClef:
+		return "unknown clef"
+	case DanglingChordNote:
+		return "chord note without a note"
+	case UnmatchedTie:
+		return "unmatched tie"
+	default:
+		return "unknown problem"
+	}
+}
+
+// Diagnostic is one problem found by validation
+type Diagnostic struct {
+	Measure int // measure number
+	Element int // element index within the measure, -1 for the measure as a whole
+	Kind    DiagnosticKind
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Element < 0 {
+		return fmt.Sprintf("measure %d: %s: %s", d.Measure, d.Kind, d.Message)
+	}
+	return fmt.Sprintf("measure %d, element %d: %s: %s", d.Measure, d.Element, d.Kind, d.Message)
+}
+
+// Diagnostics is a list of validation problems. It implements error so it
+// can be returned where a single error is expected.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// measureTolerance absorbs float rounding when summing tuplet durations
+const measureTolerance = 1e-6
+
+// Validate checks the score for measures whose durations do not add up to
+// the time signature, notes whose pitch cannot be written on their staff
+// line, unknown accidentals and clefs, chord notes without a note to join
+// and ties without a note to continue into. Measures marked as pickups may
+// be shorter than the time signature.
+func (s *Score) Validate() Diagnostics {
+	var ds Diagnostics
+	for mi := range s.Measures {
+		ds = append(ds, s.validateMeasure(mi)...)
+	}
+	return ds
+}
+
+// validateMeasure runs the Validate checks on the measure at index mi
+func (s *Score) validateMeasure(mi int) Diagnostics {
+	var ds Diagnostics
+	m := s.Measures[mi]
+
+	if m.Clef != "" && !IsValidClef(m.Clef) {
+		ds = append(ds, Diagnostic{Measure: m.Number, Element: -1, Kind: UnknownClef,
+			Message: fmt.Sprintf("clef %q", m.Clef)})
+	}
+	clef := s.ClefAt(mi)
+
+	if m.TimeSignature.Denominator > 0 {
+		expected := float64(m.TimeSignature.Numerator) * 4 / float64(m.TimeSignature.Denominator)
+		actual := 0.0
+		for i := range m.Elements {
+			actual += m.ElementQuarters(i)
+		}
+		switch {
+		case actual > expected+measureTolerance:
+			ds = append(ds, Diagnostic{Measure: m.Number, Element: -1, Kind: OverfullMeasure,
+				Message: fmt.Sprintf("%g quarters in %d/%d", actual, m.TimeSignature.Numerator, m.TimeSignature.Denominator)})
+		case actual < expected-measureTolerance && !m.Pickup:
+			ds = append(ds, Diagnostic{Measure: m.Number, Element: -1, Kind: UnderfullMeasure,
+				Message: fmt.Sprintf("%g quarters in %d/%d", actual, m.TimeSignature.Numerator, m.TimeSignature.Denominator)})
+		}
+	}
+
+	for ei, e := range m.Elements {
+		note, ok := e.(*Note)
+		if !ok {
+			continue
+		}
+		if !IsValidAccidental(note.Accidental) {
+			ds = append(ds, Diagnostic{Measure: m.Number, Element: ei, Kind: InvalidAccidental,
+				Message: fmt.Sprintf("accidental %q", note.Accidental)})
+		}
+		// Staff lines mean nothing in an unknown clef, which is reported above
+		if d, ok := checkStaffLine(note, clef); !ok && IsValidClef(clef) {
+			ds = append(ds, Diagnostic{Measure: m.Number, Element: ei, Kind: StaffLinePitchMismatch, Message: d})
+		}
+		if note.Chord {
+			if prev, ok := previousNote(m, ei); !ok || prev.IsGrace() != note.IsGrace() {
+				ds = append(ds, Diagnostic{Measure: m.Number, Element: ei, Kind: DanglingChordNote,
+					Message: "chord note does not follow a note"})
+			}
+		}
+		if note.Tie {
+			if _, ok := s.TieTarget(ElementRef{Measure: mi, Element: ei}); !ok {
+				ds = append(ds, Diagnostic{Measure: m.Number, Element: ei, Kind: UnmatchedTie,
+					Message: fmt.Sprintf("no following note with pitch %d", note.Pitch)})
+			}
+		}
+	}
+
+	return ds
+}
+
+// previousNote returns element ei-1 if it is a note
+func previousNote(m *Measure, ei int) (*Note, bool) {
+	if ei == 0 {
+		return nil, false
+	}
+	n, ok := m.Elements[ei-1].(*Note)
+	return n, ok
+}
+
+// checkStaffLine verifies that the note's pitch is its staff position's
+// natural note altered by at most a double accidental, and by exactly the
+// written accidental when there is one
+func checkStaffLine(note *Note, clef string) (string, bool) {
+	natural := NaturalPitchAt(clef, note.StaffLine)
+	alter := note.Pitch - natural
+	if alter < -2 || alter > 2 {
+		return fmt.Sprintf("pitch %d is %d semitones from %d on staff line %d in %s clef",
+			note.Pitch, alter, natural, note.StaffLine, clef), false
+	}
+	if written, ok := AccidentalAlter(note.Accidental); ok && written != alter {
+		return fmt.Sprintf("pitch %d on staff line %d in %s clef needs an alteration of %d, but the accidental is %s",
+			note.Pitch, note.StaffLine, clef, alter, note.Accidental), false
+	}
+	return "", true
+}
+
+// Validate checks the JSON score for unknown element types and durations,
+// which LoadScoreFromJSON silently drops or reads as quarter notes, and then
+// runs Score.Validate. Element indices refer to the JSON elements arrays.
+func (js *JSONScore) Validate() (*Score, Diagnostics, error) {
+	var ds Diagnostics
+
+	for mi, jm := range js.Measures {
+		number := jm.Number
+		if number == 0 {
+			number = mi + 1
+		}
+		for ei, elem := range jm.Elements {
+			switch elem.Type {
+			case "note", "rest":
+				if _, ok := lookupDuration(elem.Duration); !ok {
+					ds = append(ds, Diagnostic{Measure: number, Element: ei, Kind: UnknownDuration,
+						Message: fmt.Sprintf("duration %q", elem.Duration)})
+				}
+			default:
+				ds = append(ds, Diagnostic{Measure: number, Element: ei, Kind: UnknownElementType,
+					Message: fmt.Sprintf("type %q", elem.Type)})
+			}
+		}
+	}
+
+	score, jsonIndex, err := js.buildScore()
+	if err != nil {
+		return nil, ds, err
+	}
+
+	for mi := range score.Measures {
+		for _, d := range score.validateMeasure(mi) {
+			if d.Element >= 0 && d.Element < len(jsonIndex[mi]) {
+				d.Element = jsonIndex[mi][d.Element]
+			}
+			ds = append(ds, d)
+		}
+	}
+	return score, ds, nil
+}
+
+// LoadAndValidateScore loads a JSON score like LoadScoreFromJSON and also
+// returns the problems found in it
+func LoadAndValidateScore(path string) (*Score, Diagnostics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	js, err := DecodeJSONScore(data, DecodeOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return js.Validate()
+}
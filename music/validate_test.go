@@ -0,0 +1,130 @@
+package music
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// diagnosed is a diagnostic without its message
+type diagnosed struct {
+	measure, element int
+	kind             DiagnosticKind
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		measures string
+		want     []diagnosed
+	}{
+		{"full measure", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "quarter", "staff_line": -2},
+			{"type": "rest", "duration": "quarter"}]}`, nil},
+		{"overfull", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2},
+			{"type": "rest", "duration": "eighth"}]}`, []diagnosed{{1, -1, OverfullMeasure}}},
+		{"underfull", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "quarter", "dots": 1, "staff_line": -2}]}`, []diagnosed{{1, -1, UnderfullMeasure}}},
+		{"pickup may be short", `{"number": 1, "pickup": true, "elements": [
+			{"type": "note", "pitch": 67, "duration": "eighth", "staff_line": 2}]}`, nil},
+		{"pickup may not be long", `{"number": 1, "pickup": true, "elements": [
+			{"type": "note", "pitch": 67, "duration": "whole", "staff_line": 2}]}`, []diagnosed{{1, -1, OverfullMeasure}}},
+		{"measure's own time signature", `{"number": 1, "time_signature": {"numerator": 3, "denominator": 8}, "elements": [
+			{"type": "note", "pitch": 60, "duration": "quarter", "dots": 1, "staff_line": -2}]}`, nil},
+		{"triplet fills a beat", `{"number": 1, "tuplets": [{"actual": 3, "normal": 2, "start": 0, "end": 2}], "elements": [
+			{"type": "note", "pitch": 60, "duration": "eighth", "staff_line": -2},
+			{"type": "note", "pitch": 62, "duration": "eighth", "staff_line": -1},
+			{"type": "note", "pitch": 64, "duration": "eighth", "staff_line": 0},
+			{"type": "rest", "duration": "quarter"}]}`, nil},
+		{"chord and grace notes take no time", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 62, "duration": "eighth", "staff_line": -1, "grace": "acciaccatura"},
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2},
+			{"type": "note", "pitch": 64, "duration": "half", "staff_line": 0, "chord": true}]}`, nil},
+		{"unknown duration", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2},
+			{"type": "rest", "duration": "crotchet"}]}`, []diagnosed{{1, 1, UnknownDuration}, {1, -1, OverfullMeasure}}},
+		{"unknown element type, later indices kept", `{"number": 1, "elements": [
+			{"type": "lyric", "duration": "quarter"},
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2, "accidental": "sharp"}]}`,
+			[]diagnosed{{1, 0, UnknownElementType}, {1, 1, StaffLinePitchMismatch}}},
+		{"pitch too far from the staff line", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 64, "duration": "half", "staff_line": -2}]}`, []diagnosed{{1, 0, StaffLinePitchMismatch}}},
+		{"unwritten alteration is allowed", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 66, "duration": "half", "staff_line": 1}]}`, nil},
+		{"invalid accidental", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 66, "duration": "half", "staff_line": 1, "accidental": "sharpish"}]}`, []diagnosed{{1, 0, InvalidAccidental}}},
+		{"bass clef", `{"number": 1, "clef": "bass", "elements": [
+			{"type": "note", "pitch": 43, "duration": "half", "staff_line": 0}]}`, nil},
+		{"unknown clef", `{"number": 1, "clef": "soprano", "elements": [
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2}]}`, []diagnosed{{1, -1, UnknownClef}}},
+		{"dangling chord note", `{"number": 1, "elements": [
+			{"type": "rest", "duration": "quarter"},
+			{"type": "note", "pitch": 60, "duration": "quarter", "staff_line": -2, "chord": true},
+			{"type": "note", "pitch": 60, "duration": "quarter", "staff_line": -2}]}`, []diagnosed{{1, 1, DanglingChordNote}}},
+		{"chord of a grace note and a note", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 62, "duration": "eighth", "staff_line": -1, "grace": "appoggiatura"},
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2, "chord": true}]}`,
+			[]diagnosed{{1, -1, UnderfullMeasure}, {1, 1, DanglingChordNote}}},
+		{"tie over the barline", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2, "tie": true}]},
+			{"number": 2, "elements": [
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2}]}`, nil},
+		{"tie to another pitch", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "quarter", "staff_line": -2, "tie": true},
+			{"type": "note", "pitch": 62, "duration": "quarter", "staff_line": -1}]}`, []diagnosed{{1, 0, UnmatchedTie}}},
+		{"tie at the end", `{"number": 1, "elements": [
+			{"type": "note", "pitch": 60, "duration": "half", "staff_line": -2, "tie": true}]}`, []diagnosed{{1, 0, UnmatchedTie}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := `{"schema_version": 3, "time_signature": {"numerator": 2, "denominator": 4}, "measures": [` + tt.measures + `]}`
+			js, err := DecodeJSONScore([]byte(doc), DecodeOptions{Strict: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, ds, err := js.Validate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ds) != len(tt.want) {
+				t.Fatalf("got %v, want %v", ds, tt.want)
+			}
+			for i, d := range ds {
+				if got := (diagnosed{d.Measure, d.Element, d.Kind}); got != tt.want[i] {
+					t.Errorf("diagnostic %d is %v, want %v", i, d, tt.want[i])
+				}
+				if d.Message == "" {
+					t.Errorf("diagnostic %d has no message", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	ds := Diagnostics{
+		{Measure: 2, Element: -1, Kind: UnderfullMeasure, Message: "1 quarters in 2/4"},
+		{Measure: 3, Element: 1, Kind: UnmatchedTie, Message: "no following note with pitch 60"},
+	}
+	want := "measure 2: underfull measure: 1 quarters in 2/4\n" +
+		"measure 3, element 1: unmatched tie: no following note with pitch 60"
+	if got := ds.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBundledScoresValidate(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "assets", "scores", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no bundled scores: %v", err)
+	}
+	for _, path := range paths {
+		_, ds, err := LoadAndValidateScore(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		if len(ds) > 0 {
+			t.Errorf("%s:\n%v", path, ds)
+		}
+	}
+}
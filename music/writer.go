@@ -0,0 +1,133 @@
+package music
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ToJSON converts the score to its JSON form at CurrentSchemaVersion.
+// Loading the result gives back an equal score, except that an opening
+// measure without a clef comes back with the treble clef it is drawn in.
+func (s *Score) ToJSON() *JSONScore {
+	js := &JSONScore{
+		SchemaVersion: CurrentSchemaVersion,
+		Title:         s.Title,
+		Composer:      s.Composer,
+		KeySignature:  JSONKeySignature{Tonic: s.KeySignature.Tonic, Mode: s.KeySignature.Mode},
+		TimeSignature: JSONTimeSignature{Numerator: s.TimeSignature.Numerator, Denominator: s.TimeSignature.Denominator},
+		Tempo:         s.Tempo,
+		Measures:      make([]JSONMeasure, 0, len(s.Measures)),
+	}
+
+	hairpinStarts := make(map[ElementRef]Hairpin, len(s.Hairpins))
+	hairpinEnds := make(map[ElementRef]bool, len(s.Hairpins))
+	for _, hp := range s.Hairpins {
+		hairpinStarts[hp.Start] = hp
+		hairpinEnds[hp.End] = true
+	}
+
+	for mi, m := range s.Measures {
+		jm := JSONMeasure{
+			Number:      m.Number,
+			Clef:        m.Clef,
+			Pickup:      m.Pickup,
+			RepeatStart: m.RepeatStart,
+			RepeatEnd:   m.RepeatEnd,
+			Ending:      m.Ending,
+			Elements:    make([]JSONElement, 0, len(m.Elements)),
+		}
+		if mi == 0 {
+			// The opening clef is stored with the score
+			js.Clef = s.ClefAt(0)
+			jm.Clef = ""
+		}
+		if m.TimeSignature != s.TimeSignature {
+			jm.TimeSignature = &JSONTimeSignature{Numerator: m.TimeSignature.Numerator, Denominator: m.TimeSignature.Denominator}
+		}
+
+		for ei, e := range m.Elements {
+			var elem JSONElement
+			switch e := e.(type) {
+			case *Note:
+				elem = noteToJSON(e)
+			case *Rest:
+				elem = JSONElement{Type: "rest", Duration: durationName(e.Duration), Dots: e.Dots}
+			default:
+				continue
+			}
+
+			ref := ElementRef{Measure: mi, Element: ei}
+			elem.HairpinStop = hairpinEnds[ref]
+			if hp, ok := hairpinStarts[ref]; ok {
+				elem.Hairpin = hairpinTypeName(hp.Type)
+				elem.HairpinPlacement = placementName(hp.Placement)
+				if elem.HairpinPlacement == "" && elem.DynamicPlacement != "" {
+					// Otherwise the hairpin would take the dynamic's placement
+					elem.HairpinPlacement = "auto"
+				}
+			}
+			jm.Elements = append(jm.Elements, elem)
+		}
+
+		for _, t := range m.Tuplets {
+			jm.Tuplets = append(jm.Tuplets, JSONTuplet{
+				Actual:    t.Actual,
+				Normal:    t.Normal,
+				Start:     t.Start,
+				End:       t.End,
+				Placement: placementName(t.Placement),
+				ShowRatio: t.ShowRatio,
+			})
+		}
+		js.Measures = append(js.Measures, jm)
+	}
+	return js
+}
+
+// noteToJSON converts a note and its marks to a JSON element
+func noteToJSON(n *Note) JSONElement {
+	elem := JSONElement{
+		Type:                  "note",
+		Pitch:                 n.Pitch,
+		Duration:              durationName(n.Duration),
+		Dots:                  n.Dots,
+		StaffLine:             n.StaffLine,
+		Accidental:            n.Accidental,
+		Courtesy:              n.Courtesy,
+		Dynamic:               string(n.Dynamic),
+		DynamicPlacement:      placementName(n.DynamicPlacement),
+		ArticulationPlacement: placementName(n.ArticulationPlacement),
+		Grace:                 graceTypeName(n.Grace),
+		Tie:                   n.Tie,
+		Chord:                 n.Chord,
+	}
+	for _, a := range n.Articulations {
+		elem.Articulations = append(elem.Articulations, string(a))
+	}
+	for _, o := range n.Ornaments {
+		elem.Ornaments = append(elem.Ornaments, string(o))
+	}
+	return elem
+}
+
+// EncodeScoreJSON encodes the score as an indented JSON document
+func EncodeScoreJSON(s *Score) ([]byte, error) {
+	data, err := json.MarshalIndent(s.ToJSON(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// SaveScoreToJSON writes the score to a JSON file at CurrentSchemaVersion
+func SaveScoreToJSON(s *Score, path string) error {
+	data, err := EncodeScoreJSON(s)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write score: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package music
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// roundTrip encodes the score and decodes it again
+func roundTrip(t *testing.T, s *Score) *Score {
+	t.Helper()
+	data, err := EncodeScoreJSON(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := DecodeScoreJSON(data, DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, data)
+	}
+	return back
+}
+
+// everything uses each field of the current schema
+const everything = `{"schema_version": 3, "title": "Alt", "composer": "Ingen", "clef": "bass",
+	"key_signature": {"tonic": "ess", "mode": "moll"}, "time_signature": {"numerator": 3, "denominator": 4}, "tempo": 80,
+	"measures": [
+	{"number": 0, "pickup": true, "repeat_start": true, "elements": [
+		{"type": "note", "pitch": 51, "duration": "quarter", "staff_line": 3, "dynamic": "p", "dynamic_placement": "below",
+		 "hairpin": "crescendo", "hairpin_placement": "auto"}]},
+	{"number": 1, "elements": [
+		{"type": "note", "pitch": 53, "duration": "eighth", "staff_line": 4, "grace": "acciaccatura"},
+		{"type": "note", "pitch": 55, "duration": "eighth", "staff_line": 5, "articulations": ["staccato", "accent"], "articulation_placement": "above"},
+		{"type": "note", "pitch": 56, "duration": "eighth", "staff_line": 6},
+		{"type": "note", "pitch": 58, "duration": "eighth", "staff_line": 7, "hairpin_stop": true},
+		{"type": "note", "pitch": 58, "duration": "half", "dots": 1, "staff_line": 7, "ornaments": ["trill"], "hairpin": "diminuendo"},
+		{"type": "note", "pitch": 62, "duration": "half", "dots": 1, "staff_line": 9, "chord": true, "accidental": "natural", "courtesy": true, "tie": true}],
+	 "tuplets": [{"actual": 3, "normal": 2, "start": 1, "end": 3, "placement": "above", "show_ratio": true}]},
+	{"number": 2, "clef": "treble", "time_signature": {"numerator": 2, "denominator": 4}, "repeat_end": true, "ending": 1, "elements": [
+		{"type": "note", "pitch": 74, "duration": "quarter", "staff_line": 5, "hairpin_stop": true},
+		{"type": "rest", "duration": "quarter"}]},
+	{"number": 3, "ending": 2, "elements": [
+		{"type": "rest", "duration": "half"}]}]}`
+
+func TestScoreJSONRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "assets", "scores", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no bundled scores: %v", err)
+	}
+	scores := map[string]*Score{}
+	for _, path := range paths {
+		s, err := LoadScoreFromJSON(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scores[filepath.Base(path)] = s
+	}
+	s, err := DecodeScoreJSON([]byte(everything), DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores["every field"] = s
+
+	for name, s := range scores {
+		t.Run(name, func(t *testing.T) {
+			if back := roundTrip(t, s); !reflect.DeepEqual(back, s) {
+				t.Errorf("got\n%+v\nwant\n%+v", back, s)
+			}
+		})
+	}
+}
+
+func TestToJSONWritesTheOpeningClef(t *testing.T) {
+	s := NewScore("", "", "C", "dur", 2, 4, 90)
+	s.AddMeasure(nil).AddRest(&Rest{Duration: HalfNote})
+	if got := s.ToJSON().Clef; got != TrebleClef {
+		t.Errorf("opening clef written as %q, want %q", got, TrebleClef)
+	}
+
+	s.Measures[0].Clef = AltoClef
+	js := s.ToJSON()
+	if js.Clef != AltoClef || js.Measures[0].Clef != "" {
+		t.Errorf("clef %q and first measure clef %q, want the alto clef with the score", js.Clef, js.Measures[0].Clef)
+	}
+	if back := roundTrip(t, s); back.Measures[0].Clef != AltoClef {
+		t.Errorf("clef %q after loading, want %q", back.Measures[0].Clef, AltoClef)
+	}
+}
@@ -0,0 +1,87 @@
+package musicfont
+
+import (
+	"fmt"
+)
+
+// FillRule decides which parts of a shape's contours are inside, as the SVG
+// fill-rule property does
+type FillRule int
+
+const (
+	FillNonZero FillRule = iota // the SVG default
+	FillEvenOdd
+	FillNone // outline only, as for fill="none"
+)
+
+// Contour is one outline of a custom glyph, in staff spaces with y pointing up
+type Contour struct {
+	Points [][2]float64
+	Closed bool
+	Fill   FillRule
+	Shape  int // contours of the same shape are filled together, so one can cut a hole in another
+}
+
+// CustomGlyph is a symbol imported from outside the SMuFL font, such as a
+// Figurenotes shape or a pictogram. Coordinates follow the SMuFL metadata
+// conventions: staff spaces, origin at the glyph's left edge on the baseline.
+type CustomGlyph struct {
+	Name     string
+	Contours []Contour
+	BBox     GlyphBBox
+	Anchors  map[string][2]float64
+}
+
+// RegisterCustomGlyph adds a custom glyph to the font's lookups. Its bounding
+// box and anchors are also stored in BoundingBoxes and Anchors so layout code
+// can treat custom and SMuFL glyphs the same way.
+func (mf *MusicFont) RegisterCustomGlyph(g *CustomGlyph) error {
+	if g == nil || g.Name == "" {
+		return fmt.Errorf("custom glyph has no name")
+	}
+	if _, ok := mf.CustomGlyphs[g.Name]; ok {
+		return fmt.Errorf("custom glyph %q is already registered", g.Name)
+	}
+	if mf.isSMuFLName(g.Name) {
+		return fmt.Errorf("custom glyph %q clashes with a SMuFL glyph", g.Name)
+	}
+
+	if mf.CustomGlyphs == nil {
+		mf.CustomGlyphs = make(map[string]*CustomGlyph)
+	}
+	mf.CustomGlyphs[g.Name] = g
+
+	if mf.BoundingBoxes == nil {
+		mf.BoundingBoxes = make(map[string]GlyphBBox)
+	}
+	mf.BoundingBoxes[g.Name] = g.BBox
+
+	if len(g.Anchors) > 0 {
+		if mf.Anchors == nil {
+			mf.Anchors = make(Anchors)
+		}
+		mf.Anchors[g.Name] = g.Anchors
+	}
+	return nil
+}
+
+// isSMuFLName reports whether name is a glyph of the SMuFL metadata or the
+// font's own, including glyphs the loaded font has no codepoint for
+func (mf *MusicFont) isSMuFLName(name string) bool {
+	if _, ok := mf.GlyphMap[name]; ok {
+		return true
+	}
+	if mf.Metadata != nil {
+		if _, ok := mf.Metadata.Glyphs[name]; ok {
+			return true
+		}
+	}
+	_, ok := mf.BoundingBoxes[name]
+	return ok
+}
+
+// GetCustomGlyph looks up a registered custom glyph by name
+func (mf *MusicFont) GetCustomGlyph(name string) (*CustomGlyph, bool) {
+	g, ok := mf.CustomGlyphs[name]
+	return g, ok
+}
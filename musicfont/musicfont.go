@@ -18,6 +18,7 @@ type MusicFont struct {
 	EngravingDefaults *EngravingDefaults
 	BoundingBoxes     map[string]GlyphBBox
 	GlyphMap          map[string]*Glyph
+	CustomGlyphs      map[string]*CustomGlyph
 }
 
 // EngravingDefaults represents the engravingDefaults JSON object with correct types.
@@ -0,0 +1,1335 @@
+package notation
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// ParseABC reads the part of ABC 2.1 a single-staff score can hold. A tune
+// starts at an X: field and ends at a blank line; fields before the first
+// tune apply to every tune. The header fields read are T: (the first title),
+// C:, M:, L:, Q: and K:, which ends the header and may name a clef
+// (K:G clef=bass).
+//
+// In the body, notes carry accidentals (^ ^^ _ __ =) that last to the end of
+// the bar, octave marks (C is middle C, c the octave above, ' and , move an
+// octave) and lengths in units of L: (A2, A/, A//, A3/2). z and x are rests
+// and Z4 is four bars of rest. > and < make broken rhythms, - ties, [CEG] is
+// a chord and (3 or (p:q:r a tuplet. {g} and {/g} are grace notes. The bar
+// lines | || [| |] |: :| :: and the endings [1 |1 :|2 set repeats; an ending
+// lasts until a double bar, a repeat sign or the next ending.
+//
+// Decorations !p! !mf! ..., !trill! !mordent! !turn!, !staccato! !accent!
+// !tenuto!, !<(! !<)! !>(! !>)! (or !crescendo(! ...) and the shorthands .
+// T M P L are kept; other decorations, chord symbols, annotations, lyrics,
+// slurs and fields other than [K:] [M:] [L:] [Q:] in the body are skipped.
+// Only the first voice is read. Lengths that no single note value can
+// show are written as tied notes, and an incomplete first bar becomes a
+// pickup.
+
+// fraction is a length as a fraction of a whole note
+type fraction struct {
+	num, den int
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func newFraction(num, den int) fraction {
+	g := gcd(num, den)
+	if g == 0 {
+		return fraction{0, 1}
+	}
+	return fraction{num / g, den / g}
+}
+
+func (f fraction) add(g fraction) fraction {
+	return newFraction(f.num*g.den+g.num*f.den, f.den*g.den)
+}
+
+func (f fraction) sub(g fraction) fraction {
+	return newFraction(f.num*g.den-g.num*f.den, f.den*g.den)
+}
+
+func (f fraction) mul(g fraction) fraction {
+	return newFraction(f.num*g.num, f.den*g.den)
+}
+
+// cmp returns -1, 0 or 1 as f is less than, equal to or greater than g
+func (f fraction) cmp(g fraction) int {
+	a, b := f.num*g.den, g.num*f.den
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (f fraction) String() string {
+	if f.den == 1 {
+		return strconv.Itoa(f.num)
+	}
+	return fmt.Sprintf("%d/%d", f.num, f.den)
+}
+
+// noteLength is the length of a note value with dots
+func noteLength(nv music.NoteValue, dots int) fraction {
+	return newFraction(1<<(dots+1)-1, 1<<(int(nv)+dots))
+}
+
+// lengthPiece is one note value of a length split into tied notes
+type lengthPiece struct {
+	value music.NoteValue
+	dots  int
+}
+
+// maxTiedPieces bounds how many tied notes one written length may need
+const maxTiedPieces = 16
+
+// splitLength writes a length as note values to be tied, longest first.
+// ok is false for lengths that do not come out even in 64th notes.
+func splitLength(l fraction) ([]lengthPiece, bool) {
+	var pieces []lengthPiece
+	for l.num > 0 {
+		found := false
+		for nv := music.WholeNote; nv <= music.SixtyFourthNote && !found; nv++ {
+			for dots := 2; dots >= 0; dots-- {
+				if v := noteLength(nv, dots); v.cmp(l) <= 0 {
+					pieces = append(pieces, lengthPiece{nv, dots})
+					l = l.sub(v)
+					found = true
+					break
+				}
+			}
+		}
+		if !found || len(pieces) > maxTiedPieces {
+			return nil, false
+		}
+	}
+	return pieces, len(pieces) > 0
+}
+
+// abcModes maps K: mode abbreviations to the Norwegian mode names
+var abcModes = map[string]string{
+	"maj": "dur",
+	"ion": "ionisk",
+	"min": "moll",
+	"aeo": "æolisk",
+	"dor": "dorisk",
+	"phr": "frygisk",
+	"lyd": "lydisk",
+	"mix": "miksisk",
+	"loc": "lokrisk",
+}
+
+// lookupABCMode reads a mode such as "", "m", "min", "Dorian" or "mix"
+func lookupABCMode(s string) (string, bool) {
+	s = strings.ToLower(s)
+	switch {
+	case s == "":
+		return "dur", true
+	case s == "m":
+		return "moll", true
+	case len(s) < 3:
+		return "", false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return "", false
+		}
+	}
+	m, ok := abcModes[s[:3]]
+	return m, ok
+}
+
+// abcAccidentals are the alterations of the accidental signs
+var abcAccidentals = map[string]int{"^": 1, "^^": 2, "_": -1, "__": -2, "=": 0}
+
+// abcClefs maps the clef names ABC uses to score clefs
+var abcClefs = map[string]string{
+	"treble": music.TrebleClef,
+	"bass":   music.BassClef,
+	"alto":   music.AltoClef,
+	"tenor":  music.TenorClef,
+}
+
+var abcArticulations = map[string]music.Articulation{
+	"staccato": music.Staccato,
+	"wedge":    music.Staccatissimo,
+	"accent":   music.Accent,
+	"emphasis": music.Accent,
+	">":        music.Accent,
+	"marcato":  music.Marcato,
+	"^":        music.Marcato,
+	"tenuto":   music.Tenuto,
+}
+
+var abcOrnaments = map[string]music.Ornament{
+	"trill":        music.Trill,
+	"mordent":      music.Mordent,
+	"lowermordent": music.Mordent,
+	"uppermordent": music.InvertedMordent,
+	"pralltriller": music.InvertedMordent,
+	"turn":         music.Turn,
+}
+
+// abcShorthands are the single-character decorations
+var abcShorthands = map[rune]string{
+	'.': "staccato",
+	'T': "trill",
+	'M': "lowermordent",
+	'P': "uppermordent",
+	'L': "accent",
+	'H': "fermata",
+	'O': "coda",
+	'S': "segno",
+	'u': "upbow",
+	'v': "downbow",
+	'~': "roll",
+}
+
+func isNoteLetter(r rune) bool {
+	return (r >= 'A' && r <= 'G') || (r >= 'a' && r <= 'g')
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// abcField is an information field such as "M:3/4", with the position of
+// its value
+type abcField struct {
+	name      rune
+	value     string
+	line, col int
+}
+
+// splitABCField recognises a field line
+func splitABCField(text string) (abcField, bool) {
+	runes := []rune(text)
+	if len(runes) < 2 || runes[1] != ':' || runes[0] > unicode.MaxASCII ||
+		!(unicode.IsLetter(runes[0]) || runes[0] == '+') {
+		return abcField{}, false
+	}
+	value := string(runes[2:])
+	col := 3 + len([]rune(value)) - len([]rune(strings.TrimLeft(value, " \t")))
+	return abcField{name: runes[0], value: strings.TrimSpace(value), col: col}, true
+}
+
+// stripABCComment removes a % comment, keeping \% as text
+func stripABCComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '%' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// abcMarks are decorations waiting for the next note
+type abcMarks struct {
+	dynamic       music.Dynamic
+	articulations []music.Articulation
+	ornaments     []music.Ornament
+	hairpinStart  bool
+	hairpinType   music.HairpinType
+	hairpinEnd    bool
+}
+
+// abcTuplet is an open (p:q:r group
+type abcTuplet struct {
+	actual, normal int
+	remaining      int // notes still to come
+	first, last    music.ElementRef
+	placed         bool // whether first and last are set
+	line, col      int
+}
+
+// abcNote is a note as read, before it is placed in a measure
+type abcNote struct {
+	diatonic   int
+	pitch      int
+	accidental string // written accidental, if any
+	length     fraction
+	tie        bool
+}
+
+type abcParser struct {
+	score  *music.Score
+	line   int
+	src    []rune // body line being read
+	pos    int
+	inBody bool
+
+	unit        fraction // L:, zero until given
+	meter       music.TimeSignature
+	freeMeter   bool
+	keyAlters   [7]int
+	clef        string
+	pendingClef string // clef change for the next measure
+
+	voice        string // the voice kept, "" before any V: field
+	currentVoice string
+
+	measure     *music.Measure
+	barAlters   map[int]int // accidentals so far in the bar, by diatonic number
+	repeatStart bool        // |: seen, for the next measure
+	ending      int         // ending number of the measures being read
+
+	marks     abcMarks
+	hairpin   *music.Hairpin
+	tuplets   []*abcTuplet
+	grace     music.GraceType
+	graceLine int
+	graceCol  int
+	broken    fraction // length factor for the next note after > or <
+	last      music.ElementRef
+	lastNotes []*music.Note // for a - that stands apart from its note
+	placed    bool          // whether any element has been placed
+}
+
+// LoadABCFile reads every tune in an ABC file
+func LoadABCFile(path string) ([]*music.Score, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := ParseABC(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return scores, nil
+}
+
+// ParseABC parses the ABC tunes in src, described at the top of this file,
+// into one score per tune. Errors are *ParseError values.
+func ParseABC(src string) ([]*music.Score, error) {
+	var scores []*music.Score
+	var fileHeader []abcField
+	var p *abcParser
+
+	finish := func() error {
+		if p == nil {
+			return nil
+		}
+		if err := p.finish(); err != nil {
+			return err
+		}
+		scores = append(scores, p.score)
+		p = nil
+		return nil
+	}
+
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimSuffix(raw, "\r")
+		line := i + 1
+		if strings.TrimSpace(raw) == "" {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		text := stripABCComment(raw)
+		f, isField := splitABCField(text)
+		f.line = line
+
+		if isField && f.name == 'X' {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			p = newABCParser()
+			for _, hf := range fileHeader {
+				if err := p.field(hf); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if p == nil {
+			// Text between tunes is allowed; fields there apply to all tunes
+			if isField && strings.ContainsRune("CMLQ", f.name) {
+				fileHeader = append(fileHeader, f)
+			}
+			continue
+		}
+
+		p.line = line
+		var err error
+		if isField {
+			err = p.field(f)
+		} else {
+			err = p.parseMusic(text)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return nil, &ParseError{Line: 1, Column: 1, Message: "no tune found: tunes start with an X: field"}
+	}
+	return scores, nil
+}
+
+func newABCParser() *abcParser {
+	return &abcParser{
+		score:     music.NewScore("", "", "C", "dur", 4, 4, 0),
+		meter:     music.TimeSignature{Numerator: 4, Denominator: 4},
+		freeMeter: true, // until an M: field
+		clef:      music.TrebleClef,
+		barAlters: make(map[int]int),
+	}
+}
+
+func (p *abcParser) errorf(col int, format string, args ...any) error {
+	return &ParseError{Line: p.line, Column: col, Message: fmt.Sprintf(format, args...)}
+}
+
+// field handles a header field, a field line in the body or an inline field
+func (p *abcParser) field(f abcField) error {
+	p.line = f.line
+	if p.inBody && !p.inVoice() && f.name != 'V' {
+		return nil
+	}
+	switch f.name {
+	case 'T':
+		if !p.inBody && p.score.Title == "" {
+			p.score.Title = f.value
+		}
+	case 'C':
+		if !p.inBody && p.score.Composer == "" {
+			p.score.Composer = f.value
+		}
+	case 'M':
+		return p.parseMeter(f)
+	case 'L':
+		return p.parseUnit(f)
+	case 'Q':
+		return p.parseTempo(f)
+	case 'K':
+		return p.parseKey(f)
+	case 'V':
+		p.switchVoice(f.value)
+	}
+	return nil
+}
+
+// inVoice reports whether the body being read belongs to the kept voice
+func (p *abcParser) inVoice() bool {
+	return p.currentVoice == p.voice
+}
+
+func (p *abcParser) switchVoice(value string) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return
+	}
+	if p.voice == "" {
+		p.voice = fields[0]
+	}
+	if p.inBody {
+		p.currentVoice = fields[0]
+	}
+}
+
+// startBody ends the header, fixing the unit length
+func (p *abcParser) startBody() {
+	p.unit = p.unitLength()
+	p.inBody = true
+	p.currentVoice = p.voice
+}
+
+// unitLength returns L:, or its default from the meter
+func (p *abcParser) unitLength() fraction {
+	if p.unit.num != 0 {
+		return p.unit
+	}
+	if !p.freeMeter && 4*p.meter.Numerator < 3*p.meter.Denominator {
+		return newFraction(1, 16)
+	}
+	return newFraction(1, 8)
+}
+
+// parseFraction reads "n/m" or "n" with positive parts
+func parseFraction(s string) (fraction, bool) {
+	num, den := s, "1"
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		num, den = s[:i], s[i+1:]
+	}
+	n, err1 := strconv.Atoi(num)
+	d, err2 := strconv.Atoi(den)
+	if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return fraction{}, false
+	}
+	return newFraction(n, d), true
+}
+
+func (p *abcParser) parseMeter(f abcField) error {
+	switch f.value {
+	case "", "none":
+		p.freeMeter = true
+		p.meter = music.TimeSignature{Numerator: 4, Denominator: 4}
+	case "C":
+		p.freeMeter = false
+		p.meter = music.TimeSignature{Numerator: 4, Denominator: 4}
+	case "C|":
+		p.freeMeter = false
+		p.meter = music.TimeSignature{Numerator: 2, Denominator: 2}
+	default:
+		num, den, ok := strings.Cut(f.value, "/")
+		if !ok {
+			return p.errorf(f.col, "invalid meter %q", f.value)
+		}
+		// Additive meters such as (2+3)/8 are summed
+		total := 0
+		for _, part := range strings.Split(strings.Trim(strings.TrimSpace(num), "()"), "+") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n <= 0 {
+				return p.errorf(f.col, "invalid meter %q", f.value)
+			}
+			total += n
+		}
+		d, err := strconv.Atoi(strings.TrimSpace(den))
+		if err != nil || d <= 0 || d&(d-1) != 0 {
+			return p.errorf(f.col, "meter denominator in %q is not a power of two", f.value)
+		}
+		p.freeMeter = false
+		p.meter = music.TimeSignature{Numerator: total, Denominator: d}
+	}
+	if !p.placed {
+		p.score.TimeSignature = p.meter
+	}
+	return nil
+}
+
+func (p *abcParser) parseUnit(f abcField) error {
+	unit, ok := parseFraction(f.value)
+	if !ok {
+		return p.errorf(f.col, "invalid unit note length %q", f.value)
+	}
+	p.unit = unit
+	return nil
+}
+
+func (p *abcParser) parseTempo(f abcField) error {
+	// Tempo text such as "Allegro" is not kept
+	v := f.value
+	for {
+		i := strings.IndexByte(v, '"')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(v[i+1:], '"')
+		if j < 0 {
+			return p.errorf(f.col, "unterminated tempo text")
+		}
+		v = v[:i] + " " + v[i+1+j+1:]
+	}
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+
+	beat, bpmText := p.unitLength(), v
+	if before, after, ok := strings.Cut(v, "="); ok {
+		beat = fraction{0, 1}
+		for _, part := range strings.Fields(before) {
+			fr, ok := parseFraction(part)
+			if !ok {
+				return p.errorf(f.col, "invalid tempo %q", f.value)
+			}
+			beat = beat.add(fr)
+		}
+		bpmText = strings.TrimSpace(after)
+	}
+	bpm, err := strconv.Atoi(bpmText)
+	if err != nil || bpm <= 0 || beat.num == 0 {
+		return p.errorf(f.col, "invalid tempo %q", f.value)
+	}
+	p.score.Tempo = int(math.Round(float64(bpm) * 4 * float64(beat.num) / float64(beat.den)))
+	return nil
+}
+
+func (p *abcParser) parseKey(f abcField) error {
+	fields := strings.Fields(f.value)
+	step, alter, mode, fifths := 0, 0, "dur", 0
+	rest := fields
+	if len(fields) > 0 && !strings.Contains(fields[0], "=") {
+		if _, isClef := abcClefs[fields[0]]; !isClef {
+			tok := fields[0]
+			rest = fields[1:]
+			if tok != "none" {
+				step = strings.IndexRune("CDEFGAB", unicode.ToUpper(rune(tok[0])))
+				if step < 0 {
+					return p.errorf(f.col, "unknown key %q", tok)
+				}
+				name := tok[1:]
+				if strings.HasPrefix(name, "#") {
+					alter, name = 1, name[1:]
+				} else if strings.HasPrefix(name, "b") {
+					alter, name = -1, name[1:]
+				}
+				if name == "" && len(rest) > 0 {
+					if _, ok := lookupABCMode(rest[0]); ok {
+						name, rest = rest[0], rest[1:]
+					}
+				}
+				var ok bool
+				if mode, ok = lookupABCMode(name); !ok {
+					return p.errorf(f.col, "unknown mode %q", name)
+				}
+				fifths = theory.Key{Step: step, Alter: alter, Mode: mode}.Fifths()
+			}
+		}
+	}
+
+	alters := theory.SignatureAlterations(fifths)
+	clef := ""
+	for _, tok := range rest {
+		if name, ok := strings.CutPrefix(tok, "clef="); ok {
+			tok = name
+		}
+		if c, ok := abcClefs[tok]; ok {
+			clef = c
+			continue
+		}
+		// Explicit key accidentals such as ^f or _b
+		acc := strings.TrimRight(tok, "ABCDEFGabcdefg")
+		if len(tok) == len(acc)+1 {
+			if a, ok := abcAccidentals[acc]; ok {
+				alters[strings.IndexRune("CDEFGAB", unicode.ToUpper(rune(tok[len(acc)])))] = a
+			}
+		}
+		// Other key options (middle=, transpose=, ...) are ignored
+	}
+
+	p.keyAlters = alters
+	if !p.placed {
+		p.score.KeySignature = keySignature(step, alter, mode)
+		if clef != "" {
+			p.clef = clef
+		}
+	} else if clef != "" {
+		p.pendingClef = clef
+	}
+	if !p.inBody {
+		p.startBody()
+	}
+	return nil
+}
+
+func (p *abcParser) peekAt(offset int) rune {
+	if i := p.pos + offset; i < len(p.src) {
+		return p.src[i]
+	}
+	return 0
+}
+
+func (p *abcParser) accept(r rune) bool {
+	if p.peekAt(0) == r {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// readInt reads the digits at the current position
+func (p *abcParser) readInt() (int, error) {
+	col := p.pos + 1
+	start := p.pos
+	for isDigit(p.peekAt(0)) {
+		p.pos++
+	}
+	n, err := strconv.Atoi(string(p.src[start:p.pos]))
+	if err != nil {
+		return 0, p.errorf(col, "invalid number %q", string(p.src[start:p.pos]))
+	}
+	return n, nil
+}
+
+// parseMusic reads a line of the tune body
+func (p *abcParser) parseMusic(text string) error {
+	if !p.inBody {
+		// Music without a K: field is read in C major
+		p.startBody()
+	}
+	p.src = []rune(text)
+	p.pos = 0
+	for p.pos < len(p.src) {
+		col := p.pos + 1
+		r := p.src[p.pos]
+		inlineField := r == '[' && unicode.IsLetter(p.peekAt(1)) && p.peekAt(2) == ':'
+		if !p.inVoice() && !inlineField {
+			p.pos++
+			continue
+		}
+
+		var err error
+		switch {
+		case unicode.IsSpace(r), r == '`', r == '\\', r == '(' && !isDigit(p.peekAt(1)), r == ')':
+			// Beam breaks, line continuations and slurs
+			p.pos++
+		case r == 'y':
+			p.pos++
+			_, err = p.readLength()
+		case r == '"':
+			err = p.skipQuoted()
+		case r == '!' || r == '+':
+			err = p.parseDecoration()
+		case inlineField:
+			err = p.parseInlineField()
+		case r == '[' && isDigit(p.peekAt(1)):
+			p.pos++
+			var n int
+			if n, err = p.readEndingNumber(); err == nil {
+				p.ending = n
+			}
+		case r == '[' && p.peekAt(1) != '|':
+			err = p.parseChord()
+		case r == '[' || r == '|' || r == ':':
+			err = p.parseBar()
+		case r == '{':
+			if p.grace != music.GraceNone {
+				return p.errorf(col, "grace notes cannot be nested")
+			}
+			p.pos++
+			p.grace = music.Appoggiatura
+			if p.accept('/') {
+				p.grace = music.Acciaccatura
+			}
+			p.graceLine, p.graceCol = p.line, col
+		case r == '}':
+			if p.grace == music.GraceNone {
+				return p.errorf(col, "unmatched }")
+			}
+			p.pos++
+			p.grace = music.GraceNone
+		case r == '(':
+			err = p.parseTuplet()
+		case abcShorthands[r] != "":
+			p.pos++
+			p.decorate(abcShorthands[r])
+		case r == '^' || r == '_' || r == '=' || isNoteLetter(r):
+			err = p.parseNote()
+		case r == 'z' || r == 'x':
+			err = p.parseRest()
+		case r == 'Z' || r == 'X':
+			err = p.parseMeasureRests()
+		case r == '-':
+			p.pos++
+			for _, n := range p.lastNotes {
+				n.Tie = true
+			}
+		case r == '&':
+			err = p.errorf(col, "voice overlay (&) is not supported")
+		default:
+			err = p.errorf(col, "unexpected character %q", r)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *abcParser) skipQuoted() error {
+	col := p.pos + 1
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		if p.src[p.pos] == '"' {
+			p.pos++
+			return nil
+		}
+	}
+	return p.errorf(col, "unterminated chord symbol or annotation")
+}
+
+// parseDecoration reads !name! (or the older +name+)
+func (p *abcParser) parseDecoration() error {
+	col := p.pos + 1
+	delim := p.src[p.pos]
+	for end := p.pos + 1; end < len(p.src); end++ {
+		if p.src[end] == delim {
+			name := string(p.src[p.pos+1 : end])
+			p.pos = end + 1
+			p.decorate(name)
+			return nil
+		}
+	}
+	return p.errorf(col, "unterminated decoration")
+}
+
+// decorate records a decoration for the next note. Unknown decorations are
+// ignored.
+func (p *abcParser) decorate(name string) {
+	switch name {
+	case "crescendo(", "<(":
+		p.marks.hairpinStart, p.marks.hairpinType = true, music.Crescendo
+		return
+	case "diminuendo(", ">(":
+		p.marks.hairpinStart, p.marks.hairpinType = true, music.Diminuendo
+		return
+	case "crescendo)", "<)", "diminuendo)", ">)":
+		p.marks.hairpinEnd = true
+		return
+	}
+	if a, ok := abcArticulations[name]; ok {
+		p.marks.articulations = append(p.marks.articulations, a)
+	} else if o, ok := abcOrnaments[name]; ok {
+		p.marks.ornaments = append(p.marks.ornaments, o)
+	} else if d := music.Dynamic(name); d.GlyphName() != "" {
+		p.marks.dynamic = d
+	}
+}
+
+func (p *abcParser) parseInlineField() error {
+	col := p.pos + 1
+	for end := p.pos + 3; end < len(p.src); end++ {
+		if p.src[end] == ']' {
+			f := abcField{
+				name:  p.src[p.pos+1],
+				value: strings.TrimSpace(string(p.src[p.pos+3 : end])),
+				line:  p.line,
+				col:   col + 3,
+			}
+			p.pos = end + 1
+			return p.field(f)
+		}
+	}
+	return p.errorf(col, "unclosed inline field")
+}
+
+// readEndingNumber reads an ending number such as 1 or 1,2 and returns the
+// first
+func (p *abcParser) readEndingNumber() (int, error) {
+	n, err := p.readInt()
+	if err != nil {
+		return 0, err
+	}
+	for (p.peekAt(0) == ',' || p.peekAt(0) == '-') && isDigit(p.peekAt(1)) {
+		p.pos++
+		if _, err := p.readInt(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// parseBar reads a bar line with its repeat signs and ending number
+func (p *abcParser) parseBar() error {
+	col := p.pos + 1
+	start := p.pos
+	if p.peekAt(0) == '[' {
+		p.pos++
+	}
+	for {
+		r := p.peekAt(0)
+		if r == '|' || r == ':' || (r == ']' && p.src[p.pos-1] == '|') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	run := string(p.src[start:p.pos])
+	if !strings.Contains(run, "|") && run != "::" {
+		return p.errorf(col, "unexpected %q", run)
+	}
+	ending := 0
+	if isDigit(p.peekAt(0)) {
+		var err error
+		if ending, err = p.readEndingNumber(); err != nil {
+			return err
+		}
+	}
+
+	endRepeat := strings.HasPrefix(run, ":")
+	startRepeat := strings.HasSuffix(run, ":")
+	double := strings.Contains(run, "||") || strings.Contains(run, "[|") || strings.Contains(run, "|]")
+
+	p.closeMeasure(endRepeat)
+	if endRepeat || startRepeat || double {
+		p.ending = 0
+	}
+	if startRepeat {
+		p.repeatStart = true
+	}
+	if ending > 0 {
+		p.ending = ending
+	}
+	return nil
+}
+
+// closeMeasure ends the current measure at a bar line
+func (p *abcParser) closeMeasure(endRepeat bool) {
+	if p.measure != nil {
+		p.measure.RepeatEnd = p.measure.RepeatEnd || endRepeat
+		p.measure = nil
+	} else if endRepeat && len(p.score.Measures) > 0 {
+		p.score.Measures[len(p.score.Measures)-1].RepeatEnd = true
+	}
+	p.barAlters = make(map[int]int)
+}
+
+// ensureMeasure starts a measure if the last one was closed by a bar line
+func (p *abcParser) ensureMeasure() {
+	if p.measure != nil {
+		return
+	}
+	var ts *music.TimeSignature
+	if p.meter != p.score.TimeSignature {
+		t := p.meter
+		ts = &t
+	}
+	m := p.score.AddMeasure(ts)
+	if len(p.score.Measures) == 1 {
+		m.Clef = p.clef
+	}
+	if p.pendingClef != "" {
+		p.clef = p.pendingClef
+		m.Clef = p.clef
+		p.pendingClef = ""
+	}
+	m.RepeatStart = p.repeatStart
+	m.Ending = p.ending
+	p.repeatStart = false
+	p.measure = m
+}
+
+// add appends an element to the current measure and returns its reference
+func (p *abcParser) add(e music.MusicElement) music.ElementRef {
+	switch el := e.(type) {
+	case *music.Note:
+		p.measure.AddNote(el)
+	case *music.Rest:
+		p.measure.AddRest(el)
+	}
+	p.placed = true
+	return music.ElementRef{Measure: len(p.score.Measures) - 1, Element: len(p.measure.Elements) - 1}
+}
+
+// readLength reads a length multiplier such as 2, /, //, 3/2 or /4
+func (p *abcParser) readLength() (fraction, error) {
+	col := p.pos + 1
+	num, den := 1, 1
+	if isDigit(p.peekAt(0)) {
+		n, err := p.readInt()
+		if err != nil {
+			return fraction{}, err
+		}
+		num = n
+	}
+	for p.accept('/') {
+		if isDigit(p.peekAt(0)) {
+			d, err := p.readInt()
+			if err != nil {
+				return fraction{}, err
+			}
+			den *= d
+		} else {
+			den *= 2
+		}
+		if den <= 0 || den > 1<<16 {
+			return fraction{}, p.errorf(col, "invalid note length")
+		}
+	}
+	if num == 0 || den == 0 {
+		return fraction{}, p.errorf(col, "note length cannot be zero")
+	}
+	return newFraction(num, den), nil
+}
+
+// readNote reads a note with its accidental, octave marks, length and tie
+func (p *abcParser) readNote() (abcNote, error) {
+	col := p.pos + 1
+	alter, explicit := 0, false
+	switch {
+	case p.accept('^'):
+		alter, explicit = 1, true
+		if p.accept('^') {
+			alter = 2
+		}
+	case p.accept('_'):
+		alter, explicit = -1, true
+		if p.accept('_') {
+			alter = -2
+		}
+	case p.accept('='):
+		explicit = true
+	}
+	r := p.peekAt(0)
+	if !isNoteLetter(r) {
+		return abcNote{}, p.errorf(p.pos+1, "expected a note, found %q", r)
+	}
+	p.pos++
+	step := strings.IndexRune("CDEFGAB", unicode.ToUpper(r))
+	diatonic := 28 + step // C is middle C
+	if unicode.IsLower(r) {
+		diatonic += 7
+	}
+	for {
+		if p.accept('\'') {
+			diatonic += 7
+		} else if p.accept(',') {
+			diatonic -= 7
+		} else {
+			break
+		}
+	}
+
+	n := abcNote{diatonic: diatonic}
+	if explicit {
+		p.barAlters[diatonic] = alter
+		n.accidental = music.AlterAccidental(alter)
+	} else if a, ok := p.barAlters[diatonic]; ok {
+		alter = a
+	} else {
+		alter = p.keyAlters[step]
+	}
+	n.pitch = music.NaturalMIDI(diatonic) + alter
+	if n.pitch < 0 || n.pitch > 127 {
+		return abcNote{}, p.errorf(col, "note is outside the MIDI range")
+	}
+
+	length, err := p.readLength()
+	if err != nil {
+		return abcNote{}, err
+	}
+	n.length = length
+	n.tie = p.accept('-')
+	return n, nil
+}
+
+func (p *abcParser) parseNote() error {
+	col := p.pos + 1
+	n, err := p.readNote()
+	if err != nil {
+		return err
+	}
+	return p.placeNotes([]abcNote{n}, n.length, col)
+}
+
+func (p *abcParser) parseChord() error {
+	col := p.pos + 1
+	p.pos++
+	var notes []abcNote
+	for !p.accept(']') {
+		r := p.peekAt(0)
+		switch {
+		case r == 0:
+			return p.errorf(col, "unclosed chord")
+		case r == '^' || r == '_' || r == '=' || isNoteLetter(r):
+			n, err := p.readNote()
+			if err != nil {
+				return err
+			}
+			notes = append(notes, n)
+		default:
+			return p.errorf(p.pos+1, "expected a note or ] in chord, found %q", r)
+		}
+	}
+	if len(notes) == 0 {
+		return p.errorf(col, "empty chord")
+	}
+	mult, err := p.readLength()
+	if err != nil {
+		return err
+	}
+	if p.accept('-') {
+		for i := range notes {
+			notes[i].tie = true
+		}
+	}
+	// A chord lasts as long as its first note
+	return p.placeNotes(notes, notes[0].length.mul(mult), col)
+}
+
+// eventLength turns a written length into a fraction of a whole note,
+// applying broken rhythm from the previous note and reading one that
+// follows this note
+func (p *abcParser) eventLength(written fraction) (fraction, error) {
+	l := written.mul(p.unitLength())
+	if p.grace != music.GraceNone {
+		return l, nil
+	}
+	if p.broken.num != 0 {
+		l = l.mul(p.broken)
+		p.broken = fraction{}
+	}
+	col := p.pos + 1
+	if c := p.peekAt(0); c == '>' || c == '<' {
+		count := 0
+		for p.accept(c) {
+			count++
+		}
+		if count > 3 {
+			return l, p.errorf(col, "broken rhythm %s is too long", strings.Repeat(string(c), count))
+		}
+		long := newFraction(1<<(count+1)-1, 1<<count)
+		short := newFraction(1, 1<<count)
+		if c == '>' {
+			l, p.broken = l.mul(long), short
+		} else {
+			l, p.broken = l.mul(short), long
+		}
+	}
+	return l, nil
+}
+
+// placeNotes places a note or chord, split into tied notes when no single
+// note value has its length
+func (p *abcParser) placeNotes(notes []abcNote, written fraction, col int) error {
+	length, err := p.eventLength(written)
+	if err != nil {
+		return err
+	}
+	pieces, ok := splitLength(length)
+	if !ok {
+		return p.errorf(col, "length %s of a whole note cannot be written", length)
+	}
+
+	p.ensureMeasure()
+	var first, head, last music.ElementRef
+	var chord []*music.Note
+	for k, piece := range pieces {
+		final := k == len(pieces)-1
+		chord = make([]*music.Note, len(notes))
+		for j, an := range notes {
+			n := &music.Note{
+				Pitch:     an.pitch,
+				Duration:  piece.value,
+				Dots:      piece.dots,
+				StaffLine: an.diatonic - music.ClefBottomLine(p.clef),
+				Grace:     p.grace,
+				Tie:       an.tie || !final,
+				Chord:     j > 0,
+			}
+			if k == 0 {
+				n.Accidental = an.accidental
+			}
+			chord[j] = n
+			last = p.add(n)
+			if j == 0 {
+				head = last
+			}
+		}
+		if k == 0 {
+			first = head
+			if p.grace == music.GraceNone {
+				// Decorations before grace notes belong to the principal note
+				p.applyMarks(chord[0], first)
+			}
+		}
+	}
+	p.last = head
+	p.lastNotes = chord
+	if p.grace == music.GraceNone {
+		return p.countTuplet(first, last)
+	}
+	return nil
+}
+
+// applyMarks puts the waiting decorations on a note
+func (p *abcParser) applyMarks(n *music.Note, ref music.ElementRef) {
+	m := p.marks
+	n.Dynamic = m.dynamic
+	n.Articulations = m.articulations
+	n.Ornaments = m.ornaments
+	if (m.hairpinEnd || m.hairpinStart) && p.hairpin != nil {
+		p.endHairpin(ref)
+	}
+	if m.hairpinStart {
+		p.hairpin = &music.Hairpin{Type: m.hairpinType, Start: ref}
+	}
+	p.marks = abcMarks{}
+}
+
+func (p *abcParser) endHairpin(ref music.ElementRef) {
+	h := *p.hairpin
+	h.End = ref
+	p.score.Hairpins = append(p.score.Hairpins, h)
+	p.hairpin = nil
+}
+
+func (p *abcParser) parseRest() error {
+	col := p.pos + 1
+	if p.grace != music.GraceNone {
+		return p.errorf(col, "rests cannot be grace notes")
+	}
+	p.pos++
+	written, err := p.readLength()
+	if err != nil {
+		return err
+	}
+	length, err := p.eventLength(written)
+	if err != nil {
+		return err
+	}
+	pieces, ok := splitLength(length)
+	if !ok {
+		return p.errorf(col, "length %s of a whole note cannot be written", length)
+	}
+
+	p.ensureMeasure()
+	var first, last music.ElementRef
+	for k, piece := range pieces {
+		last = p.add(&music.Rest{Duration: piece.value, Dots: piece.dots})
+		if k == 0 {
+			first = last
+		}
+	}
+	p.lastNotes = nil
+	return p.countTuplet(first, last)
+}
+
+// parseMeasureRests reads Z or Z4, whole bars of rest
+func (p *abcParser) parseMeasureRests() error {
+	col := p.pos + 1
+	if p.grace != music.GraceNone || len(p.tuplets) > 0 {
+		return p.errorf(col, "bar rests cannot be grace notes or tuplets")
+	}
+	if p.measure != nil {
+		return p.errorf(col, "bar rests must fill their bars")
+	}
+	p.pos++
+	count := 1
+	if isDigit(p.peekAt(0)) {
+		n, err := p.readInt()
+		if err != nil {
+			return err
+		}
+		count = n
+	}
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			p.closeMeasure(false)
+		}
+		p.ensureMeasure()
+		ts := p.measure.TimeSignature
+		pieces, ok := splitLength(newFraction(ts.Numerator, ts.Denominator))
+		if !ok {
+			return p.errorf(col, "cannot fill a bar of %d/%d with rests", ts.Numerator, ts.Denominator)
+		}
+		for _, piece := range pieces {
+			p.add(&music.Rest{Duration: piece.value, Dots: piece.dots})
+		}
+	}
+	p.lastNotes = nil
+	return nil
+}
+
+// parseTuplet reads (p, (p:q or (p:q:r
+func (p *abcParser) parseTuplet() error {
+	col := p.pos + 1
+	p.pos++
+	actual, err := p.readInt()
+	if err != nil {
+		return err
+	}
+	normal, count := 0, 0
+	if p.accept(':') {
+		if isDigit(p.peekAt(0)) {
+			if normal, err = p.readInt(); err != nil {
+				return err
+			}
+		}
+		if p.accept(':') && isDigit(p.peekAt(0)) {
+			if count, err = p.readInt(); err != nil {
+				return err
+			}
+		}
+	}
+	if normal == 0 {
+		switch actual {
+		case 3, 6:
+			normal = 2
+		case 2, 4, 8:
+			normal = 3
+		default:
+			// 5, 7 and 9 fit into three beats in compound meters
+			normal = 2
+			if p.meter.Numerator%3 == 0 && p.meter.Numerator > 3 {
+				normal = 3
+			}
+		}
+	}
+	if count == 0 {
+		count = actual
+	}
+	if actual <= 0 {
+		return p.errorf(col, "invalid tuplet (%d", actual)
+	}
+	p.tuplets = append(p.tuplets, &abcTuplet{actual: actual, normal: normal, remaining: count, line: p.line, col: col})
+	return nil
+}
+
+// countTuplet adds a placed note, chord or rest to the open tuplets and
+// closes the tuplets it completes
+func (p *abcParser) countTuplet(first, last music.ElementRef) error {
+	for _, t := range p.tuplets {
+		if !t.placed {
+			t.first = first
+			t.placed = true
+		}
+		t.last = last
+		t.remaining--
+	}
+	for len(p.tuplets) > 0 && p.tuplets[len(p.tuplets)-1].remaining <= 0 {
+		t := p.tuplets[len(p.tuplets)-1]
+		p.tuplets = p.tuplets[:len(p.tuplets)-1]
+		if t.first.Measure != t.last.Measure {
+			return &ParseError{Line: t.line, Column: t.col, Message: "tuplet crosses a bar line"}
+		}
+		err := p.score.Measures[t.first.Measure].AddTuplet(music.Tuplet{
+			Actual: t.actual,
+			Normal: t.normal,
+			Start:  t.first.Element,
+			End:    t.last.Element,
+		})
+		if err != nil {
+			return &ParseError{Line: t.line, Column: t.col, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// finish checks for unclosed groups at the end of a tune, ends a hairpin
+// left running and marks an incomplete first bar as a pickup
+func (p *abcParser) finish() error {
+	if p.grace != music.GraceNone {
+		return &ParseError{Line: p.graceLine, Column: p.graceCol, Message: "unclosed {"}
+	}
+	if len(p.tuplets) > 0 {
+		t := p.tuplets[0]
+		return &ParseError{Line: t.line, Column: t.col, Message: "tuplet has too few notes"}
+	}
+	if p.hairpin != nil {
+		p.endHairpin(p.last)
+	}
+	if !p.freeMeter && len(p.score.Measures) > 1 {
+		m := p.score.Measures[0]
+		quarters := 0.0
+		for i := range m.Elements {
+			quarters += m.ElementQuarters(i)
+		}
+		capacity := float64(m.TimeSignature.Numerator) * 4 / float64(m.TimeSignature.Denominator)
+		m.Pickup = quarters < capacity-fillTolerance
+	}
+	return nil
+}
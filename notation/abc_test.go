@@ -0,0 +1,300 @@
+package notation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gehoer/music"
+)
+
+// parseTune parses src, which must hold exactly one tune
+func parseTune(t *testing.T, src string) *music.Score {
+	t.Helper()
+	scores, err := ParseABC(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 1 {
+		t.Fatalf("%d tunes, want 1", len(scores))
+	}
+	return scores[0]
+}
+
+func TestParseABCHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		title string
+		meter music.TimeSignature
+		key   music.KeySignature
+		tempo int
+		clef  string
+		unit  music.NoteValue // the value of a note with no length
+	}{
+		{
+			"every field",
+			"X:1\nT:Fjellsang\nT:Andre tittel\nC:Ukjent\nM:3/4\nL:1/4\nQ:1/4=90\nK:Em clef=bass\nE G B|",
+			"Fjellsang", music.TimeSignature{Numerator: 3, Denominator: 4}, music.KeySignature{Tonic: "e", Mode: "moll"},
+			90, music.BassClef, music.QuarterNote,
+		},
+		{
+			"short meter defaults to sixteenths",
+			"X:1\nM:2/4\nK:G\nC D E F G A B c|",
+			"", music.TimeSignature{Numerator: 2, Denominator: 4}, music.KeySignature{Tonic: "G", Mode: "dur"},
+			0, music.TrebleClef, music.SixteenthNote,
+		},
+		{
+			"tempo in another beat",
+			"X:1\nM:6/8\nQ:\"Allegro\" 3/8=60\nK:D mix\nC D E F G A|",
+			"", music.TimeSignature{Numerator: 6, Denominator: 8}, music.KeySignature{Tonic: "D", Mode: "miksisk"},
+			90, music.TrebleClef, music.EighthNote,
+		},
+		{
+			"common time and the key's clef name",
+			"X:1\nM:C\nK:alto\nC D E F G A B c|",
+			"", music.TimeSignature{Numerator: 4, Denominator: 4}, music.KeySignature{Tonic: "C", Mode: "dur"},
+			0, music.AltoClef, music.EighthNote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseTune(t, tt.src)
+			if s.Title != tt.title || s.TimeSignature != tt.meter || s.KeySignature != tt.key || s.Tempo != tt.tempo {
+				t.Errorf("title %q, meter %v, key %v, tempo %d; want %q, %v, %v, %d",
+					s.Title, s.TimeSignature, s.KeySignature, s.Tempo, tt.title, tt.meter, tt.key, tt.tempo)
+			}
+			if got := s.ClefAt(0); got != tt.clef {
+				t.Errorf("clef %q, want %q", got, tt.clef)
+			}
+			if got := s.Measures[0].Elements[0].(*music.Note).Duration; got != tt.unit {
+				t.Errorf("first note is a %v, want a %v", got, tt.unit)
+			}
+		})
+	}
+
+	s := parseTune(t, "X:1\nT:Fjellsang\nC:Ukjent\nK:C\nC|")
+	if s.Composer != "Ukjent" {
+		t.Errorf("composer %q, want %q", s.Composer, "Ukjent")
+	}
+}
+
+func TestParseABCNotes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want [][]placed
+	}{
+		{
+			"octave marks and lower case",
+			"C,, C, C c c'",
+			[][]placed{{{value: music.EighthNote, pitch: 36}, {value: music.EighthNote, pitch: 48}, {value: music.EighthNote, pitch: 60},
+				{value: music.EighthNote, pitch: 72}, {value: music.EighthNote, pitch: 84}}},
+		},
+		{
+			"lengths in units",
+			"C2 C/ C// C3/2 C3",
+			[][]placed{{{value: music.QuarterNote, pitch: 60}, {value: music.SixteenthNote, pitch: 60}, {value: music.ThirtySecondNote, pitch: 60},
+				{value: music.EighthNote, dots: 1, pitch: 60}, {value: music.QuarterNote, dots: 1, pitch: 60}}},
+		},
+		{
+			"broken rhythms",
+			"A>B C<D A>>B",
+			[][]placed{{{value: music.EighthNote, dots: 1, pitch: 69}, {value: music.SixteenthNote, pitch: 71},
+				{value: music.SixteenthNote, pitch: 60}, {value: music.EighthNote, dots: 1, pitch: 62},
+				{value: music.EighthNote, dots: 2, pitch: 69}, {value: music.ThirtySecondNote, pitch: 71}}},
+		},
+		{
+			"accidentals last the bar",
+			"^F F =F F | F",
+			[][]placed{
+				{{value: music.EighthNote, pitch: 66, accident: music.AccidentalSharp}, {value: music.EighthNote, pitch: 66},
+					{value: music.EighthNote, pitch: 65, accident: music.AccidentalNatural}, {value: music.EighthNote, pitch: 65}},
+				{{value: music.EighthNote, pitch: 65}},
+			},
+		},
+		{
+			"ties",
+			"C2-C2 C- C | C4- | C4",
+			[][]placed{
+				{{value: music.QuarterNote, tie: true, pitch: 60}, {value: music.QuarterNote, pitch: 60},
+					{value: music.EighthNote, tie: true, pitch: 60}, {value: music.EighthNote, pitch: 60}},
+				{{value: music.HalfNote, tie: true, pitch: 60}},
+				{{value: music.HalfNote, pitch: 60}},
+			},
+		},
+		{
+			"lengths no note value shows are tied",
+			"C5 z3",
+			[][]placed{{{value: music.HalfNote, tie: true, pitch: 60}, {value: music.EighthNote, pitch: 60},
+				{rest: true, value: music.QuarterNote, dots: 1}}},
+		},
+		{
+			"bar rests",
+			"Z2 | C8",
+			[][]placed{{{rest: true, value: music.WholeNote}}, {{rest: true, value: music.WholeNote}}, {{value: music.WholeNote, pitch: 60}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseTune(t, "X:1\nM:4/4\nL:1/8\nK:C\n"+tt.body)
+			if got := measureElements(s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseABCKeyAlterations(t *testing.T) {
+	s := parseTune(t, "X:1\nL:1/4\nK:D\nF c =F f|")
+	var pitches []int
+	for _, e := range s.Measures[0].Elements {
+		pitches = append(pitches, e.(*music.Note).Pitch)
+	}
+	if want := []int{66, 73, 65, 78}; !reflect.DeepEqual(pitches, want) {
+		t.Errorf("got %v, want %v", pitches, want)
+	}
+}
+
+func TestParseABCChords(t *testing.T) {
+	s := parseTune(t, "X:1\nM:2/4\nL:1/8\nK:C\n[CEG]2 [C2E]- | [CE]2 z2|")
+	type chordNote struct {
+		pitch int
+		value music.NoteValue
+		chord bool
+		tie   bool
+	}
+	want := []chordNote{
+		{60, music.QuarterNote, false, false}, {64, music.QuarterNote, true, false}, {67, music.QuarterNote, true, false},
+		{60, music.QuarterNote, false, true}, {64, music.QuarterNote, true, true},
+	}
+	var got []chordNote
+	for _, e := range s.Measures[0].Elements {
+		n := e.(*music.Note)
+		got = append(got, chordNote{n.Pitch, n.Duration, n.Chord, n.Tie})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if len(s.Measures) != 2 {
+		t.Fatalf("%d measures, want the tied chord to end in a second", len(s.Measures))
+	}
+	for _, ref := range []music.ElementRef{{Measure: 0, Element: 3}, {Measure: 0, Element: 4}} {
+		if _, ok := s.TieTarget(ref); !ok {
+			t.Errorf("tie from %+v has no target", ref)
+		}
+	}
+}
+
+func TestParseABCRepeats(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		order []int
+	}{
+		{"plain repeat", "|: C D | E F :| G A |]", []int{0, 1, 0, 1, 2}},
+		{"repeat from the start", "C D | E F :| G A |]", []int{0, 1, 0, 1, 2}},
+		{"first and second ending", "|: C D | E F |1 G A :|2 B c || d e |]", []int{0, 1, 2, 0, 1, 3, 4}},
+		{"endings in brackets", "|: C D |[1 E F :|[2 G A |]", []int{0, 1, 0, 2}},
+		{"back to back repeats", "|: C D :: E F :|", []int{0, 0, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseTune(t, "X:1\nM:2/4\nL:1/4\nK:C\n"+tt.body)
+			if got := s.PlaybackOrder(); !reflect.DeepEqual(got, tt.order) {
+				t.Errorf("played %v, want %v", got, tt.order)
+			}
+		})
+	}
+}
+
+func TestParseABCPickup(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		pickup bool
+	}{
+		{"short first bar", "X:1\nM:3/4\nL:1/4\nK:C\nG | C D E | F3 |]", true},
+		{"full first bar", "X:1\nM:3/4\nL:1/4\nK:C\nG A B | C D E |]", false},
+		{"free meter", "X:1\nL:1/4\nK:C\nG | C D E |]", false},
+		{"single bar", "X:1\nM:3/4\nL:1/4\nK:C\nG |]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTune(t, tt.src).Measures[0].Pickup; got != tt.pickup {
+				t.Errorf("pickup %v, want %v", got, tt.pickup)
+			}
+		})
+	}
+}
+
+func TestParseABCTunes(t *testing.T) {
+	src := `Sanger fra fjellet
+M:2/4
+L:1/4
+
+X:1
+T:Den første
+K:C
+C D|
+
+X:2
+T:Den andre
+M:3/4
+K:G
+G A B|
+`
+	scores, err := ParseABC(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 {
+		t.Fatalf("%d tunes, want 2", len(scores))
+	}
+	want := []struct {
+		title string
+		meter music.TimeSignature
+		key   music.KeySignature
+	}{
+		{"Den første", music.TimeSignature{Numerator: 2, Denominator: 4}, music.KeySignature{Tonic: "C", Mode: "dur"}},
+		{"Den andre", music.TimeSignature{Numerator: 3, Denominator: 4}, music.KeySignature{Tonic: "G", Mode: "dur"}},
+	}
+	for i, s := range scores {
+		if s.Title != want[i].title || s.TimeSignature != want[i].meter || s.KeySignature != want[i].key {
+			t.Errorf("tune %d is %q in %v and %v, want %+v", i+1, s.Title, s.TimeSignature, s.KeySignature, want[i])
+		}
+		if got := s.Measures[0].Elements[0].(*music.Note).Duration; got != music.QuarterNote {
+			t.Errorf("tune %d starts with a %v, want the file's L:1/4", i+1, got)
+		}
+	}
+}
+
+func TestParseABCErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{"no tune", "T:Uten X\nK:C\nC D|", 1},
+		{"unknown key", "X:1\nK:H\nC|", 2},
+		{"meter not a power of two", "X:1\nM:3/5\nK:C\nC|", 2},
+		{"unclosed chord", "X:1\nK:C\nC D [CEG", 3},
+		{"unclosed grace notes", "X:1\nK:C\nC {g\nD|", 3},
+		{"tuplet with too few notes", "X:1\nK:C\nC (3DE|", 3},
+		{"tuplet over a bar line", "X:1\nM:2/4\nL:1/4\nK:C\nC (3DE|F|", 5},
+		{"broken rhythm too long", "X:1\nK:C\nC>>>>D|", 3},
+		{"voice overlay", "X:1\nK:C\nC D & E F|", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores, err := ParseABC(tt.src)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got %v and %d tunes, want a parse error", err, len(scores))
+			}
+			if pe.Line != tt.line {
+				t.Errorf("error %q on line %d, want line %d", pe, pe.Line, tt.line)
+			}
+		})
+	}
+}
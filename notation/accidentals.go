@@ -0,0 +1,84 @@
+package notation
+
+import (
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// AccidentalOptions chooses the reminders ResolveAccidentals adds besides
+// the accidentals the notes need
+type AccidentalOptions struct {
+	// Courtesy adds a cautionary accidental in parentheses to the first note
+	// on a line the previous measure left altered differently, or that a tie
+	// carried an alteration into, when the note itself needs none
+	Courtesy bool
+}
+
+// ResolveAccidentals sets the accidental of every note from its pitch, the
+// key signature and the earlier notes of its measure, replacing the
+// accidentals the score was read with. A note a tie continues into shows
+// none; across a barline its alteration does not carry on to later notes,
+// which show it again. Notes whose pitch does not fit their staff line are
+// left as they are. It fails for scores in an unknown key.
+func ResolveAccidentals(score *music.Score, opts AccidentalOptions) error {
+	key, err := localization.ParseKey(score.KeySignature.Tonic, score.KeySignature.Mode)
+	if err != nil {
+		return err
+	}
+	resolveAccidentals(score, key, opts)
+	return nil
+}
+
+// resolveAccidentals does the work of ResolveAccidentals in a known key
+func resolveAccidentals(score *music.Score, key theory.Key, opts AccidentalOptions) {
+	keyAlters := key.Alterations()
+	tied := tieContinuations(score)
+	var previous map[int]int // alterations heard last in the previous measure that the key does not give
+	for mi, m := range score.Measures {
+		clef := score.ClefAt(mi)
+		barAlters := make(map[int]int)
+		// reminders are the lines whose alteration a reader may still have
+		// in mind, and the alteration
+		reminders := make(map[int]int)
+		for diatonic, alter := range previous {
+			reminders[diatonic] = alter
+		}
+		heard := make(map[int]int)
+		for ei, e := range m.Elements {
+			n, ok := e.(*music.Note)
+			if !ok {
+				continue
+			}
+			p, ok := theory.NotePitch(n, clef)
+			if !ok {
+				continue
+			}
+			diatonic := p.Diatonic()
+			heard[diatonic] = p.Alter
+			n.Accidental = music.AccidentalNone
+			n.Courtesy = false
+			if tied[music.ElementRef{Measure: mi, Element: ei}] {
+				if _, ok := barAlters[diatonic]; !ok && p.Alter != keyAlters[p.Step] {
+					reminders[diatonic] = p.Alter
+				}
+				continue
+			}
+
+			n.Accidental = theory.AccidentalFor(diatonic, p.Alter, keyAlters, barAlters)
+			if reminded, ok := reminders[diatonic]; ok {
+				delete(reminders, diatonic)
+				if opts.Courtesy && n.Accidental == music.AccidentalNone && reminded != p.Alter {
+					n.Accidental = music.AlterAccidental(p.Alter)
+					n.Courtesy = true
+				}
+			}
+		}
+		previous = make(map[int]int)
+		for diatonic, alter := range heard {
+			if alter != keyAlters[((diatonic%7)+7)%7] {
+				previous[diatonic] = alter
+			}
+		}
+	}
+}
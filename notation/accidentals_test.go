@@ -0,0 +1,67 @@
+package notation
+
+import (
+	"strings"
+	"testing"
+
+	"gehoer/music"
+)
+
+// accidentalMarks writes the accidental of each note, - for none and in
+// parentheses for a courtesy accidental, with | between measures
+func accidentalMarks(score *music.Score) string {
+	var measures []string
+	for _, m := range score.Measures {
+		var marks []string
+		for _, e := range m.Elements {
+			n, ok := e.(*music.Note)
+			if !ok {
+				continue
+			}
+			switch {
+			case n.Accidental == music.AccidentalNone:
+				marks = append(marks, "-")
+			case n.Courtesy:
+				marks = append(marks, "("+n.Accidental+")")
+			default:
+				marks = append(marks, n.Accidental)
+			}
+		}
+		measures = append(measures, strings.Join(marks, " "))
+	}
+	return strings.Join(measures, " | ")
+}
+
+func TestResolveAccidentals(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		courtesy bool
+		want     string
+	}{
+		{"alteration lasts the measure", `fiss'4 fiss' f' fiss'`, false, "sharp - natural sharp"},
+		{"key signature", `\key d \major fiss'4 f' fiss' ciss''`, false, "- natural sharp -"},
+		{"other octave is another line", `fiss'4 fiss'' f'' f'`, false, "sharp sharp natural natural"},
+		{"barline cancels without reminders", `\time 2/4 fiss'4 g' | f'2`, false, "sharp - | -"},
+		{"courtesy after the barline", `\time 2/4 fiss'4 g' | f'4 f'`, true, "sharp - | (natural) -"},
+		{"courtesy for the key", `\key d \major \time 2/4 f'4 g' | fiss'2`, true, "natural - | (sharp)"},
+		{"no courtesy when the note needs an accidental", `\time 2/4 fiss'4 g' | fiss'2`, true, "sharp - | sharp"},
+		{"tie across the barline", `\time 2/4 g'4 fiss'~ | fiss'4 fiss'`, false, "- sharp | - sharp"},
+		{"courtesy after a tie across the barline", `\time 2/4 g'4 fiss'~ | fiss'4 f'`, true, "- sharp | - (natural)"},
+		{"tie inside the measure", `ess'2~ ess'4 ess'`, false, "flat - -"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := ParseText(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ResolveAccidentals(score, AccidentalOptions{Courtesy: tt.courtesy}); err != nil {
+				t.Fatal(err)
+			}
+			if got := accidentalMarks(score); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
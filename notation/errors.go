@@ -0,0 +1,26 @@
+package notation
+
+import "fmt"
+
+// ParseError is a problem at a position in a text input. Lines and columns
+// count from 1; columns count characters, not bytes.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Warning is input an importer skipped or could only read in part
+type Warning struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", w.Line, w.Column, w.Message)
+}
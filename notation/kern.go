@@ -0,0 +1,860 @@
+package notation
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// Humdrum **kern is read for single-line melodies such as those of the
+// Essen folk song collection: one **kern spine (the first, when there are
+// several), with the other spines' columns skipped.
+//
+//	!!!OTL: Der Mai
+//	**kern
+//	*M3/4
+//	*k[f#]
+//	*G:
+//	4d
+//	=1
+//	4g 4b
+//	8a 8g 4f#
+//	=2:|!
+//	2.g
+//	==
+//	*-
+//
+// A token is a duration (1 2 4 8 16 32 64, with dots; other numbers such as
+// 12 or 20 are tuplets), a pitch (c is middle C, cc an octave higher, C an
+// octave lower, with # - and n accidentals) or r for a rest, and optional
+// signifiers: [ _ ] ties, q and qq grace notes, ' ` ^ ~ articulations and
+// T t M m W w S ornaments. Space-separated tokens form a chord. = lines are
+// bar lines, numbered as the measures that follow them, with :| and |:
+// for repeats. Key (*k[...], *G:, *e:dor), meter (*M), tempo (*MM) and
+// clef interpretations are read; slurs, phrases, fermatas and other
+// signifiers the score cannot hold become warnings.
+
+// kernExtension is the file extension of **kern files read by LoadKernDir
+const kernExtension = ".krn"
+
+var kernClefs = map[string]string{
+	"*clefG2": music.TrebleClef,
+	"*clefF4": music.BassClef,
+	"*clefC3": music.AltoClef,
+	"*clefC4": music.TenorClef,
+}
+
+var kernArticulations = map[rune]music.Articulation{
+	'\'': music.Staccato,
+	'`':  music.Staccatissimo,
+	'^':  music.Accent,
+	'~':  music.Tenuto,
+}
+
+var kernOrnaments = map[rune]music.Ornament{
+	'T': music.Trill,
+	't': music.Trill,
+	'M': music.Mordent,
+	'm': music.Mordent,
+	'W': music.InvertedMordent,
+	'w': music.InvertedMordent,
+	'S': music.Turn,
+}
+
+// kernIgnored are signifiers for beams, stems and editorial marks that
+// change nothing in the score
+const kernIgnored = "LJKk/\\xXyY?<>"
+
+// kernSkipped names the signifiers the score cannot hold, for warnings
+var kernSkipped = map[rune]string{
+	'(': "slur", ')': "slur", '{': "phrase", '}': "phrase", ';': "fermata",
+	'z': "sforzando", 'v': "bowing", 'u': "bowing", 'O': "ornament", '$': "ornament",
+	'R': "ornament", 'I': "articulation", 'P': "appoggiatura", 'p': "appoggiatura",
+	'Q': "groupetto", 'H': "glissando", 'h': "glissando",
+}
+
+// kernModes maps the mode suffix of a key interpretation such as *e:dor to
+// the Norwegian mode names
+var kernModes = map[string]string{
+	"ion": "ionisk",
+	"dor": "dorisk",
+	"phr": "frygisk",
+	"lyd": "lydisk",
+	"mix": "miksisk",
+	"aeo": "æolisk",
+	"loc": "lokrisk",
+}
+
+// kernTuplet is a tuplet being read; it closes when its notes add up to a
+// plain note value
+type kernTuplet struct {
+	actual, normal int
+	first          music.ElementRef
+	length         fraction
+	line, col      int
+}
+
+type kernParser struct {
+	score    *music.Score
+	warnings []Warning
+	skipped  map[string]int // signifier name to index in warnings
+	counts   map[string]int
+
+	spine int // column of the **kern spine read
+	line  int
+	ended bool // the spine ended with *-
+
+	clef        string
+	pendingClef string
+	meter       music.TimeSignature
+	fifths      int
+	keyAlters   [7]int
+	barAlters   map[int]int
+	keyRead     bool   // whether a key has been set before the first note
+	number      int    // measure number from the last bar line, -1 if none
+	numbered    []bool // whether each measure's number was written
+	repeatStart bool
+	measure     *music.Measure
+	tuplet      *kernTuplet
+	placed      bool
+}
+
+// KernFile is a score read from a **kern corpus
+type KernFile struct {
+	Path     string
+	Score    *music.Score
+	Warnings []Warning
+}
+
+// SkippedFile is a corpus file that could not be read
+type SkippedFile struct {
+	Path string
+	Err  error
+}
+
+// LoadKernFile reads a **kern file
+func LoadKernFile(path string) (*music.Score, []Warning, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	score, warnings, err := ParseKern(string(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return score, warnings, nil
+}
+
+// LoadKernDir reads every .krn file in dir and its subdirectories, sorted
+// by path. Files that cannot be read are skipped and returned with their
+// errors; err is set only when the directory itself cannot be walked.
+func LoadKernDir(dir string) (files []KernFile, skipped []SkippedFile, err error) {
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), kernExtension) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read kern directory: %w", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		score, warnings, err := LoadKernFile(path)
+		if err != nil {
+			skipped = append(skipped, SkippedFile{Path: path, Err: err})
+			continue
+		}
+		files = append(files, KernFile{Path: path, Score: score, Warnings: warnings})
+	}
+	return files, skipped, nil
+}
+
+// ParseKern reads the first **kern spine of a Humdrum file, described at
+// the top of this file. Errors are *ParseError values.
+func ParseKern(src string) (*music.Score, []Warning, error) {
+	p := &kernParser{
+		score:     music.NewScore("", "", "C", "dur", 4, 4, 0),
+		skipped:   make(map[string]int),
+		counts:    make(map[string]int),
+		spine:     -1,
+		clef:      music.TrebleClef,
+		meter:     music.TimeSignature{Numerator: 4, Denominator: 4},
+		barAlters: make(map[int]int),
+		number:    -1,
+	}
+	for i, raw := range strings.Split(src, "\n") {
+		p.line = i + 1
+		if err := p.parseLine(strings.TrimRight(raw, "\r")); err != nil {
+			return nil, nil, err
+		}
+		if p.ended {
+			break
+		}
+	}
+	if err := p.finish(); err != nil {
+		return nil, nil, err
+	}
+	for name, i := range p.skipped {
+		if c := p.counts[name]; c > 1 {
+			p.warnings[i].Message += fmt.Sprintf(" (%d times)", c)
+		}
+	}
+	return p.score, p.warnings, nil
+}
+
+func (p *kernParser) errorf(col int, format string, args ...any) error {
+	return &ParseError{Line: p.line, Column: col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *kernParser) warn(col int, format string, args ...any) {
+	p.warnings = append(p.warnings, Warning{Line: p.line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+// skip reports something the score cannot hold once per name; ParseKern
+// adds how often it occurred
+func (p *kernParser) skip(col int, name string) {
+	p.counts[name]++
+	if _, seen := p.skipped[name]; !seen {
+		p.skipped[name] = len(p.warnings)
+		p.warn(col, "%s is not supported and was skipped", name)
+	}
+}
+
+// kernField is one tab-separated column of a line
+type kernField struct {
+	text string
+	col  int
+}
+
+func splitKernFields(line string) []kernField {
+	var fields []kernField
+	col := 1
+	for _, text := range strings.Split(line, "\t") {
+		fields = append(fields, kernField{text: text, col: col})
+		col += len([]rune(text)) + 1
+	}
+	return fields
+}
+
+func (p *kernParser) parseLine(line string) error {
+	if line == "" {
+		return nil
+	}
+	if strings.HasPrefix(line, "!!") {
+		p.parseReference(line)
+		return nil
+	}
+	fields := splitKernFields(line)
+
+	if p.spine < 0 {
+		if !strings.HasPrefix(line, "**") {
+			return p.errorf(1, "expected exclusive interpretations such as **kern before the data")
+		}
+		for i, f := range fields {
+			if f.text != "**kern" {
+				continue
+			}
+			if p.spine < 0 {
+				p.spine = i
+			} else {
+				p.warn(f.col, "only the first **kern spine is read")
+			}
+		}
+		if p.spine < 0 {
+			return p.errorf(1, "no **kern spine")
+		}
+		return nil
+	}
+
+	if p.spine >= len(fields) {
+		return p.errorf(1, "line has %d spines; the **kern spine is number %d", len(fields), p.spine+1)
+	}
+	f := fields[p.spine]
+	switch {
+	case strings.HasPrefix(line, "!"):
+		return nil
+	case strings.HasPrefix(line, "*"):
+		if isSpinePath(fields) {
+			if err := p.parseSpinePath(fields); err != nil || isSpinePath([]kernField{f}) {
+				return err
+			}
+		}
+		return p.parseInterpretation(f)
+	case strings.HasPrefix(f.text, "="):
+		return p.parseBar(f)
+	case f.text == ".":
+		return nil
+	}
+	return p.parseData(f)
+}
+
+// parseReference reads the title and composer reference records
+func (p *kernParser) parseReference(line string) {
+	key, value, ok := strings.Cut(strings.TrimPrefix(line, "!!!"), ":")
+	if !ok || !strings.HasPrefix(line, "!!!") {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch key {
+	case "OTL":
+		if p.score.Title == "" {
+			p.score.Title = value
+		}
+	case "COM":
+		if p.score.Composer == "" {
+			p.score.Composer = value
+		}
+	}
+}
+
+// isSpinePath reports whether an interpretation line splits, joins, adds,
+// exchanges or ends spines
+func isSpinePath(fields []kernField) bool {
+	for _, f := range fields {
+		switch f.text {
+		case "*^", "*v", "*+", "*x", "*-":
+			return true
+		}
+	}
+	return false
+}
+
+// parseSpinePath follows the **kern spine's column through spine
+// splits, joins and endings in the other spines
+func (p *kernParser) parseSpinePath(fields []kernField) error {
+	spine := p.spine
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		switch f.text {
+		case "*^":
+			if i == p.spine {
+				return p.errorf(f.col, "the **kern spine splits; only single-line melodies are read")
+			}
+			if i < p.spine {
+				spine++
+			}
+		case "*v":
+			j := i
+			for j+1 < len(fields) && fields[j+1].text == "*v" {
+				j++
+			}
+			if p.spine >= i && p.spine <= j && j > i {
+				return p.errorf(f.col, "the **kern spine is joined with another spine")
+			}
+			if j < p.spine {
+				spine -= j - i
+			}
+			i = j
+		case "*+":
+			if i < p.spine {
+				spine++
+			}
+		case "*x":
+			// The two *x spines exchange places
+			if i == p.spine {
+				for j := range fields {
+					if j != i && fields[j].text == "*x" {
+						spine = j
+					}
+				}
+			} else if fields[p.spine].text == "*x" {
+				spine = i
+			}
+		case "*-":
+			if i == p.spine {
+				p.ended = true
+				return nil
+			}
+			if i < p.spine {
+				spine--
+			}
+		}
+	}
+	p.spine = spine
+	return nil
+}
+
+// parseInterpretation reads a key, meter, tempo or clef interpretation
+func (p *kernParser) parseInterpretation(f kernField) error {
+	text := f.text
+	switch {
+	case text == "*":
+	case strings.HasPrefix(text, "*k["):
+		return p.parseKeySignature(f)
+	case strings.HasPrefix(text, "*MM"):
+		bpm, err := strconv.ParseFloat(strings.TrimPrefix(text, "*MM"), 64)
+		if err != nil || bpm <= 0 {
+			return p.errorf(f.col, "bad tempo %q", text)
+		}
+		if p.score.Tempo == 0 {
+			p.score.Tempo = int(bpm + 0.5)
+		}
+	case strings.HasPrefix(text, "*M"):
+		return p.parseMeter(f)
+	case strings.HasPrefix(text, "*clef"):
+		clef, ok := kernClefs[text]
+		if !ok {
+			p.warn(f.col, "clef %s is not supported", strings.TrimPrefix(text, "*clef"))
+			return nil
+		}
+		if p.placed {
+			p.pendingClef = clef
+		} else {
+			p.clef = clef
+		}
+	case strings.Contains(text, ":"):
+		return p.parseKey(f)
+	case strings.HasPrefix(text, "*>["):
+		p.warn(f.col, "section expansion lists are not supported; the music is read as written")
+	case strings.HasPrefix(text, "*I"), strings.HasPrefix(text, "*met"), strings.HasPrefix(text, "*staff"),
+		strings.HasPrefix(text, "*>"), strings.HasPrefix(text, "*tb"), strings.HasPrefix(text, "*Tr"):
+		// Instruments, mensuration signs, staff numbers, section labels and
+		// timebases say nothing the score holds
+	default:
+		p.skip(f.col, fmt.Sprintf("interpretation %s", text))
+	}
+	return nil
+}
+
+// parseKeySignature reads *k[f#c#] or *k[b-e-]
+func (p *kernParser) parseKeySignature(f kernField) error {
+	body := strings.TrimPrefix(f.text, "*k[")
+	body, ok := strings.CutSuffix(body, "]")
+	if !ok {
+		return p.errorf(f.col, "bad key signature %q", f.text)
+	}
+	fifths := 0
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte("abcdefg", body[i]) < 0 {
+			return p.errorf(f.col, "bad key signature %q", f.text)
+		}
+		for i+1 < len(body) && (body[i+1] == '#' || body[i+1] == '-') {
+			i++
+			if body[i] == '#' {
+				fifths++
+			} else {
+				fifths--
+			}
+		}
+	}
+	p.setKey(f, fifths, nil)
+	return nil
+}
+
+// parseKey reads a key such as *G:, *e-:, *f#: or *d:dor; capitals are
+// major and small letters minor
+func (p *kernParser) parseKey(f kernField) error {
+	name, modeName, _ := strings.Cut(strings.TrimPrefix(f.text, "*"), ":")
+	if name == "" || strings.IndexRune("abcdefgABCDEFG", rune(name[0])) < 0 {
+		p.skip(f.col, fmt.Sprintf("interpretation %s", f.text))
+		return nil
+	}
+	letter := rune(name[0])
+	step := strings.IndexRune("cdefgab", letter|0x20)
+	alter := 0
+	for _, r := range name[1:] {
+		switch r {
+		case '#':
+			alter++
+		case '-':
+			alter--
+		default:
+			return p.errorf(f.col, "bad key %q", f.text)
+		}
+	}
+	mode := "dur"
+	if letter >= 'a' {
+		mode = "moll"
+	}
+	if modeName != "" {
+		var ok bool
+		if mode, ok = kernModes[modeName]; !ok {
+			return p.errorf(f.col, "unknown mode %q", modeName)
+		}
+	}
+	key := keySignature(step, alter, mode)
+	p.setKey(f, theory.Key{Step: step, Alter: alter, Mode: mode}.Fifths(), &key)
+	return nil
+}
+
+// setKey sets the key signature, and the key's name when given. Keys after
+// the first note are not shown, so the accidentals keep following the
+// first key.
+func (p *kernParser) setKey(f kernField, fifths int, key *music.KeySignature) {
+	if p.placed {
+		if fifths != p.fifths {
+			p.warn(f.col, "key changes are not shown; the notes after it keep their pitch")
+		}
+		return
+	}
+	if key != nil {
+		p.score.KeySignature = *key
+	} else if fifths != p.fifths || !p.keyRead {
+		k := theory.KeyOfFifths(fifths, "dur")
+		p.score.KeySignature = keySignature(k.Step, k.Alter, k.Mode)
+	}
+	p.keyRead = true
+	p.fifths = fifths
+	p.keyAlters = theory.SignatureAlterations(fifths)
+}
+
+func (p *kernParser) parseMeter(f kernField) error {
+	num, den, ok := strings.Cut(strings.TrimPrefix(f.text, "*M"), "/")
+	n, err1 := strconv.Atoi(num)
+	d, err2 := strconv.Atoi(den)
+	if !ok || err1 != nil || err2 != nil || n <= 0 || d <= 0 || d&(d-1) != 0 {
+		p.skip(f.col, fmt.Sprintf("meter %s", strings.TrimPrefix(f.text, "*")))
+		return nil
+	}
+	p.meter = music.TimeSignature{Numerator: n, Denominator: d}
+	if !p.placed {
+		p.score.TimeSignature = p.meter
+		for _, m := range p.score.Measures {
+			m.TimeSignature = p.meter
+		}
+	}
+	return nil
+}
+
+// parseBar reads a bar line such as =, =12, =3:|!, =!|: or ==
+func (p *kernParser) parseBar(f kernField) error {
+	if p.tuplet != nil {
+		return p.errorf(p.tuplet.col, "tuplet crosses a bar line")
+	}
+	text := strings.TrimPrefix(f.text, "=")
+	digits := len(text) - len(strings.TrimLeft(text, "0123456789"))
+	p.number = -1
+	if digits > 0 {
+		p.number, _ = strconv.Atoi(text[:digits])
+	}
+	style := text[digits:]
+
+	endRepeat := strings.Contains(style, ":|") || strings.Contains(style, ":!")
+	if p.measure != nil {
+		p.measure.RepeatEnd = p.measure.RepeatEnd || endRepeat
+	} else if endRepeat && len(p.score.Measures) > 0 {
+		p.score.Measures[len(p.score.Measures)-1].RepeatEnd = true
+	}
+	p.repeatStart = strings.Contains(style, "|:") || strings.Contains(style, "!:")
+	p.measure = nil
+	p.barAlters = make(map[int]int)
+	return nil
+}
+
+// ensureMeasure starts a measure if the last one was closed by a bar line
+func (p *kernParser) ensureMeasure() {
+	if p.measure != nil {
+		return
+	}
+	var ts *music.TimeSignature
+	if p.meter != p.score.TimeSignature {
+		t := p.meter
+		ts = &t
+	}
+	m := p.score.AddMeasure(ts)
+	if p.number >= 0 {
+		m.Number = p.number
+	}
+	p.numbered = append(p.numbered, p.number >= 0)
+	if len(p.score.Measures) == 1 {
+		m.Clef = p.clef
+	}
+	if p.pendingClef != "" {
+		p.clef = p.pendingClef
+		m.Clef = p.clef
+		p.pendingClef = ""
+	}
+	m.RepeatStart = p.repeatStart
+	p.repeatStart = false
+	p.measure = m
+}
+
+// kernNote is a note or rest token of a chord
+type kernNote struct {
+	rest          bool
+	diatonic      int
+	alter         int
+	natural       bool // written with n
+	hasAccidental bool
+	tie           bool
+	grace         music.GraceType
+	articulations []music.Articulation
+	ornaments     []music.Ornament
+	// the written note value, dots and kern duration (4 for a quarter)
+	value    music.NoteValue
+	dots     int
+	duration int
+	hasDur   bool
+}
+
+// parseData reads a note, rest or chord token
+func (p *kernParser) parseData(f kernField) error {
+	var notes []kernNote
+	col := f.col
+	for _, token := range strings.Split(f.text, " ") {
+		if token == "" {
+			col++
+			continue
+		}
+		n, err := p.parseToken(token, col)
+		if err != nil {
+			return err
+		}
+		notes = append(notes, n)
+		col += len([]rune(token)) + 1
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+	head := notes[0]
+	if !head.hasDur {
+		if head.grace == music.GraceNone {
+			return p.errorf(f.col, "%q has no duration", f.text)
+		}
+		head.value, head.duration = music.EighthNote, 8
+	}
+	for i := range notes[1:] {
+		n := &notes[i+1]
+		if n.rest {
+			return p.errorf(f.col, "rest inside a chord")
+		}
+		if n.hasDur && (n.duration != head.duration || n.dots != head.dots) {
+			return p.errorf(f.col, "chord notes of different lengths")
+		}
+	}
+	if head.rest && len(notes) > 1 {
+		return p.errorf(f.col, "rest inside a chord")
+	}
+
+	p.ensureMeasure()
+	p.placed = true
+	ref := music.ElementRef{Measure: len(p.score.Measures) - 1, Element: len(p.measure.Elements)}
+	if head.rest {
+		p.measure.AddRest(&music.Rest{Duration: head.value, Dots: head.dots})
+	} else {
+		for i, n := range notes {
+			p.measure.AddNote(p.newNote(n, head, i > 0))
+		}
+	}
+	if head.grace == music.GraceNone {
+		return p.countTuplet(head, ref, f.col)
+	}
+	return nil
+}
+
+// newNote builds a chord note with the head's length and marks
+func (p *kernParser) newNote(n, head kernNote, follower bool) *music.Note {
+	step := ((n.diatonic % 7) + 7) % 7
+	implied, ok := p.barAlters[n.diatonic]
+	if !ok {
+		implied = p.keyAlters[step]
+	}
+	accidental := music.AccidentalNone
+	if n.alter != implied || (n.natural && n.alter == 0) {
+		accidental = music.AlterAccidental(n.alter)
+		p.barAlters[n.diatonic] = n.alter
+	}
+	note := &music.Note{
+		Pitch:      music.NaturalMIDI(n.diatonic) + n.alter,
+		Duration:   head.value,
+		Dots:       head.dots,
+		StaffLine:  n.diatonic - music.ClefBottomLine(p.clef),
+		Accidental: accidental,
+		Grace:      head.grace,
+		Tie:        n.tie,
+		Chord:      follower,
+	}
+	if !follower {
+		note.Articulations = head.articulations
+		note.Ornaments = head.ornaments
+	}
+	return note
+}
+
+// countTuplet adds an element to the open tuplet, or opens one for a
+// tuplet duration, and closes it once its notes fill a plain note value
+func (p *kernParser) countTuplet(n kernNote, ref music.ElementRef, col int) error {
+	actual, normal := kernTupletRatio(n.duration)
+	length := newFraction(1, n.duration)
+	dotted := length
+	for i := 0; i < n.dots; i++ {
+		length = length.add(newFraction(dotted.num, dotted.den<<(i+1)))
+	}
+	if p.tuplet == nil {
+		if actual == 1 {
+			return nil
+		}
+		p.tuplet = &kernTuplet{actual: actual, normal: normal, first: ref, length: newFraction(0, 1), line: p.line, col: col}
+	} else if actual == 1 {
+		return p.errorf(p.tuplet.col, "tuplet is not complete")
+	}
+	p.tuplet.length = p.tuplet.length.add(length)
+	if den := p.tuplet.length.den; den&(den-1) == 0 {
+		t := p.tuplet
+		p.tuplet = nil
+		err := p.measure.AddTuplet(music.Tuplet{Actual: t.actual, Normal: t.normal, Start: t.first.Element, End: len(p.measure.Elements) - 1})
+		if err != nil {
+			return &ParseError{Line: t.line, Column: t.col, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// kernNoteValue returns the written note value of a duration: the value
+// itself for plain durations, and for tuplets the plain value just longer
+// than the tuplet note, so 12 is written as an eighth
+func kernNoteValue(duration int) (music.NoteValue, bool) {
+	nv := music.WholeNote
+	for p := 2; p <= duration; p *= 2 {
+		nv++
+	}
+	return nv, nv <= music.SixtyFourthNote
+}
+
+// kernTupletRatio returns the tuplet ratio of a duration: 1:1 for the
+// plain values, 3:2 for 12 (eighth triplets), 5:4 for 20, and so on
+func kernTupletRatio(duration int) (actual, normal int) {
+	p := 1
+	for p*2 <= duration {
+		p *= 2
+	}
+	g := gcd(duration, p)
+	return duration / g, p / g
+}
+
+// parseToken reads one note or rest token
+func (p *kernParser) parseToken(token string, col int) (kernNote, error) {
+	var n kernNote
+	runes := []rune(token)
+	letter := rune(0)
+	count := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r >= '0' && r <= '9':
+			if n.hasDur {
+				return n, p.errorf(col+i, "second duration in %q", token)
+			}
+			j := i
+			for j < len(runes) && runes[j] >= '0' && runes[j] <= '9' {
+				j++
+			}
+			d, _ := strconv.Atoi(string(runes[i:j]))
+			if d == 0 {
+				return n, p.errorf(col+i, "breves and longer notes are not supported")
+			}
+			nv, ok := kernNoteValue(d)
+			if !ok {
+				return n, p.errorf(col+i, "duration %d is not supported", d)
+			}
+			n.value, n.duration, n.hasDur = nv, d, true
+			i = j - 1
+		case r == '.':
+			n.dots++
+		case strings.ContainsRune("abcdefgABCDEFG", r):
+			if letter != 0 && r != letter {
+				return n, p.errorf(col+i, "%q has more than one pitch", token)
+			}
+			letter = r
+			count++
+		case r == 'r':
+			n.rest = true
+		case r == '#':
+			n.alter++
+			n.hasAccidental = true
+		case r == '-':
+			n.alter--
+			n.hasAccidental = true
+		case r == 'n':
+			n.natural = true
+			n.hasAccidental = true
+		case r == '[' || r == '_':
+			n.tie = true
+		case r == ']':
+		case r == 'q':
+			if n.grace == music.Acciaccatura {
+				n.grace = music.Appoggiatura
+			} else {
+				n.grace = music.Acciaccatura
+			}
+		case kernArticulations[r] != "":
+			n.articulations = append(n.articulations, kernArticulations[r])
+		case kernOrnaments[r] != "":
+			n.ornaments = append(n.ornaments, kernOrnaments[r])
+		case strings.ContainsRune(kernIgnored, r):
+		case kernSkipped[r] != "":
+			p.skip(col+i, kernSkipped[r])
+		default:
+			return n, p.errorf(col+i, "unexpected %q in %q", r, token)
+		}
+	}
+	if n.rest {
+		if letter != 0 {
+			return n, p.errorf(col, "%q is both a note and a rest", token)
+		}
+		return n, nil
+	}
+	if letter == 0 {
+		return n, p.errorf(col, "%q has no pitch", token)
+	}
+	step := strings.IndexRune("cdefgab", letter|0x20)
+	octave := 3 - (count - 1)
+	if letter >= 'a' {
+		octave = 4 + count - 1
+	}
+	n.diatonic = octave*7 + step
+	if n.alter < -2 || n.alter > 2 {
+		return n, p.errorf(col, "%q has too many accidentals", token)
+	}
+	return n, nil
+}
+
+// finish checks for an unclosed tuplet, marks an incomplete first bar as a
+// pickup and numbers the measures whose bar lines had no number, counting
+// a pickup as measure 0
+func (p *kernParser) finish() error {
+	if p.spine < 0 {
+		return p.errorf(1, "no **kern spine")
+	}
+	if p.tuplet != nil {
+		return &ParseError{Line: p.tuplet.line, Column: p.tuplet.col, Message: "tuplet is not complete"}
+	}
+	if len(p.score.Measures) == 0 {
+		return p.errorf(1, "no notes")
+	}
+	if len(p.score.Measures) > 1 {
+		m := p.score.Measures[0]
+		quarters := 0.0
+		for i := range m.Elements {
+			quarters += m.ElementQuarters(i)
+		}
+		capacity := float64(m.TimeSignature.Numerator) * 4 / float64(m.TimeSignature.Denominator)
+		m.Pickup = quarters < capacity-fillTolerance
+	}
+	number := 1
+	if p.score.Measures[0].Pickup {
+		number = 0
+	}
+	for i, m := range p.score.Measures {
+		if p.numbered[i] {
+			number = m.Number
+		} else {
+			m.Number = number
+		}
+		number++
+	}
+	return nil
+}
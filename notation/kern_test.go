@@ -0,0 +1,388 @@
+package notation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gehoer/music"
+)
+
+// kern joins lines into a **kern file
+func kern(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func parseKern(t *testing.T, src string) (*music.Score, []Warning) {
+	t.Helper()
+	score, warnings, err := ParseKern(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return score, warnings
+}
+
+// firstNote returns the first note of a score
+func firstNote(t *testing.T, score *music.Score) *music.Note {
+	t.Helper()
+	for _, m := range score.Measures {
+		for _, e := range m.Elements {
+			if n, ok := e.(*music.Note); ok {
+				return n
+			}
+		}
+	}
+	t.Fatal("no notes")
+	return nil
+}
+
+func TestParseKernPitches(t *testing.T) {
+	tests := []struct {
+		token      string
+		pitch      int
+		staffLine  int
+		accidental string
+	}{
+		{"4c", 60, -2, music.AccidentalNone},
+		{"4cc", 72, 5, music.AccidentalNone},
+		{"4ccc", 84, 12, music.AccidentalNone},
+		{"4b", 71, 4, music.AccidentalNone},
+		{"4C", 48, -9, music.AccidentalNone},
+		{"4CC", 36, -16, music.AccidentalNone},
+		{"4B", 59, -3, music.AccidentalNone},
+		{"4f#", 66, 1, music.AccidentalSharp},
+		{"4e-", 63, 0, music.AccidentalFlat},
+		{"4f##", 67, 1, music.AccidentalDoubleSharp},
+		{"4b--", 69, 4, music.AccidentalDoubleFlat},
+		{"4en", 64, 0, music.AccidentalNatural},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			score, _ := parseKern(t, kern("**kern", tt.token, "*-"))
+			n := firstNote(t, score)
+			if n.Pitch != tt.pitch || n.StaffLine != tt.staffLine || n.Accidental != tt.accidental {
+				t.Errorf("pitch %d on line %d with %q, want %d on line %d with %q",
+					n.Pitch, n.StaffLine, n.Accidental, tt.pitch, tt.staffLine, tt.accidental)
+			}
+		})
+	}
+}
+
+func TestParseKernDurations(t *testing.T) {
+	tests := []struct {
+		token string
+		value music.NoteValue
+		dots  int
+		rest  bool
+	}{
+		{"1c", music.WholeNote, 0, false},
+		{"2.c", music.HalfNote, 1, false},
+		{"4..c", music.QuarterNote, 2, false},
+		{"8c", music.EighthNote, 0, false},
+		{"16c", music.SixteenthNote, 0, false},
+		{"32c", music.ThirtySecondNote, 0, false},
+		{"64c", music.SixtyFourthNote, 0, false},
+		{"c4", music.QuarterNote, 0, false},
+		{"8.r", music.EighthNote, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			score, _ := parseKern(t, kern("**kern", tt.token, "*-"))
+			got := measureElements(score)[0][0]
+			if got.value != tt.value || got.dots != tt.dots || got.rest != tt.rest {
+				t.Errorf("got %+v, want value %v with %d dots (rest %v)", got, tt.value, tt.dots, tt.rest)
+			}
+		})
+	}
+}
+
+func TestParseKernTuplets(t *testing.T) {
+	score, _ := parseKern(t, kern("**kern", "*M2/4", "12c", "12d", "12e", "4f", "*-"))
+	m := score.Measures[0]
+	want := []music.Tuplet{{Actual: 3, Normal: 2, Start: 0, End: 2}}
+	if !reflect.DeepEqual(m.Tuplets, want) {
+		t.Errorf("tuplets %+v, want %+v", m.Tuplets, want)
+	}
+	if got := m.Elements[0].(*music.Note).Duration; got != music.EighthNote {
+		t.Errorf("12 written as %v, want an eighth", got)
+	}
+
+	_, _, err := ParseKern(kern("**kern", "12c", "12d", "=", "12e", "*-"))
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 4 {
+		t.Errorf("got %v, want a tuplet crossing the bar line reported at the bar line", err)
+	}
+}
+
+func TestParseKernBarlines(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		numbers []int
+		order   []int
+		pickup  bool
+	}{
+		{"unnumbered", []string{"*M2/4", "2c", "=", "2d", "=", "2e", "=="}, []int{1, 2, 3}, []int{0, 1, 2}, false},
+		{"numbered", []string{"*M2/4", "=4", "2c", "=5", "2d", "=7", "2e", "=="}, []int{4, 5, 7}, []int{0, 1, 2}, false},
+		{"pickup", []string{"*M2/4", "4c", "=1", "2d", "=", "2e", "=="}, []int{0, 1, 2}, []int{0, 1, 2}, true},
+		{"repeats", []string{"*M2/4", "2c", "=2!|:", "2d", "=3:|!", "2e", "=="}, []int{1, 2, 3}, []int{0, 1, 1, 2}, false},
+		{"end repeat after a double bar", []string{"*M2/4", "2c", "=", "2d", "=:|!"}, []int{1, 2}, []int{0, 1, 0, 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append([]string{"**kern"}, tt.lines...)
+			score, _ := parseKern(t, kern(append(lines, "*-")...))
+			var numbers []int
+			for _, m := range score.Measures {
+				numbers = append(numbers, m.Number)
+			}
+			if !reflect.DeepEqual(numbers, tt.numbers) {
+				t.Errorf("measures numbered %v, want %v", numbers, tt.numbers)
+			}
+			if got := score.PlaybackOrder(); !reflect.DeepEqual(got, tt.order) {
+				t.Errorf("played %v, want %v", got, tt.order)
+			}
+			if got := score.Measures[0].Pickup; got != tt.pickup {
+				t.Errorf("pickup %v, want %v", got, tt.pickup)
+			}
+		})
+	}
+}
+
+func TestParseKernKeyAndMeter(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		key      music.KeySignature
+		meter    music.TimeSignature
+		pitches  []int
+		marks    string
+		warnings int
+	}{
+		{
+			"signature only", []string{"*k[f#c#]", "*M3/4", "4f#", "4fn", "4c#"},
+			music.KeySignature{Tonic: "D", Mode: "dur"}, music.TimeSignature{Numerator: 3, Denominator: 4},
+			[]int{66, 65, 61}, "- natural -", 0,
+		},
+		{
+			"pitches are written in full", []string{"*k[f#]", "4f", "4f#"},
+			music.KeySignature{Tonic: "G", Mode: "dur"}, music.TimeSignature{Numerator: 4, Denominator: 4},
+			[]int{65, 66}, "natural sharp", 0,
+		},
+		{
+			"signature and key", []string{"*k[b-e-a-]", "*c:", "*M6/8", "4.e-", "4.b-"},
+			music.KeySignature{Tonic: "c", Mode: "moll"}, music.TimeSignature{Numerator: 6, Denominator: 8},
+			[]int{63, 70}, "- -", 0,
+		},
+		{
+			"key with a mode", []string{"*k[f#]", "*e:dor", "1f#"},
+			music.KeySignature{Tonic: "E", Mode: "dorisk"}, music.TimeSignature{Numerator: 4, Denominator: 4},
+			[]int{66}, "-", 0,
+		},
+		{
+			"accidental lasts the bar", []string{"*k[]", "*M2/4", "4f#", "4f", "=", "4f", "4f#"},
+			music.KeySignature{Tonic: "C", Mode: "dur"}, music.TimeSignature{Numerator: 2, Denominator: 4},
+			[]int{66, 65, 65, 66}, "sharp natural | - sharp", 0,
+		},
+		{
+			"unreadable meter", []string{"*M3/5", "*MX", "4c"},
+			music.KeySignature{Tonic: "C", Mode: "dur"}, music.TimeSignature{Numerator: 4, Denominator: 4},
+			[]int{60}, "-", 2,
+		},
+		{
+			"key change after the first note", []string{"*k[]", "4c", "*k[f#]", "4f#"},
+			music.KeySignature{Tonic: "C", Mode: "dur"}, music.TimeSignature{Numerator: 4, Denominator: 4},
+			[]int{60, 66}, "- sharp", 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append([]string{"**kern"}, tt.lines...)
+			score, warnings := parseKern(t, kern(append(lines, "*-")...))
+			if score.KeySignature != tt.key || score.TimeSignature != tt.meter {
+				t.Errorf("key %v in %v, want %v in %v", score.KeySignature, score.TimeSignature, tt.key, tt.meter)
+			}
+			var pitches []int
+			for _, m := range measureElements(score) {
+				for _, e := range m {
+					pitches = append(pitches, e.pitch)
+				}
+			}
+			if !reflect.DeepEqual(pitches, tt.pitches) {
+				t.Errorf("pitches %v, want %v", pitches, tt.pitches)
+			}
+			if got := accidentalMarks(score); got != tt.marks {
+				t.Errorf("accidentals %q, want %q", got, tt.marks)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("warnings %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+
+	t.Run("bad signature", func(t *testing.T) {
+		if _, _, err := ParseKern(kern("**kern", "*k[x#]", "4c", "*-")); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
+
+func TestParseKernTies(t *testing.T) {
+	score, _ := parseKern(t, kern("**kern", "*M2/4", "4c", "4d[", "=", "4d_", "4d]", "=", "2e", "*-"))
+	want := [][]placed{
+		{{value: music.QuarterNote, pitch: 60}, {value: music.QuarterNote, tie: true, pitch: 62}},
+		{{value: music.QuarterNote, tie: true, pitch: 62}, {value: music.QuarterNote, pitch: 62}},
+		{{value: music.HalfNote, pitch: 64}},
+	}
+	if got := measureElements(score); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+
+	score, _ = parseKern(t, kern("**kern", "2c[ 2e[", "2c] 2e]", "*-"))
+	m := score.Measures[0]
+	for i, e := range m.Elements {
+		n := e.(*music.Note)
+		if n.Tie != (i < 2) || n.Chord != (i%2 == 1) {
+			t.Errorf("note %d has tie %v and chord %v", i, n.Tie, n.Chord)
+		}
+	}
+}
+
+func TestParseKernSpines(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		pitches  []int
+		warnings int
+	}{
+		{
+			"other spines are skipped",
+			kern("**text\t**kern\t**dynam", "*\t*M2/4\t*", "la\t4c\tp", "la\t4d\t.", "*-\t*-\t*-"),
+			[]int{60, 62}, 0,
+		},
+		{
+			"only the first kern spine is read",
+			kern("**kern\t**kern", "4C\t4c", "4D\t4d", "*-\t*-"),
+			[]int{48, 50}, 1,
+		},
+		{
+			"another spine splits and joins",
+			kern("**dynam\t**kern", "*^\t*", "p\tf\t4c", "*v\t*v\t*", ".\t4d", "*-\t*-"),
+			[]int{60, 62}, 0,
+		},
+		{
+			"spine ends before the kern spine",
+			kern("**dynam\t**kern", "p\t4c", "*-\t*", "4d", "*-"),
+			[]int{60, 62}, 0,
+		},
+		{
+			"spines exchange places",
+			kern("**kern\t**dynam", "4c\tp", "*x\t*x", "p\t4d", "*-\t*-"),
+			[]int{60, 62}, 0,
+		},
+		{
+			"null tokens and local comments",
+			kern("**kern\t**dynam", "4c\tp", ".\tf", "!komm\t!", "4d\t.", "*-\t*-"),
+			[]int{60, 62}, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, warnings := parseKern(t, tt.src)
+			var pitches []int
+			for _, m := range measureElements(score) {
+				for _, e := range m {
+					pitches = append(pitches, e.pitch)
+				}
+			}
+			if !reflect.DeepEqual(pitches, tt.pitches) {
+				t.Errorf("pitches %v, want %v", pitches, tt.pitches)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("warnings %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestParseKernReferenceRecords(t *testing.T) {
+	score, _ := parseKern(t, kern("!!!OTL: Der Mai", "!!!COM: Ukjent", "!! kommentar", "**kern", "4c", "*-"))
+	if score.Title != "Der Mai" || score.Composer != "Ukjent" {
+		t.Errorf("title %q and composer %q", score.Title, score.Composer)
+	}
+}
+
+func TestParseKernErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{"data before the header", kern("4c", "**kern"), 1},
+		{"no kern spine", kern("**text", "la", "*-"), 1},
+		{"no notes", kern("**kern", "*M2/4", "*-"), 3},
+		{"no duration", kern("**kern", "4c", "d", "*-"), 3},
+		{"no pitch", kern("**kern", "4", "*-"), 2},
+		{"chord of different lengths", kern("**kern", "4c 8e", "*-"), 2},
+		{"rest in a chord", kern("**kern", "4c 4r", "*-"), 2},
+		{"kern spine splits", kern("**kern", "*^", "4c\t4e", "*-\t*-"), 2},
+		{"missing spine", kern("**dynam\t**kern", "p", "*-"), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, _, err := ParseKern(tt.src)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got %v and %+v, want a parse error", err, score)
+			}
+			if pe.Line != tt.line {
+				t.Errorf("error %q on line %d, want line %d", pe, pe.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestLoadKernDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.krn":       kern("**kern", "4c", "*-"),
+		"b.krn":       kern("**kern", "4c", "4q#x", "*-"),
+		"sub/c.KRN":   kern("**kern", "4d", "*-"),
+		"notater.txt": "ikke kern",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read, skipped, err := LoadKernDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range read {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{filepath.Join(dir, "a.krn"), filepath.Join(dir, "sub", "c.KRN")}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("read %v, want %v", paths, want)
+	}
+	if len(skipped) != 1 || skipped[0].Path != filepath.Join(dir, "b.krn") {
+		t.Fatalf("skipped %+v, want b.krn", skipped)
+	}
+	var pe *ParseError
+	if !errors.As(skipped[0].Err, &pe) || pe.Line != 3 || !strings.Contains(skipped[0].Err.Error(), "b.krn") {
+		t.Errorf("b.krn skipped with %v, want a parse error on line 3 naming the file", skipped[0].Err)
+	}
+
+	if _, _, err := LoadKernDir(filepath.Join(dir, "mangler")); err == nil {
+		t.Error("missing directory read without an error")
+	}
+}
@@ -0,0 +1,19 @@
+package notation
+
+import (
+	"strings"
+
+	"gehoer/localization"
+	"gehoer/music"
+)
+
+// keySignature names a key the way scores do. Natural tonics are written
+// with a capital letter except in minor, as in the key list of the
+// localization package.
+func keySignature(step, alter int, mode string) music.KeySignature {
+	name := localization.FormatNoteName(step, alter)
+	if alter == 0 && mode != "moll" {
+		name = strings.ToUpper(name)
+	}
+	return music.KeySignature{Tonic: name, Mode: mode}
+}
@@ -0,0 +1,111 @@
+package notation
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gehoer/music"
+)
+
+// LilyPondVersion is the LilyPond version written in \version
+const LilyPondVersion = "2.24.0"
+
+// lilyLetters are LilyPond's default (Dutch) note names of the steps from C
+var lilyLetters = [7]string{"c", "d", "e", "f", "g", "a", "b"}
+
+// lilyNoteName spells a step and alteration with Dutch names: cis, es, as,
+// bes, fisis, ...
+func lilyNoteName(step, alter int) string {
+	name := lilyLetters[step]
+	switch {
+	case alter > 0:
+		name += strings.Repeat("is", alter)
+	case alter < 0 && (step == 2 || step == 5):
+		// es and as rather than ees and aes
+		name += "s" + strings.Repeat("es", -alter-1)
+	case alter < 0:
+		name += strings.Repeat("es", -alter)
+	}
+	return name
+}
+
+var lilyFormat = musicFormat{noteName: lilyNoteName, indent: "    ", repeats: true}
+
+// FormatLilyPond writes the score as a LilyPond file with one staff in
+// absolute octaves, one measure per line ending in a bar check. Repeats and
+// endings are written as repeat bar lines and volta brackets, so the file
+// prints the score as written without unfolding it.
+func FormatLilyPond(score *music.Score) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\\version %q\n\n", LilyPondVersion)
+	writeHeader(&b, score)
+	if score.Title != "" || score.Composer != "" {
+		b.WriteString("\n")
+	}
+	b.WriteString("\\score {\n  \\new Staff {\n")
+	writeMusic(&b, score, lilyFormat)
+	b.WriteString("  }\n  \\layout { }\n}\n")
+	return b.String()
+}
+
+// SaveLilyPondFile writes the score to a .ly file
+func SaveLilyPondFile(score *music.Score, path string) error {
+	if err := os.WriteFile(path, []byte(FormatLilyPond(score)), 0o644); err != nil {
+		return fmt.Errorf("failed to write LilyPond file: %w", err)
+	}
+	return nil
+}
+
+// repeatCommands lists the repeat commands between two measures; prev is
+// nil before the first measure and next is nil after the last
+func repeatCommands(prev, next *music.Measure) []string {
+	var cmds []string
+	if prev != nil && prev.Ending != 0 && (next == nil || next.Ending != prev.Ending) {
+		cmds = append(cmds, "(volta #f)")
+	}
+	if prev != nil && prev.RepeatEnd {
+		cmds = append(cmds, "end-repeat")
+	}
+	if next != nil && next.RepeatStart {
+		cmds = append(cmds, "start-repeat")
+	}
+	if next != nil && next.Ending != 0 && (prev == nil || prev.Ending != next.Ending) {
+		cmds = append(cmds, fmt.Sprintf(`(volta "%d.")`, next.Ending))
+	}
+	return cmds
+}
+
+// setRepeatCommands writes the commands as a Score.repeatCommands setting
+func setRepeatCommands(cmds []string) string {
+	return fmt.Sprintf(`\set Score.repeatCommands = #'(%s)`, strings.Join(cmds, " "))
+}
+
+// repeatMarks returns the repeat commands written before measure mi
+func repeatMarks(score *music.Score, mi int) []string {
+	var prev *music.Measure
+	if mi > 0 {
+		prev = score.Measures[mi-1]
+	}
+	if cmds := repeatCommands(prev, score.Measures[mi]); len(cmds) > 0 {
+		return []string{setRepeatCommands(cmds)}
+	}
+	return nil
+}
+
+// closingBar returns what follows the notes of measure mi: nothing inside
+// the score, and the closing repeat or final bar line after the last measure
+func closingBar(score *music.Score, mi int) []string {
+	if mi < len(score.Measures)-1 {
+		return nil
+	}
+	m := score.Measures[mi]
+	var items []string
+	if cmds := repeatCommands(m, nil); len(cmds) > 0 {
+		items = append(items, setRepeatCommands(cmds))
+	}
+	if !m.RepeatEnd {
+		items = append(items, `\bar "|."`)
+	}
+	return items
+}
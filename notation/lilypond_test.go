@@ -0,0 +1,68 @@
+package notation
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gehoer/music"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name, or rewrites the file with -update
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the output; run go test -update to accept it\ngot:\n%s", path, got)
+	}
+}
+
+func TestFormatLilyPondGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		score func(t *testing.T) *music.Score
+	}{
+		{"lisa_gikk_til_skolen.ly", func(t *testing.T) *music.Score {
+			score, err := music.LoadScoreFromJSON(filepath.Join("..", "assets", "scores", "lisa_gikk_til_skolen.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return score
+		}},
+		{"marks.ly", func(t *testing.T) *music.Score {
+			score, err := ParseText(`\header { title = "Merker" }
+				\clef bass \key ess \major \time 4/4 \tempo 4 = 72
+				c4\p-. d-> \< ess-- f\! | \grace g8 ass2\trill <c' ess' g'>4~ <c' ess' g'>4 |
+				\tuplet 3/2 { b,8 c d } ess4.\mordent r8 fiss,4 |`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return score
+		}},
+		{"repeats.ly", func(t *testing.T) *music.Score {
+			scores, err := ParseABC("X:1\nT:Repriser\nM:3/4\nL:1/8\nK:D\n|: A2 | d2 f2 a2 |1 g4 e2 :|2 d6 |]\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return scores[0]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			golden(t, tt.name, FormatLilyPond(tt.score(t)))
+		})
+	}
+}
@@ -0,0 +1,967 @@
+package notation
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// MEI is read for the part a single-staff score can hold: the title and
+// composer, the scoreDef's key, meter, clef and tempo, and for the first
+// staff and layer of each measure its notes, chords, rests, beams, tuplets
+// and grace notes, with ties, articulations, repeats, endings and the
+// dynam, hairpin, tempo, trill, mordent, turn and tie control events.
+// Both the MEI 4 attribute forms (key.sig, meter.count, clef.shape) and the
+// MEI 5 elements (keySig, meterSig, clef) are read. Everything else is
+// skipped and reported as a warning.
+
+// MEINamespace is the XML namespace of MEI documents
+const MEINamespace = "http://www.music-encoding.org/ns/mei"
+
+// xmlNode is an element of a parsed XML document
+type xmlNode struct {
+	name      string            // local name
+	attrs     map[string]string // by local name, so xml:id is "id"
+	children  []*xmlNode
+	text      string // character data directly inside the element
+	line, col int
+}
+
+// child returns the first child element with the name, or nil
+func (n *xmlNode) child(name string) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *xmlNode) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	return n.attrs[name]
+}
+
+// textContent returns the character data of the element and its
+// descendants with runs of white space collapsed
+func (n *xmlNode) textContent() string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(*xmlNode)
+	walk = func(n *xmlNode) {
+		b.WriteString(n.text)
+		for _, c := range n.children {
+			b.WriteString(" ")
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// parseXMLTree reads an XML document into a tree of elements with their
+// positions in the input
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	var lineStarts []int
+	lineStarts = append(lineStarts, 0)
+	for i, c := range data {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	position := func(offset int64) (line, col int) {
+		line = sort.SearchInts(lineStarts, int(offset)+1)
+		return line, int(offset) - lineStarts[line-1] + 1
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			n.line, n.col = position(offset)
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+var meiDurations = map[string]music.NoteValue{
+	"1":  music.WholeNote,
+	"2":  music.HalfNote,
+	"4":  music.QuarterNote,
+	"8":  music.EighthNote,
+	"16": music.SixteenthNote,
+	"32": music.ThirtySecondNote,
+	"64": music.SixtyFourthNote,
+}
+
+var meiAccidentals = map[string]int{"s": 1, "f": -1, "ss": 2, "x": 2, "ff": -2, "n": 0}
+
+var meiArticulations = map[string]music.Articulation{
+	"stacc":    music.Staccato,
+	"stacciss": music.Staccatissimo,
+	"acc":      music.Accent,
+	"marc":     music.Marcato,
+	"ten":      music.Tenuto,
+}
+
+// meiModes maps MEI mode names to the Norwegian mode names
+var meiModes = map[string]string{
+	"major":      "dur",
+	"minor":      "moll",
+	"ionian":     "ionisk",
+	"dorian":     "dorisk",
+	"phrygian":   "frygisk",
+	"lydian":     "lydisk",
+	"mixolydian": "miksisk",
+	"aeolian":    "æolisk",
+	"locrian":    "lokrisk",
+}
+
+// meiClefs maps clef shape and line to score clefs
+var meiClefs = map[string]string{
+	"G2": music.TrebleClef,
+	"F4": music.BassClef,
+	"C3": music.AltoClef,
+	"C4": music.TenorClef,
+}
+
+// meiIgnored are elements that carry no music the score could hold and
+// are skipped without a warning
+var meiIgnored = map[string]bool{
+	"pb": true, "sb": true, "pgHead": true, "pgFoot": true, "pgHead2": true, "pgFoot2": true,
+	"label": true, "labelAbbr": true, "instrDef": true, "grpSym": true, "annot": true,
+}
+
+// meiControl are the control events read once all notes are placed
+var meiControl = map[string]bool{
+	"dynam": true, "hairpin": true, "tempo": true, "trill": true, "mordent": true, "turn": true, "tie": true,
+}
+
+// meiEvent is a control event with the index of its measure
+type meiEvent struct {
+	node    *xmlNode
+	measure int
+}
+
+type meiReader struct {
+	score    *music.Score
+	warnings []Warning
+	skipped  map[string]int // element name to index in warnings
+	counts   map[string]int
+
+	staff       string // the staff read, by its n attribute
+	clef        string
+	pendingClef string
+	meter       music.TimeSignature
+	keyAlters   [7]int
+	barAlters   map[int]int
+	lastDur     music.NoteValue
+	grace       music.GraceType
+	ending      int
+	repeatStart bool // a rptboth bar line opens the next measure
+
+	measure *music.Measure
+	ids     map[string]music.ElementRef
+	events  []meiEvent
+}
+
+// LoadMEIFile reads an MEI file
+func LoadMEIFile(path string) (*music.Score, []Warning, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	score, warnings, err := ParseMEI(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return score, warnings, nil
+}
+
+// ParseMEI reads an MEI 4 or 5 document into a score, returning warnings
+// for the elements and attributes it skipped
+func ParseMEI(data []byte) (*music.Score, []Warning, error) {
+	root, err := parseXMLTree(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("MEI is not well-formed XML: %w", err)
+	}
+	if root.name != "mei" {
+		return nil, nil, &ParseError{Line: root.line, Column: root.col, Message: fmt.Sprintf("root element is <%s>, not <mei>", root.name)}
+	}
+
+	r := &meiReader{
+		score:     music.NewScore("", "", "C", "dur", 4, 4, 0),
+		skipped:   make(map[string]int),
+		counts:    make(map[string]int),
+		clef:      music.TrebleClef,
+		meter:     music.TimeSignature{Numerator: 4, Denominator: 4},
+		barAlters: make(map[int]int),
+		lastDur:   music.QuarterNote,
+		ids:       make(map[string]music.ElementRef),
+	}
+	if v := root.attr("meiversion"); v != "" && !strings.HasPrefix(v, "4") && !strings.HasPrefix(v, "5") {
+		r.warn(root, "MEI version %s is not 4 or 5; reading it as MEI 5", v)
+	}
+	r.readHead(root.child("meiHead"))
+
+	score := r.findScore(root.child("music").child("body"))
+	if score == nil {
+		return nil, nil, &ParseError{Line: root.line, Column: root.col, Message: "no <score> in <music><body><mdiv>"}
+	}
+	for _, c := range score.children {
+		switch c.name {
+		case "scoreDef":
+			r.readScoreDef(c)
+		case "section":
+			r.readSection(c)
+		default:
+			r.skip(c)
+		}
+	}
+	if len(r.score.Measures) == 0 {
+		return nil, nil, &ParseError{Line: score.line, Column: score.col, Message: "no measures"}
+	}
+	for _, e := range r.events {
+		r.readControlEvent(e)
+	}
+	r.markPickup()
+	r.finishWarnings()
+	return r.score, r.warnings, nil
+}
+
+func (r *meiReader) warn(n *xmlNode, format string, args ...any) {
+	r.warnings = append(r.warnings, Warning{Line: n.line, Column: n.col, Message: fmt.Sprintf(format, args...)})
+}
+
+// skip reports an unsupported element once per name; finishWarnings adds
+// how often it occurred
+func (r *meiReader) skip(n *xmlNode) {
+	if meiIgnored[n.name] {
+		return
+	}
+	r.counts[n.name]++
+	if _, seen := r.skipped[n.name]; !seen {
+		r.skipped[n.name] = len(r.warnings)
+		r.warn(n, "<%s> is not supported and was skipped", n.name)
+	}
+}
+
+func (r *meiReader) finishWarnings() {
+	for name, i := range r.skipped {
+		if c := r.counts[name]; c > 1 {
+			r.warnings[i].Message += fmt.Sprintf(" (%d times)", c)
+		}
+	}
+}
+
+func (r *meiReader) readHead(head *xmlNode) {
+	titleStmt := head.child("fileDesc").child("titleStmt")
+	r.score.Title = titleStmt.child("title").textContent()
+	if c := titleStmt.child("composer"); c != nil {
+		r.score.Composer = c.textContent()
+		return
+	}
+	// MEI 4 names the composer in a respStmt, which may be missing
+	respStmt := titleStmt.child("respStmt")
+	if respStmt == nil {
+		return
+	}
+	for _, c := range respStmt.children {
+		if c.attr("role") == "composer" {
+			r.score.Composer = c.textContent()
+			return
+		}
+	}
+}
+
+// findScore returns the score of the first mdiv that has one
+func (r *meiReader) findScore(n *xmlNode) *xmlNode {
+	var found *xmlNode
+	for _, c := range n.childrenNamed("mdiv") {
+		if found != nil {
+			r.warn(c, "only the first movement is read")
+			break
+		}
+		if s := c.child("score"); s != nil {
+			found = s
+		} else {
+			found = r.findScore(c)
+		}
+	}
+	return found
+}
+
+func (n *xmlNode) childrenNamed(name string) []*xmlNode {
+	if n == nil {
+		return nil
+	}
+	var out []*xmlNode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// readScoreDef reads the key, meter, clef and tempo of a scoreDef or
+// staffDef and its children
+func (r *meiReader) readScoreDef(n *xmlNode) {
+	initial := len(r.score.Measures) == 0
+
+	if bpm := n.attr("midi.bpm"); bpm != "" && r.score.Tempo == 0 {
+		if v, err := strconv.ParseFloat(bpm, 64); err == nil && v > 0 {
+			r.score.Tempo = int(math.Round(v))
+		}
+	}
+	r.readMeter(n, n.attr("meter.count"), n.attr("meter.unit"), n.attr("meter.sym"))
+	if sig := n.attr("key.sig"); sig != "" {
+		r.readKey(n, sig, n.attr("key.mode"), n.attr("key.pname"), n.attr("key.accid"), initial)
+	}
+	if shape := n.attr("clef.shape"); shape != "" {
+		r.readClef(n, shape, n.attr("clef.line"), initial)
+	}
+
+	for _, c := range n.children {
+		switch c.name {
+		case "meterSig":
+			r.readMeter(c, c.attr("count"), c.attr("unit"), c.attr("sym"))
+		case "keySig":
+			r.readKey(c, c.attr("sig"), c.attr("mode"), c.attr("pname"), c.attr("accid"), initial)
+		case "clef":
+			r.readClef(c, c.attr("shape"), c.attr("line"), initial)
+		case "staffGrp":
+			r.readStaffGrp(c)
+		case "staffDef":
+			r.readStaffDef(c)
+		default:
+			r.skip(c)
+		}
+	}
+}
+
+func (r *meiReader) readStaffGrp(n *xmlNode) {
+	for _, c := range n.children {
+		switch c.name {
+		case "staffGrp":
+			r.readStaffGrp(c)
+		case "staffDef":
+			r.readStaffDef(c)
+		default:
+			r.skip(c)
+		}
+	}
+}
+
+func (r *meiReader) readStaffDef(n *xmlNode) {
+	if r.staff == "" {
+		r.staff = n.attr("n")
+	}
+	if n.attr("n") != r.staff {
+		r.warn(n, "only staff %s is read; staff %s was skipped", r.staff, n.attr("n"))
+		return
+	}
+	r.readScoreDef(n)
+}
+
+func (r *meiReader) readMeter(n *xmlNode, count, unit, sym string) {
+	var ts music.TimeSignature
+	switch {
+	case sym == "common":
+		ts = music.TimeSignature{Numerator: 4, Denominator: 4}
+	case sym == "cut":
+		ts = music.TimeSignature{Numerator: 2, Denominator: 2}
+	case count == "" && unit == "":
+		return
+	default:
+		total := 0
+		for _, part := range strings.Split(count, "+") {
+			c, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || c <= 0 {
+				r.warn(n, "meter count %q is not supported", count)
+				return
+			}
+			total += c
+		}
+		u, err := strconv.Atoi(unit)
+		if err != nil || u <= 0 || u&(u-1) != 0 {
+			r.warn(n, "meter unit %q is not supported", unit)
+			return
+		}
+		ts = music.TimeSignature{Numerator: total, Denominator: u}
+	}
+	r.meter = ts
+	if len(r.score.Measures) == 0 {
+		r.score.TimeSignature = ts
+	}
+}
+
+func (r *meiReader) readKey(n *xmlNode, sig, mode, pname, accid string, initial bool) {
+	fifths := 0
+	switch {
+	case sig == "0" || sig == "":
+	case len(sig) >= 2 && (sig[len(sig)-1] == 's' || sig[len(sig)-1] == 'f'):
+		count, err := strconv.Atoi(sig[:len(sig)-1])
+		if err != nil {
+			r.warn(n, "key signature %q is not supported", sig)
+			return
+		}
+		fifths = count
+		if sig[len(sig)-1] == 'f' {
+			fifths = -count
+		}
+	default:
+		r.warn(n, "key signature %q is not supported", sig)
+		return
+	}
+
+	name := "dur"
+	if mode != "" {
+		var ok bool
+		if name, ok = meiModes[mode]; !ok {
+			r.warn(n, "mode %q is not supported; reading the key as major", mode)
+			name = "dur"
+		}
+	}
+	k := theory.KeyOfFifths(fifths, name)
+	step, alter := k.Step, k.Alter
+	if pname != "" {
+		if s := strings.Index("cdefgab", pname); s >= 0 && len(pname) == 1 {
+			step, alter = s, meiAccidentals[accid]
+		}
+	}
+
+	r.keyAlters = theory.SignatureAlterations(fifths)
+	if initial {
+		r.score.KeySignature = keySignature(step, alter, name)
+	} else {
+		r.warn(n, "key changes are not shown; the notes after it keep their pitch")
+	}
+}
+
+func (r *meiReader) readClef(n *xmlNode, shape, line string, initial bool) {
+	clef, ok := meiClefs[shape+line]
+	if !ok {
+		r.warn(n, "clef %s%s is not supported", shape, line)
+		return
+	}
+	if initial {
+		r.clef = clef
+	} else {
+		r.pendingClef = clef
+	}
+}
+
+// readSection reads the measures of a section or ending
+func (r *meiReader) readSection(n *xmlNode) {
+	for _, c := range n.children {
+		switch c.name {
+		case "measure":
+			r.readMeasure(c)
+		case "section", "expansion":
+			r.readSection(c)
+		case "ending":
+			ending, err := strconv.Atoi(strings.TrimSuffix(c.attr("n"), "."))
+			if err != nil || ending <= 0 {
+				r.warn(c, "ending number %q is not supported", c.attr("n"))
+			}
+			r.ending = ending
+			r.readSection(c)
+			r.ending = 0
+		case "scoreDef":
+			r.readScoreDef(c)
+		case "staffDef":
+			r.readStaffDef(c)
+		default:
+			r.skip(c)
+		}
+	}
+}
+
+func (r *meiReader) readMeasure(n *xmlNode) {
+	var ts *music.TimeSignature
+	if r.meter != r.score.TimeSignature {
+		t := r.meter
+		ts = &t
+	}
+	m := r.score.AddMeasure(ts)
+	mi := len(r.score.Measures) - 1
+	if num, err := strconv.Atoi(n.attr("n")); err == nil {
+		m.Number = num
+	}
+	if mi == 0 {
+		m.Clef = r.clef
+	}
+	if r.pendingClef != "" {
+		r.clef = r.pendingClef
+		m.Clef = r.clef
+		r.pendingClef = ""
+	}
+	m.Ending = r.ending
+	m.RepeatStart = r.repeatStart || n.attr("left") == "rptstart" || n.attr("left") == "rptboth"
+	m.RepeatEnd = n.attr("right") == "rptend" || n.attr("right") == "rptboth"
+	r.repeatStart = n.attr("right") == "rptboth"
+	if (n.attr("left") == "rptend" || n.attr("left") == "rptboth") && mi > 0 {
+		r.score.Measures[mi-1].RepeatEnd = true
+	}
+	m.Pickup = mi == 0 && n.attr("metcon") == "false"
+	r.measure = m
+	r.barAlters = make(map[int]int)
+
+	staffRead := false
+	for _, c := range n.children {
+		switch {
+		case c.name == "staff":
+			if r.staff == "" {
+				r.staff = c.attr("n")
+			}
+			if c.attr("n") != r.staff || staffRead {
+				r.warn(c, "only staff %s is read; staff %s was skipped", r.staff, c.attr("n"))
+				continue
+			}
+			staffRead = true
+			r.readStaff(c)
+		case meiControl[c.name]:
+			r.events = append(r.events, meiEvent{node: c, measure: mi})
+		default:
+			r.skip(c)
+		}
+	}
+}
+
+func (r *meiReader) readStaff(n *xmlNode) {
+	layerRead := false
+	for _, c := range n.children {
+		if c.name != "layer" {
+			r.skip(c)
+			continue
+		}
+		if layerRead {
+			r.warn(c, "only the first layer is read; layer %s was skipped", c.attr("n"))
+			continue
+		}
+		layerRead = true
+		r.readEvents(c.children)
+	}
+}
+
+// readEvents reads the notes, chords and rests of a layer or of a beam,
+// tuplet or grace group inside it
+func (r *meiReader) readEvents(nodes []*xmlNode) {
+	for _, c := range nodes {
+		switch c.name {
+		case "note":
+			r.readNote(c, nil, false)
+		case "chord":
+			r.readChord(c)
+		case "rest", "space":
+			if c.name == "space" {
+				r.warn(c, "<space> was read as a rest")
+			}
+			nv, dots := r.readDuration(c, nil)
+			r.add(&music.Rest{Duration: nv, Dots: dots})
+		case "mRest":
+			r.fillWithRests(c)
+		case "beam":
+			r.readEvents(c.children)
+		case "graceGrp":
+			saved := r.grace
+			r.grace = meiGrace(c.attr("grace"), music.Appoggiatura)
+			r.readEvents(c.children)
+			r.grace = saved
+		case "tuplet":
+			r.readTuplet(c)
+		case "clef":
+			if len(r.measure.Elements) > 0 {
+				r.warn(c, "clef changes inside a measure are not supported")
+				continue
+			}
+			if clef, ok := meiClefs[c.attr("shape")+c.attr("line")]; ok {
+				r.clef = clef
+				r.measure.Clef = clef
+			} else {
+				r.warn(c, "clef %s%s is not supported", c.attr("shape"), c.attr("line"))
+			}
+		default:
+			r.skip(c)
+		}
+	}
+}
+
+// meiGrace converts a grace attribute, using def for a grace group without
+// one
+func meiGrace(value string, def music.GraceType) music.GraceType {
+	switch value {
+	case "unacc":
+		return music.Acciaccatura
+	case "acc":
+		return music.Appoggiatura
+	case "":
+		return def
+	}
+	return music.Appoggiatura
+}
+
+func (r *meiReader) readTuplet(n *xmlNode) {
+	actual, err1 := strconv.Atoi(n.attr("num"))
+	normal, err2 := strconv.Atoi(n.attr("numbase"))
+	if err1 != nil || err2 != nil || actual <= 0 || normal <= 0 {
+		actual, normal = 3, 2
+		if n.attr("num") != "" || n.attr("numbase") != "" {
+			r.warn(n, "tuplet %s:%s is not supported; read as 3:2", n.attr("num"), n.attr("numbase"))
+		}
+	}
+	start := len(r.measure.Elements)
+	r.readEvents(n.children)
+	if end := len(r.measure.Elements) - 1; end >= start {
+		err := r.measure.AddTuplet(music.Tuplet{Actual: actual, Normal: normal, Start: start, End: end})
+		if err != nil {
+			r.warn(n, "%v", err)
+		}
+	}
+}
+
+// readDuration reads dur and dots, from the element or the chord around it
+func (r *meiReader) readDuration(n, chord *xmlNode) (music.NoteValue, int) {
+	dur := n.attr("dur")
+	if dur == "" {
+		dur = chord.attr("dur")
+	}
+	if nv, ok := meiDurations[dur]; ok {
+		r.lastDur = nv
+	} else if dur != "" {
+		r.warn(n, "duration %q is not supported", dur)
+	}
+
+	dotsAttr := n.attr("dots")
+	if dotsAttr == "" {
+		dotsAttr = chord.attr("dots")
+	}
+	dots, _ := strconv.Atoi(dotsAttr)
+	if dotsAttr == "" {
+		dots = len(n.childrenNamed("dot"))
+	}
+	return r.lastDur, dots
+}
+
+func (r *meiReader) add(e music.MusicElement) music.ElementRef {
+	switch el := e.(type) {
+	case *music.Note:
+		r.measure.AddNote(el)
+	case *music.Rest:
+		r.measure.AddRest(el)
+	}
+	return music.ElementRef{Measure: len(r.score.Measures) - 1, Element: len(r.measure.Elements) - 1}
+}
+
+// fillWithRests fills the measure with rests for an mRest
+func (r *meiReader) fillWithRests(n *xmlNode) {
+	if len(r.measure.Elements) > 0 {
+		r.warn(n, "<mRest> after other notes in the measure was skipped")
+		return
+	}
+	ts := r.measure.TimeSignature
+	pieces, ok := splitLength(newFraction(ts.Numerator, ts.Denominator))
+	if !ok {
+		r.warn(n, "cannot fill a %d/%d measure with rests", ts.Numerator, ts.Denominator)
+		return
+	}
+	for _, piece := range pieces {
+		r.add(&music.Rest{Duration: piece.value, Dots: piece.dots})
+	}
+}
+
+func (r *meiReader) readChord(n *xmlNode) {
+	var head music.ElementRef
+	placed := false
+	for _, c := range n.children {
+		switch c.name {
+		case "note":
+			ref, ok := r.readNote(c, n, placed)
+			if ok && !placed {
+				head, placed = ref, true
+			}
+		case "artic":
+		default:
+			r.skip(c)
+		}
+	}
+	if !placed {
+		return
+	}
+	if id := n.attr("id"); id != "" {
+		r.ids[id] = head
+	}
+	note := r.measure.Elements[head.Element].(*music.Note)
+	note.Articulations = append(note.Articulations, r.readArticulations(n)...)
+}
+
+// readNote places a note, inside a chord when chord is not nil. follower
+// marks the notes of a chord after the first.
+func (r *meiReader) readNote(n, chord *xmlNode, follower bool) (music.ElementRef, bool) {
+	step := strings.Index("cdefgab", n.attr("pname"))
+	octave, err := strconv.Atoi(n.attr("oct"))
+	if step < 0 || len(n.attr("pname")) != 1 || err != nil {
+		r.warn(n, "note without a valid pname and oct was skipped")
+		return music.ElementRef{}, false
+	}
+	diatonic := octave*7 + step
+
+	accid, accidGes := n.attr("accid"), n.attr("accid.ges")
+	for _, c := range n.childrenNamed("accid") {
+		if a := c.attr("accid"); a != "" {
+			accid = a
+		}
+		if a := c.attr("accid.ges"); a != "" {
+			accidGes = a
+		}
+	}
+	alter := 0
+	written := ""
+	if a, ok := meiAccidentals[accid]; ok {
+		alter = a
+		written = music.AlterAccidental(a)
+		r.barAlters[diatonic] = a
+	} else if a, ok := meiAccidentals[accidGes]; ok {
+		alter = a
+	} else if a, ok := r.barAlters[diatonic]; ok {
+		alter = a
+	} else {
+		alter = r.keyAlters[step]
+	}
+	if accid != "" && written == "" {
+		r.warn(n, "accidental %q is not supported", accid)
+	}
+
+	nv, dots := r.readDuration(n, chord)
+	note := &music.Note{
+		Pitch:      music.NaturalMIDI(diatonic) + alter,
+		Duration:   nv,
+		Dots:       dots,
+		StaffLine:  diatonic - music.ClefBottomLine(r.clef),
+		Accidental: written,
+		Chord:      follower,
+	}
+	grace := n.attr("grace")
+	if grace == "" {
+		grace = chord.attr("grace")
+	}
+	note.Grace = meiGrace(grace, r.grace)
+	tie := n.attr("tie")
+	if tie == "" {
+		tie = chord.attr("tie")
+	}
+	note.Tie = strings.ContainsAny(tie, "im")
+	note.Articulations = r.readArticulations(n)
+
+	for _, c := range n.children {
+		switch c.name {
+		case "accid", "artic", "dot":
+		default:
+			r.skip(c)
+		}
+	}
+
+	ref := r.add(note)
+	if id := n.attr("id"); id != "" {
+		r.ids[id] = ref
+	}
+	return ref, true
+}
+
+// readArticulations reads the artic attribute and artic children
+func (r *meiReader) readArticulations(n *xmlNode) []music.Articulation {
+	var names []string
+	names = append(names, strings.Fields(n.attr("artic"))...)
+	for _, c := range n.childrenNamed("artic") {
+		names = append(names, strings.Fields(c.attr("artic"))...)
+	}
+	var arts []music.Articulation
+	for _, name := range names {
+		if a, ok := meiArticulations[name]; ok {
+			arts = append(arts, a)
+		} else {
+			r.warn(n, "articulation %q is not supported", name)
+		}
+	}
+	return arts
+}
+
+// meiPlacement converts a place attribute
+func meiPlacement(place string) music.Placement {
+	switch place {
+	case "above":
+		return music.PlacementAbove
+	case "below":
+		return music.PlacementBelow
+	}
+	return music.PlacementAuto
+}
+
+// target finds the note a control event points at, by startid or by
+// tstamp in the event's measure
+func (r *meiReader) target(n *xmlNode, idAttr, tstampAttr string, mi int) (music.ElementRef, *music.Note, bool) {
+	if id := n.attr(idAttr); id != "" {
+		ref, ok := r.ids[strings.TrimPrefix(id, "#")]
+		if !ok {
+			return ref, nil, false
+		}
+		note, ok := r.score.Measures[ref.Measure].Elements[ref.Element].(*music.Note)
+		return ref, note, ok
+	}
+	tstamp, err := strconv.ParseFloat(n.attr(tstampAttr), 64)
+	if err != nil {
+		return music.ElementRef{}, nil, false
+	}
+	return r.noteAtBeat(mi, tstamp)
+}
+
+// noteAtBeat returns the first note in the measure that starts on the beat,
+// counted from 1 in units of the meter
+func (r *meiReader) noteAtBeat(mi int, beat float64) (music.ElementRef, *music.Note, bool) {
+	if mi < 0 || mi >= len(r.score.Measures) {
+		return music.ElementRef{}, nil, false
+	}
+	m := r.score.Measures[mi]
+	quarters := 0.0
+	for ei, e := range m.Elements {
+		onset := 1 + quarters*float64(m.TimeSignature.Denominator)/4
+		quarters += m.ElementQuarters(ei)
+		if note, ok := e.(*music.Note); ok && !note.IsGrace() && !note.Chord && math.Abs(onset-beat) < 1e-3 {
+			return music.ElementRef{Measure: mi, Element: ei}, note, true
+		}
+	}
+	return music.ElementRef{}, nil, false
+}
+
+// tstamp2Target resolves a tstamp2 such as "1m+3" from measure mi
+func (r *meiReader) tstamp2Target(value string, mi int) (music.ElementRef, *music.Note, bool) {
+	measures, beat, ok := strings.Cut(value, "m+")
+	if !ok {
+		return music.ElementRef{}, nil, false
+	}
+	offset, err1 := strconv.Atoi(measures)
+	b, err2 := strconv.ParseFloat(beat, 64)
+	if err1 != nil || err2 != nil {
+		return music.ElementRef{}, nil, false
+	}
+	return r.noteAtBeat(mi+offset, b)
+}
+
+// readControlEvent applies a dynam, hairpin, tempo, ornament or tie to the
+// notes it refers to
+func (r *meiReader) readControlEvent(e meiEvent) {
+	n := e.node
+	if n.name == "tempo" {
+		if v, err := strconv.ParseFloat(n.attr("midi.bpm"), 64); err == nil && v > 0 {
+			if r.score.Tempo == 0 {
+				r.score.Tempo = int(math.Round(v))
+			}
+		} else {
+			r.warn(n, "<tempo> without midi.bpm was skipped")
+		}
+		return
+	}
+
+	ref, note, ok := r.target(n, "startid", "tstamp", e.measure)
+	if !ok {
+		r.warn(n, "<%s> does not point at a note and was skipped", n.name)
+		return
+	}
+	switch n.name {
+	case "dynam":
+		d := music.Dynamic(n.textContent())
+		if d.GlyphName() == "" {
+			r.warn(n, "dynamic %q is not supported", n.textContent())
+			return
+		}
+		note.Dynamic = d
+		note.DynamicPlacement = meiPlacement(n.attr("place"))
+	case "hairpin":
+		end, _, ok := r.target(n, "endid", "", e.measure)
+		if !ok {
+			end, _, ok = r.tstamp2Target(n.attr("tstamp2"), e.measure)
+		}
+		if !ok {
+			r.warn(n, "<hairpin> has no end note and was skipped")
+			return
+		}
+		hp := music.Hairpin{Type: music.Crescendo, Start: ref, End: end, Placement: meiPlacement(n.attr("place"))}
+		if n.attr("form") == "dim" {
+			hp.Type = music.Diminuendo
+		}
+		r.score.Hairpins = append(r.score.Hairpins, hp)
+	case "trill":
+		note.Ornaments = append(note.Ornaments, music.Trill)
+	case "mordent":
+		if n.attr("form") == "upper" {
+			note.Ornaments = append(note.Ornaments, music.InvertedMordent)
+		} else {
+			note.Ornaments = append(note.Ornaments, music.Mordent)
+		}
+	case "turn":
+		note.Ornaments = append(note.Ornaments, music.Turn)
+	case "tie":
+		note.Tie = true
+	}
+}
+
+// markPickup marks an incomplete first measure as a pickup
+func (r *meiReader) markPickup() {
+	m := r.score.Measures[0]
+	if m.Pickup || len(r.score.Measures) < 2 {
+		return
+	}
+	quarters := 0.0
+	for i := range m.Elements {
+		quarters += m.ElementQuarters(i)
+	}
+	capacity := float64(m.TimeSignature.Numerator) * 4 / float64(m.TimeSignature.Denominator)
+	m.Pickup = quarters < capacity-fillTolerance
+}
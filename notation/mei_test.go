@@ -0,0 +1,52 @@
+package notation
+
+import (
+	"fmt"
+	"testing"
+)
+
+// meiDocument wraps a header and one measure of music in an MEI document
+func meiDocument(version, head string) []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<mei xmlns="http://www.music-encoding.org/ns/mei" meiversion="%s">
+  <meiHead>%s</meiHead>
+  <music><body><mdiv><score>
+    <scoreDef meter.count="2" meter.unit="4"/>
+    <section><measure n="1"><staff n="1"><layer n="1">
+      <note pname="c" oct="4" dur="4"/><note pname="d" oct="4" dur="4"/>
+    </layer></staff></measure></section>
+  </score></mdiv></body></music>
+</mei>`, version, head))
+}
+
+func TestParseMEIHeader(t *testing.T) {
+	tests := []struct {
+		name            string
+		version, head   string
+		title, composer string
+	}{
+		{"no header", "5.0", "", "", ""},
+		{"title only", "4.0.1", `<fileDesc><titleStmt><title>Tittel</title></titleStmt></fileDesc>`, "Tittel", ""},
+		{
+			"composer element", "5.0",
+			`<fileDesc><titleStmt><title>Tittel</title><composer>Grieg</composer></titleStmt></fileDesc>`,
+			"Tittel", "Grieg",
+		},
+		{
+			"composer in a respStmt", "4.0.1",
+			`<fileDesc><titleStmt><title>Tittel</title><respStmt><persName role="lyricist">Bjørnson</persName><persName role="composer">Nordraak</persName></respStmt></titleStmt></fileDesc>`,
+			"Tittel", "Nordraak",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, _, err := ParseMEI(meiDocument(tt.version, tt.head))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score.Title != tt.title || score.Composer != tt.composer {
+				t.Errorf("got title %q and composer %q, want %q and %q", score.Title, score.Composer, tt.title, tt.composer)
+			}
+		})
+	}
+}
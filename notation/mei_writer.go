@@ -0,0 +1,433 @@
+package notation
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// MEIVersion is the MEI version EncodeMEI writes
+const MEIVersion = "5.0"
+
+var meiDurationNames = map[music.NoteValue]string{
+	music.WholeNote:        "1",
+	music.HalfNote:         "2",
+	music.QuarterNote:      "4",
+	music.EighthNote:       "8",
+	music.SixteenthNote:    "16",
+	music.ThirtySecondNote: "32",
+	music.SixtyFourthNote:  "64",
+}
+
+var meiAccidentalNames = map[int]string{-2: "ff", -1: "f", 0: "n", 1: "s", 2: "ss"}
+
+var meiArticulationNames = map[music.Articulation]string{
+	music.Staccato:      "stacc",
+	music.Staccatissimo: "stacciss",
+	music.Accent:        "acc",
+	music.Marcato:       "marc",
+	music.Tenuto:        "ten",
+}
+
+var meiClefShapes = map[string][2]string{
+	music.TrebleClef: {"G", "2"},
+	music.BassClef:   {"F", "4"},
+	music.AltoClef:   {"C", "3"},
+	music.TenorClef:  {"C", "4"},
+}
+
+// meiWriter writes elements through an xml.Encoder, keeping the first
+// error
+type meiWriter struct {
+	enc *xml.Encoder
+	err error
+}
+
+func (w *meiWriter) start(name string, attrs ...string) {
+	if w.err != nil {
+		return
+	}
+	el := xml.StartElement{Name: xml.Name{Local: name}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			el.Attr = append(el.Attr, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+		}
+	}
+	w.err = w.enc.EncodeToken(el)
+}
+
+func (w *meiWriter) end(name string) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+}
+
+// empty writes an element without content
+func (w *meiWriter) empty(name string, attrs ...string) {
+	w.start(name, attrs...)
+	w.end(name)
+}
+
+// text writes an element holding only text
+func (w *meiWriter) text(name, text string, attrs ...string) {
+	w.start(name, attrs...)
+	if w.err == nil {
+		w.err = w.enc.EncodeToken(xml.CharData(text))
+	}
+	w.end(name)
+}
+
+// SaveMEIFile writes the score to an MEI file
+func SaveMEIFile(score *music.Score, path string) error {
+	data, err := EncodeMEI(score)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write MEI file: %w", err)
+	}
+	return nil
+}
+
+// EncodeMEI writes the score as an MEI 5 document with one staff and
+// layer. Notes get xml:ids so dynamics, hairpins and ornaments can refer
+// to them as control events.
+func EncodeMEI(score *music.Score) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	w := &meiWriter{enc: xml.NewEncoder(&buf)}
+	w.enc.Indent("", "  ")
+
+	w.start("mei", "xmlns", MEINamespace, "meiversion", MEIVersion)
+	w.start("meiHead")
+	w.start("fileDesc")
+	w.start("titleStmt")
+	w.text("title", score.Title)
+	if score.Composer != "" {
+		w.text("composer", score.Composer)
+	}
+	w.end("titleStmt")
+	w.empty("pubStmt")
+	w.end("fileDesc")
+	w.end("meiHead")
+
+	w.start("music")
+	w.start("body")
+	w.start("mdiv")
+	w.start("score")
+	fifths := writeMEIScoreDef(w, score)
+	w.start("section")
+	writeMEIMeasures(w, score, fifths)
+	w.end("section")
+	w.end("score")
+	w.end("mdiv")
+	w.end("body")
+	w.end("music")
+	w.end("mei")
+
+	if w.err == nil {
+		w.err = w.enc.Flush()
+	}
+	if w.err != nil {
+		return nil, fmt.Errorf("failed to encode MEI: %w", w.err)
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// meiModeNames maps the Norwegian mode names to MEI's
+var meiModeNames = map[string]string{}
+
+func init() {
+	for mei, name := range meiModes {
+		meiModeNames[name] = mei
+	}
+}
+
+// writeMEIScoreDef writes the key, meter, tempo and clef and returns the
+// key signature's fifths
+func writeMEIScoreDef(w *meiWriter, score *music.Score) int {
+	tempo := ""
+	if score.Tempo > 0 {
+		tempo = strconv.Itoa(score.Tempo)
+	}
+	w.start("scoreDef", "midi.bpm", tempo)
+
+	fifths := 0
+	step, alter, ok := localization.ParseNoteName(score.KeySignature.Tonic)
+	mode, known := meiModeNames[score.KeySignature.Mode]
+	if ok && known {
+		fifths = theory.Key{Step: step, Alter: alter, Mode: score.KeySignature.Mode}.Fifths()
+		accid := ""
+		if alter != 0 {
+			accid = meiAccidentalNames[alter]
+		}
+		w.empty("keySig", "sig", meiKeySig(fifths), "mode", mode, "pname", lilyLetters[step], "accid", accid)
+	}
+	writeMEIMeterSig(w, score.TimeSignature)
+
+	w.start("staffGrp")
+	w.start("staffDef", "n", "1", "lines", "5")
+	writeMEIClef(w, score.ClefAt(0))
+	w.end("staffDef")
+	w.end("staffGrp")
+	w.end("scoreDef")
+	return fifths
+}
+
+// meiKeySig writes a key signature as MEI's sig, such as "3s" or "2f"
+func meiKeySig(fifths int) string {
+	switch {
+	case fifths > 0:
+		return fmt.Sprintf("%ds", fifths)
+	case fifths < 0:
+		return fmt.Sprintf("%df", -fifths)
+	}
+	return "0"
+}
+
+func writeMEIMeterSig(w *meiWriter, ts music.TimeSignature) {
+	w.empty("meterSig", "count", strconv.Itoa(ts.Numerator), "unit", strconv.Itoa(ts.Denominator))
+}
+
+func writeMEIClef(w *meiWriter, clef string) {
+	shape, ok := meiClefShapes[clef]
+	if !ok {
+		shape = meiClefShapes[music.TrebleClef]
+	}
+	w.empty("clef", "shape", shape[0], "line", shape[1])
+}
+
+// meiID names an element for control events to refer to
+func meiID(ref music.ElementRef) string {
+	return fmt.Sprintf("m%d-e%d", ref.Measure+1, ref.Element+1)
+}
+
+// writeMEIMeasures writes the measures, grouping those of an ending in an
+// <ending> and writing meter changes as a scoreDef before the measure
+func writeMEIMeasures(w *meiWriter, score *music.Score, fifths int) {
+	tied := tieContinuations(score)
+
+	meter := score.TimeSignature
+	ending := 0
+	for mi, m := range score.Measures {
+		if m.Ending != ending {
+			if ending != 0 {
+				w.end("ending")
+			}
+			ending = m.Ending
+			if ending != 0 {
+				w.start("ending", "n", strconv.Itoa(ending))
+			}
+		}
+		if m.TimeSignature != meter {
+			meter = m.TimeSignature
+			w.start("scoreDef")
+			writeMEIMeterSig(w, meter)
+			w.end("scoreDef")
+		}
+		writeMEIMeasure(w, score, mi, fifths, tied)
+	}
+	if ending != 0 {
+		w.end("ending")
+	}
+}
+
+func writeMEIMeasure(w *meiWriter, score *music.Score, mi, fifths int, tied map[music.ElementRef]bool) {
+	m := score.Measures[mi]
+	number := m.Number
+	if number == 0 {
+		number = mi + 1
+	}
+	left, right := "", ""
+	if m.RepeatStart {
+		left = "rptstart"
+	}
+	if m.RepeatEnd {
+		right = "rptend"
+	}
+	metcon := ""
+	if m.Pickup {
+		metcon = "false"
+	}
+	w.start("measure", "n", strconv.Itoa(number), "metcon", metcon, "left", left, "right", right)
+	w.start("staff", "n", "1")
+	w.start("layer", "n", "1")
+	if mi > 0 && m.Clef != "" {
+		writeMEIClef(w, m.Clef)
+	}
+
+	clef := score.ClefAt(mi)
+	keyAlters := theory.SignatureAlterations(fifths)
+	barAlters := make(map[int]int)
+	// pitchAttrs returns pname, oct, accid and accid.ges so that the reader
+	// arrives at the note's pitch
+	pitchAttrs := func(n *music.Note) []string {
+		diatonic, alter := spellPitch(n, clef)
+		octave := diatonic / 7
+		step := diatonic - octave*7
+		implied, ok := barAlters[diatonic]
+		if !ok {
+			implied = keyAlters[step]
+		}
+		accid, ges := "", ""
+		switch {
+		case n.Accidental != music.AccidentalNone:
+			accid = meiAccidentalNames[alter]
+			barAlters[diatonic] = alter
+		case alter != implied:
+			ges = meiAccidentalNames[alter]
+		}
+		return []string{"pname", lilyLetters[step], "oct", strconv.Itoa(octave), "accid", accid, "accid.ges", ges}
+	}
+
+	// Outer tuplets open first
+	tuplets := append([]music.Tuplet(nil), m.Tuplets...)
+	sort.SliceStable(tuplets, func(i, j int) bool {
+		return tuplets[i].End-tuplets[i].Start > tuplets[j].End-tuplets[j].Start
+	})
+	for ei := 0; ei < len(m.Elements); ei++ {
+		for _, t := range tuplets {
+			if t.Start == ei {
+				w.start("tuplet", "num", strconv.Itoa(t.Actual), "numbase", strconv.Itoa(t.Normal))
+			}
+		}
+
+		dur := meiDurationNames[m.Elements[ei].GetDuration()]
+		dots := ""
+		if d := m.Elements[ei].GetDots(); d > 0 {
+			dots = strconv.Itoa(d)
+		}
+		switch el := m.Elements[ei].(type) {
+		case *music.Rest:
+			w.empty("rest", "dur", dur, "dots", dots)
+		case *music.Note:
+			notes := m.ChordNotes(ei)
+			grace := ""
+			switch el.Grace {
+			case music.Appoggiatura:
+				grace = "acc"
+			case music.Acciaccatura:
+				grace = "unacc"
+			}
+			if len(notes) > 1 {
+				w.start("chord", "dur", dur, "dots", dots, "grace", grace)
+				writeMEIArticulations(w, el)
+			}
+			for i, n := range notes {
+				nref := music.ElementRef{Measure: mi, Element: ei + i}
+				attrs := []string{"xml:id", meiID(nref)}
+				if len(notes) == 1 {
+					attrs = append(attrs, "dur", dur, "dots", dots, "grace", grace)
+				}
+				attrs = append(attrs, pitchAttrs(n)...)
+				attrs = append(attrs, "tie", meiTie(n.Tie, tied[nref]))
+				if len(notes) == 1 && len(n.Articulations) > 0 {
+					w.start("note", attrs...)
+					writeMEIArticulations(w, n)
+					w.end("note")
+				} else {
+					w.empty("note", attrs...)
+				}
+			}
+			if len(notes) > 1 {
+				w.end("chord")
+			}
+			// The loop continues after the chord's last note
+			for _, t := range m.Tuplets {
+				if t.End >= ei && t.End < ei+len(notes)-1 {
+					w.end("tuplet")
+				}
+			}
+			ei += len(notes) - 1
+		}
+
+		for _, t := range m.Tuplets {
+			if t.End == ei {
+				w.end("tuplet")
+			}
+		}
+	}
+
+	w.end("layer")
+	w.end("staff")
+	writeMEIControlEvents(w, score, mi)
+	w.end("measure")
+}
+
+// meiTie returns the tie attribute of a note that starts and/or ends a tie
+func meiTie(starts, ends bool) string {
+	switch {
+	case starts && ends:
+		return "m"
+	case starts:
+		return "i"
+	case ends:
+		return "t"
+	}
+	return ""
+}
+
+func writeMEIArticulations(w *meiWriter, n *music.Note) {
+	for _, a := range n.Articulations {
+		if name, ok := meiArticulationNames[a]; ok {
+			w.empty("artic", "artic", name, "place", meiPlace(n.ArticulationPlacement))
+		}
+	}
+}
+
+// meiPlace writes a placement as a place attribute, "" for automatic
+func meiPlace(p music.Placement) string {
+	switch p {
+	case music.PlacementAbove:
+		return "above"
+	case music.PlacementBelow:
+		return "below"
+	}
+	return ""
+}
+
+// writeMEIControlEvents writes the dynamics, ornaments and hairpins that
+// start in measure mi
+func writeMEIControlEvents(w *meiWriter, score *music.Score, mi int) {
+	for ei, e := range score.Measures[mi].Elements {
+		n, ok := e.(*music.Note)
+		if !ok {
+			continue
+		}
+		id := "#" + meiID(music.ElementRef{Measure: mi, Element: ei})
+		if n.Dynamic != music.DynamicNone {
+			w.text("dynam", string(n.Dynamic), "startid", id, "place", meiPlace(n.DynamicPlacement))
+		}
+		for _, o := range n.Ornaments {
+			switch o {
+			case music.Trill:
+				w.empty("trill", "startid", id)
+			case music.Mordent:
+				w.empty("mordent", "startid", id, "form", "lower")
+			case music.InvertedMordent:
+				w.empty("mordent", "startid", id, "form", "upper")
+			case music.Turn:
+				w.empty("turn", "startid", id)
+			}
+		}
+	}
+	for _, hp := range score.Hairpins {
+		if hp.Start.Measure != mi {
+			continue
+		}
+		form := "cres"
+		if hp.Type == music.Diminuendo {
+			form = "dim"
+		}
+		w.empty("hairpin", "form", form, "startid", "#"+meiID(hp.Start), "endid", "#"+meiID(hp.End), "place", meiPlace(hp.Placement))
+	}
+}
@@ -0,0 +1,75 @@
+package notation
+
+import (
+	"fmt"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// KeyScale returns the scale of a key signature's mode and its tonic in
+// the octave from middle C
+func KeyScale(key music.KeySignature) (theory.Scale, theory.Pitch, error) {
+	k, err := localization.ParseKey(key.Tonic, key.Mode)
+	if err != nil {
+		return theory.Scale{}, theory.Pitch{}, err
+	}
+	scale, ok := theory.ModeScale(k.Mode)
+	if !ok {
+		return theory.Scale{}, theory.Pitch{}, fmt.Errorf("no scale for the mode %q", k.Mode)
+	}
+	return scale, theory.Pitch{Step: k.Step, Alter: k.Alter, Octave: 4}, nil
+}
+
+// ScaleScore writes a scale up from the tonic to its octave and back down
+// in quarter notes in 4/4, with the key signature of the scale's mode and
+// accidentals where the notes leave it. Microtonal offsets cannot be
+// written and are left out. It fails when the key signature would need
+// more than seven sharps or flats.
+func ScaleScore(scale theory.Scale, tonic theory.Pitch, clef string) (*music.Score, error) {
+	k, err := theory.NewKey(tonic.Step, tonic.Alter, scale.Key)
+	if err != nil {
+		return nil, err
+	}
+	fifths := k.Fifths()
+	if fifths < -maxKeyFifths || fifths > maxKeyFifths {
+		return nil, fmt.Errorf("the key of %s %s has %d accidentals in its key signature", localization.FormatNoteName(tonic.Step, tonic.Alter), scale.Key, abs(fifths))
+	}
+	key := keySignature(tonic.Step, tonic.Alter, scale.Key)
+	score := music.NewScore("", "", key.Tonic, key.Mode, 4, 4, 0)
+
+	top := tonic
+	top.Octave++
+	pitches := append(scale.Pitches(tonic), top)
+	down := scale.DownPitches(tonic)
+	for i := len(down) - 1; i >= 0; i-- {
+		pitches = append(pitches, down[i])
+	}
+
+	keyAlters := k.Alterations()
+	var measure *music.Measure
+	var barAlters map[int]int
+	for i, p := range pitches {
+		if i%4 == 0 {
+			measure = score.AddMeasure(nil)
+			barAlters = make(map[int]int)
+			if i == 0 {
+				measure.Clef = clef
+			}
+		}
+		measure.AddNote(&music.Note{
+			Pitch:      p.MIDI(),
+			Duration:   music.QuarterNote,
+			StaffLine:  p.Diatonic() - music.ClefBottomLine(clef),
+			Accidental: theory.AccidentalFor(p.Diatonic(), p.Alter, keyAlters, barAlters),
+		})
+	}
+	if rest := len(pitches) % 4; rest != 0 {
+		pieces, _ := splitLength(newFraction(4-rest, 4))
+		for _, piece := range pieces {
+			measure.AddRest(&music.Rest{Duration: piece.value, Dots: piece.dots})
+		}
+	}
+	return score, nil
+}
@@ -0,0 +1,1181 @@
+package notation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// The text syntax is a small LilyPond-like language with Norwegian note
+// names and absolute octaves:
+//
+//	\header { title = "Lisa gikk til skolen" composer = "Norsk barnesang" }
+//	\clef treble \key g \major \time 3/4 \tempo 4 = 96
+//	\partial 4 d'4 | g'4 a'8 h' c''4 | d''2. |
+//
+// A note is a Norwegian name (c d e f g a h, fiss, ess, b, ...), octave marks
+// (c' is middle C; each ' raises and each , lowers an octave), an optional !
+// to print the accidental even when the key and measure imply it or ? to
+// print it in parentheses as a reminder, a duration (1 2 4 8 16 32 64)
+// and dots. Names give the pitch, not the accidental: in G major fiss' is
+// printed without one, and f' with a natural. As on paper, an accidental
+// lasts to the end of its measure, and a note a tie continues into shows
+// none. A missing duration repeats the previous one, and r is a rest.
+// <c' e' g'>4 is a chord. A ~ after a note or chord ties it to the next one;
+// inside a chord it ties just the note before it. Measures are filled from
+// the time signature; | checks that a bar line falls there. A note or rest
+// longer than the rest of its measure is split at the bar line, a note
+// into tied notes: in 3/4, g'4 a'8 h' c''2. reads as g'4 a'8 h' c''4~ | c''2
+// and leaves the second measure with a quarter to fill.
+//
+// Marks follow the note: dynamics (\p, \mf, ...), hairpins (\< \> and \! to
+// end), articulations (-. -! -> -^ --) and ornaments (\trill \mordent \prall
+// \turn). A ^ or _ instead of - (or before a command) places the mark above
+// or below. \tuplet 3/2 { ... } groups a tuplet, and \grace, \appoggiatura
+// and \acciaccatura mark the next note (or a { ... } group) as grace notes.
+// % starts a comment.
+
+type tokenKind int
+
+const (
+	tokEOF     tokenKind = iota
+	tokWord              // note names, rests, clef names, header fields
+	tokCommand           // \name, \<, \> or \!
+	tokNumber
+	tokString
+	tokSymbol // a single punctuation character
+)
+
+type token struct {
+	kind      tokenKind
+	text      string
+	line, col int
+}
+
+// describe names the token for error messages
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return strconv.Quote(t.text)
+}
+
+// errorAt returns a ParseError at the token's position
+func errorAt(t token, format string, args ...any) error {
+	return &ParseError{Line: t.line, Column: t.col, Message: fmt.Sprintf(format, args...)}
+}
+
+const textSymbols = "',.|{}=/*-^_!?<>~"
+
+// textScanner splits the input into tokens, tracking line and column
+type textScanner struct {
+	src       []rune
+	pos       int
+	line, col int
+}
+
+func (s *textScanner) peek() rune {
+	if s.pos < len(s.src) {
+		return s.src[s.pos]
+	}
+	return 0
+}
+
+func (s *textScanner) next() rune {
+	r := s.src[s.pos]
+	s.pos++
+	if r == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+	return r
+}
+
+// readWhile consumes runes while ok holds and returns them
+func (s *textScanner) readWhile(ok func(rune) bool) string {
+	start := s.pos
+	for s.pos < len(s.src) && ok(s.src[s.pos]) {
+		s.next()
+	}
+	return string(s.src[start:s.pos])
+}
+
+func tokenizeText(src string) ([]token, error) {
+	s := &textScanner{src: []rune(src), line: 1, col: 1}
+	var toks []token
+	for {
+		if s.pos >= len(s.src) {
+			return append(toks, token{kind: tokEOF, line: s.line, col: s.col}), nil
+		}
+		tok := token{line: s.line, col: s.col}
+		r := s.peek()
+		switch {
+		case unicode.IsSpace(r):
+			s.next()
+			continue
+		case r == '%':
+			s.readWhile(func(r rune) bool { return r != '\n' })
+			continue
+		case unicode.IsLetter(r):
+			tok.kind = tokWord
+			tok.text = s.readWhile(unicode.IsLetter)
+		case unicode.IsDigit(r):
+			tok.kind = tokNumber
+			tok.text = s.readWhile(unicode.IsDigit)
+		case r == '\\':
+			s.next()
+			tok.kind = tokCommand
+			switch c := s.peek(); {
+			case unicode.IsLetter(c):
+				tok.text = `\` + s.readWhile(unicode.IsLetter)
+			case c == '<' || c == '>' || c == '!':
+				s.next()
+				tok.text = `\` + string(c)
+			default:
+				return nil, errorAt(tok, "expected a command name after \\")
+			}
+		case r == '"':
+			s.next()
+			var raw strings.Builder
+			raw.WriteRune('"')
+			for {
+				if s.pos >= len(s.src) || s.peek() == '\n' {
+					return nil, errorAt(tok, "unterminated string")
+				}
+				c := s.next()
+				raw.WriteRune(c)
+				if c == '\\' && s.pos < len(s.src) {
+					raw.WriteRune(s.next())
+				} else if c == '"' {
+					break
+				}
+			}
+			text, err := strconv.Unquote(raw.String())
+			if err != nil {
+				return nil, errorAt(tok, "invalid string %s", raw.String())
+			}
+			tok.kind = tokString
+			tok.text = text
+		case strings.ContainsRune(textSymbols, r):
+			s.next()
+			tok.kind = tokSymbol
+			tok.text = string(r)
+		default:
+			return nil, errorAt(tok, "unexpected character %q", r)
+		}
+		toks = append(toks, tok)
+	}
+}
+
+// fillTolerance absorbs float rounding when adding up tuplet durations
+const fillTolerance = 1e-6
+
+var textDurations = map[string]music.NoteValue{
+	"1":  music.WholeNote,
+	"2":  music.HalfNote,
+	"4":  music.QuarterNote,
+	"8":  music.EighthNote,
+	"16": music.SixteenthNote,
+	"32": music.ThirtySecondNote,
+	"64": music.SixtyFourthNote,
+}
+
+// keyModes maps mode commands to the Norwegian mode names used in scores
+var keyModes = map[string]string{
+	`\major`:      "dur",
+	`\minor`:      "moll",
+	`\dur`:        "dur",
+	`\moll`:       "moll",
+	`\ionian`:     "ionisk",
+	`\dorian`:     "dorisk",
+	`\phrygian`:   "frygisk",
+	`\lydian`:     "lydisk",
+	`\mixolydian`: "miksisk",
+	`\aeolian`:    "æolisk",
+	`\locrian`:    "lokrisk",
+}
+
+var textOrnaments = map[string]music.Ornament{
+	`\trill`:   music.Trill,
+	`\mordent`: music.Mordent,
+	`\prall`:   music.InvertedMordent,
+	`\turn`:    music.Turn,
+}
+
+var textArticulations = map[string]music.Articulation{
+	".": music.Staccato,
+	"!": music.Staccatissimo,
+	">": music.Accent,
+	"^": music.Marcato,
+	"-": music.Tenuto,
+}
+
+var textGraces = map[string]music.GraceType{
+	`\grace`:        music.Appoggiatura,
+	`\appoggiatura`: music.Appoggiatura,
+	`\acciaccatura`: music.Acciaccatura,
+}
+
+// textDynamic returns the dynamic named by a command such as \mf
+func textDynamic(command string) (music.Dynamic, bool) {
+	d := music.Dynamic(strings.TrimPrefix(command, `\`))
+	return d, d.GlyphName() != ""
+}
+
+type frameKind int
+
+const (
+	frameGroup frameKind = iota
+	frameTuplet
+	frameGrace
+)
+
+// frame is an open { ... } block
+type frame struct {
+	kind           frameKind
+	open           token
+	actual, normal int
+	grace          music.GraceType
+	first, last    music.ElementRef
+	placed         bool // whether first and last are set
+}
+
+type textParser struct {
+	toks  []token
+	pos   int
+	names *localization.Localization
+	score *music.Score
+
+	clef        string
+	time        music.TimeSignature
+	partial     float64 // length of the pickup in quarters, 0 for none
+	pendingClef string  // clef change for the next measure
+
+	measure  *music.Measure
+	filled   float64 // quarters used in the current measure
+	capacity float64 // quarters the current measure holds
+
+	keyAlters [7]int      // alteration of each step in the key signature
+	barAlters map[int]int // alterations printed so far in the measure, by diatonic number
+
+	duration music.NoteValue // carried over to notes without a duration
+	dots     int
+	grace    music.GraceType // applies to the next note only
+	frames   []*frame
+	hairpin  *music.Hairpin
+	last     music.ElementRef
+	placed   bool // whether any element has been placed
+}
+
+// ParseText parses the compact text syntax described at the top of this
+// file into a score. Errors are *ParseError values.
+func ParseText(src string) (*music.Score, error) {
+	toks, err := tokenizeText(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &textParser{
+		toks:     toks,
+		names:    localization.NewNynorskLocalization("C", "dur"),
+		score:    music.NewScore("", "", "C", "dur", 4, 4, 0),
+		clef:     music.TrebleClef,
+		duration: music.QuarterNote,
+	}
+	p.time = p.score.TimeSignature
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.score, nil
+}
+
+func (p *textParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *textParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// acceptSymbol consumes the symbol if it comes next
+func (p *textParser) acceptSymbol(s string) bool {
+	if t := p.peek(); t.kind == tokSymbol && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *textParser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, errorAt(t, "expected %s, found %s", what, t.describe())
+	}
+	return t, nil
+}
+
+func (p *textParser) expectSymbol(s string) error {
+	if t := p.next(); t.kind != tokSymbol || t.text != s {
+		return errorAt(t, "expected %q, found %s", s, t.describe())
+	}
+	return nil
+}
+
+func (p *textParser) expectNumber() (int, token, error) {
+	t, err := p.expect(tokNumber, "a number")
+	if err != nil {
+		return 0, t, err
+	}
+	n, err := strconv.Atoi(t.text)
+	if err != nil {
+		return 0, t, errorAt(t, "number %s is too large", t.text)
+	}
+	return n, t, nil
+}
+
+func (p *textParser) parse() error {
+	for {
+		tok := p.next()
+		var err error
+		switch tok.kind {
+		case tokEOF:
+			return p.finish(tok)
+		case tokWord:
+			err = p.parseElement(tok)
+		case tokCommand:
+			err = p.parseCommand(tok)
+		case tokSymbol:
+			switch tok.text {
+			case "|":
+				err = p.barCheck(tok)
+			case "<":
+				err = p.parseChord(tok)
+			case "{":
+				p.frames = append(p.frames, &frame{kind: frameGroup, open: tok})
+			case "}":
+				err = p.closeFrame(tok)
+			case "-", "^", "_":
+				err = errorAt(tok, "articulations must follow a note")
+			default:
+				err = errorAt(tok, "unexpected %s", tok.describe())
+			}
+		default:
+			err = errorAt(tok, "unexpected %s", tok.describe())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// finish checks for unclosed blocks and ends a hairpin left running
+func (p *textParser) finish(eof token) error {
+	if len(p.frames) > 0 {
+		return errorAt(p.frames[len(p.frames)-1].open, "unclosed {")
+	}
+	if p.grace != music.GraceNone {
+		return errorAt(eof, "grace note command without a note")
+	}
+	if p.hairpin != nil {
+		p.endHairpin(p.last)
+	}
+	return nil
+}
+
+// atBarLine reports whether the next element starts a new measure
+func (p *textParser) atBarLine() bool {
+	return p.measure == nil || p.filled >= p.capacity-fillTolerance
+}
+
+func (p *textParser) barCheck(tok token) error {
+	if !p.atBarLine() {
+		return errorAt(tok, "bar check failed: measure %d has %g of %g quarters", p.measure.Number, p.filled, p.capacity)
+	}
+	return nil
+}
+
+func (p *textParser) parseCommand(tok token) error {
+	switch tok.text {
+	case `\header`:
+		return p.parseHeader()
+	case `\clef`:
+		return p.parseClef(tok)
+	case `\key`:
+		return p.parseKey(tok)
+	case `\time`:
+		return p.parseTime(tok)
+	case `\tempo`:
+		return p.parseTempo()
+	case `\partial`:
+		return p.parsePartial(tok)
+	case `\tuplet`:
+		return p.parseTuplet(tok)
+	case `\grace`, `\appoggiatura`, `\acciaccatura`:
+		kind := textGraces[tok.text]
+		if t := p.peek(); t.kind == tokSymbol && t.text == "{" {
+			p.next()
+			p.frames = append(p.frames, &frame{kind: frameGrace, open: t, grace: kind})
+			return nil
+		}
+		p.grace = kind
+		return nil
+	}
+	if _, ok := textDynamic(tok.text); ok || isMarkCommand(tok.text) {
+		return errorAt(tok, "%s must follow a note", tok.text)
+	}
+	return errorAt(tok, "unknown command %s", tok.text)
+}
+
+// isMarkCommand reports whether the command is a hairpin or ornament mark
+func isMarkCommand(command string) bool {
+	_, ornament := textOrnaments[command]
+	return ornament || command == `\<` || command == `\>` || command == `\!`
+}
+
+func (p *textParser) parseHeader() error {
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	for !p.acceptSymbol("}") {
+		field, err := p.expect(tokWord, "a header field or }")
+		if err != nil {
+			return err
+		}
+		if err := p.expectSymbol("="); err != nil {
+			return err
+		}
+		value, err := p.expect(tokString, "a quoted string")
+		if err != nil {
+			return err
+		}
+		// Other LilyPond header fields are accepted and ignored
+		switch field.text {
+		case "title":
+			p.score.Title = value.text
+		case "composer":
+			p.score.Composer = value.text
+		}
+	}
+	return nil
+}
+
+func (p *textParser) parseClef(tok token) error {
+	name := p.next()
+	if name.kind != tokWord && name.kind != tokString {
+		return errorAt(name, "expected a clef name, found %s", name.describe())
+	}
+	clef := strings.ToLower(name.text)
+	if !music.IsValidClef(clef) {
+		return errorAt(name, "unknown clef %q", name.text)
+	}
+	if !p.atBarLine() {
+		return errorAt(tok, "clef changes must be at a bar line")
+	}
+	p.clef = clef
+	p.pendingClef = clef
+	return nil
+}
+
+func (p *textParser) parseKey(tok token) error {
+	tonic, err := p.expect(tokWord, "a key tonic")
+	if err != nil {
+		return err
+	}
+	step, alter, ok := localization.ParseNoteName(tonic.text)
+	if !ok {
+		return errorAt(tonic, "unknown note name %q", tonic.text)
+	}
+	modeTok, err := p.expect(tokCommand, `a mode such as \major or \minor`)
+	if err != nil {
+		return err
+	}
+	mode, ok := keyModes[modeTok.text]
+	if !ok {
+		return errorAt(modeTok, "unknown mode %s", modeTok.text)
+	}
+	if p.placed {
+		return errorAt(tok, "key changes after the first note are not supported")
+	}
+
+	key, err := theory.NewKey(step, alter, mode)
+	if err != nil {
+		return errorAt(tonic, "%v", err)
+	}
+	p.score.KeySignature = keySignature(step, alter, mode)
+	p.keyAlters = key.Alterations()
+	return nil
+}
+
+func (p *textParser) parseTime(tok token) error {
+	num, _, err := p.expectNumber()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol("/"); err != nil {
+		return err
+	}
+	den, denTok, err := p.expectNumber()
+	if err != nil {
+		return err
+	}
+	if num <= 0 {
+		return errorAt(tok, "time signature needs a positive numerator")
+	}
+	if den <= 0 || den&(den-1) != 0 {
+		return errorAt(denTok, "time signature denominator %d is not a power of two", den)
+	}
+	if !p.atBarLine() {
+		return errorAt(tok, "time signature changes must be at a bar line")
+	}
+	p.time = music.TimeSignature{Numerator: num, Denominator: den}
+	if p.measure == nil {
+		p.score.TimeSignature = p.time
+	}
+	return nil
+}
+
+func (p *textParser) parseTempo() error {
+	if p.peek().kind == tokString {
+		// Tempo text such as "Allegro" is not kept
+		p.next()
+		if p.peek().kind != tokNumber {
+			return nil
+		}
+	}
+	nv, dots, err := p.parseDuration()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol("="); err != nil {
+		return err
+	}
+	bpm, bpmTok, err := p.expectNumber()
+	if err != nil {
+		return err
+	}
+	if bpm <= 0 {
+		return errorAt(bpmTok, "tempo must be positive")
+	}
+	p.score.Tempo = int(math.Round(float64(bpm) * nv.DottedQuarters(dots)))
+	return nil
+}
+
+func (p *textParser) parsePartial(tok token) error {
+	if p.placed {
+		return errorAt(tok, `\partial must come before the first note`)
+	}
+	nv, dots, err := p.parseDuration()
+	if err != nil {
+		return err
+	}
+	p.partial = nv.DottedQuarters(dots)
+	if p.acceptSymbol("*") {
+		n, nTok, err := p.expectNumber()
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return errorAt(nTok, "partial multiplier must be positive")
+		}
+		p.partial *= float64(n)
+	}
+	return nil
+}
+
+func (p *textParser) parseTuplet(tok token) error {
+	actual, _, err := p.expectNumber()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol("/"); err != nil {
+		return err
+	}
+	normal, _, err := p.expectNumber()
+	if err != nil {
+		return err
+	}
+	if actual <= 0 || normal <= 0 {
+		return errorAt(tok, "invalid tuplet ratio %d/%d", actual, normal)
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	p.frames = append(p.frames, &frame{kind: frameTuplet, open: tok, actual: actual, normal: normal})
+	return nil
+}
+
+func (p *textParser) closeFrame(tok token) error {
+	if len(p.frames) == 0 {
+		return errorAt(tok, "unmatched }")
+	}
+	f := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+	if f.kind != frameTuplet {
+		return nil
+	}
+	if !f.placed {
+		return errorAt(f.open, "empty tuplet")
+	}
+	if f.first.Measure != f.last.Measure {
+		return errorAt(f.open, "tuplet crosses a bar line")
+	}
+	err := p.score.Measures[f.first.Measure].AddTuplet(music.Tuplet{
+		Actual: f.actual,
+		Normal: f.normal,
+		Start:  f.first.Element,
+		End:    f.last.Element,
+	})
+	if err != nil {
+		return errorAt(f.open, "%v", err)
+	}
+	return nil
+}
+
+// parseDuration reads a duration number and its dots
+func (p *textParser) parseDuration() (music.NoteValue, int, error) {
+	t, err := p.expect(tokNumber, "a duration")
+	if err != nil {
+		return 0, 0, err
+	}
+	nv, ok := textDurations[t.text]
+	if !ok {
+		return 0, 0, errorAt(t, "unknown duration %s", t.text)
+	}
+	dots := 0
+	for p.acceptSymbol(".") {
+		dots++
+	}
+	return nv, dots, nil
+}
+
+// writtenPitch is a note name with its octave marks as written
+type writtenPitch struct {
+	tok         token
+	step, alter int
+	octave      int
+	forced      bool // ! after the name
+	cautionary  bool // ? after the name
+}
+
+// parseOctave reads the octave marks and ! or ? following a name
+func (p *textParser) parseOctave() (octave int, forced, cautionary bool) {
+	octave = 3 // c is the octave below middle C
+	for {
+		if p.acceptSymbol("'") {
+			octave++
+		} else if p.acceptSymbol(",") {
+			octave--
+		} else {
+			break
+		}
+	}
+	if p.acceptSymbol("!") {
+		return octave, true, false
+	}
+	return octave, false, p.acceptSymbol("?")
+}
+
+// parsePitch reads the octave marks after the note name tok
+func (p *textParser) parsePitch(tok token) (writtenPitch, error) {
+	step, alter, ok := localization.ParseNoteName(tok.text)
+	if !ok {
+		return writtenPitch{}, errorAt(tok, "unknown note name %q", tok.text)
+	}
+	octave, forced, cautionary := p.parseOctave()
+	return writtenPitch{tok: tok, step: step, alter: alter, octave: octave, forced: forced, cautionary: cautionary}, nil
+}
+
+// parseOptionalDuration reads a duration if one follows, otherwise the
+// previous one is kept
+func (p *textParser) parseOptionalDuration() error {
+	if p.peek().kind != tokNumber {
+		return nil
+	}
+	nv, dots, err := p.parseDuration()
+	if err != nil {
+		return err
+	}
+	p.duration, p.dots = nv, dots
+	return nil
+}
+
+// newNote makes a note of the current duration at the written pitch
+func (p *textParser) newNote(wp writtenPitch) (*music.Note, error) {
+	pitch := p.names.ConvertNoteToMIDI(wp.tok.text, wp.octave)
+	if pitch < 0 || pitch > 127 {
+		return nil, errorAt(wp.tok, "%s in octave %d is outside the MIDI range", wp.tok.text, wp.octave)
+	}
+	note := &music.Note{
+		Pitch:     pitch,
+		Duration:  p.duration,
+		Dots:      p.dots,
+		StaffLine: wp.octave*7 + wp.step - music.ClefBottomLine(p.clef),
+		Grace:     p.graceKind(),
+	}
+	if wp.forced || wp.cautionary {
+		note.Accidental = music.AlterAccidental(wp.alter)
+		note.Courtesy = wp.cautionary
+	}
+	return note, nil
+}
+
+// spell sets the accidental of the note just placed: the one written with
+// ! or ?, or the one its pitch needs after the key signature and the
+// accidentals earlier in the measure. Notes a tie continues into get none
+// and leave the measure's accidentals alone.
+func (p *textParser) spell(n *music.Note) {
+	if n.Accidental == music.AccidentalNone && p.tiedInto(n) {
+		return
+	}
+
+	diatonic := n.StaffLine + music.ClefBottomLine(p.clef)
+	alter := n.Pitch - music.NaturalMIDI(diatonic)
+	implied, ok := p.barAlters[diatonic]
+	if !ok {
+		implied = p.keyAlters[((diatonic%7)+7)%7]
+	}
+	if n.Accidental == music.AccidentalNone && alter == implied {
+		return
+	}
+	n.Accidental = music.AlterAccidental(alter)
+	p.barAlters[diatonic] = alter
+}
+
+// tiedInto reports whether the note or chord before the note just placed
+// ties a note of its pitch into it. Grace notes are skipped, as in
+// music.Score.TieTarget, and are never tied into.
+func (p *textParser) tiedInto(n *music.Note) bool {
+	if n.IsGrace() {
+		return false
+	}
+	mi := len(p.score.Measures) - 1
+	ei := p.measure.ChordHead(len(p.measure.Elements)-1) - 1
+	for mi >= 0 {
+		m := p.score.Measures[mi]
+		if ei < 0 {
+			if mi--; mi >= 0 {
+				ei = len(p.score.Measures[mi].Elements) - 1
+			}
+			continue
+		}
+		prev, ok := m.Elements[ei].(*music.Note)
+		if !ok {
+			return false
+		}
+		head := m.ChordHead(ei)
+		if prev.IsGrace() {
+			ei = head - 1
+			continue
+		}
+		for _, c := range m.ChordNotes(head) {
+			if c.Tie && c.Pitch == n.Pitch {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// parseElement reads a note or rest starting with the word tok
+func (p *textParser) parseElement(tok token) error {
+	if tok.text == "r" {
+		if octave, forced, cautionary := p.parseOctave(); octave != 3 || forced || cautionary {
+			return errorAt(tok, "rests have no pitch")
+		}
+		if err := p.parseOptionalDuration(); err != nil {
+			return err
+		}
+		_, err := p.placeSplit(lengthPiece{p.duration, p.dots}, true, tok, func(i int, l lengthPiece) []music.MusicElement {
+			return []music.MusicElement{&music.Rest{Duration: l.value, Dots: l.dots}}
+		})
+		return err
+	}
+
+	wp, err := p.parsePitch(tok)
+	if err != nil {
+		return err
+	}
+	if err := p.parseOptionalDuration(); err != nil {
+		return err
+	}
+	note, err := p.newNote(wp)
+	if err != nil {
+		return err
+	}
+	p.grace = music.GraceNone
+
+	first, last, ref, err := p.placeNotes([]*music.Note{note}, tok)
+	if err != nil {
+		return err
+	}
+	return p.parseMarks(first, last, ref)
+}
+
+// parseChord reads a chord after the < token open. The marks after the
+// chord go on its first note; a ~ after it ties every note.
+func (p *textParser) parseChord(open token) error {
+	var pitches []writtenPitch
+	var ties []bool
+	for !p.acceptSymbol(">") {
+		tok := p.next()
+		if tok.kind != tokWord || tok.text == "r" {
+			return errorAt(tok, "expected a note or > in chord, found %s", tok.describe())
+		}
+		wp, err := p.parsePitch(tok)
+		if err != nil {
+			return err
+		}
+		pitches = append(pitches, wp)
+		ties = append(ties, p.acceptSymbol("~"))
+	}
+	if len(pitches) == 0 {
+		return errorAt(open, "empty chord")
+	}
+	if err := p.parseOptionalDuration(); err != nil {
+		return err
+	}
+
+	notes := make([]*music.Note, len(pitches))
+	for i, wp := range pitches {
+		note, err := p.newNote(wp)
+		if err != nil {
+			return err
+		}
+		note.Tie = ties[i]
+		note.Chord = i > 0
+		notes[i] = note
+	}
+	p.grace = music.GraceNone
+
+	first, last, ref, err := p.placeNotes(notes, open)
+	if err != nil {
+		return err
+	}
+	return p.parseMarks(first, last, ref)
+}
+
+// graceKind returns the grace type for the next note: a pending single
+// grace command or the innermost grace block
+func (p *textParser) graceKind() music.GraceType {
+	if p.grace != music.GraceNone {
+		return p.grace
+	}
+	for i := len(p.frames) - 1; i >= 0; i-- {
+		if p.frames[i].kind == frameGrace {
+			return p.frames[i].grace
+		}
+	}
+	return music.GraceNone
+}
+
+// newMeasure starts a measure with the current time signature and any
+// pending clef change
+func (p *textParser) newMeasure() {
+	var ts *music.TimeSignature
+	if p.time != p.score.TimeSignature {
+		t := p.time
+		ts = &t
+	}
+	m := p.score.AddMeasure(ts)
+	first := len(p.score.Measures) == 1
+	if first {
+		m.Clef = music.TrebleClef
+	}
+	if p.pendingClef != "" {
+		m.Clef = p.pendingClef
+		p.pendingClef = ""
+	}
+
+	p.measure = m
+	p.barAlters = make(map[int]int)
+	p.filled = 0
+	p.capacity = float64(m.TimeSignature.Numerator) * 4 / float64(m.TimeSignature.Denominator)
+	if first && p.partial > 0 {
+		m.Pickup = true
+		p.capacity = p.partial
+	}
+}
+
+// measureQuarters returns the quarters a measure in the time signature holds
+func measureQuarters(ts music.TimeSignature) float64 {
+	return float64(ts.Numerator) * 4 / float64(ts.Denominator)
+}
+
+// splitQuarters writes a length in quarters as note values to be tied, as
+// splitLength does
+func splitQuarters(quarters float64) ([]lengthPiece, bool) {
+	sixtyFourths := quarters * 16
+	n := math.Round(sixtyFourths)
+	if math.Abs(sixtyFourths-n) > fillTolerance {
+		return nil, false
+	}
+	return splitLength(newFraction(int(n), 64))
+}
+
+// splitAtBars returns the parts of a length that starts at the next
+// element: the length itself when it fits in the measure, otherwise the
+// note values filling the rest of the measure and each measure after it.
+// ok is false when the parts cannot be written as note values.
+func (p *textParser) splitAtBars(length lengthPiece) (parts []lengthPiece, ok bool) {
+	quarters := length.value.DottedQuarters(length.dots)
+	left := p.capacity - p.filled
+	if p.atBarLine() {
+		left = measureQuarters(p.time)
+		if p.measure == nil && p.partial > 0 {
+			left = p.partial
+		}
+	}
+	if quarters <= left+fillTolerance {
+		return []lengthPiece{length}, true
+	}
+	for quarters > fillTolerance {
+		chunk := min(quarters, left)
+		lengths, ok := splitQuarters(chunk)
+		if !ok {
+			return nil, false
+		}
+		parts = append(parts, lengths...)
+		quarters -= chunk
+		left = measureQuarters(p.time)
+	}
+	return parts, true
+}
+
+// placeSplit places a rest, note or chord whose length may cross bar lines.
+// When split is set and the element is not in a tuplet, a length that
+// crosses a bar line is broken into parts that fill each measure; piece
+// returns the elements to place for part i, a chord's notes in order. It
+// returns the reference of the first part's first element and leaves p.last
+// at that of the last part.
+func (p *textParser) placeSplit(length lengthPiece, split bool, tok token, piece func(i int, l lengthPiece) []music.MusicElement) (music.ElementRef, error) {
+	parts := []lengthPiece{length}
+	if split && !p.inTuplet() {
+		if ps, ok := p.splitAtBars(length); ok {
+			parts = ps
+		}
+	}
+	var first, head music.ElementRef
+	for i, l := range parts {
+		for j, e := range piece(i, l) {
+			if err := p.place(e, tok); err != nil {
+				return first, err
+			}
+			if j == 0 {
+				head = p.last
+			}
+		}
+		if i == 0 {
+			first = head
+		}
+	}
+	p.last = head
+	return first, nil
+}
+
+// inTuplet reports whether a tuplet block is open
+func (p *textParser) inTuplet() bool {
+	for _, f := range p.frames {
+		if f.kind == frameTuplet {
+			return true
+		}
+	}
+	return false
+}
+
+// placeNotes places a note or chord, splitting it into tied notes at the
+// bar lines it crosses. first and last are the notes of the first and last
+// part, the same when it fits in the measure; ref is that of the first
+// part. Ties written inside a chord stay on the last part.
+func (p *textParser) placeNotes(notes []*music.Note, tok token) (first, last []*music.Note, ref music.ElementRef, err error) {
+	ties := make([]bool, len(notes))
+	for i, n := range notes {
+		ties[i] = n.Tie
+	}
+	var parts [][]*music.Note
+	length := lengthPiece{notes[0].Duration, notes[0].Dots}
+	ref, err = p.placeSplit(length, !notes[0].IsGrace(), tok, func(i int, l lengthPiece) []music.MusicElement {
+		part := notes
+		if i > 0 {
+			part = make([]*music.Note, len(notes))
+			for j, n := range notes {
+				// Continuations repeat the pitch without its accidental
+				part[j] = &music.Note{Pitch: n.Pitch, StaffLine: n.StaffLine, Chord: n.Chord}
+			}
+		}
+		elems := make([]music.MusicElement, len(part))
+		for j, n := range part {
+			n.Duration, n.Dots, n.Tie = l.value, l.dots, true
+			elems[j] = n
+		}
+		parts = append(parts, part)
+		return elems
+	})
+	if err != nil {
+		return nil, nil, ref, err
+	}
+	last = parts[len(parts)-1]
+	for i, n := range last {
+		n.Tie = ties[i]
+	}
+	return parts[0], last, ref, nil
+}
+
+// place appends an element to the current measure, starting a new measure
+// when the current one is full
+func (p *textParser) place(elem music.MusicElement, tok token) error {
+	n, isNote := elem.(*music.Note)
+	chord := isNote && n.Chord
+	if !chord && p.atBarLine() {
+		p.newMeasure()
+	}
+
+	quarters := elem.GetDuration().DottedQuarters(elem.GetDots())
+	if isNote && (n.IsGrace() || n.Chord) {
+		quarters = 0
+	}
+	for _, f := range p.frames {
+		if f.kind == frameTuplet {
+			quarters = quarters * float64(f.normal) / float64(f.actual)
+		}
+	}
+	if p.filled+quarters > p.capacity+fillTolerance {
+		return errorAt(tok, "%s crosses the bar line: measure %d has %g of %g quarters left",
+			tok.text, p.measure.Number, p.capacity-p.filled, p.capacity)
+	}
+
+	switch e := elem.(type) {
+	case *music.Note:
+		p.measure.AddNote(e)
+		p.spell(e)
+	case *music.Rest:
+		p.measure.AddRest(e)
+	}
+	ref := music.ElementRef{Measure: len(p.score.Measures) - 1, Element: len(p.measure.Elements) - 1}
+	for _, f := range p.frames {
+		if f.kind != frameTuplet {
+			continue
+		}
+		if !f.placed {
+			f.first = ref
+			f.placed = true
+		}
+		f.last = ref
+	}
+	p.filled += quarters
+	p.last = ref
+	p.placed = true
+	return nil
+}
+
+// parseMarks reads the ties, dynamics, hairpins, articulations and
+// ornaments following a note or chord. first and last are the notes of the
+// first and last parts of a note split at bar lines, and ref the first
+// part. Marks go on the first note and start at ref; a tie goes on every
+// note of the last part.
+func (p *textParser) parseMarks(first, last []*music.Note, ref music.ElementRef) error {
+	note := first[0]
+	for {
+		tok := p.peek()
+		if tok.kind == tokSymbol && tok.text == "~" {
+			p.next()
+			for _, n := range last {
+				n.Tie = true
+			}
+			continue
+		}
+		placement := music.PlacementAuto
+		directed := false
+		if tok.kind == tokSymbol && (tok.text == "-" || tok.text == "^" || tok.text == "_") {
+			p.next()
+			switch tok.text {
+			case "^":
+				placement = music.PlacementAbove
+			case "_":
+				placement = music.PlacementBelow
+			}
+			directed = true
+
+			mark := p.peek()
+			if mark.kind == tokSymbol {
+				art, ok := textArticulations[mark.text]
+				if !ok {
+					return errorAt(mark, "unknown articulation %s%s", tok.text, mark.text)
+				}
+				p.next()
+				note.Articulations = append(note.Articulations, art)
+				if placement != music.PlacementAuto {
+					note.ArticulationPlacement = placement
+				}
+				continue
+			}
+			if mark.kind != tokCommand {
+				return errorAt(mark, "expected an articulation or mark after %q", tok.text)
+			}
+			tok = mark
+		}
+		if tok.kind != tokCommand {
+			return nil
+		}
+
+		if d, ok := textDynamic(tok.text); ok {
+			p.next()
+			note.Dynamic = d
+			note.DynamicPlacement = placement
+			// A dynamic ends a hairpin leading to it
+			if p.hairpin != nil && p.hairpin.Start != ref {
+				p.endHairpin(ref)
+			}
+			continue
+		}
+		switch tok.text {
+		case `\<`, `\>`:
+			p.next()
+			if p.hairpin != nil {
+				if p.hairpin.Start == ref {
+					return errorAt(tok, "a hairpin already starts on this note")
+				}
+				p.endHairpin(ref)
+			}
+			kind := music.Crescendo
+			if tok.text == `\>` {
+				kind = music.Diminuendo
+			}
+			p.hairpin = &music.Hairpin{Type: kind, Start: ref, Placement: placement}
+			continue
+		case `\!`:
+			p.next()
+			if p.hairpin != nil {
+				p.endHairpin(ref)
+			}
+			continue
+		}
+		if o, ok := textOrnaments[tok.text]; ok {
+			p.next()
+			note.Ornaments = append(note.Ornaments, o)
+			continue
+		}
+		if directed {
+			return errorAt(tok, "%s is not a mark", tok.text)
+		}
+		return nil
+	}
+}
+
+func (p *textParser) endHairpin(ref music.ElementRef) {
+	h := *p.hairpin
+	h.End = ref
+	p.score.Hairpins = append(p.score.Hairpins, h)
+	p.hairpin = nil
+}
@@ -0,0 +1,179 @@
+package notation
+
+import (
+	"testing"
+
+	"gehoer/music"
+)
+
+// placed is a note or rest as the parser left it
+type placed struct {
+	rest     bool
+	value    music.NoteValue
+	dots     int
+	tie      bool
+	pitch    int
+	accident string
+}
+
+// measureElements returns the notes and rests of each measure
+func measureElements(score *music.Score) [][]placed {
+	var measures [][]placed
+	for _, m := range score.Measures {
+		var elems []placed
+		for _, e := range m.Elements {
+			switch e := e.(type) {
+			case *music.Note:
+				elems = append(elems, placed{value: e.Duration, dots: e.Dots, tie: e.Tie, pitch: e.Pitch, accident: e.Accidental})
+			case *music.Rest:
+				elems = append(elems, placed{rest: true, value: e.Duration, dots: e.Dots})
+			}
+		}
+		measures = append(measures, elems)
+	}
+	return measures
+}
+
+func TestParseTextSplitsAtBarLines(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want [][]placed
+	}{
+		{
+			"note crossing the bar",
+			`\key g \major \time 3/4 g'4 a'8 h' c''2.`,
+			[][]placed{
+				{{value: music.QuarterNote, pitch: 67}, {value: music.EighthNote, pitch: 69}, {value: music.EighthNote, pitch: 71}, {value: music.QuarterNote, tie: true, pitch: 72}},
+				{{value: music.HalfNote, pitch: 72}},
+			},
+		},
+		{
+			"accidental only on the first part",
+			`\time 2/4 c'4 fiss'2 fiss'4`,
+			[][]placed{
+				{{value: music.QuarterNote, pitch: 60}, {value: music.QuarterNote, tie: true, pitch: 66, accident: "sharp"}},
+				{{value: music.QuarterNote, pitch: 66}, {value: music.QuarterNote, pitch: 66, accident: "sharp"}},
+			},
+		},
+		{
+			"rest over two bars",
+			`\time 2/4 c'4 r1`,
+			[][]placed{
+				{{value: music.QuarterNote, pitch: 60}, {rest: true, value: music.QuarterNote}},
+				{{rest: true, value: music.HalfNote}},
+				{{rest: true, value: music.QuarterNote}},
+			},
+		},
+		{
+			"pickup",
+			`\time 3/4 \partial 4 d'2 e'2`,
+			[][]placed{
+				{{value: music.QuarterNote, tie: true, pitch: 62}},
+				{{value: music.QuarterNote, pitch: 62}, {value: music.HalfNote, pitch: 64}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := ParseText(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := measureElements(score)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d measures %v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range tt.want {
+				if len(got[i]) != len(tt.want[i]) {
+					t.Fatalf("measure %d: got %v, want %v", i+1, got[i], tt.want[i])
+				}
+				for j := range tt.want[i] {
+					if got[i][j] != tt.want[i][j] {
+						t.Errorf("measure %d element %d: got %+v, want %+v", i+1, j+1, got[i][j], tt.want[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseTextSplitChordKeepsMarksOnFirstPart(t *testing.T) {
+	score, err := ParseText(`\time 2/4 c'4 <c' e'>2-> d'4`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := score.Measures[0].Elements[1].(*music.Note)
+	if !first.HasArticulation(music.Accent) || !first.Tie {
+		t.Errorf("first part %+v, want an accent and a tie", first)
+	}
+	second := score.Measures[0].Elements[2].(*music.Note)
+	if !second.Chord || !second.Tie {
+		t.Errorf("second chord note %+v, want it tied in the chord", second)
+	}
+	for _, e := range score.Measures[1].Elements[:2] {
+		if n := e.(*music.Note); n.Tie || len(n.Articulations) > 0 {
+			t.Errorf("last part %+v, want it untied and unmarked", n)
+		}
+	}
+}
+
+func TestParseTextTupletStillRejectsBarCrossing(t *testing.T) {
+	if _, err := ParseText(`\time 2/4 c'4 \tuplet 3/2 { c'4 d' e' }`); err == nil {
+		t.Error("a tuplet crossing the bar line parsed")
+	}
+}
+
+func TestParseTextAccidentals(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"altered by the key", `\key fiss \minor \time 2/4 fiss'4 giss'`, "- -"},
+		{"natural against the key", `\key fiss \minor \time 2/4 c''4 c''`, "natural -"},
+		{"alteration the key does not give", `\time 2/4 fiss'4 fiss'`, "sharp -"},
+		{"lasts to the bar line", `\time 2/4 fiss'4 fiss' | fiss' f'`, "sharp - | sharp natural"},
+		{"other octave is another line", `\time 2/4 fiss'4 fiss''`, "sharp sharp"},
+		{"chord notes", `\key d \major \time 2/4 <d' fiss' a'>4 <d' f' a'>`, "- - - - natural -"},
+		{"tied over the bar line", `\time 2/4 g'4 fiss'~ | fiss'4 fiss'`, "- sharp | - sharp"},
+		{"forced", `\key d \major \time 2/4 fiss'!4 f'!`, "sharp natural"},
+		{"reminder", `\key d \major \time 2/4 f'4 fiss'?`, "natural (sharp)"},
+		{"grace note", `\time 2/4 \grace ess'8 d'4 ess'`, "flat - -"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := ParseText(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := accidentalMarks(score); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			// Written back, the text asks for the same accidentals
+			text := FormatText(score)
+			again, err := ParseText(text)
+			if err != nil {
+				t.Fatalf("%v in\n%s", err, text)
+			}
+			if got := accidentalMarks(again); got != tt.want {
+				t.Errorf("got %q after writing\n%s", got, text)
+			}
+		})
+	}
+}
+
+func TestParseTextAccidentalsAgreeWithResolve(t *testing.T) {
+	score, err := ParseText(`\key ess \major \time 3/4 ess'4 e' ess' | <c' e' g'>2 ass'4~ | ass'4 a' h | b2.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := accidentalMarks(score)
+	if err := ResolveAccidentals(score, AccidentalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if resolved := accidentalMarks(score); parsed != resolved {
+		t.Errorf("parsed %q, resolved %q", parsed, resolved)
+	}
+}
@@ -0,0 +1,385 @@
+package notation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gehoer/localization"
+	"gehoer/music"
+)
+
+// modeCommands maps the Norwegian mode names used in scores to \key modes
+var modeCommands = map[string]string{
+	"dur":     `\major`,
+	"moll":    `\minor`,
+	"ionisk":  `\ionian`,
+	"dorisk":  `\dorian`,
+	"frygisk": `\phrygian`,
+	"lydisk":  `\lydian`,
+	"miksisk": `\mixolydian`,
+	"æolisk":  `\aeolian`,
+	"lokrisk": `\locrian`,
+}
+
+// musicFormat holds what differs between the text syntax and LilyPond
+// output; the notes themselves are written the same way
+type musicFormat struct {
+	noteName func(step, alter int) string
+	indent   string
+	repeats  bool // write repeat bar lines, endings and a final bar line
+}
+
+var textFormat = musicFormat{noteName: localization.FormatNoteName}
+
+// FormatText writes the score in the syntax read by ParseText, one measure
+// per line. Repeats and endings, tuplet ratios shown as "3:2", tuplet
+// placements and written accidentals the syntax cannot express are left
+// out.
+func FormatText(score *music.Score) string {
+	var b strings.Builder
+	writeHeader(&b, score)
+	writeMusic(&b, score, textFormat)
+	return b.String()
+}
+
+// writeHeader writes a \header block with the title and composer, if any
+func writeHeader(b *strings.Builder, score *music.Score) {
+	if score.Title == "" && score.Composer == "" {
+		return
+	}
+	b.WriteString("\\header {\n")
+	if score.Title != "" {
+		fmt.Fprintf(b, "  title = %s\n", strconv.Quote(score.Title))
+	}
+	if score.Composer != "" {
+		fmt.Fprintf(b, "  composer = %s\n", strconv.Quote(score.Composer))
+	}
+	b.WriteString("}\n")
+}
+
+// writeMusic writes the clef, key, time, tempo and pickup followed by one
+// line per measure
+func writeMusic(b *strings.Builder, score *music.Score, f musicFormat) {
+	clef := score.ClefAt(0)
+	fmt.Fprintf(b, "%s\\clef %s\n", f.indent, clef)
+	if key := formatKey(score.KeySignature, f.noteName); key != "" {
+		b.WriteString(f.indent + key + "\n")
+	}
+	time := score.TimeSignature
+	fmt.Fprintf(b, "%s\\time %d/%d\n", f.indent, time.Numerator, time.Denominator)
+	if score.Tempo > 0 {
+		fmt.Fprintf(b, "%s\\tempo 4 = %d\n", f.indent, score.Tempo)
+	}
+	if len(score.Measures) > 0 && score.Measures[0].Pickup {
+		quarters := 0.0
+		for i := range score.Measures[0].Elements {
+			quarters += score.Measures[0].ElementQuarters(i)
+		}
+		fmt.Fprintf(b, "%s\\partial %s\n", f.indent, formatLength(quarters))
+	}
+
+	hairpinStarts := make(map[music.ElementRef]music.Hairpin)
+	hairpinEnds := make(map[music.ElementRef]bool)
+	for _, hp := range score.Hairpins {
+		hairpinStarts[hp.Start] = hp
+		if hp.Start != hp.End {
+			hairpinEnds[hp.End] = true
+		}
+	}
+
+	accidentals := &accidentalContext{tied: map[int]bool{}}
+	if key, err := localization.ParseKey(score.KeySignature.Tonic, score.KeySignature.Mode); err == nil {
+		accidentals.keyAlters = key.Alterations()
+	}
+
+	for mi, m := range score.Measures {
+		accidentals.barAlters = make(map[int]int)
+		if mi > 0 && m.Clef != "" {
+			clef = m.Clef
+			fmt.Fprintf(b, "%s\\clef %s\n", f.indent, clef)
+		}
+		if m.TimeSignature != time {
+			time = m.TimeSignature
+			fmt.Fprintf(b, "%s\\time %d/%d\n", f.indent, time.Numerator, time.Denominator)
+		}
+
+		// Outer tuplets open first
+		tuplets := append([]music.Tuplet(nil), m.Tuplets...)
+		sort.SliceStable(tuplets, func(i, j int) bool {
+			return tuplets[i].End-tuplets[i].Start > tuplets[j].End-tuplets[j].Start
+		})
+
+		var items []string
+		if f.repeats {
+			items = append(items, repeatMarks(score, mi)...)
+		}
+		lastDuration, lastDots := music.NoteValue(-1), -1
+		for ei, e := range m.Elements {
+			for _, t := range tuplets {
+				if t.Start == ei {
+					items = append(items, fmt.Sprintf("\\tuplet %d/%d {", t.Actual, t.Normal))
+				}
+			}
+
+			var item strings.Builder
+			var notes []*music.Note
+			switch el := e.(type) {
+			case *music.Note:
+				if el.Chord {
+					// Written with the chord's first note
+					break
+				}
+				switch el.Grace {
+				case music.Appoggiatura:
+					item.WriteString("\\appoggiatura ")
+				case music.Acciaccatura:
+					item.WriteString("\\acciaccatura ")
+				}
+				notes = m.ChordNotes(ei)
+				item.WriteString(formatChord(notes, clef, f.noteName, accidentals))
+				if !el.IsGrace() {
+					accidentals.tied = map[int]bool{}
+					for _, n := range notes {
+						if n.Tie {
+							accidentals.tied[n.Pitch] = true
+						}
+					}
+				}
+			case *music.Rest:
+				item.WriteString("r")
+				accidentals.tied = map[int]bool{}
+			}
+			if item.Len() > 0 {
+				if e.GetDuration() != lastDuration || e.GetDots() != lastDots {
+					lastDuration, lastDots = e.GetDuration(), e.GetDots()
+					item.WriteString(formatDuration(lastDuration, lastDots))
+				}
+				if len(notes) > 0 {
+					if allTied(notes) {
+						item.WriteString("~")
+					}
+					ref := music.ElementRef{Measure: mi, Element: ei}
+					item.WriteString(formatMarks(notes[0], ref, hairpinStarts, hairpinEnds))
+				}
+				items = append(items, item.String())
+			}
+
+			for _, t := range tuplets {
+				if t.End == ei {
+					items = append(items, "}")
+				}
+			}
+		}
+
+		if f.repeats {
+			items = append(items, closingBar(score, mi)...)
+		}
+		line := strings.Join(items, " ")
+		if mi < len(score.Measures)-1 {
+			line += " |"
+		}
+		b.WriteString(f.indent + line + "\n")
+	}
+}
+
+// formatKey writes a \key command, or "" for keys it cannot name
+func formatKey(key music.KeySignature, noteName func(step, alter int) string) string {
+	step, alter, ok := localization.ParseNoteName(key.Tonic)
+	mode, known := modeCommands[key.Mode]
+	if !ok || !known {
+		return ""
+	}
+	return fmt.Sprintf("\\key %s %s", noteName(step, alter), mode)
+}
+
+// pitchSpellings spells each pitch class with sharps, except for b
+var pitchSpellings = [12][2]int{
+	{0, 0}, {0, 1}, {1, 0}, {1, 1}, {2, 0}, {3, 0},
+	{3, 1}, {4, 0}, {4, 1}, {5, 0}, {6, -1}, {6, 0},
+}
+
+// spellPitch returns the note's diatonic number and alteration, spelled
+// from its staff line in the clef
+func spellPitch(n *music.Note, clef string) (diatonic, alter int) {
+	diatonic = music.ClefBottomLine(clef) + n.StaffLine
+	alter = n.Pitch - music.NaturalMIDI(diatonic)
+	if alter < -2 || alter > 2 {
+		// The staff line does not fit the pitch; spell from the pitch alone
+		octave := n.Pitch/12 - 1
+		s := pitchSpellings[n.Pitch%12]
+		diatonic = octave*7 + s[0]
+		alter = s[1]
+	}
+	return diatonic, alter
+}
+
+// accidentalContext follows the alterations a reader assumes, as ParseText
+// and LilyPond do: those of the key signature, those printed earlier in
+// the measure, and those of the notes the previous note or chord ties on
+type accidentalContext struct {
+	keyAlters [7]int
+	barAlters map[int]int  // by diatonic number
+	tied      map[int]bool // pitches tied from the previous note or chord
+}
+
+// mark returns ! for a written accidental the reader would not print by
+// itself, ? for a courtesy accidental, and otherwise "". It records the
+// accidental the note is printed with.
+func (c *accidentalContext) mark(n *music.Note, diatonic, alter int) string {
+	if c.tied[n.Pitch] && !n.IsGrace() && n.Accidental == music.AccidentalNone {
+		return ""
+	}
+	implied, ok := c.barAlters[diatonic]
+	if !ok {
+		implied = c.keyAlters[((diatonic%7)+7)%7]
+	}
+	if alter != implied || n.Accidental != music.AccidentalNone {
+		c.barAlters[diatonic] = alter
+	}
+	switch {
+	case n.Accidental == music.AccidentalNone:
+		return ""
+	case n.Courtesy:
+		return "?"
+	case alter == implied || c.tied[n.Pitch] && !n.IsGrace():
+		return "!"
+	}
+	return ""
+}
+
+// formatPitch writes the note name and octave marks, spelled from the staff
+// line in the clef, with ! or ? where the note's written accidental calls
+// for it
+func formatPitch(n *music.Note, clef string, noteName func(step, alter int) string, accidentals *accidentalContext) string {
+	diatonic, alter := spellPitch(n, clef)
+	octave := int(math.Floor(float64(diatonic) / 7))
+	step := diatonic - octave*7
+
+	name := noteName(step, alter)
+	if octave > 3 {
+		name += strings.Repeat("'", octave-3)
+	} else if octave < 3 {
+		name += strings.Repeat(",", 3-octave)
+	}
+	return name + accidentals.mark(n, diatonic, alter)
+}
+
+// formatChord writes a single note's pitch, or a chord's pitches in < >
+// with ~ after each tied note when not all of them are tied
+func formatChord(notes []*music.Note, clef string, noteName func(step, alter int) string, accidentals *accidentalContext) string {
+	if len(notes) == 1 {
+		return formatPitch(notes[0], clef, noteName, accidentals)
+	}
+	tieEach := !allTied(notes)
+	pitches := make([]string, len(notes))
+	for i, n := range notes {
+		pitches[i] = formatPitch(n, clef, noteName, accidentals)
+		if tieEach && n.Tie {
+			pitches[i] += "~"
+		}
+	}
+	return "<" + strings.Join(pitches, " ") + ">"
+}
+
+// allTied reports whether every note of a chord is tied
+func allTied(notes []*music.Note) bool {
+	for _, n := range notes {
+		if !n.Tie {
+			return false
+		}
+	}
+	return true
+}
+
+// formatDuration writes a duration number with dots
+func formatDuration(nv music.NoteValue, dots int) string {
+	for text, v := range textDurations {
+		if v == nv {
+			return text + strings.Repeat(".", dots)
+		}
+	}
+	return "4"
+}
+
+// formatLength writes a length in quarters as a duration, or as a duration
+// times a count when no dotted value fits
+func formatLength(quarters float64) string {
+	for nv := music.WholeNote; nv <= music.SixtyFourthNote; nv++ {
+		for dots := 0; dots <= 2; dots++ {
+			if math.Abs(nv.DottedQuarters(dots)-quarters) < fillTolerance {
+				return formatDuration(nv, dots)
+			}
+		}
+	}
+	for nv := music.WholeNote; nv <= music.SixtyFourthNote; nv++ {
+		count := quarters / nv.DottedQuarters(0)
+		if n := math.Round(count); n >= 1 && math.Abs(count-n) < fillTolerance {
+			return fmt.Sprintf("%s*%d", formatDuration(nv, 0), int(n))
+		}
+	}
+	return "4"
+}
+
+// placementPrefix returns the direction character for a mark
+func placementPrefix(p music.Placement, auto string) string {
+	switch p {
+	case music.PlacementAbove:
+		return "^"
+	case music.PlacementBelow:
+		return "_"
+	default:
+		return auto
+	}
+}
+
+var articulationMarks = map[music.Articulation]string{
+	music.Staccato:      ".",
+	music.Staccatissimo: "!",
+	music.Accent:        ">",
+	music.Marcato:       "^",
+	music.Tenuto:        "-",
+}
+
+var ornamentCommands = map[music.Ornament]string{
+	music.Trill:           `\trill`,
+	music.Mordent:         `\mordent`,
+	music.InvertedMordent: `\prall`,
+	music.Turn:            `\turn`,
+}
+
+// formatMarks writes the note's marks in the order ParseText reads them
+// back: articulations, ornaments, the dynamic, then hairpin ends and starts
+func formatMarks(n *music.Note, ref music.ElementRef, starts map[music.ElementRef]music.Hairpin, ends map[music.ElementRef]bool) string {
+	var b strings.Builder
+	for _, a := range n.Articulations {
+		if mark, ok := articulationMarks[a]; ok {
+			b.WriteString(placementPrefix(n.ArticulationPlacement, "-") + mark)
+		}
+	}
+	for _, o := range n.Ornaments {
+		if cmd, ok := ornamentCommands[o]; ok {
+			b.WriteString(cmd)
+		}
+	}
+	if n.Dynamic != music.DynamicNone {
+		b.WriteString(placementPrefix(n.DynamicPlacement, "") + `\` + string(n.Dynamic))
+	}
+	if ends[ref] && n.Dynamic == music.DynamicNone {
+		b.WriteString(`\!`)
+	}
+	if hp, ok := starts[ref]; ok {
+		b.WriteString(placementPrefix(hp.Placement, ""))
+		if hp.Type == music.Diminuendo {
+			b.WriteString(`\>`)
+		} else {
+			b.WriteString(`\<`)
+		}
+		if hp.End == ref {
+			b.WriteString(`\!`)
+		}
+	}
+	return b.String()
+}
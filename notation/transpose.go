@@ -0,0 +1,168 @@
+package notation
+
+import (
+	"fmt"
+
+	"gehoer/localization"
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// TransposeOptions bounds the notes of a transposed score. When Low or
+// High is set, the whole score is moved by octaves so that as few notes
+// as possible, by as little as possible, fall outside the MIDI range.
+type TransposeOptions struct {
+	Low  int // lowest MIDI pitch, 0 for no bound
+	High int // highest MIDI pitch, 0 for no bound
+}
+
+// maxKeyFifths is the most sharps or flats a key signature can have
+const maxKeyFifths = 7
+
+// Transpose returns a copy of the score moved by the interval, with the
+// key signature, pitches, staff lines and accidentals respelled. Accidentals
+// are resolved in the new key as by ResolveAccidentals, without courtesy
+// accidentals. Notes that would need more than a double sharp or flat are
+// spelled enharmonically.
+// It fails when the new key would need more than seven sharps or flats.
+func Transpose(score *music.Score, iv theory.Interval, opts TransposeOptions) (*music.Score, error) {
+	iv = fitRange(score, iv, opts)
+
+	key, err := localization.ParseKey(score.KeySignature.Tonic, score.KeySignature.Mode)
+	if err != nil {
+		return nil, err
+	}
+	newStep, newAlter := transposePitch(key.Step, music.NaturalMIDI(key.Step)+key.Alter, iv)
+	newStep = ((newStep % 7) + 7) % 7
+	if newAlter < -2 || newAlter > 2 {
+		return nil, fmt.Errorf("cannot transpose the key of %s %s by %d steps and %d semitones", score.KeySignature.Tonic, key.Mode, iv.Steps, iv.Semitones)
+	}
+	newKey := theory.Key{Step: newStep, Alter: newAlter, Mode: key.Mode}
+	if f := newKey.Fifths(); f < -maxKeyFifths || f > maxKeyFifths {
+		return nil, fmt.Errorf("the key of %s %s has %d accidentals in its key signature", localization.FormatNoteName(newStep, newAlter), key.Mode, abs(f))
+	}
+
+	out := score.Clone()
+	out.KeySignature = keySignature(newStep, newAlter, key.Mode)
+	for mi, m := range out.Measures {
+		clef := out.ClefAt(mi)
+		for _, e := range m.Elements {
+			n, ok := e.(*music.Note)
+			if !ok {
+				continue
+			}
+			diatonic, _ := spellPitch(n, clef)
+			newDiatonic, alter := transposePitch(diatonic, n.Pitch, iv)
+			n.Pitch += iv.Semitones
+			if alter < -2 || alter > 2 {
+				// Spell enharmonically on the step the pitch lies closer to
+				if alter < 0 {
+					newDiatonic--
+				} else {
+					newDiatonic++
+				}
+			}
+			n.StaffLine = newDiatonic - music.ClefBottomLine(clef)
+		}
+	}
+	resolveAccidentals(out, newKey, AccidentalOptions{})
+	return out, nil
+}
+
+// tieContinuations returns the notes a tie from an earlier note ends on
+func tieContinuations(score *music.Score) map[music.ElementRef]bool {
+	tied := make(map[music.ElementRef]bool)
+	for mi, m := range score.Measures {
+		for ei, e := range m.Elements {
+			if n, ok := e.(*music.Note); ok && n.Tie {
+				if target, ok := score.TieTarget(music.ElementRef{Measure: mi, Element: ei}); ok {
+					tied[target] = true
+				}
+			}
+		}
+	}
+	return tied
+}
+
+// TransposeToKey returns a copy of the score transposed to a key in the
+// same mode, by the smallest step up or down
+func TransposeToKey(score *music.Score, key music.KeySignature, opts TransposeOptions) (*music.Score, error) {
+	if key.Mode != score.KeySignature.Mode {
+		return nil, fmt.Errorf("cannot transpose from %s to %s", score.KeySignature.Mode, key.Mode)
+	}
+	iv, err := KeyInterval(score.KeySignature, key)
+	if err != nil {
+		return nil, err
+	}
+	return Transpose(score, iv, opts)
+}
+
+// KeyInterval returns the interval from one key's tonic to another's,
+// taking the smallest step up or down (at most a fourth)
+func KeyInterval(from, to music.KeySignature) (theory.Interval, error) {
+	fromStep, fromAlter, ok := localization.ParseNoteName(from.Tonic)
+	if !ok {
+		return theory.Interval{}, fmt.Errorf("unknown key tonic %q", from.Tonic)
+	}
+	toStep, toAlter, ok := localization.ParseNoteName(to.Tonic)
+	if !ok {
+		return theory.Interval{}, fmt.Errorf("unknown key tonic %q", to.Tonic)
+	}
+	steps := toStep - fromStep
+	if steps > 3 {
+		steps -= 7
+	} else if steps < -3 {
+		steps += 7
+	}
+	semitones := music.NaturalMIDI(fromStep+steps) + toAlter - music.NaturalMIDI(fromStep) - fromAlter
+	return theory.Interval{Steps: steps, Semitones: semitones}, nil
+}
+
+// transposePitch moves a spelled pitch by the interval and returns its new
+// diatonic number and alteration
+func transposePitch(diatonic, pitch int, iv theory.Interval) (newDiatonic, alter int) {
+	newDiatonic = diatonic + iv.Steps
+	return newDiatonic, pitch + iv.Semitones - music.NaturalMIDI(newDiatonic)
+}
+
+// fitRange adds the octaves to the interval that keep the transposed notes
+// closest to the range in the options
+func fitRange(score *music.Score, iv theory.Interval, opts TransposeOptions) theory.Interval {
+	if opts.Low == 0 && opts.High == 0 {
+		return iv
+	}
+	outside := func(octaves int) int {
+		total := 0
+		for _, m := range score.Measures {
+			for _, e := range m.Elements {
+				n, ok := e.(*music.Note)
+				if !ok {
+					continue
+				}
+				p := n.Pitch + iv.Semitones + 12*octaves
+				if opts.Low != 0 && p < opts.Low {
+					total += opts.Low - p
+				}
+				if opts.High != 0 && p > opts.High {
+					total += p - opts.High
+				}
+			}
+		}
+		return total
+	}
+	best := 0
+	bestOutside := outside(0)
+	for _, octaves := range []int{-1, 1, -2, 2, -3, 3, -4, 4} {
+		if o := outside(octaves); o < bestOutside {
+			best, bestOutside = octaves, o
+		}
+	}
+	return iv.Add(theory.Interval{Steps: 7 * best, Semitones: 12 * best})
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,137 @@
+package notation
+
+import (
+	"testing"
+
+	"gehoer/music"
+	"gehoer/theory"
+)
+
+// notePitches returns the MIDI pitch and staff line of each note
+func notePitches(score *music.Score) (pitches, lines []int) {
+	for _, m := range score.Measures {
+		for _, e := range m.Elements {
+			if n, ok := e.(*music.Note); ok {
+				pitches = append(pitches, n.Pitch)
+				lines = append(lines, n.StaffLine)
+			}
+		}
+	}
+	return pitches, lines
+}
+
+func interval(t *testing.T, q theory.Quality, number int) theory.Interval {
+	t.Helper()
+	iv, err := theory.NewInterval(q, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return iv
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTranspose(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		iv       theory.Interval
+		opts     TransposeOptions
+		key      music.KeySignature
+		pitches  []int
+		lines    []int
+		accident string
+	}{
+		{
+			"up a major second",
+			`c'4 e' g' c''`, theory.Interval{Steps: 1, Semitones: 2}, TransposeOptions{},
+			music.KeySignature{Tonic: "D", Mode: "dur"},
+			[]int{62, 66, 69, 74}, []int{-1, 1, 3, 6}, "- - - -",
+		},
+		{
+			"chromatic notes respelled in the new key",
+			`fiss'4 b' c'' ess''`, theory.Interval{Steps: 1, Semitones: 2}, TransposeOptions{},
+			music.KeySignature{Tonic: "D", Mode: "dur"},
+			[]int{68, 72, 74, 77}, []int{2, 5, 6, 8}, "sharp natural - natural",
+		},
+		{
+			"down a minor third in minor",
+			`\key a \minor a'4 giss' h' c''`, theory.Interval{Steps: -2, Semitones: -3}, TransposeOptions{},
+			music.KeySignature{Tonic: "fiss", Mode: "moll"},
+			[]int{66, 65, 68, 69}, []int{1, 0, 2, 3}, "- sharp - -",
+		},
+		{
+			"moved down an octave to fit the range",
+			`c'4 g' c'' g''`, theory.Interval{Steps: 1, Semitones: 2}, TransposeOptions{High: 72},
+			music.KeySignature{Tonic: "D", Mode: "dur"},
+			[]int{50, 57, 62, 69}, []int{-8, -4, -1, 3}, "- - - -",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := ParseText(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, _ := notePitches(score)
+			out, err := Transpose(score, tt.iv, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.KeySignature != tt.key {
+				t.Errorf("key %v, want %v", out.KeySignature, tt.key)
+			}
+			pitches, lines := notePitches(out)
+			if !equalInts(pitches, tt.pitches) || !equalInts(lines, tt.lines) {
+				t.Errorf("pitches %v on lines %v, want %v on %v", pitches, lines, tt.pitches, tt.lines)
+			}
+			if got := accidentalMarks(out); got != tt.accident {
+				t.Errorf("accidentals %q, want %q", got, tt.accident)
+			}
+			if after, _ := notePitches(score); !equalInts(after, before) {
+				t.Errorf("the original score changed from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+func TestTransposeRejectsKeysPastSevenAccidentals(t *testing.T) {
+	score, err := ParseText(`\key fiss \major fiss'1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Transpose(score, interval(t, theory.Major, 2), TransposeOptions{}); err == nil {
+		t.Error("transposing F sharp major up a major second to G sharp major succeeded")
+	}
+	if _, err := Transpose(score, interval(t, theory.Minor, 2), TransposeOptions{}); err != nil {
+		t.Errorf("transposing F sharp major up a minor second to G major: %v", err)
+	}
+}
+
+func TestTransposeToKey(t *testing.T) {
+	score, err := ParseText(`c'4 d' e' f'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := TransposeToKey(score, music.KeySignature{Tonic: "G", Mode: "dur"}, TransposeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pitches, _ := notePitches(out)
+	if want := []int{55, 57, 59, 60}; !equalInts(pitches, want) {
+		t.Errorf("pitches %v, want %v a fourth down", pitches, want)
+	}
+	if _, err := TransposeToKey(score, music.KeySignature{Tonic: "A", Mode: "moll"}, TransposeOptions{}); err == nil {
+		t.Error("transposing from major to a minor key succeeded")
+	}
+}
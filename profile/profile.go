@@ -0,0 +1,104 @@
+package profile
+
+import (
+	"time"
+
+	"gehoer/exercise"
+)
+
+// Profile is one student's progress: every answer given, statistics per
+// item and the spaced-repetition state of the items
+type Profile struct {
+	Name     string                   `json:"name"`
+	Created  time.Time                `json:"created"`
+	Updated  time.Time                `json:"updated"`
+	History  []Answer                 `json:"history"`
+	Items    map[string]*ItemStats    `json:"items"`
+	Schedule []exercise.ScheduledItem `json:"schedule"`
+}
+
+// Answer is one answered question
+type Answer struct {
+	Time     time.Time `json:"time"`
+	Exercise string    `json:"exercise"` // such as "note_reading" or "chord"
+	Item     string    `json:"item"`     // the item asked about, a scheduler key
+	Given    string    `json:"given"`    // the answer, as an item key where it names an item
+	Correct  bool      `json:"correct"`
+	Response int64     `json:"response_ms"` // time taken to answer
+}
+
+// ResponseTime returns the time taken to answer
+func (a Answer) ResponseTime() time.Duration {
+	return time.Duration(a.Response) * time.Millisecond
+}
+
+// ItemStats counts the answers about one item
+type ItemStats struct {
+	Asked    int   `json:"asked"`
+	Correct  int   `json:"correct"`
+	Response int64 `json:"response_ms"` // total time taken to answer
+}
+
+// New returns an empty profile
+func New(name string, now time.Time) *Profile {
+	return &Profile{
+		Name:    name,
+		Created: now,
+		Updated: now,
+		Items:   make(map[string]*ItemStats),
+	}
+}
+
+// Record adds an answer to the history and the item's statistics
+func (p *Profile) Record(exerciseName, item, given string, correct bool, at time.Time, response time.Duration) {
+	a := Answer{
+		Time:     at,
+		Exercise: exerciseName,
+		Item:     item,
+		Given:    given,
+		Correct:  correct,
+		Response: response.Milliseconds(),
+	}
+	p.History = append(p.History, a)
+	if p.Items == nil {
+		p.Items = make(map[string]*ItemStats)
+	}
+	s := p.Items[item]
+	if s == nil {
+		s = &ItemStats{}
+		p.Items[item] = s
+	}
+	s.Asked++
+	if correct {
+		s.Correct++
+	}
+	s.Response += a.Response
+	p.Updated = at
+}
+
+// SaveSchedule stores a scheduler's state, replacing the saved state of
+// the same items and keeping that of items other exercises drill
+func (p *Profile) SaveSchedule(s *exercise.Scheduler) {
+	index := make(map[string]int, len(p.Schedule))
+	for i, it := range p.Schedule {
+		index[it.Key] = i
+	}
+	for _, it := range s.Snapshot() {
+		if i, ok := index[it.Key]; ok {
+			p.Schedule[i] = it
+		} else {
+			p.Schedule = append(p.Schedule, it)
+		}
+	}
+}
+
+// RestoreSchedule loads the saved state of the scheduler's items
+func (p *Profile) RestoreSchedule(s *exercise.Scheduler) {
+	var items []exercise.ScheduledItem
+	for _, it := range p.Schedule {
+		if _, ok := s.Items[it.Key]; ok {
+			items = append(items, it)
+		}
+	}
+	s.Restore(items)
+}
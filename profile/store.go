@@ -0,0 +1,289 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FormatVersion is the version of the profile file format written
+const FormatVersion = 1
+
+// errNewerVersion marks files written by a newer version of the program,
+// which are left alone rather than recovered
+var errNewerVersion = errors.New("profile written by a newer version")
+
+// fileFormat is a profile file on disk
+type fileFormat struct {
+	Version int      `json:"version"`
+	Profile *Profile `json:"profile"`
+}
+
+// Store keeps profiles as JSON files in a directory, one per profile. Each
+// file is written to a temporary file and renamed into place, keeping the
+// previous version as a backup to recover from.
+type Store struct {
+	Dir string
+}
+
+// NewStore returns a store in dir, creating the directory when needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// DefaultStore returns the store in the user's config directory, such as
+// ~/.config/gehoer/profiles on Linux
+func DefaultStore() (*Store, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return NewStore(filepath.Join(config, "gehoer", "profiles"))
+}
+
+// path returns the file of a profile; names are reduced to letters, digits,
+// '-' and '_' so any name makes a safe file name
+func (s *Store) path(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		}
+	}
+	file := b.String()
+	if file == "" {
+		file = "_"
+	}
+	return filepath.Join(s.Dir, file+".json")
+}
+
+// List returns the names of the stored profiles, the most recently used
+// first. Files that cannot be read are left out.
+func (s *Store) List() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	backups, err := filepath.Glob(filepath.Join(s.Dir, "*.json.bak"))
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if path := strings.TrimSuffix(b, ".bak"); !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	var profiles []*Profile
+	for _, path := range paths {
+		p, err := readFile(path)
+		if err != nil {
+			p, err = readFile(path + ".bak")
+		}
+		if err == nil {
+			profiles = append(profiles, p)
+		}
+	}
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].Updated.After(profiles[j].Updated) })
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+// Exists reports whether a profile of the name is stored, or only its
+// backup is left
+func (s *Store) Exists(name string) bool {
+	path := s.path(name)
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	_, err := os.Stat(path + ".bak")
+	return err == nil
+}
+
+// Create stores a new, empty profile. It fails when the name is taken.
+func (s *Store) Create(name string, now time.Time) (*Profile, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("a profile needs a name")
+	}
+	if s.Exists(name) {
+		return nil, fmt.Errorf("a profile named %q already exists", name)
+	}
+	p := New(name, now)
+	if err := s.Save(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Load reads a profile. A file that cannot be read is moved aside and the
+// backup of the previous save is used instead, or a new profile when there
+// is none; warnings tell what was recovered.
+func (s *Store) Load(name string) (*Profile, []string, error) {
+	path := s.path(name)
+	p, err := readFile(path)
+	if err == nil {
+		return p, nil, nil
+	}
+	if os.IsNotExist(err) {
+		// Saves never leave the file missing, but it may have been removed
+		// by hand or by a crash outside this program
+		p, err := readFile(path + ".bak")
+		if err != nil {
+			return nil, nil, fmt.Errorf("no profile named %q", name)
+		}
+		warnings := []string{fmt.Sprintf("profile %q was missing; restored the previous save", name)}
+		return p, warnings, s.Save(p)
+	}
+	if errors.Is(err, errNewerVersion) {
+		return nil, nil, fmt.Errorf("profile %q: %w", name, err) // leave it for the newer version
+	}
+
+	warnings := []string{fmt.Sprintf("profile %q is damaged: %v", name, err)}
+	aside := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	if err := os.Rename(path, aside); err == nil {
+		warnings = append(warnings, "the damaged file was kept as "+filepath.Base(aside))
+	}
+	if p, err := readFile(path + ".bak"); err == nil {
+		warnings = append(warnings, "restored the previous save")
+		return p, warnings, s.Save(p)
+	}
+	warnings = append(warnings, "started the profile afresh")
+	p = New(name, time.Now())
+	return p, warnings, s.Save(p)
+}
+
+// Save writes a profile atomically: to a temporary file in the same
+// directory, synced and renamed straight over the old file, so the profile
+// is never missing. The old file is first linked, or copied where links
+// are not supported, as the backup.
+func (s *Store) Save(p *Profile) error {
+	data, err := json.MarshalIndent(fileFormat{Version: FormatVersion, Profile: p}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+	path := s.path(p.Name)
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	defer os.Remove(tmp) // only left behind when something failed
+	if _, err := readFile(path); err == nil {
+		if err := backup(path); err != nil {
+			return fmt.Errorf("failed to keep backup of profile: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	if err := syncDir(s.Dir); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// writeTemp writes data to a synced temporary file next to path and returns
+// its name
+func writeTemp(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// backup replaces path's backup with a hard link to path, or a copy of it,
+// leaving path in place
+func backup(path string) error {
+	link := path + ".bak-new"
+	os.Remove(link)
+	if err := os.Link(path, link); err != nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if link, err = writeTemp(path, data); err != nil {
+			return err
+		}
+	}
+	defer os.Remove(link)
+	return os.Rename(link, path+".bak")
+}
+
+// syncDir makes the renames in a directory durable. Windows cannot sync a
+// directory, and its renames need no help.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Delete removes a profile and its backup
+func (s *Store) Delete(name string) error {
+	path := s.path(name)
+	err := os.Remove(path)
+	if errBak := os.Remove(path + ".bak"); err != nil && (errBak != nil || !os.IsNotExist(err)) {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+	return nil
+}
+
+// readFile reads and checks one profile file
+func readFile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version > FormatVersion {
+		return nil, fmt.Errorf("%w (%d)", errNewerVersion, f.Version)
+	}
+	if f.Version < 1 {
+		return nil, fmt.Errorf("unsupported profile version %d", f.Version)
+	}
+	if f.Profile == nil || f.Profile.Name == "" {
+		return nil, fmt.Errorf("the file holds no profile")
+	}
+	if f.Profile.Items == nil {
+		f.Profile.Items = make(map[string]*ItemStats)
+	}
+	return f.Profile, nil
+}
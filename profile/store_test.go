@@ -0,0 +1,143 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// saveAnswers creates a profile and saves it once per answer recorded
+func saveAnswers(t *testing.T, s *Store, name string, answers int) *Profile {
+	t.Helper()
+	p, err := s.Create(name, testTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < answers; i++ {
+		p.Record("note_reading", "note:treble:4", "note:treble:4", true, testTime.Add(time.Duration(i)*time.Minute), time.Second)
+		if err := s.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestSaveKeepsPreviousVersionAsBackup(t *testing.T) {
+	s := newTestStore(t)
+	saveAnswers(t, s, "Kari", 2)
+
+	path := s.path("Kari")
+	current, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := readFile(path + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current.History) != 2 || len(previous.History) != 1 {
+		t.Errorf("saved %d answers with %d in the backup, want 2 and 1", len(current.History), len(previous.History))
+	}
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if name := e.Name(); name != "kari.json" && name != "kari.json.bak" {
+			t.Errorf("save left %s behind", name)
+		}
+	}
+}
+
+func TestLoadFallsBackToBackup(t *testing.T) {
+	s := newTestStore(t)
+	saveAnswers(t, s, "Kari", 2)
+	path := s.path("Kari")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.Exists("Kari") {
+		t.Error("a profile with only a backup does not exist")
+	}
+	if names, err := s.List(); err != nil || len(names) != 1 || names[0] != "Kari" {
+		t.Errorf("listed %v, %v, want Kari", names, err)
+	}
+
+	p, warnings, err := s.Load("Kari")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.History) != 1 || len(warnings) == 0 {
+		t.Errorf("loaded %d answers with warnings %v, want the backup's 1 and a warning", len(p.History), warnings)
+	}
+	if _, err := readFile(path); err != nil {
+		t.Errorf("the restored profile was not saved: %v", err)
+	}
+}
+
+func TestLoadRecoversDamagedFile(t *testing.T) {
+	s := newTestStore(t)
+	saveAnswers(t, s, "Kari", 2)
+	path := s.path("Kari")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "profile": {`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, warnings, err := s.Load("Kari")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.History) != 1 {
+		t.Errorf("loaded %d answers, want the backup's 1", len(p.History))
+	}
+	if !strings.Contains(strings.Join(warnings, "\n"), "damaged") {
+		t.Errorf("warnings %v do not mention the damage", warnings)
+	}
+	aside, _ := filepath.Glob(path + ".corrupt-*")
+	if len(aside) != 1 {
+		t.Errorf("kept %v aside, want the damaged file", aside)
+	}
+}
+
+func TestLoadMissingProfile(t *testing.T) {
+	s := newTestStore(t)
+	if _, _, err := s.Load("Ola"); err == nil || !strings.Contains(err.Error(), "no profile named") {
+		t.Errorf("got error %v, want no profile named Ola", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s := newTestStore(t)
+	saveAnswers(t, s, "Kari", 1)
+	saveAnswers(t, s, "Ola", 1)
+	if err := os.Remove(s.path("Ola")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Kari", "Ola"} {
+		if err := s.Delete(name); err != nil {
+			t.Errorf("deleting %s: %v", name, err)
+		}
+		if s.Exists(name) {
+			t.Errorf("%s exists after deleting it", name)
+		}
+	}
+	if err := s.Delete("Kari"); err == nil {
+		t.Error("deleting a deleted profile succeeded")
+	}
+}
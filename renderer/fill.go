@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"math"
+	"sort"
+)
+
+// FillPathCommand fills closed outlines, drawn as one horizontal line per
+// pixel row. Contours are filled together, so with the even-odd rule or
+// opposite windings one contour cuts a hole in another.
+type FillPathCommand struct {
+	Contours [][]Vector2
+	EvenOdd  bool // the even-odd rule instead of non-zero winding
+	Color    Color
+}
+
+func (cmd FillPathCommand) Execute(renderer Renderer) {
+	minY, maxY := float32(math.Inf(1)), float32(math.Inf(-1))
+	for _, contour := range cmd.Contours {
+		for _, p := range contour {
+			minY, maxY = min(minY, p.Y), max(maxY, p.Y)
+		}
+	}
+	// Sample each row through its middle
+	for y := float32(math.Floor(float64(minY))) + 0.5; y < maxY; y++ {
+		for _, span := range FillSpans(cmd.Contours, cmd.EvenOdd, y) {
+			renderer.DrawLine(Vector2{X: span[0], Y: y}, Vector2{X: span[1], Y: y}, 1, cmd.Color)
+		}
+	}
+}
+
+func NewFillPathCommand(contours [][]Vector2, evenOdd bool, color Color) FillPathCommand {
+	return FillPathCommand{Contours: contours, EvenOdd: evenOdd, Color: color}
+}
+
+// crossing is where a contour edge crosses a row, and which way it winds
+type crossing struct {
+	x   float32
+	dir int
+}
+
+// FillSpans returns the inside parts of the row at y as [start, end] pairs
+// of x, left to right. Each contour is closed from its last point to its
+// first.
+func FillSpans(contours [][]Vector2, evenOdd bool, y float32) [][2]float32 {
+	var crossings []crossing
+	for _, contour := range contours {
+		for i := range contour {
+			a, b := contour[i], contour[(i+1)%len(contour)]
+			dir := 0
+			switch {
+			case a.Y <= y && y < b.Y:
+				dir = 1
+			case b.Y <= y && y < a.Y:
+				dir = -1
+			default:
+				continue
+			}
+			x := a.X + (y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			crossings = append(crossings, crossing{x, dir})
+		}
+	}
+	sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+	var spans [][2]float32
+	winding, count := 0, 0
+	var start float32
+	for _, c := range crossings {
+		wasInside := inside(winding, count, evenOdd)
+		winding += c.dir
+		count++
+		isInside := inside(winding, count, evenOdd)
+		switch {
+		case !wasInside && isInside:
+			start = c.x
+		case wasInside && !isInside && c.x > start:
+			spans = append(spans, [2]float32{start, c.x})
+		}
+	}
+	return spans
+}
+
+func inside(winding, count int, evenOdd bool) bool {
+	if evenOdd {
+		return count%2 == 1
+	}
+	return winding != 0
+}
@@ -0,0 +1,85 @@
+package renderer
+
+import (
+	"gehoer/input"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// raylibKeys maps keys to Raylib key codes
+var raylibKeys = map[input.Key]int32{
+	input.KeyEnter:     rl.KeyEnter,
+	input.KeyEscape:    rl.KeyEscape,
+	input.KeyTab:       rl.KeyTab,
+	input.KeyBackspace: rl.KeyBackspace,
+	input.KeySpace:     rl.KeySpace,
+	input.KeyUp:        rl.KeyUp,
+	input.KeyDown:      rl.KeyDown,
+	input.KeyLeft:      rl.KeyLeft,
+	input.KeyRight:     rl.KeyRight,
+}
+
+func init() {
+	for k := input.KeyA; k <= input.KeyZ; k++ {
+		raylibKeys[k] = rl.KeyA + int32(k-input.KeyA)
+	}
+}
+
+// raylibButtons maps mouse buttons to Raylib's
+var raylibButtons = map[input.MouseButton]rl.MouseButton{
+	input.MouseLeft:   rl.MouseButtonLeft,
+	input.MouseRight:  rl.MouseButtonRight,
+	input.MouseMiddle: rl.MouseButtonMiddle,
+}
+
+// RaylibInput implements input.Source with the Raylib window's keyboard
+// and mouse
+type RaylibInput struct {
+	chars []rune
+	wheel float32
+}
+
+func NewRaylibInput() *RaylibInput {
+	return &RaylibInput{}
+}
+
+// Poll reads the characters and wheel movement of the frame, which Raylib
+// hands out only once
+func (r *RaylibInput) Poll() {
+	r.chars = r.chars[:0]
+	for c := rl.GetCharPressed(); c != 0; c = rl.GetCharPressed() {
+		r.chars = append(r.chars, c)
+	}
+	r.wheel = rl.GetMouseWheelMove()
+}
+
+func (r *RaylibInput) KeyDown(k input.Key) bool {
+	code, ok := raylibKeys[k]
+	return ok && rl.IsKeyDown(code)
+}
+
+func (r *RaylibInput) KeyPressed(k input.Key) bool {
+	code, ok := raylibKeys[k]
+	return ok && rl.IsKeyPressed(code)
+}
+
+func (r *RaylibInput) MouseDown(b input.MouseButton) bool {
+	return rl.IsMouseButtonDown(raylibButtons[b])
+}
+
+func (r *RaylibInput) MousePressed(b input.MouseButton) bool {
+	return rl.IsMouseButtonPressed(raylibButtons[b])
+}
+
+func (r *RaylibInput) MousePosition() input.Point {
+	p := rl.GetMousePosition()
+	return input.Point{X: p.X, Y: p.Y}
+}
+
+func (r *RaylibInput) Wheel() float32 {
+	return r.wheel
+}
+
+func (r *RaylibInput) Chars() []rune {
+	return r.chars
+}
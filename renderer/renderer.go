@@ -19,6 +19,7 @@ var (
 	Black     = Color{0, 0, 0, 255}
 	White     = Color{255, 255, 255, 255}
 	Red       = Color{255, 0, 0, 255}
+	Green     = Color{0, 160, 0, 255}
 	DarkGray  = Color{80, 80, 80, 255}
 	LightGray = Color{220, 220, 220, 255}
 )
@@ -84,6 +85,23 @@ func (cmd RectangleLinesCommand) Execute(renderer Renderer) {
 	renderer.DrawRectangleLines(cmd.X, cmd.Y, cmd.Width, cmd.Height, cmd.LineThickness, cmd.Color)
 }
 
+// PathCommand represents a polyline, drawn as connected line segments
+type PathCommand struct {
+	Points    []Vector2
+	Closed    bool
+	Thickness float32
+	Color     Color
+}
+
+func (cmd PathCommand) Execute(renderer Renderer) {
+	for i := 1; i < len(cmd.Points); i++ {
+		renderer.DrawLine(cmd.Points[i-1], cmd.Points[i], cmd.Thickness, cmd.Color)
+	}
+	if cmd.Closed && len(cmd.Points) > 2 {
+		renderer.DrawLine(cmd.Points[len(cmd.Points)-1], cmd.Points[0], cmd.Thickness, cmd.Color)
+	}
+}
+
 // CommandBuffer accumulates drawing commands
 type CommandBuffer struct {
 	commands []DrawCommand
@@ -125,3 +143,7 @@ func NewGlyphCommand(font rl.Font, glyph rune, position Vector2, fontSize float3
 func NewRectangleLinesCommand(x, y, width, height, lineThickness float32, color Color) RectangleLinesCommand {
 	return RectangleLinesCommand{X: x, Y: y, Width: width, Height: height, LineThickness: lineThickness, Color: color}
 }
+
+func NewPathCommand(points []Vector2, closed bool, thickness float32, color Color) PathCommand {
+	return PathCommand{Points: points, Closed: closed, Thickness: thickness, Color: color}
+}
@@ -0,0 +1,165 @@
+package scene
+
+import (
+	"fmt"
+	"time"
+
+	"gehoer/input"
+)
+
+// ID names a scene
+type ID string
+
+// Event asks for a change of scene, such as "back" or "start"
+type Event string
+
+// Any registers a transition from every scene, and Quit as a target ends
+// the machine
+const (
+	Any  ID = "*"
+	Quit ID = "quit"
+)
+
+// Scene is one screen of the game. Enter and Exit run when the machine
+// moves to and away from it; Update runs once a frame while it is current
+// and sends events to move on.
+type Scene interface {
+	Enter(ctx *Context)
+	Update(ctx *Context, dt time.Duration)
+	Exit(ctx *Context)
+}
+
+// Context is what scenes see of the game: input, the clock and the queue
+// of events
+type Context struct {
+	Input input.Source
+	Now   func() time.Time // time.Now unless replaced, as in tests
+
+	events []Event
+}
+
+// Send queues an event; the machine acts on it after the current scene's
+// update
+func (c *Context) Send(ev Event) {
+	c.events = append(c.events, ev)
+}
+
+// Machine runs one scene at a time and moves between them on events, by a
+// table of transitions
+type Machine struct {
+	Context *Context
+
+	scenes      map[ID]Scene
+	transitions map[ID]map[Event]ID
+	current     ID
+	lastUpdate  time.Time
+	done        bool
+}
+
+// NewMachine returns a machine reading in and timing frames by now, or
+// time.Now when now is nil
+func NewMachine(in input.Source, now func() time.Time) *Machine {
+	if now == nil {
+		now = time.Now
+	}
+	return &Machine{
+		Context:     &Context{Input: in, Now: now},
+		scenes:      make(map[ID]Scene),
+		transitions: make(map[ID]map[Event]ID),
+	}
+}
+
+// Add registers a scene, replacing any of the same ID
+func (m *Machine) Add(id ID, s Scene) {
+	m.scenes[id] = s
+}
+
+// Scene returns a registered scene, so it can be wrapped and added again
+func (m *Machine) Scene(id ID) (Scene, bool) {
+	s, ok := m.scenes[id]
+	return s, ok
+}
+
+// On moves from one scene, or Any, to another when the event is sent.
+// Transitions from the current scene take precedence over those from Any.
+func (m *Machine) On(from ID, ev Event, to ID) {
+	if m.transitions[from] == nil {
+		m.transitions[from] = make(map[Event]ID)
+	}
+	m.transitions[from][ev] = to
+}
+
+// Start enters the first scene
+func (m *Machine) Start(id ID) error {
+	s, ok := m.scenes[id]
+	if !ok {
+		return fmt.Errorf("unknown scene %q", id)
+	}
+	m.current, m.done = id, false
+	m.lastUpdate = m.Context.Now()
+	s.Enter(m.Context)
+	return nil
+}
+
+// Current returns the current scene and its ID
+func (m *Machine) Current() (ID, Scene) {
+	return m.current, m.scenes[m.current]
+}
+
+// Done reports whether the machine has moved to Quit
+func (m *Machine) Done() bool {
+	return m.done
+}
+
+// Update polls the input, updates the current scene with the time since
+// the last update and then acts on the events it sent, in order, and on
+// those the scenes it moves to send as they enter. Events without a
+// transition are dropped and reported.
+func (m *Machine) Update() error {
+	if m.done || m.current == "" {
+		return nil
+	}
+	now := m.Context.Now()
+	dt := now.Sub(m.lastUpdate)
+	m.lastUpdate = now
+	m.Context.Input.Poll()
+	m.scenes[m.current].Update(m.Context, dt)
+
+	var err error
+	for len(m.Context.events) > 0 {
+		ev := m.Context.events[0]
+		m.Context.events = m.Context.events[1:]
+		if e := m.Send(ev); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Send moves to the scene the event leads to from the current one,
+// running the exit and enter hooks
+func (m *Machine) Send(ev Event) error {
+	if m.done {
+		return nil
+	}
+	to, ok := m.transitions[m.current][ev]
+	if !ok {
+		to, ok = m.transitions[Any][ev]
+	}
+	if !ok {
+		return fmt.Errorf("no transition from scene %q on %q", m.current, ev)
+	}
+	if to != Quit {
+		if _, ok := m.scenes[to]; !ok {
+			return fmt.Errorf("unknown scene %q", to)
+		}
+	}
+	m.scenes[m.current].Exit(m.Context)
+	if to == Quit {
+		m.done = true
+		return nil
+	}
+	m.current = to
+	m.scenes[to].Enter(m.Context)
+	return nil
+}
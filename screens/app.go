@@ -0,0 +1,157 @@
+package screens
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gehoer/exercise"
+	"gehoer/input"
+	"gehoer/localization"
+	"gehoer/profile"
+	"gehoer/scene"
+)
+
+// WarningTime is how long a warning stays on the screen
+const WarningTime = 8 * time.Second
+
+// Warning is a problem the player should know about, such as a profile
+// that could not be saved
+type Warning struct {
+	Message string
+	At      time.Time
+}
+
+// App is the state the screens share: the profile, the exercise settings
+// and the last round, and the scene machine moving between the screens.
+// It draws nothing; the game draws each screen from its state.
+type App struct {
+	Machine        *scene.Machine
+	Loc            *localization.Localization
+	Profiles       *profile.Store // nil when profiles cannot be saved
+	Profile        *profile.Profile
+	ReadingOptions exercise.NoteReadingOptions
+	Questions      int                        // per round, 0 for no limit
+	LastRound      *exercise.NoteReadingStats // shown on the results screen
+
+	// ToWorld maps a point on the screen into the world the screens are
+	// drawn in, for clicks; unchanged unless set
+	ToWorld func(input.Point) input.Point
+	// Log receives every warning as well; the standard logger unless set
+	Log *log.Logger
+
+	warnings []Warning
+}
+
+// Options replace the clock and profile directory, so the screens can run
+// in tests
+type Options struct {
+	Now        func() time.Time // time.Now unless set
+	ProfileDir string           // the user's configuration directory unless set
+	Log        *log.Logger      // the standard logger unless set
+}
+
+// defaultProfileName names the profile made on the first run
+const defaultProfileName = "Elev"
+
+// New returns the app at the main menu, reading in
+func New(in input.Source, opts Options) *App {
+	if opts.Log == nil {
+		opts.Log = log.Default()
+	}
+	a := &App{
+		Machine:        scene.NewMachine(in, opts.Now),
+		Loc:            localization.NewNynorskLocalization("C", "dur"),
+		ReadingOptions: exercise.DefaultNoteReadingOptions(),
+		Questions:      20,
+		ToWorld:        func(p input.Point) input.Point { return p },
+		Log:            opts.Log,
+	}
+	a.openProfile(opts.ProfileDir)
+	a.addScreens()
+	if err := a.Machine.Start(Main); err != nil {
+		panic("Failed to start: " + err.Error())
+	}
+	return a
+}
+
+// openProfile opens the most recently used profile, creating one on the
+// first run. Without a usable profile directory the progress is kept for
+// this run only.
+func (a *App) openProfile(dir string) {
+	now := a.Machine.Context.Now()
+	var store *profile.Store
+	var err error
+	if dir != "" {
+		store, err = profile.NewStore(dir)
+	} else {
+		store, err = profile.DefaultStore()
+	}
+	if err != nil {
+		a.Warn("%v", err)
+		a.Profile = profile.New(defaultProfileName, now)
+		return
+	}
+	a.Profiles = store
+	names, err := store.List()
+	if err != nil || len(names) == 0 {
+		if a.Profile, err = store.Create(defaultProfileName, now); err == nil {
+			return
+		}
+		names = []string{defaultProfileName}
+	}
+	p, warnings, err := store.Load(names[0])
+	for _, w := range warnings {
+		a.Warn("%s", w)
+	}
+	if err != nil {
+		a.Warn("%v", err)
+		a.Profiles, p = nil, profile.New(names[0], now)
+	}
+	a.Profile = p
+}
+
+// save stores a profile, warning when it cannot
+func (a *App) save(p *profile.Profile) {
+	if a.Profiles == nil {
+		return
+	}
+	if err := a.Profiles.Save(p); err != nil {
+		a.Warn("%v", err)
+	}
+}
+
+// Warn shows a warning on the screen for WarningTime and logs it
+func (a *App) Warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	a.warnings = append(a.warnings, Warning{Message: msg, At: a.Machine.Context.Now()})
+	a.Log.Print("warning: ", msg)
+}
+
+// Warnings returns the warnings still shown, oldest first
+func (a *App) Warnings() []Warning {
+	now := a.Machine.Context.Now()
+	i := 0
+	for i < len(a.warnings) && now.Sub(a.warnings[i].At) >= WarningTime {
+		i++
+	}
+	a.warnings = a.warnings[i:]
+	return a.warnings
+}
+
+// Update runs a frame of the current screen
+func (a *App) Update() {
+	if err := a.Machine.Update(); err != nil {
+		a.Warn("%v", err)
+	}
+}
+
+// Screen returns the current screen's ID and state
+func (a *App) Screen() (scene.ID, scene.Scene) {
+	return a.Machine.Current()
+}
+
+// Done reports whether the player has quit
+func (a *App) Done() bool {
+	return a.Machine.Done()
+}
@@ -0,0 +1,127 @@
+package screens
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"gehoer/exercise"
+	"gehoer/input"
+)
+
+// Layout of the note-reading drill in world coordinates
+const (
+	DrillButtonY      = 160
+	DrillButtonSize   = 50
+	DrillButtonGap    = 10
+	DrillFeedbackTime = 1500 * time.Millisecond
+	drillTarget       = 3 * time.Second // a confident answer, for rating answers
+)
+
+// Drill is the note-reading flashcard drill: it shows one note and takes
+// its name typed on the keyboard or clicked on a button. The notes come
+// from a scheduler, and answers are saved to the profile.
+type Drill struct {
+	Choices []string                    // the names on the answer buttons
+	Typed   string                      // the answer typed so far
+	Last    *exercise.NoteReadingResult // the last answer, nil before the first
+
+	app       *App
+	exercise  *exercise.NoteReadingExercise
+	scheduler *exercise.Scheduler
+	item      string // the scheduler's key of the card shown
+	shownAt   time.Time
+}
+
+// NewDrill starts a drill for the app's profile with its reading options
+func NewDrill(a *App) (*Drill, error) {
+	now := a.Machine.Context.Now
+	ex, err := exercise.NewNoteReadingExercise(a.ReadingOptions, a.Loc, rand.New(rand.NewPCG(uint64(now().UnixNano()), 0)))
+	if err != nil {
+		return nil, err
+	}
+	ex.Now = now
+	d := &Drill{
+		Choices:   exercise.NoteNameChoices(a.Loc),
+		app:       a,
+		exercise:  ex,
+		scheduler: exercise.NewScheduler(ex.Items()...),
+	}
+	d.scheduler.Now = now
+	a.Profile.RestoreSchedule(d.scheduler)
+	d.next()
+	return d, nil
+}
+
+// Card returns the card being shown
+func (d *Drill) Card() exercise.NoteCard {
+	return d.exercise.Card()
+}
+
+// Stats returns the statistics of the drill so far
+func (d *Drill) Stats() *exercise.NoteReadingStats {
+	return &d.exercise.Stats
+}
+
+// ShowsFeedback reports whether the result of the last answer is still
+// shown
+func (d *Drill) ShowsFeedback() bool {
+	return d.Last != nil && d.app.Machine.Context.Now().Sub(d.shownAt) < DrillFeedbackTime
+}
+
+// ButtonRect returns the area of answer button i
+func ButtonRect(i int) Rect {
+	return Rect{X: float32(i * (DrillButtonSize + DrillButtonGap)), Y: DrillButtonY, Width: DrillButtonSize, Height: DrillButtonSize}
+}
+
+// next shows the card the scheduler picks
+func (d *Drill) next() {
+	item, ok := d.scheduler.Next()
+	card, err := d.exercise.Ask(item)
+	if !ok || err != nil {
+		card = d.exercise.Next()
+	}
+	d.item = exercise.NoteItem(card)
+	d.Typed = ""
+}
+
+// Update reads typed letters, Backspace, Enter and clicks on the answer
+// buttons
+func (d *Drill) Update(in input.Source) {
+	d.Typed += string(in.Chars())
+	if in.KeyPressed(input.KeyBackspace) && len(d.Typed) > 0 {
+		runes := []rune(d.Typed)
+		d.Typed = string(runes[:len(runes)-1])
+	}
+	if in.KeyPressed(input.KeyEnter) && d.Typed != "" {
+		d.answer(d.Typed)
+	}
+	if in.MousePressed(input.MouseLeft) {
+		p := d.app.ToWorld(in.MousePosition())
+		for i, name := range d.Choices {
+			if ButtonRect(i).Contains(p) {
+				d.answer(name)
+			}
+		}
+	}
+}
+
+// answer checks a name, saves it to the profile and moves on to the next
+// card
+func (d *Drill) answer(name string) {
+	now := d.app.Machine.Context.Now()
+	r := d.exercise.Answer(name)
+	d.Last, d.shownAt = &r, now
+
+	d.scheduler.Review(d.item, exercise.RatingFor(r.Correct, r.Elapsed, drillTarget))
+	// Record the card answered when the name gives one, so the report can
+	// tell which notes are taken for which
+	given := name
+	if card, ok := d.exercise.CardNamed(name); ok {
+		given = exercise.NoteItem(card)
+	}
+	p := d.app.Profile
+	p.Record("note_reading", d.item, given, r.Correct, now, r.Elapsed)
+	p.SaveSchedule(d.scheduler)
+	d.app.save(p)
+	d.next()
+}
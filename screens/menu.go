@@ -0,0 +1,65 @@
+package screens
+
+import "gehoer/input"
+
+// Layout of the menus in world coordinates, shared by the hit tests here
+// and the drawing in the game
+const (
+	MenuX          = -250
+	MenuY          = -220
+	MenuRowHeight  = 40
+	MenuItemWidth  = 500
+	MenuTitleSpace = 60
+)
+
+// Rect is an area of the world
+type Rect struct {
+	X, Y, Width, Height float32
+}
+
+// Contains reports whether p lies in the area
+func (r Rect) Contains(p input.Point) bool {
+	return p.X >= r.X && p.X < r.X+r.Width && p.Y >= r.Y && p.Y < r.Y+r.Height
+}
+
+// Menu is a list of items chosen with the arrow keys and Enter or a click
+type Menu struct {
+	Title    string
+	Items    []string
+	Selected int
+}
+
+// ItemTop returns the y of item i's row; past the last item it gives the
+// rows below the menu
+func ItemTop(i int) float32 {
+	return float32(MenuY + MenuTitleSpace + i*MenuRowHeight)
+}
+
+// ItemRect returns the area that chooses item i
+func (m *Menu) ItemRect(i int) Rect {
+	return Rect{X: MenuX, Y: ItemTop(i), Width: MenuItemWidth, Height: MenuRowHeight}
+}
+
+// update moves the selection and reports the item chosen this frame;
+// toWorld maps the mouse into the world the menu is drawn in
+func (m *Menu) update(in input.Source, toWorld func(input.Point) input.Point) (chosen int, ok bool) {
+	if in.KeyPressed(input.KeyDown) {
+		m.Selected = (m.Selected + 1) % len(m.Items)
+	}
+	if in.KeyPressed(input.KeyUp) {
+		m.Selected = (m.Selected + len(m.Items) - 1) % len(m.Items)
+	}
+	if in.KeyPressed(input.KeyEnter) {
+		return m.Selected, true
+	}
+	if in.MousePressed(input.MouseLeft) {
+		p := toWorld(in.MousePosition())
+		for i := range m.Items {
+			if m.ItemRect(i).Contains(p) {
+				m.Selected = i
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,368 @@
+package screens
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gehoer/analytics"
+	"gehoer/input"
+	"gehoer/music"
+	"gehoer/profile"
+	"gehoer/scene"
+)
+
+// Screens of the game
+const (
+	Main      scene.ID = "menu"
+	Selection scene.ID = "selection"
+	Exercise  scene.ID = "exercise"
+	Results   scene.ID = "results"
+	Viewer    scene.ID = "viewer"
+	Stats     scene.ID = "stats"
+	Settings  scene.ID = "settings"
+)
+
+// Events that move between the screens
+const (
+	selectEvent   scene.Event = "select"   // choose an exercise
+	startEvent    scene.Event = "start"    // start the chosen exercise
+	finishEvent   scene.Event = "finish"   // end the round and show the results
+	againEvent    scene.Event = "again"    // another round of the same exercise
+	viewEvent     scene.Event = "view"     // open the score viewer
+	statsEvent    scene.Event = "stats"    // open the statistics
+	settingsEvent scene.Event = "settings" // open the settings
+	backEvent     scene.Event = "back"     // back to the main menu
+	quitEvent     scene.Event = "quit"
+)
+
+// addScreens registers the screens and the transitions between them
+func (a *App) addScreens() {
+	m := a.Machine
+	m.Add(Main, &MainMenu{app: a})
+	m.Add(Selection, &SelectionMenu{app: a})
+	m.Add(Exercise, &ExerciseScreen{app: a})
+	m.Add(Results, &ResultsScreen{app: a})
+	m.Add(Viewer, &ViewerScreen{})
+	m.Add(Stats, &StatsScreen{app: a})
+	m.Add(Settings, &SettingsScreen{app: a})
+
+	m.On(Main, selectEvent, Selection)
+	m.On(Main, viewEvent, Viewer)
+	m.On(Main, statsEvent, Stats)
+	m.On(Main, settingsEvent, Settings)
+	m.On(Selection, startEvent, Exercise)
+	m.On(Exercise, finishEvent, Results)
+	m.On(Results, againEvent, Exercise)
+	m.On(scene.Any, backEvent, Main)
+	m.On(scene.Any, quitEvent, scene.Quit)
+}
+
+// MainMenu is the main menu
+type MainMenu struct {
+	app *App
+	Menu
+}
+
+var mainMenuEvents = []scene.Event{selectEvent, viewEvent, statsEvent, settingsEvent, quitEvent}
+
+func (s *MainMenu) Enter(ctx *scene.Context) {
+	t := s.app.Loc.GetTerm
+	s.Menu = Menu{
+		Title:    t("main_menu") + " - " + s.app.Profile.Name,
+		Items:    []string{t("practice"), t("score_viewer"), t("statistics"), t("settings"), t("quit")},
+		Selected: s.Selected,
+	}
+}
+
+func (s *MainMenu) Update(ctx *scene.Context, dt time.Duration) {
+	if i, ok := s.update(ctx.Input, s.app.ToWorld); ok {
+		ctx.Send(mainMenuEvents[i])
+	}
+	if ctx.Input.KeyPressed(input.KeyEscape) {
+		ctx.Send(quitEvent)
+	}
+}
+
+func (s *MainMenu) Exit(ctx *scene.Context) {}
+
+// clefChoices are the clef sets the exercise selection offers, by term
+var clefChoices = []struct {
+	term  string
+	clefs []string
+}{
+	{"all_clefs", nil},
+	{music.TrebleClef, []string{music.TrebleClef}},
+	{music.BassClef, []string{music.BassClef}},
+	{music.AltoClef, []string{music.AltoClef}},
+	{music.TenorClef, []string{music.TenorClef}},
+}
+
+// SelectionMenu chooses the clefs of a note-reading round
+type SelectionMenu struct {
+	app *App
+	Menu
+}
+
+func (s *SelectionMenu) Enter(ctx *scene.Context) {
+	t := s.app.Loc.GetTerm
+	items := make([]string, 0, len(clefChoices)+1)
+	for _, c := range clefChoices {
+		items = append(items, t("note_reading")+": "+t(c.term))
+	}
+	s.Menu = Menu{Title: t("choose_exercise"), Items: append(items, t("back")), Selected: s.Selected}
+}
+
+func (s *SelectionMenu) Update(ctx *scene.Context, dt time.Duration) {
+	i, ok := s.update(ctx.Input, s.app.ToWorld)
+	switch {
+	case ctx.Input.KeyPressed(input.KeyEscape), ok && i == len(clefChoices):
+		ctx.Send(backEvent)
+	case ok:
+		s.app.ReadingOptions.Clefs = clefChoices[i].clefs
+		ctx.Send(startEvent)
+	}
+}
+
+func (s *SelectionMenu) Exit(ctx *scene.Context) {}
+
+// ExerciseScreen runs a round of note reading until the set number of
+// questions is answered or Escape ends it early
+type ExerciseScreen struct {
+	app   *App
+	Drill *Drill // nil when the drill could not start
+}
+
+func (s *ExerciseScreen) Enter(ctx *scene.Context) {
+	d, err := NewDrill(s.app)
+	if err != nil {
+		s.app.Warn("%v", err)
+		ctx.Send(backEvent)
+		return
+	}
+	s.Drill = d
+}
+
+func (s *ExerciseScreen) Update(ctx *scene.Context, dt time.Duration) {
+	if s.Drill == nil {
+		return
+	}
+	s.Drill.Update(ctx.Input)
+	stats := s.Drill.Stats()
+	if ctx.Input.KeyPressed(input.KeyEscape) || s.app.Questions > 0 && stats.Asked >= s.app.Questions {
+		ctx.Send(finishEvent)
+	}
+}
+
+func (s *ExerciseScreen) Exit(ctx *scene.Context) {
+	if s.Drill != nil {
+		s.app.LastRound = s.Drill.Stats()
+	}
+	s.Drill = nil
+}
+
+// ResultsScreen sums up the last round, in the app's LastRound
+type ResultsScreen struct {
+	app *App
+	Menu
+}
+
+func (s *ResultsScreen) Enter(ctx *scene.Context) {
+	t := s.app.Loc.GetTerm
+	s.Menu = Menu{Title: t("results"), Items: []string{t("again"), t("main_menu")}}
+}
+
+func (s *ResultsScreen) Update(ctx *scene.Context, dt time.Duration) {
+	i, ok := s.update(ctx.Input, s.app.ToWorld)
+	switch {
+	case ctx.Input.KeyPressed(input.KeyEscape), ok && i == 1:
+		ctx.Send(backEvent)
+	case ok:
+		ctx.Send(againEvent)
+	}
+}
+
+func (s *ResultsScreen) Exit(ctx *scene.Context) {}
+
+// ViewerScreen shows the loaded score; the game pans and zooms it
+type ViewerScreen struct{}
+
+func (s *ViewerScreen) Enter(ctx *scene.Context) {}
+
+func (s *ViewerScreen) Update(ctx *scene.Context, dt time.Duration) {
+	if ctx.Input.KeyPressed(input.KeyEscape) {
+		ctx.Send(backEvent)
+	}
+}
+
+func (s *ViewerScreen) Exit(ctx *scene.Context) {}
+
+// StatsScreen shows the profile's accuracy per item, response times, daily
+// trend and most frequent confusions, and exports them with E
+type StatsScreen struct {
+	app     *App
+	Report  analytics.Report
+	Message string // the result of the last export
+	answers int    // history length the report was made from
+}
+
+func (s *StatsScreen) Enter(ctx *scene.Context) {
+	s.answers, s.Message = -1, ""
+}
+
+// Update refreshes the report after new answers and exports it on E
+func (s *StatsScreen) Update(ctx *scene.Context, dt time.Duration) {
+	p := s.app.Profile
+	if len(p.History) != s.answers {
+		s.Report = analytics.NewReport(p, "", ctx.Now())
+		s.answers = len(p.History)
+	}
+	if ctx.Input.KeyPressed(input.KeyE) && s.app.Profiles != nil {
+		dir := filepath.Join(filepath.Dir(s.app.Profiles.Dir), "rapportar")
+		if _, err := analytics.ExportFiles(dir, p, s.Report); err != nil {
+			s.Message = err.Error()
+		} else {
+			s.Message = s.app.Loc.GetTerm("exported_to") + " " + dir
+		}
+	}
+	if ctx.Input.KeyPressed(input.KeyEscape) {
+		ctx.Send(backEvent)
+	}
+}
+
+func (s *StatsScreen) Exit(ctx *scene.Context) {}
+
+// SettingsScreen sets the round length and answer checking, and switches
+// between profiles or creates new ones
+type SettingsScreen struct {
+	app     *App
+	Naming  bool   // typing the name of a new profile
+	Name    string // typed so far
+	Message string
+	Menu
+}
+
+// questionChoices are the round lengths offered; 0 is unlimited
+var questionChoices = []int{10, 20, 30, 0}
+
+func (s *SettingsScreen) Enter(ctx *scene.Context) {
+	s.Naming, s.Name, s.Message = false, "", ""
+	s.refresh()
+}
+
+// refresh writes the menu items with the current values
+func (s *SettingsScreen) refresh() {
+	t := s.app.Loc.GetTerm
+	questions := "∞"
+	if s.app.Questions > 0 {
+		questions = strconv.Itoa(s.app.Questions)
+	}
+	octave := t("no")
+	if s.app.ReadingOptions.RequireOctave {
+		octave = t("yes")
+	}
+	s.Menu = Menu{
+		Title: t("settings"),
+		Items: []string{
+			t("questions_per_round") + ": " + questions,
+			t("require_octave") + ": " + octave,
+			t("profile") + ": " + s.app.Profile.Name,
+			t("new_profile") + ": " + s.Name,
+			t("back"),
+		},
+		Selected: s.Selected,
+	}
+}
+
+func (s *SettingsScreen) Update(ctx *scene.Context, dt time.Duration) {
+	in := ctx.Input
+	if s.Naming {
+		s.Name += string(in.Chars())
+		if in.KeyPressed(input.KeyBackspace) && s.Name != "" {
+			runes := []rune(s.Name)
+			s.Name = string(runes[:len(runes)-1])
+		}
+		switch {
+		case in.KeyPressed(input.KeyEscape):
+			s.Naming, s.Name = false, ""
+		case in.KeyPressed(input.KeyEnter):
+			s.createProfile(ctx.Now())
+		}
+		s.refresh()
+		return
+	}
+
+	i, ok := s.update(in, s.app.ToWorld)
+	if in.KeyPressed(input.KeyEscape) {
+		ctx.Send(backEvent)
+		return
+	}
+	if !ok {
+		return
+	}
+	switch i {
+	case 0:
+		next := 0
+		for k, q := range questionChoices {
+			if q == s.app.Questions {
+				next = (k + 1) % len(questionChoices)
+			}
+		}
+		s.app.Questions = questionChoices[next]
+	case 1:
+		s.app.ReadingOptions.RequireOctave = !s.app.ReadingOptions.RequireOctave
+	case 2:
+		s.nextProfile()
+	case 3:
+		s.Naming, s.Message = true, ""
+	case 4:
+		ctx.Send(backEvent)
+	}
+	s.refresh()
+}
+
+// nextProfile switches to the stored profile after the current one
+func (s *SettingsScreen) nextProfile() {
+	a := s.app
+	if a.Profiles == nil {
+		return
+	}
+	names, err := a.Profiles.List()
+	if err != nil || len(names) < 2 {
+		return
+	}
+	next := names[0]
+	for i, name := range names {
+		if name == a.Profile.Name {
+			next = names[(i+1)%len(names)]
+		}
+	}
+	p, warnings, err := a.Profiles.Load(next)
+	if err != nil {
+		s.Message = err.Error()
+		return
+	}
+	for _, w := range warnings {
+		a.Warn("%s", w)
+	}
+	a.Profile = p
+}
+
+// createProfile makes a profile of the typed name and switches to it
+func (s *SettingsScreen) createProfile(now time.Time) {
+	var p *profile.Profile
+	var err error
+	if s.app.Profiles != nil {
+		p, err = s.app.Profiles.Create(s.Name, now)
+	} else {
+		p = profile.New(s.Name, now)
+	}
+	if err != nil {
+		s.Message = err.Error()
+		return
+	}
+	s.app.Profile = p
+	s.Naming, s.Name = false, ""
+}
+
+func (s *SettingsScreen) Exit(ctx *scene.Context) {}
@@ -0,0 +1,156 @@
+package screens
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"gehoer/input"
+	"gehoer/scene"
+)
+
+// testApp is an app reading a fake input, on a clock that moves a second
+// each frame, with its profiles in a temporary directory
+type testApp struct {
+	*App
+	t   *testing.T
+	in  *input.Fake
+	now time.Time
+}
+
+func newTestApp(t *testing.T) *testApp {
+	ta := &testApp{t: t, in: input.NewFake(), now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	ta.App = New(ta.in, Options{
+		Now:        func() time.Time { return ta.now },
+		ProfileDir: t.TempDir(),
+		Log:        log.New(io.Discard, "", 0),
+	})
+	return ta
+}
+
+// step runs one frame of input
+func (ta *testApp) step(f input.Frame) {
+	ta.t.Helper()
+	ta.in.Queue(f)
+	ta.now = ta.now.Add(time.Second)
+	ta.Update()
+}
+
+func (ta *testApp) press(keys ...input.Key) {
+	ta.t.Helper()
+	ta.step(input.Frame{Pressed: keys})
+}
+
+func (ta *testApp) expect(want scene.ID) {
+	ta.t.Helper()
+	if id, _ := ta.Screen(); id != want {
+		ta.t.Fatalf("on screen %q, want %q", id, want)
+	}
+}
+
+// drill returns the drill of the exercise screen
+func (ta *testApp) drill() *Drill {
+	ta.t.Helper()
+	_, s := ta.Screen()
+	ex, ok := s.(*ExerciseScreen)
+	if !ok || ex.Drill == nil {
+		ta.t.Fatal("no drill running")
+	}
+	return ex.Drill
+}
+
+func TestMenuExerciseResults(t *testing.T) {
+	ta := newTestApp(t)
+	ta.Questions = 2
+	ta.expect(Main)
+
+	ta.press(input.KeyEnter) // practice
+	ta.expect(Selection)
+	ta.press(input.KeyDown)
+	ta.press(input.KeyEnter) // treble clef only
+	ta.expect(Exercise)
+
+	d := ta.drill()
+	if card := d.Card(); card.Clef != "treble" {
+		t.Fatalf("card in the %s clef, want treble", card.Clef)
+	}
+	right := ta.Loc.GetNoteName(d.Card().Pitch())
+	ta.step(input.Frame{Text: right, Pressed: []input.Key{input.KeyEnter}})
+	if !d.Last.Correct || !d.ShowsFeedback() {
+		t.Fatalf("answer %q got %+v, want it right and shown", right, d.Last)
+	}
+	ta.expect(Exercise)
+
+	// A wrong answer by clicking a button ends the round of two
+	right = ta.Loc.GetNoteName(d.Card().Pitch())
+	wrong := 0
+	for strings.HasPrefix(right, d.Choices[wrong]) {
+		wrong++
+	}
+	r := ButtonRect(wrong)
+	ta.step(input.Frame{MousePressed: []input.MouseButton{input.MouseLeft}, Mouse: input.Point{X: r.X + 1, Y: r.Y + 1}})
+	ta.expect(Results)
+
+	if round := ta.LastRound; round == nil || round.Asked != 2 || round.Correct != 1 {
+		t.Fatalf("last round %+v, want 1 of 2 right", round)
+	}
+	if h := ta.Profile.History; len(h) != 2 {
+		t.Errorf("profile has %d answers, want 2", len(h))
+	} else if h[0].Given != h[0].Item || h[1].Given == h[1].Item || !strings.HasPrefix(h[1].Given, "note:treble:") {
+		// Answers are recorded as the cards they name
+		t.Errorf("answers recorded as %q for %q and %q for %q", h[0].Given, h[0].Item, h[1].Given, h[1].Item)
+	}
+	saved, _, err := ta.Profiles.Load(ta.Profile.Name)
+	if err != nil || len(saved.History) != 2 {
+		t.Errorf("saved profile %v with error %v, want 2 answers", saved, err)
+	}
+
+	ta.press(input.KeyEnter) // again
+	ta.expect(Exercise)
+	ta.press(input.KeyEscape)
+	ta.expect(Results)
+	ta.press(input.KeyEscape)
+	ta.expect(Main)
+	ta.press(input.KeyEscape)
+	if !ta.Done() {
+		t.Error("Escape on the main menu did not quit")
+	}
+	if w := ta.Warnings(); len(w) != 0 {
+		t.Errorf("warnings %v", w)
+	}
+}
+
+func TestMenuClick(t *testing.T) {
+	ta := newTestApp(t)
+	_, s := ta.Screen()
+	r := s.(*MainMenu).ItemRect(3)
+	ta.step(input.Frame{MousePressed: []input.MouseButton{input.MouseLeft}, Mouse: input.Point{X: r.X + 5, Y: r.Y + 5}})
+	ta.expect(Settings)
+
+	// Round length cycles 20, 30, unlimited
+	ta.press(input.KeyEnter)
+	ta.press(input.KeyEnter)
+	if ta.Questions != 0 {
+		t.Errorf("%d questions per round, want unlimited", ta.Questions)
+	}
+	ta.press(input.KeyEscape)
+	ta.expect(Main)
+}
+
+func TestExerciseThatCannotStartWarns(t *testing.T) {
+	ta := newTestApp(t)
+	ta.ReadingOptions.Lowest, ta.ReadingOptions.Highest = 4, 2
+	ta.press(input.KeyEnter)
+	ta.press(input.KeyEnter)
+	ta.expect(Main)
+
+	if w := ta.Warnings(); len(w) != 1 {
+		t.Fatalf("warnings %v, want one about the range", w)
+	}
+	ta.now = ta.now.Add(WarningTime)
+	if w := ta.Warnings(); len(w) != 0 {
+		t.Errorf("warnings %v still shown after %v", w, WarningTime)
+	}
+}
@@ -0,0 +1,332 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gehoer/musicfont"
+)
+
+// AnchorPrefix marks SVG elements that define an anchor rather than an
+// outline, e.g. <circle id="anchor-stemUpSE" cx="10" cy="4" r="1"/>.
+const AnchorPrefix = "anchor-"
+
+// Document holds the outlines and anchors read from an SVG file, in user units
+type Document struct {
+	Subpaths []Subpath
+	Anchors  map[string]Point
+}
+
+// matrix is an affine transform [a b c d e f] as used by the SVG transform attribute
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m·n, i.e. n is applied first
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(p Point) Point {
+	return Point{m[0]*p.X + m[2]*p.Y + m[4], m[1]*p.X + m[3]*p.Y + m[5]}
+}
+
+// parseTransform parses a transform list such as "translate(10,5) scale(2)"
+func parseTransform(s string) (matrix, error) {
+	m := identity
+	s = strings.TrimSpace(s)
+	for s != "" {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m, fmt.Errorf("malformed transform %q", s)
+		}
+		name := strings.TrimSpace(s[:open])
+		args, err := parseNumberList(s[open+1 : end])
+		if err != nil {
+			return m, err
+		}
+		s = strings.TrimLeft(s[end+1:], " ,\t\n\r")
+
+		var t matrix
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(t[:], args)
+		case name == "translate" && len(args) == 1:
+			t = matrix{1, 0, 0, 1, args[0], 0}
+		case name == "translate" && len(args) == 2:
+			t = matrix{1, 0, 0, 1, args[0], args[1]}
+		case name == "scale" && len(args) == 1:
+			t = matrix{args[0], 0, 0, args[0], 0, 0}
+		case name == "scale" && len(args) == 2:
+			t = matrix{args[0], 0, 0, args[1], 0, 0}
+		case name == "rotate" && (len(args) == 1 || len(args) == 3):
+			a := args[0] * math.Pi / 180
+			t = matrix{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0}
+			if len(args) == 3 {
+				cx, cy := args[1], args[2]
+				t = matrix{1, 0, 0, 1, cx, cy}.mul(t).mul(matrix{1, 0, 0, 1, -cx, -cy})
+			}
+		case name == "skewX" && len(args) == 1:
+			t = matrix{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(args) == 1:
+			t = matrix{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return m, fmt.Errorf("unsupported transform %s with %d arguments", name, len(args))
+		}
+		m = m.mul(t)
+	}
+	return m, nil
+}
+
+func parseNumberList(s string) ([]float64, error) {
+	sc := &pathScanner{d: s}
+	var out []float64
+	for {
+		sc.skipSeparators()
+		if sc.pos >= len(sc.d) {
+			return out, nil
+		}
+		v, err := sc.number()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+}
+
+// transform maps every curve of the subpaths through m
+func (m matrix) transform(subpaths []Subpath) []Subpath {
+	for i := range subpaths {
+		for j, c := range subpaths[i].Curves {
+			subpaths[i].Curves[j] = BezierCurve{
+				Start:    m.apply(c.Start),
+				Control1: m.apply(c.Control1),
+				Control2: m.apply(c.Control2),
+				End:      m.apply(c.End),
+			}
+		}
+	}
+	return subpaths
+}
+
+// skippedElements hold definitions that are not rendered directly
+var skippedElements = map[string]bool{
+	"defs": true, "clipPath": true, "mask": true, "symbol": true,
+	"marker": true, "pattern": true, "metadata": true, "title": true, "desc": true,
+}
+
+// paint is the fill an element inherits and passes on to its children
+type paint struct {
+	none    bool // fill="none"
+	evenOdd bool // fill-rule="evenodd"
+}
+
+// with returns the paint of an element from its fill and fill-rule
+// attributes or style declarations
+func (p paint) with(attrs map[string]string) paint {
+	props := map[string]string{"fill": attrs["fill"], "fill-rule": attrs["fill-rule"]}
+	for _, decl := range strings.Split(attrs["style"], ";") {
+		if name, value, ok := strings.Cut(decl, ":"); ok {
+			props[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	switch props["fill"] {
+	case "", "inherit":
+	case "none", "transparent":
+		p.none = true
+	default:
+		p.none = false
+	}
+	switch props["fill-rule"] {
+	case "nonzero":
+		p.evenOdd = false
+	case "evenodd":
+		p.evenOdd = true
+	}
+	return p
+}
+
+func (p paint) rule() musicfont.FillRule {
+	switch {
+	case p.none:
+		return musicfont.FillNone
+	case p.evenOdd:
+		return musicfont.FillEvenOdd
+	}
+	return musicfont.FillNonZero
+}
+
+// Parse reads the drawable outlines of an SVG document. Supported elements
+// are path, rect, circle, ellipse, line, polyline and polygon, nested in
+// groups with transforms. The fill and fill-rule properties are kept so that
+// shapes can be filled; other styling is ignored.
+func Parse(r io.Reader) (*Document, error) {
+	doc := &Document{Anchors: make(map[string]Point)}
+	dec := xml.NewDecoder(r)
+
+	stack := []matrix{identity}
+	paints := []paint{{}}
+	skipDepth := 0
+	shapes := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SVG: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || skippedElements[t.Name.Local] {
+				skipDepth++
+				continue
+			}
+			attrs := attrMap(t.Attr)
+			m := stack[len(stack)-1]
+			if tr, ok := attrs["transform"]; ok {
+				local, err := parseTransform(tr)
+				if err != nil {
+					return nil, err
+				}
+				m = m.mul(local)
+			}
+			stack = append(stack, m)
+			paints = append(paints, paints[len(paints)-1].with(attrs))
+
+			subpaths, err := shapeSubpaths(t.Name.Local, attrs)
+			if err != nil {
+				return nil, fmt.Errorf("<%s>: %w", t.Name.Local, err)
+			}
+			if len(subpaths) == 0 {
+				continue
+			}
+			subpaths = m.transform(subpaths)
+
+			if id := attrs["id"]; strings.HasPrefix(id, AnchorPrefix) {
+				minP, maxP := bounds(subpaths, 8)
+				doc.Anchors[strings.TrimPrefix(id, AnchorPrefix)] = Point{(minP.X + maxP.X) / 2, (minP.Y + maxP.Y) / 2}
+				continue
+			}
+			for i := range subpaths {
+				subpaths[i].Fill, subpaths[i].Shape = paints[len(paints)-1].rule(), shapes
+			}
+			shapes++
+			doc.Subpaths = append(doc.Subpaths, subpaths...)
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+				paints = paints[:len(paints)-1]
+			}
+		}
+	}
+	return doc, nil
+}
+
+func attrMap(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		m[a.Name.Local] = a.Value
+	}
+	return m
+}
+
+// shapeSubpaths converts a basic shape element to path curves in its own user space
+func shapeSubpaths(element string, attrs map[string]string) ([]Subpath, error) {
+	num := func(name string) float64 {
+		v, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(attrs[name]), "px"), 64)
+		return v
+	}
+
+	switch element {
+	case "path":
+		return ParsePath(attrs["d"])
+
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		if w <= 0 || h <= 0 {
+			return nil, nil
+		}
+		return polygon([]Point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}, true), nil
+
+	case "circle":
+		return ellipse(num("cx"), num("cy"), num("r"), num("r")), nil
+
+	case "ellipse":
+		return ellipse(num("cx"), num("cy"), num("rx"), num("ry")), nil
+
+	case "line":
+		return polygon([]Point{{num("x1"), num("y1")}, {num("x2"), num("y2")}}, false), nil
+
+	case "polyline", "polygon":
+		vals, err := parseNumberList(attrs["points"])
+		if err != nil {
+			return nil, err
+		}
+		pts := make([]Point, 0, len(vals)/2)
+		for i := 0; i+1 < len(vals); i += 2 {
+			pts = append(pts, Point{vals[i], vals[i+1]})
+		}
+		return polygon(pts, element == "polygon"), nil
+	}
+	return nil, nil
+}
+
+func polygon(pts []Point, closed bool) []Subpath {
+	if len(pts) < 2 {
+		return nil
+	}
+	sp := Subpath{Closed: closed}
+	for i := 1; i < len(pts); i++ {
+		sp.Curves = append(sp.Curves, lineCurve(pts[i-1], pts[i]))
+	}
+	if closed && pts[0] != pts[len(pts)-1] {
+		sp.Curves = append(sp.Curves, lineCurve(pts[len(pts)-1], pts[0]))
+	}
+	return []Subpath{sp}
+}
+
+func ellipse(cx, cy, rx, ry float64) []Subpath {
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+	left, right := Point{cx - rx, cy}, Point{cx + rx, cy}
+	sp := Subpath{Closed: true}
+	sp.Curves = append(sp.Curves, arcCurves(left, right, rx, ry, 0, false, false)...)
+	sp.Curves = append(sp.Curves, arcCurves(right, left, rx, ry, 0, false, false)...)
+	return []Subpath{sp}
+}
+
+// bounds returns the extent of the flattened subpaths
+func bounds(subpaths []Subpath, steps int) (minP, maxP Point) {
+	minP = Point{math.Inf(1), math.Inf(1)}
+	maxP = Point{math.Inf(-1), math.Inf(-1)}
+	for _, sp := range subpaths {
+		for _, p := range sp.Flatten(steps) {
+			minP.X = math.Min(minP.X, p.X)
+			minP.Y = math.Min(minP.Y, p.Y)
+			maxP.X = math.Max(maxP.X, p.X)
+			maxP.Y = math.Max(maxP.Y, p.Y)
+		}
+	}
+	return minP, maxP
+}
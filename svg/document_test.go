@@ -0,0 +1,164 @@
+package svg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gehoer/musicfont"
+)
+
+func TestParseTransform(t *testing.T) {
+	tests := []struct {
+		transform string
+		in, want  Point
+	}{
+		{"", Point{1, 2}, Point{1, 2}},
+		{"translate(10,5)", Point{1, 1}, Point{11, 6}},
+		{"translate(10)", Point{1, 1}, Point{11, 1}},
+		{"scale(2)", Point{1, 3}, Point{2, 6}},
+		{"scale(2 3)", Point{1, 1}, Point{2, 3}},
+		{"rotate(90)", Point{1, 0}, Point{0, 1}},
+		{"rotate(90 10 10)", Point{10, 0}, Point{20, 10}},
+		{"skewX(45)", Point{0, 1}, Point{1, 1}},
+		{"skewY(45)", Point{1, 0}, Point{1, 1}},
+		{"matrix(1 0 0 1 5 6)", Point{1, 1}, Point{6, 7}},
+		{"matrix(0,1,-1,0,0,0)", Point{1, 0}, Point{0, 1}},
+		// The rightmost transform applies first
+		{"translate(10,0) scale(2)", Point{1, 1}, Point{12, 2}},
+		{"scale(2), translate(10,0)", Point{1, 1}, Point{22, 2}},
+	}
+	for _, tt := range tests {
+		m, err := parseTransform(tt.transform)
+		if err != nil {
+			t.Errorf("%q: %v", tt.transform, err)
+			continue
+		}
+		if got := m.apply(tt.in); !near(got, tt.want, epsilon) {
+			t.Errorf("%q maps %v to %v, want %v", tt.transform, tt.in, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"rotate(1,2)", "translate 10", "skew(1)", "scale(a)", "matrix(1 2 3)"} {
+		if _, err := parseTransform(s); err == nil {
+			t.Errorf("%q parsed, want an error", s)
+		}
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg">
+  <defs><rect width="5" height="5"/></defs>
+  <g transform="translate(10,0)" fill-rule="evenodd">
+    <rect x="0" y="0" width="2" height="4"/>
+    <circle id="anchor-stemUpSE" cx="1" cy="1" r="0.5"/>
+    <g transform="scale(2)"><line x1="0" y1="0" x2="1" y2="0" style="fill: none"/></g>
+  </g>
+  <polygon points="0,0 1,0 1,1"/>
+  <rect width="0" height="3"/>
+</svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		min, max Point
+		fill     musicfont.FillRule
+		shape    int
+		closed   bool
+	}{
+		{Point{10, 0}, Point{12, 4}, musicfont.FillEvenOdd, 0, true},
+		{Point{10, 0}, Point{12, 0}, musicfont.FillNone, 1, false},
+		{Point{0, 0}, Point{1, 1}, musicfont.FillNonZero, 2, true},
+	}
+	if len(doc.Subpaths) != len(want) {
+		t.Fatalf("%d subpaths, want %d", len(doc.Subpaths), len(want))
+	}
+	for i, w := range want {
+		sp := doc.Subpaths[i]
+		minP, maxP := bounds([]Subpath{sp}, 8)
+		if !near(minP, w.min, epsilon) || !near(maxP, w.max, epsilon) {
+			t.Errorf("subpath %d spans %v to %v, want %v to %v", i, minP, maxP, w.min, w.max)
+		}
+		if sp.Fill != w.fill || sp.Shape != w.shape || sp.Closed != w.closed {
+			t.Errorf("subpath %d has fill %v, shape %d, closed %v; want %v, %d, %v", i, sp.Fill, sp.Shape, sp.Closed, w.fill, w.shape, w.closed)
+		}
+	}
+
+	if len(doc.Anchors) != 1 || !near(doc.Anchors["stemUpSE"], Point{11, 1}, 1e-6) {
+		t.Errorf("anchors %v, want stemUpSE at (11, 1)", doc.Anchors)
+	}
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	for _, src := range []string{
+		`<svg><path d="M0 0 X"/></svg>`,
+		`<svg><g transform="spin(1)"><rect width="1" height="1"/></g></svg>`,
+		`<svg><polygon points="0,0 a"/></svg>`,
+		`<svg><rect`,
+	} {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("%s parsed, want an error", src)
+		}
+	}
+}
+
+func TestToCustomGlyph(t *testing.T) {
+	g, err := ImportPathData("square", "M10 10 h20 v20 h-20 z", ImportOptions{Height: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.BBox.SW != [2]float64{0, -1} || g.BBox.NE != [2]float64{2, 1} {
+		t.Errorf("bounding box %v, want 2 staff spaces centred on the baseline", g.BBox)
+	}
+	// y is flipped: the top edge of the SVG square is the top of the glyph
+	if first := g.Contours[0].Points[0]; first != [2]float64{0, 1} {
+		t.Errorf("first point %v, want (0, 1)", first)
+	}
+	if g.Anchors["stemUpSE"] != [2]float64{2, 0} || g.Anchors["stemDownNW"] != [2]float64{0, 0} {
+		t.Errorf("anchors %v, want the stems at the edges on the baseline", g.Anchors)
+	}
+
+	if _, err := ImportPathData("empty", "", DefaultImportOptions()); err == nil {
+		t.Error("glyph without outlines imported")
+	}
+}
+
+func TestRegisterDirSkipsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.svg":     `<svg><rect width="1" height="1"/></svg>`,
+		"b.svg":     `<svg><rect width="1" height="1"`,
+		"c.svg":     `<svg></svg>`,
+		"d.svg":     `<svg><circle r="1"/></svg>`,
+		"a.SVG":     `<svg><rect width="2" height="1"/></svg>`, // a second "a"
+		"notes.txt": `not a glyph`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mf := &musicfont.MusicFont{}
+	skipped, err := RegisterDir(mf, dir, DefaultImportOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mf.CustomGlyphs) != 2 || mf.CustomGlyphs["a"] == nil || mf.CustomGlyphs["d"] == nil {
+		t.Errorf("registered %v, want a and d", mf.CustomGlyphs)
+	}
+	if len(skipped) != 3 {
+		t.Fatalf("skipped %v, want the malformed, empty and duplicate glyphs", skipped)
+	}
+	for i, want := range []string{"b.svg", `"c"`, `"a" is already registered`} {
+		if !strings.Contains(skipped[i].Error(), want) {
+			t.Errorf("skipped %d: %v, want it to mention %s", i, skipped[i], want)
+		}
+	}
+
+	if _, err := RegisterDir(mf, filepath.Join(dir, "missing"), DefaultImportOptions()); err == nil {
+		t.Error("missing directory registered")
+	}
+}
@@ -0,0 +1,161 @@
+package svg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gehoer/musicfont"
+)
+
+// ImportOptions controls how SVG user units are mapped to staff spaces
+type ImportOptions struct {
+	// Height of the imported glyph in staff spaces. A notehead is one staff space tall.
+	Height float64
+	// CurveSteps is the number of line segments each Bézier curve is flattened into
+	CurveSteps int
+}
+
+// DefaultImportOptions sizes glyphs like a notehead
+func DefaultImportOptions() ImportOptions {
+	return ImportOptions{Height: 1.0, CurveSteps: 16}
+}
+
+// ToCustomGlyph normalises the document into a custom glyph. The outline is
+// scaled to opts.Height staff spaces, flipped to y-up and placed with its left
+// edge at x = 0 and its vertical centre on the baseline, like a SMuFL notehead.
+//
+// Anchors defined in the SVG are converted the same way. Missing stemUpSE and
+// stemDownNW anchors default to the right and left edges on the baseline so
+// that shapes can stand in for noteheads.
+func (d *Document) ToCustomGlyph(name string, opts ImportOptions) (*musicfont.CustomGlyph, error) {
+	if len(d.Subpaths) == 0 {
+		return nil, fmt.Errorf("svg glyph %q has no outlines", name)
+	}
+	if opts.Height <= 0 {
+		opts.Height = DefaultImportOptions().Height
+	}
+	if opts.CurveSteps <= 0 {
+		opts.CurveSteps = DefaultImportOptions().CurveSteps
+	}
+
+	minP, maxP := bounds(d.Subpaths, opts.CurveSteps)
+	rawHeight := maxP.Y - minP.Y
+	if rawHeight <= 0 {
+		// Horizontal strokes only; size them by width instead
+		rawHeight = maxP.X - minP.X
+	}
+	if rawHeight <= 0 {
+		return nil, fmt.Errorf("svg glyph %q has an empty bounding box", name)
+	}
+	scale := opts.Height / rawHeight
+	midY := (minP.Y + maxP.Y) / 2
+
+	toStaffSpaces := func(p Point) [2]float64 {
+		return [2]float64{(p.X - minP.X) * scale, (midY - p.Y) * scale}
+	}
+
+	glyph := &musicfont.CustomGlyph{
+		Name: name,
+		BBox: musicfont.GlyphBBox{
+			SW: [2]float64{0, (midY - maxP.Y) * scale},
+			NE: [2]float64{(maxP.X - minP.X) * scale, (midY - minP.Y) * scale},
+		},
+		Anchors: make(map[string][2]float64),
+	}
+
+	for _, sp := range d.Subpaths {
+		pts := sp.Flatten(opts.CurveSteps)
+		contour := musicfont.Contour{Points: make([][2]float64, len(pts)), Closed: sp.Closed, Fill: sp.Fill, Shape: sp.Shape}
+		for i, p := range pts {
+			contour.Points[i] = toStaffSpaces(p)
+		}
+		glyph.Contours = append(glyph.Contours, contour)
+	}
+
+	for anchorName, p := range d.Anchors {
+		glyph.Anchors[anchorName] = toStaffSpaces(p)
+	}
+	if _, ok := glyph.Anchors["stemUpSE"]; !ok {
+		glyph.Anchors["stemUpSE"] = [2]float64{glyph.BBox.NE[0], 0}
+	}
+	if _, ok := glyph.Anchors["stemDownNW"]; !ok {
+		glyph.Anchors["stemDownNW"] = [2]float64{0, 0}
+	}
+
+	return glyph, nil
+}
+
+// ImportPathData imports a bare SVG path ("d" attribute) as a custom glyph
+func ImportPathData(name, d string, opts ImportOptions) (*musicfont.CustomGlyph, error) {
+	subpaths, err := ParsePath(d)
+	if err != nil {
+		return nil, fmt.Errorf("svg glyph %q: %w", name, err)
+	}
+	doc := &Document{Subpaths: subpaths}
+	return doc.ToCustomGlyph(name, opts)
+}
+
+// ImportFile imports an SVG file as a custom glyph named after the file
+// (without extension), e.g. "figurenotesC.svg" becomes "figurenotesC".
+func ImportFile(path string, opts ImportOptions) (*musicfont.CustomGlyph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open svg glyph: %w", err)
+	}
+	defer f.Close()
+
+	doc, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return doc.ToCustomGlyph(name, opts)
+}
+
+// ImportDir imports every .svg file in dir, sorted by name. Files that
+// cannot be imported are skipped and their errors returned in skipped; err
+// is set only when the directory itself cannot be read.
+func ImportDir(dir string, opts ImportOptions) (glyphs []*musicfont.CustomGlyph, skipped []error, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read svg glyph directory: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".svg") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	glyphs = make([]*musicfont.CustomGlyph, 0, len(names))
+	for _, n := range names {
+		g, err := ImportFile(filepath.Join(dir, n), opts)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs, skipped, nil
+}
+
+// RegisterDir imports every .svg file in dir and registers it with the
+// font. Glyphs that cannot be imported or registered are skipped, as in
+// ImportDir.
+func RegisterDir(mf *musicfont.MusicFont, dir string, opts ImportOptions) (skipped []error, err error) {
+	glyphs, skipped, err := ImportDir(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range glyphs {
+		if err := mf.RegisterCustomGlyph(g); err != nil {
+			skipped = append(skipped, err)
+		}
+	}
+	return skipped, nil
+}
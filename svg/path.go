@@ -0,0 +1,429 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gehoer/musicfont"
+)
+
+// Point is a 2D point in SVG user units (y grows downwards)
+type Point struct{ X, Y float64 }
+
+// BezierCurve is a cubic Bézier segment. Straight lines are stored as
+// degenerate curves whose control points sit on the end points.
+type BezierCurve struct {
+	Start, Control1, Control2, End Point
+}
+
+// Subpath is a run of curves started by a moveto command
+type Subpath struct {
+	Curves []BezierCurve
+	Closed bool
+	Fill   musicfont.FillRule
+	Shape  int // index of the element the subpath came from
+}
+
+// lineCurve returns a straight segment as a degenerate cubic curve
+func lineCurve(a, b Point) BezierCurve {
+	return BezierCurve{Start: a, Control1: a, Control2: b, End: b}
+}
+
+// pathScanner reads commands, numbers and flags from path data
+type pathScanner struct {
+	d   string
+	pos int
+}
+
+func (s *pathScanner) skipSeparators() {
+	for s.pos < len(s.d) {
+		c := s.d[s.pos]
+		if c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			s.pos++
+			continue
+		}
+		break
+	}
+}
+
+func (s *pathScanner) number() (float64, error) {
+	s.skipSeparators()
+	start := s.pos
+	if s.pos < len(s.d) && (s.d[s.pos] == '-' || s.d[s.pos] == '+') {
+		s.pos++
+	}
+	seenDot, seenDigit := false, false
+scan:
+	for s.pos < len(s.d) {
+		c := s.d[s.pos]
+		switch {
+		case c >= '0' && c <= '9':
+			seenDigit = true
+		case c == '.' && !seenDot:
+			seenDot = true
+		case (c == 'e' || c == 'E') && seenDigit:
+			// Exponent, optionally signed
+			if s.pos+1 < len(s.d) && (s.d[s.pos+1] == '-' || s.d[s.pos+1] == '+') {
+				s.pos++
+			}
+			seenDot = true // no fraction after an exponent
+		default:
+			break scan
+		}
+		s.pos++
+	}
+	if !seenDigit {
+		return 0, fmt.Errorf("expected number at offset %d", start)
+	}
+	return strconv.ParseFloat(s.d[start:s.pos], 64)
+}
+
+// flag reads an arc flag, which may be written without separators ("011")
+func (s *pathScanner) flag() (bool, error) {
+	s.skipSeparators()
+	if s.pos < len(s.d) {
+		switch s.d[s.pos] {
+		case '0':
+			s.pos++
+			return false, nil
+		case '1':
+			s.pos++
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("expected arc flag at offset %d", s.pos)
+}
+
+func (s *pathScanner) numbers(n int) ([]float64, error) {
+	out := make([]float64, n)
+	for i := range out {
+		v, err := s.number()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// ParsePath parses SVG path data ("d" attribute) into cubic Bézier subpaths.
+// Lines and quadratic curves are promoted to cubics and elliptical arcs are
+// approximated with one cubic per quarter turn.
+func ParsePath(d string) ([]Subpath, error) {
+	s := &pathScanner{d: d}
+	var subpaths []Subpath
+	var curr *Subpath
+	var current, start, lastCP Point
+	var lastCmd byte
+
+	ensure := func() {
+		if curr == nil {
+			subpaths = append(subpaths, Subpath{})
+			curr = &subpaths[len(subpaths)-1]
+		}
+	}
+	add := func(c BezierCurve) {
+		ensure()
+		curr.Curves = append(curr.Curves, c)
+	}
+
+	for {
+		s.skipSeparators()
+		if s.pos >= len(s.d) {
+			break
+		}
+		cmd := s.d[s.pos]
+		if (cmd >= 'a' && cmd <= 'z') || (cmd >= 'A' && cmd <= 'Z') {
+			s.pos++
+		} else if lastCmd != 0 && lastCmd != 'Z' && lastCmd != 'z' {
+			// Implicit repetition of the previous command
+			cmd = lastCmd
+			if cmd == 'M' {
+				cmd = 'L'
+			} else if cmd == 'm' {
+				cmd = 'l'
+			}
+		} else {
+			return nil, fmt.Errorf("unexpected %q at offset %d", cmd, s.pos)
+		}
+		isRel := cmd >= 'a' && cmd <= 'z'
+		rel := func(x, y float64) Point {
+			if isRel {
+				return Point{current.X + x, current.Y + y}
+			}
+			return Point{x, y}
+		}
+
+		switch cmd {
+		case 'M', 'm':
+			v, err := s.numbers(2)
+			if err != nil {
+				return nil, err
+			}
+			current = rel(v[0], v[1])
+			start = current
+			subpaths = append(subpaths, Subpath{})
+			curr = &subpaths[len(subpaths)-1]
+
+		case 'L', 'l':
+			v, err := s.numbers(2)
+			if err != nil {
+				return nil, err
+			}
+			next := rel(v[0], v[1])
+			add(lineCurve(current, next))
+			current = next
+
+		case 'H', 'h':
+			x, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			next := Point{x, current.Y}
+			if isRel {
+				next.X = current.X + x
+			}
+			add(lineCurve(current, next))
+			current = next
+
+		case 'V', 'v':
+			y, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			next := Point{current.X, y}
+			if isRel {
+				next.Y = current.Y + y
+			}
+			add(lineCurve(current, next))
+			current = next
+
+		case 'C', 'c':
+			v, err := s.numbers(6)
+			if err != nil {
+				return nil, err
+			}
+			p1, p2, p3 := rel(v[0], v[1]), rel(v[2], v[3]), rel(v[4], v[5])
+			add(BezierCurve{Start: current, Control1: p1, Control2: p2, End: p3})
+			current = p3
+			lastCP = p2
+
+		case 'S', 's':
+			v, err := s.numbers(4)
+			if err != nil {
+				return nil, err
+			}
+			reflect := current
+			if strings.IndexByte("CcSs", lastCmd) >= 0 {
+				reflect = Point{2*current.X - lastCP.X, 2*current.Y - lastCP.Y}
+			}
+			p2, p3 := rel(v[0], v[1]), rel(v[2], v[3])
+			add(BezierCurve{Start: current, Control1: reflect, Control2: p2, End: p3})
+			current = p3
+			lastCP = p2
+
+		case 'Q', 'q':
+			v, err := s.numbers(4)
+			if err != nil {
+				return nil, err
+			}
+			q, p3 := rel(v[0], v[1]), rel(v[2], v[3])
+			add(quadCurve(current, q, p3))
+			current = p3
+			lastCP = q
+
+		case 'T', 't':
+			v, err := s.numbers(2)
+			if err != nil {
+				return nil, err
+			}
+			q := current
+			if strings.IndexByte("QqTt", lastCmd) >= 0 {
+				q = Point{2*current.X - lastCP.X, 2*current.Y - lastCP.Y}
+			}
+			p3 := rel(v[0], v[1])
+			add(quadCurve(current, q, p3))
+			current = p3
+			lastCP = q
+
+		case 'A', 'a':
+			r, err := s.numbers(3)
+			if err != nil {
+				return nil, err
+			}
+			large, err := s.flag()
+			if err != nil {
+				return nil, err
+			}
+			sweep, err := s.flag()
+			if err != nil {
+				return nil, err
+			}
+			v, err := s.numbers(2)
+			if err != nil {
+				return nil, err
+			}
+			end := rel(v[0], v[1])
+			for _, c := range arcCurves(current, end, r[0], r[1], r[2], large, sweep) {
+				add(c)
+			}
+			current = end
+
+		case 'Z', 'z':
+			ensure()
+			if current != start {
+				add(lineCurve(current, start))
+			}
+			curr.Closed = true
+			current = start
+			curr = nil
+
+		default:
+			return nil, fmt.Errorf("unsupported path command %q", cmd)
+		}
+		lastCmd = cmd
+	}
+
+	// Drop empty subpaths left by consecutive movetos
+	out := subpaths[:0]
+	for _, sp := range subpaths {
+		if len(sp.Curves) > 0 {
+			out = append(out, sp)
+		}
+	}
+	return out, nil
+}
+
+// quadCurve promotes a quadratic Bézier to a cubic one
+func quadCurve(p0, q, p3 Point) BezierCurve {
+	return BezierCurve{
+		Start:    p0,
+		Control1: Point{p0.X + 2.0/3.0*(q.X-p0.X), p0.Y + 2.0/3.0*(q.Y-p0.Y)},
+		Control2: Point{p3.X + 2.0/3.0*(q.X-p3.X), p3.Y + 2.0/3.0*(q.Y-p3.Y)},
+		End:      p3,
+	}
+}
+
+// arcCurves converts an SVG endpoint arc to cubic curves (SVG 1.1 appendix F.6)
+func arcCurves(p0, p1 Point, rx, ry, rotationDeg float64, large, sweep bool) []BezierCurve {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || p0 == p1 {
+		return []BezierCurve{lineCurve(p0, p1)}
+	}
+	phi := rotationDeg * math.Pi / 180
+	cosPhi, sinPhi := math.Cos(phi), math.Sin(phi)
+
+	dx, dy := (p0.X-p1.X)/2, (p0.Y-p1.Y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	// Scale up radii that are too small to reach the end point
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx *= math.Sqrt(l)
+		ry *= math.Sqrt(l)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+	cx := cosPhi*cx1 - sinPhi*cy1 + (p0.X+p1.X)/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + (p0.Y+p1.Y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	segments := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(segments)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+
+	point := func(t float64) (Point, Point) {
+		cosT, sinT := math.Cos(t), math.Sin(t)
+		p := Point{
+			cx + rx*cosT*cosPhi - ry*sinT*sinPhi,
+			cy + rx*cosT*sinPhi + ry*sinT*cosPhi,
+		}
+		// Derivative with respect to t
+		d := Point{
+			-rx*sinT*cosPhi - ry*cosT*sinPhi,
+			-rx*sinT*sinPhi + ry*cosT*cosPhi,
+		}
+		return p, d
+	}
+
+	curves := make([]BezierCurve, 0, segments)
+	from := p0
+	for i := 0; i < segments; i++ {
+		t0 := theta + float64(i)*step
+		t1 := t0 + step
+		_, d0 := point(t0)
+		to, d1 := point(t1)
+		if i == segments-1 {
+			to = p1
+		}
+		curves = append(curves, BezierCurve{
+			Start:    from,
+			Control1: Point{from.X + k*d0.X, from.Y + k*d0.Y},
+			Control2: Point{to.X - k*d1.X, to.Y - k*d1.Y},
+			End:      to,
+		})
+		from = to
+	}
+	return curves
+}
+
+func cubicBezier(p0, p1, p2, p3 Point, t float64) Point {
+	u := 1 - t
+	tt := t * t
+	uu := u * u
+	uuu := uu * u
+	ttt := tt * t
+	x := uuu*p0.X + 3*uu*t*p1.X + 3*u*tt*p2.X + ttt*p3.X
+	y := uuu*p0.Y + 3*uu*t*p1.Y + 3*u*tt*p2.Y + ttt*p3.Y
+	return Point{x, y}
+}
+
+// Flatten samples the curve into steps line segments, returning steps+1 points.
+// Straight segments are returned as their two end points.
+func (c BezierCurve) Flatten(steps int) []Point {
+	if c.Control1 == c.Start && c.Control2 == c.End {
+		return []Point{c.Start, c.End}
+	}
+	if steps < 1 {
+		steps = 1
+	}
+	pts := make([]Point, 0, steps+1)
+	pts = append(pts, c.Start)
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		pts = append(pts, cubicBezier(c.Start, c.Control1, c.Control2, c.End, t))
+	}
+	return pts
+}
+
+// Flatten converts a subpath to a polyline
+func (sp Subpath) Flatten(steps int) []Point {
+	var pts []Point
+	for i, c := range sp.Curves {
+		seg := c.Flatten(steps)
+		if i > 0 {
+			seg = seg[1:] // shared with previous curve's end
+		}
+		pts = append(pts, seg...)
+	}
+	return pts
+}
@@ -0,0 +1,252 @@
+package svg
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func near(a, b Point, tolerance float64) bool {
+	return math.Abs(a.X-b.X) <= tolerance && math.Abs(a.Y-b.Y) <= tolerance
+}
+
+// ends returns the end points of each subpath's curves, starting with the
+// first curve's start
+func ends(subpaths []Subpath) [][]Point {
+	var out [][]Point
+	for _, sp := range subpaths {
+		pts := []Point{sp.Curves[0].Start}
+		for _, c := range sp.Curves {
+			pts = append(pts, c.End)
+		}
+		out = append(out, pts)
+	}
+	return out
+}
+
+func TestParsePathCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		d      string
+		ends   [][]Point
+		closed []bool
+	}{
+		{"absolute lines", "M0 0 L10 0 L10 10 Z", [][]Point{{{0, 0}, {10, 0}, {10, 10}, {0, 0}}}, []bool{true}},
+		{"relative lines", "m1 1 l2 0 h3 v4 z", [][]Point{{{1, 1}, {3, 1}, {6, 1}, {6, 5}, {1, 1}}}, []bool{true}},
+		{"absolute H and V", "M1 1 H5 V7", [][]Point{{{1, 1}, {5, 1}, {5, 7}}}, []bool{false}},
+		{"moveto repeats as lineto", "M0 0 10 0 10 10", [][]Point{{{0, 0}, {10, 0}, {10, 10}}}, []bool{false}},
+		{"relative moveto repeats as relative lineto", "m1 1 2 0 0 2", [][]Point{{{1, 1}, {3, 1}, {3, 3}}}, []bool{false}},
+		{"compact numbers", "M-1-2.5.5,1e1", [][]Point{{{-1, -2.5}, {0.5, 10}}}, []bool{false}},
+		{"closed at the start point adds no line", "M0 0 L4 0 L0 0 Z", [][]Point{{{0, 0}, {4, 0}, {0, 0}}}, []bool{true}},
+		{
+			"two subpaths, empty moveto dropped",
+			"M0 0 L1 0 Z M5 5 M6 6 l1 1",
+			[][]Point{{{0, 0}, {1, 0}, {0, 0}}, {{6, 6}, {7, 7}}},
+			[]bool{true, false},
+		},
+		{"drawing after closepath starts at the start point", "M2 2 L4 2 Z L2 4", [][]Point{{{2, 2}, {4, 2}, {2, 2}}, {{2, 2}, {2, 4}}}, []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subpaths, err := ParsePath(tt.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ends(subpaths)
+			if len(got) != len(tt.ends) {
+				t.Fatalf("%d subpaths %v, want %d", len(got), got, len(tt.ends))
+			}
+			for i := range got {
+				if len(got[i]) != len(tt.ends[i]) {
+					t.Fatalf("subpath %d ends at %v, want %v", i, got[i], tt.ends[i])
+				}
+				for j := range got[i] {
+					if !near(got[i][j], tt.ends[i][j], epsilon) {
+						t.Fatalf("subpath %d ends at %v, want %v", i, got[i], tt.ends[i])
+					}
+				}
+				if subpaths[i].Closed != tt.closed[i] {
+					t.Errorf("subpath %d closed %v, want %v", i, subpaths[i].Closed, tt.closed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParsePathCurves(t *testing.T) {
+	tests := []struct {
+		name   string
+		d      string
+		curves []BezierCurve
+	}{
+		{
+			"line as a degenerate cubic", "M0 0 L3 0",
+			[]BezierCurve{{Start: Point{0, 0}, Control1: Point{0, 0}, Control2: Point{3, 0}, End: Point{3, 0}}},
+		},
+		{
+			"relative cubic", "M1 1 c1 0 2 1 2 2",
+			[]BezierCurve{{Start: Point{1, 1}, Control1: Point{2, 1}, Control2: Point{3, 2}, End: Point{3, 3}}},
+		},
+		{
+			"smooth cubic reflects the last control point", "M0 0 C0 1 2 1 2 0 S4 -1 4 0",
+			[]BezierCurve{
+				{Start: Point{0, 0}, Control1: Point{0, 1}, Control2: Point{2, 1}, End: Point{2, 0}},
+				{Start: Point{2, 0}, Control1: Point{2, -1}, Control2: Point{4, -1}, End: Point{4, 0}},
+			},
+		},
+		{
+			"smooth cubic after a line starts at the current point", "M0 0 L2 0 S4 1 4 0",
+			[]BezierCurve{
+				{Start: Point{0, 0}, Control1: Point{0, 0}, Control2: Point{2, 0}, End: Point{2, 0}},
+				{Start: Point{2, 0}, Control1: Point{2, 0}, Control2: Point{4, 1}, End: Point{4, 0}},
+			},
+		},
+		{
+			"quadratic promoted to cubic", "M0 0 Q3 3 6 0",
+			[]BezierCurve{{Start: Point{0, 0}, Control1: Point{2, 2}, Control2: Point{4, 2}, End: Point{6, 0}}},
+		},
+		{
+			"smooth quadratic reflects the control point", "M0 0 Q3 3 6 0 t6 0",
+			[]BezierCurve{
+				{Start: Point{0, 0}, Control1: Point{2, 2}, Control2: Point{4, 2}, End: Point{6, 0}},
+				{Start: Point{6, 0}, Control1: Point{8, -2}, Control2: Point{10, -2}, End: Point{12, 0}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subpaths, err := ParsePath(tt.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(subpaths) != 1 || len(subpaths[0].Curves) != len(tt.curves) {
+				t.Fatalf("got %+v, want one subpath of %+v", subpaths, tt.curves)
+			}
+			for i, c := range subpaths[0].Curves {
+				want := tt.curves[i]
+				if !near(c.Start, want.Start, epsilon) || !near(c.Control1, want.Control1, epsilon) ||
+					!near(c.Control2, want.Control2, epsilon) || !near(c.End, want.End, epsilon) {
+					t.Errorf("curve %d is %+v, want %+v", i, c, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, d := range []string{
+		"M1",                  // missing coordinate
+		"M0 0 X1 2",           // unknown command
+		"1 2",                 // numbers before any command
+		"M0 0 Z 1 2",          // no implicit command after closepath
+		"M0 0 A1 1 0 2 0 1 1", // arc flag other than 0 or 1
+		"M0 0 L1 -",           // sign without digits
+	} {
+		if subpaths, err := ParsePath(d); err == nil {
+			t.Errorf("%q parsed as %+v, want an error", d, subpaths)
+		}
+	}
+}
+
+// onCircle checks that the flattened curves stay on the circle of the given
+// centre and radius, and end where they should
+func onCircle(t *testing.T, curves []BezierCurve, centre Point, r float64, from, to Point) {
+	t.Helper()
+	if !near(curves[0].Start, from, epsilon) || !near(curves[len(curves)-1].End, to, epsilon) {
+		t.Errorf("arc runs from %v to %v, want %v to %v", curves[0].Start, curves[len(curves)-1].End, from, to)
+	}
+	for _, c := range curves {
+		for _, p := range c.Flatten(8) {
+			// A quarter turn per cubic is within 0.03 % of the radius
+			if d := math.Hypot(p.X-centre.X, p.Y-centre.Y); math.Abs(d-r) > 3e-4*r {
+				t.Fatalf("point %v is %g from %v, want %g", p, d, centre, r)
+			}
+		}
+	}
+}
+
+func TestArcCurves(t *testing.T) {
+	from, to := Point{1, 0}, Point{0, 1}
+	tests := []struct {
+		name         string
+		large, sweep bool
+		centre       Point
+		segments     int
+	}{
+		{"small arc, positive sweep", false, true, Point{0, 0}, 1},
+		{"small arc, negative sweep", false, false, Point{1, 1}, 1},
+		{"large arc, positive sweep", true, true, Point{1, 1}, 3},
+		{"large arc, negative sweep", true, false, Point{0, 0}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curves := arcCurves(from, to, 1, 1, 0, tt.large, tt.sweep)
+			if len(curves) != tt.segments {
+				t.Errorf("%d curves, want %d", len(curves), tt.segments)
+			}
+			onCircle(t, curves, tt.centre, 1, from, to)
+		})
+	}
+
+	t.Run("radius too small is scaled up", func(t *testing.T) {
+		curves := arcCurves(Point{0, 0}, Point{2, 0}, 0.1, 0.1, 0, false, true)
+		if len(curves) != 2 {
+			t.Errorf("%d curves for a half circle, want 2", len(curves))
+		}
+		onCircle(t, curves, Point{1, 0}, 1, Point{0, 0}, Point{2, 0})
+	})
+
+	t.Run("rotated ellipse", func(t *testing.T) {
+		// An ellipse 2 wide and 1 tall, turned upright, through its ends
+		curves := arcCurves(Point{0, -2}, Point{0, 2}, 2, 1, 90, false, true)
+		for _, c := range curves {
+			for _, p := range c.Flatten(8) {
+				if v := p.X*p.X + p.Y*p.Y/4; math.Abs(v-1) > 1e-3 {
+					t.Fatalf("point %v is off the ellipse (%g)", p, v)
+				}
+			}
+		}
+	})
+
+	t.Run("zero radius is a line", func(t *testing.T) {
+		curves := arcCurves(Point{0, 0}, Point{3, 4}, 0, 1, 0, false, true)
+		if len(curves) != 1 || curves[0] != lineCurve(Point{0, 0}, Point{3, 4}) {
+			t.Errorf("got %+v, want a straight line", curves)
+		}
+	})
+
+	t.Run("arc command", func(t *testing.T) {
+		subpaths, err := ParsePath("M1 0 a1 1 0 1 1 -1 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subpaths) != 1 || len(subpaths[0].Curves) != 3 {
+			t.Fatalf("got %+v, want three quarter turns", subpaths)
+		}
+		onCircle(t, subpaths[0].Curves, Point{1, 1}, 1, from, to)
+	})
+}
+
+func TestFlatten(t *testing.T) {
+	line := lineCurve(Point{0, 0}, Point{4, 0})
+	if pts := line.Flatten(16); len(pts) != 2 {
+		t.Errorf("line flattened to %d points, want its two ends", len(pts))
+	}
+	curve := quadCurve(Point{0, 0}, Point{2, 2}, Point{4, 0})
+	pts := curve.Flatten(4)
+	if len(pts) != 5 || pts[0] != curve.Start || pts[4] != curve.End {
+		t.Fatalf("curve flattened to %v, want 5 points from start to end", pts)
+	}
+	if !near(pts[2], Point{2, 1}, epsilon) {
+		t.Errorf("curve midpoint %v, want (2, 1)", pts[2])
+	}
+	if pts := curve.Flatten(0); len(pts) != 2 {
+		t.Errorf("curve flattened in 0 steps to %d points, want 2", len(pts))
+	}
+
+	sp := Subpath{Curves: []BezierCurve{line, curve}}
+	if pts := sp.Flatten(4); len(pts) != 6 {
+		t.Errorf("subpath flattened to %d points, want 6 with the shared end once", len(pts))
+	}
+}
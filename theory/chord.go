@@ -0,0 +1,103 @@
+package theory
+
+import "fmt"
+
+// ChordQuality is the kind of a triad or seventh chord
+type ChordQuality int
+
+const (
+	MajorTriad ChordQuality = iota
+	MinorTriad
+	DiminishedTriad
+	AugmentedTriad
+	DominantSeventh
+	MajorSeventh
+	MinorSeventh
+	HalfDiminishedSeventh
+	DiminishedSeventh
+)
+
+// chordQualityNames are the localization terms of the chord qualities
+var chordQualityNames = map[ChordQuality]string{
+	MajorTriad:            "major_triad",
+	MinorTriad:            "minor_triad",
+	DiminishedTriad:       "diminished_triad",
+	AugmentedTriad:        "augmented_triad",
+	DominantSeventh:       "dominant_seventh_chord",
+	MajorSeventh:          "major_seventh_chord",
+	MinorSeventh:          "minor_seventh_chord",
+	HalfDiminishedSeventh: "half_diminished_seventh_chord",
+	DiminishedSeventh:     "diminished_seventh_chord",
+}
+
+// chordIntervals are the intervals of each quality's notes above the root
+var chordIntervals = map[ChordQuality][]Interval{
+	MajorTriad:            intervals("M3", "P5"),
+	MinorTriad:            intervals("m3", "P5"),
+	DiminishedTriad:       intervals("m3", "d5"),
+	AugmentedTriad:        intervals("M3", "A5"),
+	DominantSeventh:       intervals("M3", "P5", "m7"),
+	MajorSeventh:          intervals("M3", "P5", "M7"),
+	MinorSeventh:          intervals("m3", "P5", "m7"),
+	HalfDiminishedSeventh: intervals("m3", "d5", "m7"),
+	DiminishedSeventh:     intervals("m3", "d5", "d7"),
+}
+
+func intervals(names ...string) []Interval {
+	ivs := make([]Interval, len(names))
+	for i, name := range names {
+		ivs[i] = mustParseInterval(name)
+	}
+	return ivs
+}
+
+// Triads and Sevenths list the chord qualities, for menus and exercises
+var (
+	Triads   = []ChordQuality{MajorTriad, MinorTriad, DiminishedTriad, AugmentedTriad}
+	Sevenths = []ChordQuality{DominantSeventh, MajorSeventh, MinorSeventh, HalfDiminishedSeventh, DiminishedSeventh}
+)
+
+// String returns the quality's localization term, such as "minor_triad"
+func (q ChordQuality) String() string {
+	return chordQualityNames[q]
+}
+
+// Size returns the number of notes in a chord of the quality
+func (q ChordQuality) Size() int {
+	return len(chordIntervals[q]) + 1
+}
+
+// Chord is a chord quality built on a root, in root position (Inversion 0)
+// or with its third (1), fifth (2) or seventh (3) in the bass
+type Chord struct {
+	Root      Pitch
+	Quality   ChordQuality
+	Inversion int
+}
+
+// NewChord returns the chord of a quality on a root in an inversion. It
+// fails for unknown qualities and inversions the chord does not have.
+func NewChord(root Pitch, q ChordQuality, inversion int) (Chord, error) {
+	if _, ok := chordIntervals[q]; !ok {
+		return Chord{}, fmt.Errorf("unknown chord quality %d", q)
+	}
+	if inversion < 0 || inversion >= q.Size() {
+		return Chord{}, fmt.Errorf("a %s has no inversion %d", q, inversion)
+	}
+	return Chord{Root: root, Quality: q, Inversion: inversion}, nil
+}
+
+// Pitches returns the notes of the chord in close position from the bass
+// up: the notes below the bass in root position are raised an octave
+func (c Chord) Pitches() []Pitch {
+	tones := []Pitch{c.Root}
+	for _, iv := range chordIntervals[c.Quality] {
+		tones = append(tones, c.Root.Transpose(iv))
+	}
+	octave := Interval{Steps: 7, Semitones: 12}
+	pitches := append([]Pitch(nil), tones[c.Inversion:]...)
+	for _, p := range tones[:c.Inversion] {
+		pitches = append(pitches, p.Transpose(octave))
+	}
+	return pitches
+}
@@ -0,0 +1,203 @@
+package theory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Quality is the quality of an interval
+type Quality int
+
+const (
+	DoublyDiminished Quality = iota
+	Diminished
+	Minor
+	Major
+	Perfect
+	Augmented
+	DoublyAugmented
+)
+
+var qualityNames = map[Quality]string{
+	DoublyDiminished: "doubly_diminished",
+	Diminished:       "diminished",
+	Minor:            "minor",
+	Major:            "major",
+	Perfect:          "perfect",
+	Augmented:        "augmented",
+	DoublyAugmented:  "doubly_augmented",
+}
+
+var qualityAbbreviations = map[Quality]string{
+	DoublyDiminished: "dd",
+	Diminished:       "d",
+	Minor:            "m",
+	Major:            "M",
+	Perfect:          "P",
+	Augmented:        "A",
+	DoublyAugmented:  "AA",
+}
+
+// String returns the quality's name, such as "diminished"
+func (q Quality) String() string {
+	return qualityNames[q]
+}
+
+// Interval is the distance between two spelled pitches: a number of
+// diatonic steps and of semitones, both negative for a descending
+// interval. A major third up is {2, 4}, an augmented fourth {3, 6}, a
+// diminished fifth {4, 6} and a perfect fourth down {-3, -5}.
+type Interval struct {
+	Steps     int
+	Semitones int
+}
+
+// majorSemitones are the semitones of the perfect and major simple
+// intervals from the unison to the seventh
+var majorSemitones = [7]int{0, 2, 4, 5, 7, 9, 11}
+
+// perfectOffsets and majorOffsets are each quality's semitones from the
+// perfect or major interval of the same number
+var (
+	perfectOffsets = map[Quality]int{DoublyDiminished: -2, Diminished: -1, Perfect: 0, Augmented: 1, DoublyAugmented: 2}
+	majorOffsets   = map[Quality]int{DoublyDiminished: -3, Diminished: -2, Minor: -1, Major: 0, Augmented: 1, DoublyAugmented: 2}
+)
+
+// qualityOffsets returns the offsets that apply to intervals of the simple
+// size (0 for a unison)
+func qualityOffsets(simpleSteps int) map[Quality]int {
+	if simpleSteps == 0 || simpleSteps == 3 || simpleSteps == 4 {
+		return perfectOffsets
+	}
+	return majorOffsets
+}
+
+// NewInterval returns the ascending interval of a quality and number (1 for
+// a unison, 8 for an octave, 10 for a tenth). It fails for qualities the
+// number cannot have, such as a major fifth or a diminished unison.
+func NewInterval(q Quality, number int) (Interval, error) {
+	if number < 1 {
+		return Interval{}, fmt.Errorf("invalid interval number %d", number)
+	}
+	steps := number - 1
+	offset, ok := qualityOffsets(steps % 7)[q]
+	if !ok || (steps == 0 && offset < 0) {
+		return Interval{}, fmt.Errorf("a %s cannot be %s", ordinal(number), q)
+	}
+	return Interval{Steps: steps, Semitones: majorSemitones[steps%7] + 12*(steps/7) + offset}, nil
+}
+
+// ordinal writes an interval number for error messages
+func ordinal(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return fmt.Sprintf("%dst", n)
+	case n%10 == 2 && n%100 != 12:
+		return fmt.Sprintf("%dnd", n)
+	case n%10 == 3 && n%100 != 13:
+		return fmt.Sprintf("%drd", n)
+	}
+	return fmt.Sprintf("%dth", n)
+}
+
+// Between returns the interval from one pitch to another, descending when
+// to lies below from
+func Between(from, to Pitch) Interval {
+	return Interval{Steps: to.Diatonic() - from.Diatonic(), Semitones: to.MIDI() - from.MIDI()}
+}
+
+// Descending reports whether the interval goes down. A unison goes down
+// when its semitones do, as C to C flat.
+func (iv Interval) Descending() bool {
+	return iv.Steps < 0 || (iv.Steps == 0 && iv.Semitones < 0)
+}
+
+// Abs returns the interval going up
+func (iv Interval) Abs() Interval {
+	if iv.Descending() {
+		return iv.Negate()
+	}
+	return iv
+}
+
+// Negate returns the interval in the other direction
+func (iv Interval) Negate() Interval {
+	return Interval{Steps: -iv.Steps, Semitones: -iv.Semitones}
+}
+
+// Add returns the sum of two intervals
+func (iv Interval) Add(other Interval) Interval {
+	return Interval{Steps: iv.Steps + other.Steps, Semitones: iv.Semitones + other.Semitones}
+}
+
+// Number returns the interval's size counted the usual way: 1 for a
+// unison, 3 for a third, 8 for an octave, 10 for a tenth
+func (iv Interval) Number() int {
+	return iv.Abs().Steps + 1
+}
+
+// Quality returns the interval's quality. ok is false for intervals beyond
+// doubly augmented or doubly diminished.
+func (iv Interval) Quality() (q Quality, ok bool) {
+	a := iv.Abs()
+	simple := a.Steps % 7
+	offset := a.Semitones - majorSemitones[simple] - 12*(a.Steps/7)
+	for q, o := range qualityOffsets(simple) {
+		if o == offset {
+			return q, true
+		}
+	}
+	return 0, false
+}
+
+// IsCompound reports whether the interval is wider than an octave
+func (iv Interval) IsCompound() bool {
+	return iv.Abs().Steps > 7
+}
+
+// Simple returns the interval reduced by whole octaves to at most an
+// octave, keeping its direction. A fifteenth becomes an octave.
+func (iv Interval) Simple() Interval {
+	a := iv.Abs()
+	for a.Steps > 7 {
+		a.Steps -= 7
+		a.Semitones -= 12
+	}
+	if iv.Descending() {
+		return a.Negate()
+	}
+	return a
+}
+
+// Octaves returns how many octaves Simple takes off
+func (iv Interval) Octaves() int {
+	return (iv.Abs().Steps - iv.Simple().Abs().Steps) / 7
+}
+
+// Inversion returns the inversion of the simple interval: a major third
+// becomes a minor sixth, an augmented fourth a diminished fifth and a
+// unison an octave. The direction is kept.
+func (iv Interval) Inversion() Interval {
+	s := iv.Simple().Abs()
+	inv := Interval{Steps: 7 - s.Steps, Semitones: 12 - s.Semitones}
+	if iv.Descending() {
+		return inv.Negate()
+	}
+	return inv
+}
+
+// String writes the interval in short form, such as "M3", "P5", "d7",
+// "AA4" or "-m3" for a descending minor third
+func (iv Interval) String() string {
+	var b strings.Builder
+	if iv.Descending() {
+		b.WriteString("-")
+	}
+	if q, ok := iv.Quality(); ok {
+		b.WriteString(qualityAbbreviations[q])
+	} else {
+		fmt.Fprintf(&b, "(%+d)", iv.Abs().Semitones)
+	}
+	fmt.Fprintf(&b, "%d", iv.Number())
+	return b.String()
+}
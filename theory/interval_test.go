@@ -0,0 +1,159 @@
+package theory
+
+import "testing"
+
+func TestNewInterval(t *testing.T) {
+	tests := []struct {
+		q      Quality
+		number int
+		want   Interval
+	}{
+		{Perfect, 1, Interval{0, 0}},
+		{Augmented, 1, Interval{0, 1}},
+		{Minor, 2, Interval{1, 1}},
+		{Major, 3, Interval{2, 4}},
+		{Augmented, 4, Interval{3, 6}},
+		{Diminished, 5, Interval{4, 6}},
+		{DoublyDiminished, 5, Interval{4, 5}},
+		{Diminished, 7, Interval{6, 9}},
+		{Diminished, 8, Interval{7, 11}},
+		{Minor, 10, Interval{9, 15}},
+		{DoublyAugmented, 11, Interval{10, 19}},
+		{Perfect, 15, Interval{14, 24}},
+	}
+	for _, tt := range tests {
+		got, err := NewInterval(tt.q, tt.number)
+		if err != nil || got != tt.want {
+			t.Errorf("NewInterval(%v, %d) = %v, %v; want %v", tt.q, tt.number, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []struct {
+		q      Quality
+		number int
+	}{{Major, 5}, {Minor, 4}, {Perfect, 3}, {Diminished, 1}, {Perfect, 0}, {Minor, 11}} {
+		if got, err := NewInterval(bad.q, bad.number); err == nil {
+			t.Errorf("NewInterval(%v, %d) = %v, want an error", bad.q, bad.number, got)
+		}
+	}
+}
+
+func TestIntervalQuality(t *testing.T) {
+	tests := []struct {
+		from, to Pitch
+		want     string
+		quality  Quality
+		ok       bool
+	}{
+		{Pitch{0, 0, 4}, Pitch{0, 0, 4}, "P1", Perfect, true},
+		{Pitch{0, 0, 4}, Pitch{0, 1, 4}, "A1", Augmented, true},
+		{Pitch{0, 0, 4}, Pitch{0, -1, 4}, "-A1", Augmented, true},
+		{Pitch{0, 0, 4}, Pitch{2, 0, 4}, "M3", Major, true},
+		{Pitch{0, 0, 4}, Pitch{2, -1, 4}, "m3", Minor, true},
+		{Pitch{2, 0, 4}, Pitch{0, 0, 4}, "-M3", Major, true},
+		{Pitch{0, 0, 4}, Pitch{3, 1, 4}, "A4", Augmented, true},
+		{Pitch{0, 0, 4}, Pitch{4, -1, 4}, "d5", Diminished, true},
+		{Pitch{0, 0, 4}, Pitch{3, 2, 4}, "AA4", DoublyAugmented, true},
+		{Pitch{0, 0, 4}, Pitch{4, -2, 4}, "dd5", DoublyDiminished, true},
+		{Pitch{0, 1, 4}, Pitch{6, -2, 4}, "dd7", DoublyDiminished, true},
+		{Pitch{0, 1, 4}, Pitch{6, -1, 4}, "d7", Diminished, true},
+		{Pitch{6, 0, 3}, Pitch{3, 0, 4}, "d5", Diminished, true},
+		{Pitch{0, 0, 4}, Pitch{0, 0, 5}, "P8", Perfect, true},
+		{Pitch{0, 0, 4}, Pitch{2, 0, 5}, "M10", Major, true},
+		{Pitch{0, 0, 5}, Pitch{6, -1, 3}, "-M9", Major, true},
+		{Pitch{0, 0, 4}, Pitch{2, 3, 4}, "(+7)3", 0, false},
+	}
+	for _, tt := range tests {
+		iv := Between(tt.from, tt.to)
+		q, ok := iv.Quality()
+		if iv.String() != tt.want || q != tt.quality || ok != tt.ok {
+			t.Errorf("%v to %v is %v (%v, %v), want %s (%v, %v)", tt.from, tt.to, iv, q, ok, tt.want, tt.quality, tt.ok)
+		}
+	}
+}
+
+func TestIntervalCompound(t *testing.T) {
+	tests := []struct {
+		iv       Interval
+		compound bool
+		simple   Interval
+		octaves  int
+	}{
+		{Interval{4, 7}, false, Interval{4, 7}, 0},
+		{Interval{7, 12}, false, Interval{7, 12}, 0},
+		{Interval{8, 13}, true, Interval{1, 1}, 1},
+		{Interval{9, 16}, true, Interval{2, 4}, 1},
+		{Interval{14, 24}, true, Interval{7, 12}, 1},
+		{Interval{16, 28}, true, Interval{2, 4}, 2},
+		{Interval{-9, -15}, true, Interval{-2, -3}, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.iv.IsCompound(); got != tt.compound {
+			t.Errorf("%v compound %v, want %v", tt.iv, got, tt.compound)
+		}
+		if got := tt.iv.Simple(); got != tt.simple {
+			t.Errorf("%v simple %v, want %v", tt.iv, got, tt.simple)
+		}
+		if got := tt.iv.Octaves(); got != tt.octaves {
+			t.Errorf("%v octaves %d, want %d", tt.iv, got, tt.octaves)
+		}
+		if got := tt.iv.Number(); got != tt.iv.Abs().Steps+1 {
+			t.Errorf("%v number %d", tt.iv, got)
+		}
+	}
+}
+
+func TestIntervalInversion(t *testing.T) {
+	tests := []struct {
+		iv, want string
+	}{
+		{"M3", "m6"},
+		{"m3", "M6"},
+		{"A4", "d5"},
+		{"d5", "A4"},
+		{"P5", "P4"},
+		{"M2", "m7"},
+		{"P1", "P8"},
+		{"P8", "P1"},
+		{"M10", "m6"},
+		{"-m3", "-M6"},
+	}
+	for _, tt := range tests {
+		iv := parseTestInterval(t, tt.iv)
+		if got := iv.Inversion().String(); got != tt.want {
+			t.Errorf("%s inverts to %s, want %s", tt.iv, got, tt.want)
+		}
+		if simple := iv.Simple(); simple.Add(iv.Inversion()).Abs() != (Interval{7, 12}) {
+			t.Errorf("%s and its inversion do not make an octave", tt.iv)
+		}
+	}
+}
+
+// parseTestInterval reads the short form written by Interval.String
+func parseTestInterval(t *testing.T, s string) Interval {
+	t.Helper()
+	descending := s[0] == '-'
+	if descending {
+		s = s[1:]
+	}
+	for q, abbr := range qualityAbbreviations {
+		var number int
+		if len(s) > len(abbr) && s[:len(abbr)] == abbr {
+			for _, r := range s[len(abbr):] {
+				if r < '0' || r > '9' {
+					number = 0
+					break
+				}
+				number = number*10 + int(r-'0')
+			}
+			if iv, err := NewInterval(q, number); err == nil {
+				if descending {
+					return iv.Negate()
+				}
+				return iv
+			}
+		}
+	}
+	t.Fatalf("bad interval %q", s)
+	return Interval{}
+}
@@ -0,0 +1,174 @@
+package theory
+
+import (
+	"fmt"
+
+	"gehoer/music"
+)
+
+// Key is a tonic in a mode. Modes have their Norwegian names, as in key
+// signatures: "dur", "moll", "ionisk", "dorisk", "frygisk", "lydisk",
+// "miksisk", "æolisk" and "lokrisk".
+type Key struct {
+	Step  int // tonic step, 0 = C ... 6 = H
+	Alter int // tonic alteration in semitones
+	Mode  string
+}
+
+// modeFifths is how far each mode's key signature lies from that of the
+// major key on the same tonic, in fifths
+var modeFifths = map[string]int{
+	"dur":     0,
+	"ionisk":  0,
+	"moll":    -3,
+	"æolisk":  -3,
+	"dorisk":  -2,
+	"frygisk": -4,
+	"lydisk":  1,
+	"miksisk": -1,
+	"lokrisk": -5,
+}
+
+// stepFifths places the natural steps C D E F G A H on the circle of fifths
+var stepFifths = [7]int{0, 2, 4, -1, 1, 3, 5}
+
+// sharpOrder lists the steps in the order sharps enter a key signature.
+// Flats enter in the reverse order.
+var sharpOrder = [7]int{3, 0, 4, 1, 5, 2, 6} // F C G D A E H
+
+// NewKey returns the key of a tonic in a mode. It fails for unknown modes
+// and for tonics beyond a double sharp or flat.
+func NewKey(step, alter int, mode string) (Key, error) {
+	if _, ok := modeFifths[mode]; !ok {
+		return Key{}, fmt.Errorf("unknown key mode %q", mode)
+	}
+	if step < 0 || step > 6 || alter < -2 || alter > 2 {
+		return Key{}, fmt.Errorf("invalid key tonic step %d alteration %d", step, alter)
+	}
+	return Key{Step: step, Alter: alter, Mode: mode}, nil
+}
+
+// IsMode reports whether the mode name is known
+func IsMode(mode string) bool {
+	_, ok := modeFifths[mode]
+	return ok
+}
+
+// KeyOfFifths returns the key in the mode whose signature has the given
+// number of sharps (or flats, when negative)
+func KeyOfFifths(fifths int, mode string) Key {
+	step, alter := fifthsPitchClass(fifths - modeFifths[mode])
+	return Key{Step: step, Alter: alter, Mode: mode}
+}
+
+// fifthsPitchClass returns the step and alteration at a place on the line
+// of fifths, where C is 0, G 1 and F -1
+func fifthsPitchClass(f int) (step, alter int) {
+	step = ((4*f)%7 + 7) % 7
+	alter = (f + 1) / 7
+	if f+1 < 0 && (f+1)%7 != 0 {
+		alter-- // round towards negative infinity
+	}
+	return step, alter
+}
+
+// Fifths returns the number of sharps (or flats, when negative) in the
+// key signature. Keys such as G sharp major give more than seven.
+func (k Key) Fifths() int {
+	return stepFifths[k.Step] + 7*k.Alter + modeFifths[k.Mode]
+}
+
+// Alterations returns the alteration the key signature gives each step
+func (k Key) Alterations() [7]int {
+	return SignatureAlterations(k.Fifths())
+}
+
+// SignatureAlterations returns the alteration of each step in a key
+// signature with the given number of sharps (or flats, when negative)
+func SignatureAlterations(fifths int) [7]int {
+	var alters [7]int
+	for i := 0; i < fifths; i++ {
+		alters[sharpOrder[i%7]]++
+	}
+	for i := 0; i < -fifths; i++ {
+		alters[sharpOrder[6-i%7]]--
+	}
+	return alters
+}
+
+// AlteredSteps returns the steps the key signature sharpens or flattens, in
+// the order they are written: F C G ... for sharps, H E A ... for flats
+func (k Key) AlteredSteps() []int {
+	fifths := k.Fifths()
+	n := min(abs(fifths), 7)
+	steps := make([]int, n)
+	for i := range steps {
+		if fifths > 0 {
+			steps[i] = sharpOrder[i]
+		} else {
+			steps[i] = sharpOrder[6-i]
+		}
+	}
+	return steps
+}
+
+// Spell returns the spelling of a MIDI pitch in the key. Notes of the key
+// signature keep its spelling; other notes take the spelling closest to it
+// on the line of fifths with at most one sharp or flat, taking sharps in
+// sharp keys and flats in flat keys when two are equally close. In C major
+// that gives C sharp, E flat, F sharp, G sharp and B flat.
+func (k Key) Spell(midi int) Pitch {
+	fifths := k.Fifths()
+	// The signature's notes lie from fifths-1 (F in C major) to fifths+5 (H)
+	low, high := fifths-1, fifths+5
+	distance := func(f int) int {
+		switch {
+		case f < low:
+			return low - f
+		case f > high:
+			return f - high
+		}
+		return 0
+	}
+	pc := ((midi % 12) + 12) % 12
+	best, bestDistance := 0, -1
+	// Spellings of the pitch class lie 12 fifths apart. Notes outside the
+	// signature get at most a single sharp or flat (-8 is F flat, 12 is H
+	// sharp).
+	for f := -15; f <= 19; f++ {
+		if ((7*f)%12+12)%12 != pc || (distance(f) > 0 && (f < -8 || f > 12)) {
+			continue
+		}
+		d := distance(f)
+		if bestDistance < 0 || d < bestDistance || (d == bestDistance && fifths >= 0 && f > best) {
+			best, bestDistance = f, d
+		}
+	}
+	step, alter := fifthsPitchClass(best)
+	p := Pitch{Step: step, Alter: alter}
+	p.Octave = (midi - p.MIDI()) / 12
+	return p
+}
+
+// AccidentalFor returns the accidental a note needs in a measure: none when
+// the key signature or an earlier note on the same line already gives its
+// alteration. barAlters records the accidentals written so far in the
+// measure, by diatonic number.
+func AccidentalFor(diatonic, alter int, keyAlters [7]int, barAlters map[int]int) string {
+	implied, ok := barAlters[diatonic]
+	if !ok {
+		implied = keyAlters[((diatonic%7)+7)%7]
+	}
+	if alter == implied {
+		return music.AccidentalNone
+	}
+	barAlters[diatonic] = alter
+	return music.AlterAccidental(alter)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
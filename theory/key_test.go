@@ -0,0 +1,156 @@
+package theory
+
+import (
+	"reflect"
+	"testing"
+
+	"gehoer/music"
+)
+
+func TestNewKey(t *testing.T) {
+	if k, err := NewKey(4, 0, "dur"); err != nil || k != (Key{4, 0, "dur"}) {
+		t.Errorf("G major: %v, %v", k, err)
+	}
+	for _, bad := range []Key{{0, 0, "blues"}, {7, 0, "dur"}, {-1, 0, "moll"}, {0, 3, "dur"}, {0, -3, "dur"}} {
+		if _, err := NewKey(bad.Step, bad.Alter, bad.Mode); err == nil {
+			t.Errorf("NewKey(%d, %d, %q) gave no error", bad.Step, bad.Alter, bad.Mode)
+		}
+	}
+}
+
+func TestKeyFifths(t *testing.T) {
+	tests := []struct {
+		key    Key
+		fifths int
+	}{
+		{Key{0, 0, "dur"}, 0},
+		{Key{4, 0, "dur"}, 1},
+		{Key{3, 0, "dur"}, -1},
+		{Key{2, -1, "dur"}, -3},
+		{Key{3, 1, "dur"}, 6},
+		{Key{4, -1, "dur"}, -6},
+		{Key{4, 1, "dur"}, 8},
+		{Key{0, -1, "dur"}, -7},
+		{Key{5, 0, "moll"}, 0},
+		{Key{3, 1, "moll"}, 3},
+		{Key{0, 0, "moll"}, -3},
+		{Key{1, 0, "dorisk"}, 0},
+		{Key{2, 0, "frygisk"}, 0},
+		{Key{3, 0, "lydisk"}, 0},
+		{Key{4, 0, "miksisk"}, 0},
+		{Key{6, 0, "lokrisk"}, 0},
+		{Key{0, 0, "lydisk"}, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.key.Fifths(); got != tt.fifths {
+			t.Errorf("%+v has %d fifths, want %d", tt.key, got, tt.fifths)
+		}
+		if got := KeyOfFifths(tt.fifths, tt.key.Mode); tt.fifths >= -7 && tt.fifths <= 7 && got != tt.key {
+			t.Errorf("KeyOfFifths(%d, %q) = %+v, want %+v", tt.fifths, tt.key.Mode, got, tt.key)
+		}
+	}
+}
+
+func TestKeyAlterations(t *testing.T) {
+	tests := []struct {
+		name   string
+		fifths int
+		alters [7]int
+		steps  []int
+	}{
+		{"C major", 0, [7]int{}, []int{}},
+		{"D major", 2, [7]int{1, 0, 0, 1, 0, 0, 0}, []int{3, 0}},
+		{"A major", 3, [7]int{1, 0, 0, 1, 1, 0, 0}, []int{3, 0, 4}},
+		{"F major", -1, [7]int{0, 0, 0, 0, 0, 0, -1}, []int{6}},
+		{"E flat major", -3, [7]int{0, 0, -1, 0, 0, -1, -1}, []int{6, 2, 5}},
+		{"C sharp major", 7, [7]int{1, 1, 1, 1, 1, 1, 1}, []int{3, 0, 4, 1, 5, 2, 6}},
+		{"C flat major", -7, [7]int{-1, -1, -1, -1, -1, -1, -1}, []int{6, 2, 5, 1, 4, 0, 3}},
+		{"G sharp major", 8, [7]int{1, 1, 1, 2, 1, 1, 1}, []int{3, 0, 4, 1, 5, 2, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignatureAlterations(tt.fifths); got != tt.alters {
+				t.Errorf("alterations %v, want %v", got, tt.alters)
+			}
+			k := KeyOfFifths(tt.fifths, "dur")
+			if got := k.Alterations(); got != tt.alters {
+				t.Errorf("%+v alterations %v, want %v", k, got, tt.alters)
+			}
+			if got := k.AlteredSteps(); !reflect.DeepEqual(got, tt.steps) {
+				t.Errorf("altered steps %v, want %v", got, tt.steps)
+			}
+		})
+	}
+}
+
+func TestKeySpell(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+		midi []int
+		want []string
+	}{
+		{
+			"C major prefers the usual chromatic spellings", Key{0, 0, "dur"},
+			[]int{61, 63, 66, 68, 70}, []string{"C#4", "Eb4", "F#4", "G#4", "Bb4"},
+		},
+		{
+			"sharp key takes the sharp on a tie", Key{4, 0, "dur"},
+			[]int{61, 63, 68, 70}, []string{"C#4", "D#4", "G#4", "Bb4"},
+		},
+		{
+			"flat key takes the flat on a tie", Key{3, 0, "dur"},
+			[]int{61, 63, 66, 68}, []string{"Db4", "Eb4", "F#4", "Ab4"},
+		},
+		{
+			"signature notes keep their spelling", Key{3, 1, "dur"},
+			[]int{65, 66, 70, 71}, []string{"E#4", "F#4", "A#4", "B4"},
+		},
+		{
+			"flat signature crosses the octave", Key{0, -1, "dur"},
+			[]int{59, 71, 64, 60}, []string{"Cb4", "Cb5", "Fb4", "C4"},
+		},
+		{
+			"minor key", Key{5, 0, "moll"},
+			[]int{68, 66, 70, 57}, []string{"G#4", "F#4", "Bb4", "A3"},
+		},
+		{
+			"double sharps only from the signature", Key{1, 1, "dur"},
+			[]int{62, 67, 64, 69, 61}, []string{"Cx4", "Fx4", "E4", "A4", "C#4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, midi := range tt.midi {
+				p := tt.key.Spell(midi)
+				if p.String() != tt.want[i] || p.MIDI() != midi {
+					t.Errorf("%d spelled %v (MIDI %d), want %s", midi, p, p.MIDI(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAccidentalFor(t *testing.T) {
+	keyAlters := SignatureAlterations(2) // D major
+	barAlters := map[int]int{}
+	f4, f5, c5 := 31, 38, 35
+	steps := []struct {
+		diatonic, alter int
+		want            string
+	}{
+		{f4, 1, music.AccidentalNone},
+		{f4, 0, music.AccidentalNatural},
+		{f4, 0, music.AccidentalNone},
+		{f5, 1, music.AccidentalNone},
+		{f4, 1, music.AccidentalSharp},
+		{c5, -1, music.AccidentalFlat},
+		{c5, 0, music.AccidentalNatural},
+		{c5, 2, music.AccidentalDoubleSharp},
+	}
+	for i, s := range steps {
+		if got := AccidentalFor(s.diatonic, s.alter, keyAlters, barAlters); got != s.want {
+			t.Errorf("note %d (%d, %+d) got %q, want %q", i, s.diatonic, s.alter, got, s.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package theory
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gehoer/music"
+)
+
+// Pitch is a spelled pitch: a diatonic step with an alteration, in an
+// octave. F sharp and G flat are different pitches with the same MIDI
+// number.
+type Pitch struct {
+	Step   int // 0 = C ... 6 = H
+	Alter  int // semitones, -2 (double flat) to 2 (double sharp)
+	Octave int // scientific octave; C4 is middle C
+}
+
+// stepLetters are the letters of the steps from C, in English for String
+var stepLetters = [7]string{"C", "D", "E", "F", "G", "A", "B"}
+
+// NewPitch returns the pitch with the given diatonic number (octave*7 +
+// step, where C4 is 28) and alteration
+func NewPitch(diatonic, alter int) Pitch {
+	octave := diatonic / 7
+	if diatonic < 0 && diatonic%7 != 0 {
+		octave--
+	}
+	return Pitch{Step: diatonic - octave*7, Alter: alter, Octave: octave}
+}
+
+// NotePitch returns the pitch of a note as written on the staff in the
+// clef. ok is false when the note's MIDI pitch lies more than a double
+// sharp or flat from its staff line.
+func NotePitch(n *music.Note, clef string) (p Pitch, ok bool) {
+	diatonic := music.ClefBottomLine(clef) + n.StaffLine
+	alter := n.Pitch - music.NaturalMIDI(diatonic)
+	return NewPitch(diatonic, alter), alter >= -2 && alter <= 2
+}
+
+// Diatonic returns the diatonic number, octave*7 + step
+func (p Pitch) Diatonic() int {
+	return p.Octave*7 + p.Step
+}
+
+// MIDI returns the MIDI note number
+func (p Pitch) MIDI() int {
+	return music.NaturalMIDI(p.Diatonic()) + p.Alter
+}
+
+// Transpose returns the pitch moved by the interval, spelled by its steps
+func (p Pitch) Transpose(iv Interval) Pitch {
+	diatonic := p.Diatonic() + iv.Steps
+	return NewPitch(diatonic, p.MIDI()+iv.Semitones-music.NaturalMIDI(diatonic))
+}
+
+// concertA is the frequency of A4 (MIDI 69) in hertz
+const concertA = 440.0
+
+// Frequency returns the frequency in hertz of a MIDI pitch moved by a
+// number of cents, in equal temperament
+func Frequency(midi int, cents float64) float64 {
+	return concertA * math.Exp2((float64(midi-69)+cents/100)/12)
+}
+
+// String writes the pitch as in scientific pitch notation, such as C#4,
+// Bb3 or Fx5
+func (p Pitch) String() string {
+	accidental := ""
+	switch {
+	case p.Alter == 2:
+		accidental = "x"
+	case p.Alter > 0:
+		accidental = strings.Repeat("#", p.Alter)
+	case p.Alter < 0:
+		accidental = strings.Repeat("b", -p.Alter)
+	}
+	return fmt.Sprintf("%s%s%d", stepLetters[p.Step], accidental, p.Octave)
+}
@@ -0,0 +1,147 @@
+package theory
+
+// ScaleDegree is a note of a scale: its spelled interval above the tonic
+// and a microtonal offset for scales outside equal temperament
+type ScaleDegree struct {
+	Interval Interval
+	Cents    float64 // added to the equally tempered pitch, 0 for none
+}
+
+// Scale is a pattern of degrees above a tonic. Name is the scale's
+// localization term, and Key the mode whose key signature the scale is
+// written with.
+type Scale struct {
+	Name    string
+	Key     string
+	Degrees []ScaleDegree // ascending, from the tonic, without the octave
+	Down    []ScaleDegree // descending form when it differs, in ascending order
+}
+
+// degrees builds scale degrees from intervals written as quality and
+// number pairs, such as "M3"
+func degrees(names ...string) []ScaleDegree {
+	ds := make([]ScaleDegree, len(names))
+	for i, name := range names {
+		ds[i] = ScaleDegree{Interval: mustParseInterval(name)}
+	}
+	return ds
+}
+
+// mustParseInterval reads the short form of an ascending simple interval
+// written by Interval.String, for the scale tables
+func mustParseInterval(s string) Interval {
+	for q, abbr := range qualityAbbreviations {
+		if len(s) == len(abbr)+1 && s[:len(abbr)] == abbr {
+			iv, err := NewInterval(q, int(s[len(abbr)]-'0'))
+			if err == nil {
+				return iv
+			}
+		}
+	}
+	panic("theory: bad interval " + s)
+}
+
+// withCents returns a copy of the degrees with microtonal offsets on some
+// of them, by index
+func withCents(ds []ScaleDegree, cents map[int]float64) []ScaleDegree {
+	out := append([]ScaleDegree(nil), ds...)
+	for i, c := range cents {
+		out[i].Cents = c
+	}
+	return out
+}
+
+// neutralCents is how far a neutral third or seventh lies from the major
+// one: halfway to the minor
+const neutralCents = -50
+
+var (
+	majorDegrees        = degrees("P1", "M2", "M3", "P4", "P5", "M6", "M7")
+	naturalMinorDegrees = degrees("P1", "M2", "m3", "P4", "P5", "m6", "m7")
+
+	MajorScale         = Scale{Name: "major", Key: "dur", Degrees: majorDegrees}
+	NaturalMinorScale  = Scale{Name: "natural_minor", Key: "moll", Degrees: naturalMinorDegrees}
+	HarmonicMinorScale = Scale{Name: "harmonic_minor", Key: "moll", Degrees: degrees("P1", "M2", "m3", "P4", "P5", "m6", "M7")}
+	// MelodicMinorScale raises the sixth and seventh going up and descends as
+	// the natural minor
+	MelodicMinorScale = Scale{Name: "melodic_minor", Key: "moll", Degrees: degrees("P1", "M2", "m3", "P4", "P5", "M6", "M7"), Down: naturalMinorDegrees}
+
+	IonianScale     = Scale{Name: "ionian", Key: "ionisk", Degrees: majorDegrees}
+	DorianScale     = Scale{Name: "dorian", Key: "dorisk", Degrees: degrees("P1", "M2", "m3", "P4", "P5", "M6", "m7")}
+	PhrygianScale   = Scale{Name: "phrygian", Key: "frygisk", Degrees: degrees("P1", "m2", "m3", "P4", "P5", "m6", "m7")}
+	LydianScale     = Scale{Name: "lydian", Key: "lydisk", Degrees: degrees("P1", "M2", "M3", "A4", "P5", "M6", "M7")}
+	MixolydianScale = Scale{Name: "mixolydian", Key: "miksisk", Degrees: degrees("P1", "M2", "M3", "P4", "P5", "M6", "m7")}
+	AeolianScale    = Scale{Name: "aeolian", Key: "æolisk", Degrees: naturalMinorDegrees}
+	LocrianScale    = Scale{Name: "locrian", Key: "lokrisk", Degrees: degrees("P1", "m2", "m3", "P4", "d5", "m6", "m7")}
+
+	MajorPentatonicScale = Scale{Name: "major_pentatonic", Key: "dur", Degrees: degrees("P1", "M2", "M3", "P5", "M6")}
+	MinorPentatonicScale = Scale{Name: "minor_pentatonic", Key: "moll", Degrees: degrees("P1", "m3", "P4", "P5", "m7")}
+	BluesScale           = Scale{Name: "blues", Key: "moll", Degrees: degrees("P1", "m3", "P4", "A4", "P5", "m7")}
+
+	// NeutralThirdScale is the major scale with the third between major and
+	// minor, as heard in much Norwegian folk music
+	NeutralThirdScale = Scale{Name: "neutral_third", Key: "dur", Degrees: withCents(majorDegrees, map[int]float64{2: neutralCents})}
+	// NorwegianFolkScale also has a neutral seventh and a fourth halfway between
+	// perfect and augmented, as the older folk scale is often described
+	NorwegianFolkScale = Scale{Name: "norwegian_folk", Key: "dur", Degrees: withCents(majorDegrees, map[int]float64{2: neutralCents, 3: -neutralCents, 6: neutralCents})}
+)
+
+// Scales lists the scales, for menus and exercise generators
+var Scales = []Scale{
+	MajorScale, NaturalMinorScale, HarmonicMinorScale, MelodicMinorScale,
+	IonianScale, DorianScale, PhrygianScale, LydianScale, MixolydianScale, AeolianScale, LocrianScale,
+	MajorPentatonicScale, MinorPentatonicScale, BluesScale,
+	NeutralThirdScale, NorwegianFolkScale,
+}
+
+// modeScales maps the Norwegian mode names of key signatures to scales
+var modeScales = map[string]Scale{
+	"dur":     MajorScale,
+	"moll":    NaturalMinorScale,
+	"ionisk":  IonianScale,
+	"dorisk":  DorianScale,
+	"frygisk": PhrygianScale,
+	"lydisk":  LydianScale,
+	"miksisk": MixolydianScale,
+	"æolisk":  AeolianScale,
+	"lokrisk": LocrianScale,
+}
+
+// ModeScale returns the scale of a key signature mode such as "dur",
+// "moll" or "dorisk"
+func ModeScale(mode string) (Scale, bool) {
+	s, ok := modeScales[mode]
+	return s, ok
+}
+
+// Pitches returns the ascending scale from the tonic, without the octave
+func (s Scale) Pitches(tonic Pitch) []Pitch {
+	return scalePitches(s.Degrees, tonic)
+}
+
+// DownPitches returns the notes of the descending form from the tonic,
+// in ascending order and without the octave
+func (s Scale) DownPitches(tonic Pitch) []Pitch {
+	if s.Down == nil {
+		return s.Pitches(tonic)
+	}
+	return scalePitches(s.Down, tonic)
+}
+
+func scalePitches(ds []ScaleDegree, tonic Pitch) []Pitch {
+	pitches := make([]Pitch, len(ds))
+	for i, d := range ds {
+		pitches[i] = tonic.Transpose(d.Interval)
+	}
+	return pitches
+}
+
+// Frequencies returns the ascending scale from the tonic in hertz, with the
+// microtonal offsets applied
+func (s Scale) Frequencies(tonic Pitch) []float64 {
+	freqs := make([]float64, len(s.Degrees))
+	for i, d := range s.Degrees {
+		freqs[i] = Frequency(tonic.Transpose(d.Interval).MIDI(), d.Cents)
+	}
+	return freqs
+}
@@ -0,0 +1,121 @@
+package theory
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrequency(t *testing.T) {
+	tests := []struct {
+		midi  int
+		cents float64
+		want  float64
+	}{
+		{69, 0, 440},
+		{81, 0, 880},
+		{57, 0, 220},
+		{60, 0, 261.6255653},
+		{69, 100, 466.1637615},
+		{69, -50, 427.4740541},
+		{70, -100, 440},
+	}
+	for _, tt := range tests {
+		if got := Frequency(tt.midi, tt.cents); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("Frequency(%d, %g) = %.7f, want %.7f", tt.midi, tt.cents, got, tt.want)
+		}
+	}
+}
+
+func TestScaleCents(t *testing.T) {
+	tonic := Pitch{0, 0, 4}
+	tests := []struct {
+		scale Scale
+		cents []float64
+	}{
+		{MajorScale, []float64{0, 0, 0, 0, 0, 0, 0}},
+		{NeutralThirdScale, []float64{0, 0, -50, 0, 0, 0, 0}},
+		{NorwegianFolkScale, []float64{0, 0, -50, 50, 0, 0, -50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scale.Name, func(t *testing.T) {
+			freqs := tt.scale.Frequencies(tonic)
+			for i, d := range tt.scale.Degrees {
+				if d.Cents != tt.cents[i] {
+					t.Errorf("degree %d has %g cents, want %g", i+1, d.Cents, tt.cents[i])
+				}
+				midi := tonic.Transpose(d.Interval).MIDI()
+				want := 440 * math.Exp2((float64(midi-69)+tt.cents[i]/100)/12)
+				if math.Abs(freqs[i]-want) > 1e-9 {
+					t.Errorf("degree %d at %g Hz, want %g", i+1, freqs[i], want)
+				}
+			}
+		})
+	}
+
+	// The neutral third lies halfway between the minor and major thirds
+	third := NeutralThirdScale.Frequencies(tonic)[2]
+	if want := math.Sqrt(Frequency(63, 0) * Frequency(64, 0)); math.Abs(third-want) > 1e-9 {
+		t.Errorf("neutral third at %g Hz, want %g", third, want)
+	}
+}
+
+func TestScalePitches(t *testing.T) {
+	tests := []struct {
+		name     string
+		scale    Scale
+		tonic    Pitch
+		up, down []string
+	}{
+		{"D major", MajorScale, Pitch{1, 0, 4}, []string{"D4", "E4", "F#4", "G4", "A4", "B4", "C#5"}, nil},
+		{
+			"A melodic minor", MelodicMinorScale, Pitch{5, 0, 3},
+			[]string{"A3", "B3", "C4", "D4", "E4", "F#4", "G#4"},
+			[]string{"A3", "B3", "C4", "D4", "E4", "F4", "G4"},
+		},
+		{"E flat harmonic minor", HarmonicMinorScale, Pitch{2, -1, 4}, []string{"Eb4", "F4", "Gb4", "Ab4", "Bb4", "Cb5", "D5"}, nil},
+		{"B locrian", LocrianScale, Pitch{6, 0, 3}, []string{"B3", "C4", "D4", "E4", "F4", "G4", "A4"}, nil},
+		{"G blues", BluesScale, Pitch{4, 0, 3}, []string{"G3", "Bb3", "C4", "C#4", "D4", "F4"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := tt.down
+			if down == nil {
+				down = tt.up
+			}
+			for _, c := range []struct {
+				pitches []Pitch
+				want    []string
+			}{{tt.scale.Pitches(tt.tonic), tt.up}, {tt.scale.DownPitches(tt.tonic), down}} {
+				if len(c.pitches) != len(c.want) {
+					t.Fatalf("got %v, want %v", c.pitches, c.want)
+				}
+				for i, p := range c.pitches {
+					if p.String() != c.want[i] {
+						t.Errorf("got %v, want %v", c.pitches, c.want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestModeScale(t *testing.T) {
+	for _, mode := range []string{"dur", "moll", "ionisk", "dorisk", "frygisk", "lydisk", "miksisk", "æolisk", "lokrisk"} {
+		s, ok := ModeScale(mode)
+		if !ok || s.Key != mode {
+			t.Errorf("ModeScale(%q) = %v, %v", mode, s.Name, ok)
+		}
+		// The mode's scale on its key's tonic uses only the key signature
+		key := KeyOfFifths(0, mode)
+		tonic := Pitch{key.Step, key.Alter, 4}
+		for _, p := range s.Pitches(tonic) {
+			if p.Alter != 0 {
+				t.Errorf("%s scale on %v has %v", mode, tonic, p)
+			}
+		}
+	}
+	if _, ok := ModeScale("blues"); ok {
+		t.Error("blues is not a mode")
+	}
+}